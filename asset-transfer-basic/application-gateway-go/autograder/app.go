@@ -0,0 +1,274 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"assetTransfer/graderr"
+	"assetTransfer/seal"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// workSubmittedEvent is the chaincode event emitted by SubmitAssignment
+const workSubmittedEvent = "WorkSubmitted"
+
+// submittedWork is the part of the WorkSubmitted event payload the autograder needs.
+type submittedWork struct {
+	ID            string
+	Title         string
+	ClassID       string
+	Work          string
+	TestSuiteHash string
+}
+
+// testSuiteGrader is a Grader whose tests are published on the assignment by content hash.
+type testSuiteGrader interface {
+	Grader
+	TestSuiteHash() string
+}
+
+// transactionSubmitter submits a transaction and waits for it to commit. *client.Contract implements it.
+type transactionSubmitter interface {
+	SubmitTransaction(name string, args ...string) ([]byte, error)
+}
+
+// main listens for WorkSubmitted events and grades each submission whose assignment has a grader configured.
+// Run it from the application-gateway-go directory so the test-network crypto paths resolve, for example:
+//
+//	go run ./autograder -config autograder.json -cert <autograder cert.pem> -keystore <autograder keystore dir>
+//
+// Submissions that fail to grade, or whose grade can not be submitted, are kept in the -retry file and
+// retried every minute, so no event is skipped.
+//
+// Once a class has assignments only its instructor and teaching assistants may grade them, so the autograder
+// must be registered with the class before it runs:
+//
+//  1. Print the autograder's client ID with -client-id, run with the autograder's -cert and -keystore, and
+//     set it as clientID in the configuration file.
+//  2. After creating the assignments, the instructor runs -publish with their own -cert and -keystore. It
+//     makes the autograder a teaching assistant of each configured assignment and publishes the test suite
+//     hashes. Classes that require approval of teaching assistants' grades hold the autograder's grades too.
+func main() {
+	configPath := flag.String("config", "autograder.json", "autograder configuration file")
+	certPath := flag.String("cert", defaultCertPath, "certificate of the autograder identity")
+	keyPath := flag.String("keystore", defaultKeyPath, "private key directory of the autograder identity")
+	checkpointPath := flag.String("checkpoint", "autograder.checkpoint", "file recording the last event processed")
+	retryPath := flag.String("retry", "autograder.retry", "file of submissions that failed to grade, retried every minute")
+	publish := flag.Bool("publish", false, "register the autograder for its assignments, publish their test suite hashes and exit")
+	printClientID := flag.Bool("client-id", false, "print the client ID of the identity and exit")
+	keyDir := flag.String("keys", "class-keys", "directory of class private keys for opening encrypted work")
+	flag.Parse()
+
+	graders, config, err := loadGraders(*configPath)
+	if err != nil {
+		panic(err)
+	}
+
+	clientConnection := newGrpcConnection()
+	defer clientConnection.Close()
+
+	id := newIdentity(*certPath)
+	sign := newSign(*keyPath)
+
+	gw, err := client.Connect(
+		id,
+		client.WithSign(sign),
+		client.WithClientConnection(clientConnection),
+		client.WithEvaluateTimeout(5*time.Second),
+		client.WithEndorseTimeout(15*time.Second),
+		client.WithSubmitTimeout(5*time.Second),
+		client.WithCommitStatusTimeout(1*time.Minute),
+	)
+	if err != nil {
+		panic(err)
+	}
+	defer gw.Close()
+
+	chaincodeName := "basic"
+	if ccname := os.Getenv("CHAINCODE_NAME"); ccname != "" {
+		chaincodeName = ccname
+	}
+
+	channelName := "mychannel"
+	if cname := os.Getenv("CHANNEL_NAME"); cname != "" {
+		channelName = cname
+	}
+
+	network := gw.GetNetwork(channelName)
+	contract := network.GetContract(chaincodeName)
+
+	if *printClientID {
+		clientID, err := contract.EvaluateTransaction("GetClientID")
+		if err != nil {
+			panic(fmt.Errorf("failed to evaluate transaction: %w", err))
+		}
+		fmt.Println(string(clientID))
+		return
+	}
+
+	if *publish {
+		if err := registerAutograder(contract, config); err != nil {
+			panic(err)
+		}
+		if err := publishTestSuites(contract, graders); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	retries, err := openRetryQueue(*retryPath)
+	if err != nil {
+		panic(err)
+	}
+
+	checkpointer, err := client.NewFileCheckpointer(*checkpointPath)
+	if err != nil {
+		panic(err)
+	}
+	defer checkpointer.Close()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	events, err := network.ChaincodeEvents(ctx, chaincodeName, client.WithCheckpoint(checkpointer))
+	if err != nil {
+		panic(fmt.Errorf("failed to start chaincode event listening: %w", err))
+	}
+
+	keyring := seal.NewKeyring(*keyDir)
+	grade := func(payload []byte) error {
+		return gradeSubmission(contract, graders, keyring, payload)
+	}
+
+	fmt.Printf("*** Autograding %d assignments, waiting for submissions\n", len(graders))
+	if err := retries.retry(grade); err != nil {
+		panic(err)
+	}
+	ticker := time.NewTicker(retryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			// A submission that fails to grade is queued for retry before its event is checkpointed, so it
+			// is never skipped
+			if event.EventName == workSubmittedEvent {
+				if err := retries.handle(event.Payload, grade); err != nil {
+					panic(err)
+				}
+			}
+			if err := checkpointer.CheckpointChaincodeEvent(event); err != nil {
+				panic(fmt.Errorf("failed to checkpoint event: %w", err))
+			}
+		case <-ticker.C:
+			if err := retries.retry(grade); err != nil {
+				panic(err)
+			}
+		}
+	}
+}
+
+// gradeSubmission grades the work in a WorkSubmitted event payload and records the result with GradeAssignment.
+// Submissions for assignments without a configured grader are left for the instructor. Encrypted work is
+// opened with the class keys in keyring before grading.
+func gradeSubmission(contract transactionSubmitter, graders map[string]Grader, keyring *seal.Keyring, payload []byte) error {
+	var work submittedWork
+	if err := json.Unmarshal(payload, &work); err != nil {
+		return fmt.Errorf("failed to parse submitted work: %w", err)
+	}
+
+	grader, ok := graders[assignmentKey(work.ClassID, work.Title)]
+	if !ok {
+		return nil
+	}
+
+	if suiteGrader, ok := grader.(testSuiteGrader); ok && suiteGrader.TestSuiteHash() != work.TestSuiteHash {
+		return fmt.Errorf("not grading %s: assignment references test suite %q but the configured suite is %q",
+			work.ID, work.TestSuiteHash, suiteGrader.TestSuiteHash())
+	}
+
+	answer, err := keyring.Open(work.ClassID, work.ID, work.Work)
+	if err != nil {
+		return fmt.Errorf("failed to open work for %s: %w", work.ID, err)
+	}
+
+	score, feedback, err := grader.Grade(answer)
+	if err != nil {
+		return fmt.Errorf("failed to grade %s: %w", work.ID, err)
+	}
+
+	fmt.Printf("\n--> Submit Transaction: GradeAssignment, %s scored %d\n", work.ID, score)
+
+	if _, err := contract.SubmitTransaction("GradeAssignment", work.ID, strconv.Itoa(score), feedback); err != nil {
+		return fmt.Errorf("failed to submit grade for %s: %w", work.ID, err)
+	}
+
+	fmt.Printf("*** Transaction committed successfully\n")
+	return nil
+}
+
+// registerAutograder makes the autograder client a teaching assistant of each class in the configuration and
+// delegates the configured assignments to it. It must be run by the instructor after creating the
+// assignments, and may be run again after adding assignments to the configuration.
+func registerAutograder(contract transactionSubmitter, config *Config) error {
+	if config.ClientID == "" {
+		return fmt.Errorf("the autograder config has no clientID; print it with -client-id using the autograder's identity")
+	}
+
+	registered := map[string]bool{}
+	for _, spec := range config.Assignments {
+		if !registered[spec.Class] {
+			fmt.Printf("\n--> Submit Transaction: AddTA, %s grades for class %s\n", config.ClientID, spec.Class)
+
+			_, err := contract.SubmitTransaction("AddTA", spec.Class, config.ClientID)
+			if err != nil && graderr.Classify(err).Code != graderr.Conflict {
+				return fmt.Errorf("failed to register the autograder for %s: %w", spec.Class, err)
+			}
+			registered[spec.Class] = true
+		}
+
+		fmt.Printf("\n--> Submit Transaction: AssignGrader, the autograder grades %s\n", assignmentKey(spec.Class, spec.Title))
+
+		if _, err := contract.SubmitTransaction("AssignGrader", spec.Class, config.ClientID, spec.Title, "[]"); err != nil {
+			return fmt.Errorf("failed to assign %s to the autograder: %w", assignmentKey(spec.Class, spec.Title), err)
+		}
+
+		fmt.Printf("*** Transaction committed successfully\n")
+	}
+	return nil
+}
+
+// publishTestSuites records the content hash of each configured test suite on its assignment with
+// SetTestSuiteHash. It must be run by the instructor before students submit.
+func publishTestSuites(contract transactionSubmitter, graders map[string]Grader) error {
+	for key, grader := range graders {
+		suiteGrader, ok := grader.(testSuiteGrader)
+		if !ok {
+			continue
+		}
+		class, title, _ := strings.Cut(key, "/")
+
+		fmt.Printf("\n--> Submit Transaction: SetTestSuiteHash, %s uses test suite %s\n", key, suiteGrader.TestSuiteHash())
+
+		if _, err := contract.SubmitTransaction("SetTestSuiteHash", class, title, suiteGrader.TestSuiteHash()); err != nil {
+			return fmt.Errorf("failed to publish test suite for %s: %w", key, err)
+		}
+
+		fmt.Printf("*** Transaction committed successfully\n")
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"assetTransfer/seal"
+)
+
+type fakeSubmitter struct {
+	calls [][]string
+	err   error
+}
+
+func (f *fakeSubmitter) SubmitTransaction(name string, args ...string) ([]byte, error) {
+	f.calls = append(f.calls, append([]string{name}, args...))
+	return nil, f.err
+}
+
+func TestGradeSubmission(t *testing.T) {
+	graders := map[string]Grader{
+		assignmentKey("cs101", "hw1"): &ExactMatchGrader{Answer: "Paris", Points: 100},
+	}
+
+	submitter := &fakeSubmitter{}
+	payload := []byte(`{"ID":"hw1alice","Title":"hw1","ClassID":"cs101","Work":"Paris"}`)
+	if err := gradeSubmission(submitter, graders, seal.NewKeyring(t.TempDir()), payload); err != nil {
+		t.Fatalf("failed to grade submission: %v", err)
+	}
+	expected := [][]string{{"GradeAssignment", "hw1alice", "100", "Correct"}}
+	if !reflect.DeepEqual(expected, submitter.calls) {
+		t.Errorf("expected %v, got %v", expected, submitter.calls)
+	}
+
+	submitter = &fakeSubmitter{}
+	payload = []byte(`{"ID":"essayalice","Title":"essay","ClassID":"cs101","Work":"..."}`)
+	if err := gradeSubmission(submitter, graders, seal.NewKeyring(t.TempDir()), payload); err != nil {
+		t.Fatalf("unexpected error for ungraded assignment: %v", err)
+	}
+	if len(submitter.calls) != 0 {
+		t.Errorf("expected assignment without a grader to be left alone, got %v", submitter.calls)
+	}
+
+	submitter = &fakeSubmitter{err: errors.New("endorsement failed")}
+	payload = []byte(`{"ID":"hw1bob","Title":"hw1","ClassID":"cs101","Work":"London"}`)
+	if err := gradeSubmission(submitter, graders, seal.NewKeyring(t.TempDir()), payload); err == nil {
+		t.Error("expected submit error to be returned")
+	}
+
+	if err := gradeSubmission(submitter, graders, seal.NewKeyring(t.TempDir()), []byte("not json")); err == nil {
+		t.Error("expected error for malformed payload")
+	}
+}
+
+func TestGradeSubmissionChecksTestSuiteHash(t *testing.T) {
+	grader, err := loadGoProgramGrader("wordcount.tests.json", testLimits)
+	if err != nil {
+		t.Fatalf("failed to load test suite: %v", err)
+	}
+	graders := map[string]Grader{assignmentKey("cs101", "wordcount"): grader}
+
+	submitter := &fakeSubmitter{}
+	payload := []byte(`{"ID":"wordcountalice","Title":"wordcount","ClassID":"cs101","Work":"package main","TestSuiteHash":"stale"}`)
+	if err := gradeSubmission(submitter, graders, seal.NewKeyring(t.TempDir()), payload); err == nil {
+		t.Error("expected mismatched test suite hash to be rejected")
+	}
+	if len(submitter.calls) != 0 {
+		t.Errorf("expected no grade to be submitted, got %v", submitter.calls)
+	}
+
+	if err := publishTestSuites(submitter, graders); err != nil {
+		t.Fatalf("failed to publish test suites: %v", err)
+	}
+	expected := [][]string{{"SetTestSuiteHash", "cs101", "wordcount", grader.TestSuiteHash()}}
+	if !reflect.DeepEqual(expected, submitter.calls) {
+		t.Errorf("expected %v, got %v", expected, submitter.calls)
+	}
+}
+
+func TestRegisterAutograder(t *testing.T) {
+	config := &Config{ClientID: "x509::CN=autograder", Assignments: []GraderSpec{
+		{Class: "cs101", Title: "hw1"},
+		{Class: "cs101", Title: "hw2"},
+		{Class: "cs102", Title: "hw1"},
+	}}
+
+	submitter := &fakeSubmitter{}
+	if err := registerAutograder(submitter, config); err != nil {
+		t.Fatalf("failed to register autograder: %v", err)
+	}
+	expected := [][]string{
+		{"AddTA", "cs101", "x509::CN=autograder"},
+		{"AssignGrader", "cs101", "x509::CN=autograder", "hw1", "[]"},
+		{"AssignGrader", "cs101", "x509::CN=autograder", "hw2", "[]"},
+		{"AddTA", "cs102", "x509::CN=autograder"},
+		{"AssignGrader", "cs102", "x509::CN=autograder", "hw1", "[]"},
+	}
+	if !reflect.DeepEqual(expected, submitter.calls) {
+		t.Errorf("expected %v, got %v", expected, submitter.calls)
+	}
+
+	if err := registerAutograder(&fakeSubmitter{}, &Config{Assignments: config.Assignments}); err == nil {
+		t.Error("expected a config without a client ID to be rejected")
+	}
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// GraderSpec configures the grader for one assignment in the autograder configuration file.
+type GraderSpec struct {
+	Class         string  `json:"class"`
+	Title         string  `json:"title"`
+	Type          string  `json:"type"`
+	Answer        string  `json:"answer"`
+	Tolerance     float64 `json:"tolerance"`
+	Points        int     `json:"points"`
+	CaseSensitive bool    `json:"caseSensitive"`
+
+	// Tests, CPUSeconds, MemoryMB and TimeoutSeconds configure the "go" grader, which runs a submitted
+	// program against the test suite file named by Tests, relative to the configuration file.
+	Tests          string `json:"tests"`
+	CPUSeconds     int    `json:"cpuSeconds"`
+	MemoryMB       int    `json:"memoryMB"`
+	TimeoutSeconds int    `json:"timeoutSeconds"`
+}
+
+// Default resource limits for each run of a submitted program
+const (
+	defaultCPUSeconds     = 2
+	defaultMemoryMB       = 256
+	defaultTimeoutSeconds = 10
+)
+
+// Config is the autograder configuration file, listing the assignments it is responsible for.
+type Config struct {
+	// ClientID is the client ID of the autograder identity, as printed with -client-id. Publishing the test
+	// suites makes it a teaching assistant of each configured assignment, so its grades are accepted.
+	ClientID    string       `json:"clientID"`
+	Assignments []GraderSpec `json:"assignments"`
+}
+
+// assignmentKey identifies an assignment by class and title.
+func assignmentKey(class string, title string) string {
+	return class + "/" + title
+}
+
+// loadConfig reads the configuration file.
+func loadConfig(filename string) (*Config, error) {
+	configJSON, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read autograder config: %w", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse autograder config: %w", err)
+	}
+	return &config, nil
+}
+
+// loadGraders reads the configuration file and builds a grader for each assignment in it, returning them
+// with the configuration.
+func loadGraders(filename string) (map[string]Grader, *Config, error) {
+	config, err := loadConfig(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	graders := make(map[string]Grader, len(config.Assignments))
+	for _, spec := range config.Assignments {
+		grader, err := newGrader(spec, filepath.Dir(filename))
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid grader for %s: %w", assignmentKey(spec.Class, spec.Title), err)
+		}
+		graders[assignmentKey(spec.Class, spec.Title)] = grader
+	}
+	return graders, config, nil
+}
+
+// newGrader creates the built-in grader named by the spec type. Relative test suite paths are resolved
+// against baseDir.
+func newGrader(spec GraderSpec, baseDir string) (Grader, error) {
+	switch spec.Type {
+	case "exact":
+		return &ExactMatchGrader{Answer: spec.Answer, Points: spec.Points, CaseSensitive: spec.CaseSensitive}, nil
+	case "regex":
+		pattern, err := regexp.Compile(spec.Answer)
+		if err != nil {
+			return nil, err
+		}
+		return &RegexGrader{Pattern: pattern, Points: spec.Points}, nil
+	case "numeric":
+		answer, err := strconv.ParseFloat(spec.Answer, 64)
+		if err != nil {
+			return nil, err
+		}
+		return &NumericToleranceGrader{Answer: answer, Tolerance: spec.Tolerance, Points: spec.Points}, nil
+	case "go":
+		tests := spec.Tests
+		if !filepath.IsAbs(tests) {
+			tests = filepath.Join(baseDir, tests)
+		}
+		limits := Limits{
+			CPUTime:  time.Duration(orDefault(spec.CPUSeconds, defaultCPUSeconds)) * time.Second,
+			WallTime: time.Duration(orDefault(spec.TimeoutSeconds, defaultTimeoutSeconds)) * time.Second,
+			MemoryMB: orDefault(spec.MemoryMB, defaultMemoryMB),
+		}
+		return loadGoProgramGrader(tests, limits)
+	default:
+		return nil, fmt.Errorf("unknown grader type %q", spec.Type)
+	}
+}
+
+func orDefault(value int, defaultValue int) int {
+	if value <= 0 {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Grader grades the work submitted for an assignment, returning the score awarded and feedback for the student.
+// An error means the work could not be graded at all and should be left for the instructor.
+type Grader interface {
+	Grade(work string) (score int, feedback string, err error)
+}
+
+// ExactMatchGrader awards full points when the work matches the expected answer, ignoring surrounding whitespace.
+type ExactMatchGrader struct {
+	Answer        string
+	Points        int
+	CaseSensitive bool
+}
+
+// Grade implements Grader.
+func (g *ExactMatchGrader) Grade(work string) (int, string, error) {
+	answer, got := strings.TrimSpace(g.Answer), strings.TrimSpace(work)
+	if g.CaseSensitive && answer == got || !g.CaseSensitive && strings.EqualFold(answer, got) {
+		return g.Points, "Correct", nil
+	}
+	return 0, "Incorrect answer", nil
+}
+
+// RegexGrader awards full points when the work matches a regular expression.
+type RegexGrader struct {
+	Pattern *regexp.Regexp
+	Points  int
+}
+
+// Grade implements Grader.
+func (g *RegexGrader) Grade(work string) (int, string, error) {
+	if g.Pattern.MatchString(strings.TrimSpace(work)) {
+		return g.Points, "Correct", nil
+	}
+	return 0, "Answer does not have the expected form", nil
+}
+
+// NumericToleranceGrader awards full points when the work is a number within Tolerance of the expected answer.
+type NumericToleranceGrader struct {
+	Answer    float64
+	Tolerance float64
+	Points    int
+}
+
+// Grade implements Grader.
+func (g *NumericToleranceGrader) Grade(work string) (int, string, error) {
+	value, err := strconv.ParseFloat(strings.TrimSpace(work), 64)
+	if err != nil {
+		return 0, fmt.Sprintf("Answer %q is not a number", strings.TrimSpace(work)), nil
+	}
+	if math.Abs(value-g.Answer) <= g.Tolerance {
+		return g.Points, "Correct", nil
+	}
+	return 0, fmt.Sprintf("Answer %g is outside the accepted tolerance", value), nil
+}
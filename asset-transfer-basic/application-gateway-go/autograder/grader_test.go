@@ -0,0 +1,78 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"testing"
+)
+
+func TestBuiltInGraders(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  GraderSpec
+		work  string
+		score int
+	}{
+		{"exact match", GraderSpec{Type: "exact", Answer: "Paris", Points: 10}, " paris\n", 10},
+		{"exact mismatch", GraderSpec{Type: "exact", Answer: "Paris", Points: 10}, "London", 0},
+		{"exact case sensitive", GraderSpec{Type: "exact", Answer: "Paris", Points: 10, CaseSensitive: true}, "paris", 0},
+		{"regex match", GraderSpec{Type: "regex", Answer: `^O\(n ?log ?n\)$`, Points: 5}, "O(n log n)", 5},
+		{"regex mismatch", GraderSpec{Type: "regex", Answer: `^O\(n ?log ?n\)$`, Points: 5}, "O(n^2)", 0},
+		{"numeric within tolerance", GraderSpec{Type: "numeric", Answer: "3.14159", Tolerance: 0.001, Points: 7}, "3.1413", 7},
+		{"numeric outside tolerance", GraderSpec{Type: "numeric", Answer: "3.14159", Tolerance: 0.001, Points: 7}, "3.2", 0},
+		{"numeric not a number", GraderSpec{Type: "numeric", Answer: "3.14159", Tolerance: 0.001, Points: 7}, "pi", 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			grader, err := newGrader(test.spec, ".")
+			if err != nil {
+				t.Fatalf("failed to create grader: %v", err)
+			}
+			score, feedback, err := grader.Grade(test.work)
+			if err != nil {
+				t.Fatalf("failed to grade: %v", err)
+			}
+			if score != test.score {
+				t.Errorf("expected score %d, got %d", test.score, score)
+			}
+			if feedback == "" {
+				t.Error("expected feedback")
+			}
+		})
+	}
+}
+
+func TestNewGraderRejectsInvalidSpecs(t *testing.T) {
+	for _, spec := range []GraderSpec{
+		{Type: "essay"},
+		{Type: "regex", Answer: "("},
+		{Type: "numeric", Answer: "pi"},
+	} {
+		if _, err := newGrader(spec, "."); err == nil {
+			t.Errorf("expected error for %+v", spec)
+		}
+	}
+}
+
+func TestLoadGraders(t *testing.T) {
+	graders, config, err := loadGraders("autograder.example.json")
+	if err != nil {
+		t.Fatalf("failed to load example config: %v", err)
+	}
+	if config.ClientID == "" {
+		t.Error("expected the example config to name the autograder client")
+	}
+	for _, title := range []string{"hw1", "hw2", "hw3", "wordcount"} {
+		if _, ok := graders[assignmentKey("cs101", title)]; !ok {
+			t.Errorf("missing grader for %s", title)
+		}
+	}
+
+	if _, _, err := loadGraders("missing.json"); err == nil {
+		t.Error("expected error for missing config")
+	}
+}
@@ -0,0 +1,156 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// retryInterval is how often submissions that failed to grade are retried.
+const retryInterval = time.Minute
+
+// retryQueue is a file of WorkSubmitted payloads the autograder failed to grade or to record a grade for.
+// Queueing a failed submission lets its event be checkpointed without losing it. A later submission of the
+// same assignment replaces the queued one, so a retry never grades work that has since been resubmitted.
+//
+// The file holds one payload per line, oldest first, and is rewritten in full, through a temporary file,
+// on every change.
+type retryQueue struct {
+	path     string
+	payloads []json.RawMessage
+}
+
+// openRetryQueue returns the retry queue stored at path, which is created when the first submission fails.
+func openRetryQueue(path string) (*retryQueue, error) {
+	q := &retryQueue{path: path}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read retry queue: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, len(content)+1)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		if _, err := submissionID(scanner.Bytes()); err != nil {
+			return nil, fmt.Errorf("malformed retry entry on line %d of %s: %w", line, path, err)
+		}
+		q.payloads = append(q.payloads, append(json.RawMessage(nil), scanner.Bytes()...))
+	}
+	return q, scanner.Err()
+}
+
+// handle grades a WorkSubmitted payload with grade, queueing it for retry if grading fails and dropping
+// any earlier payload queued for the same assignment. It returns an error only if the queue could not be
+// saved, in which case the event must not be checkpointed.
+func (q *retryQueue) handle(payload []byte, grade func(payload []byte) error) error {
+	id, err := submissionID(payload)
+	if err != nil {
+		fmt.Printf("!!! %v\n", err)
+		return nil
+	}
+
+	err = grade(payload)
+	if err != nil {
+		fmt.Printf("!!! %v; will retry\n", err)
+	}
+	return q.replace(id, payload, err != nil)
+}
+
+// retry grades each queued payload again, oldest first, and removes those that succeed.
+func (q *retryQueue) retry(grade func(payload []byte) error) error {
+	if len(q.payloads) == 0 {
+		return nil
+	}
+
+	var failed []json.RawMessage
+	for _, payload := range q.payloads {
+		if err := grade(payload); err != nil {
+			fmt.Printf("!!! %v; will retry\n", err)
+			failed = append(failed, payload)
+		}
+	}
+	if len(failed) == len(q.payloads) {
+		return nil
+	}
+	q.payloads = failed
+	return q.save()
+}
+
+// replace removes the payload queued for the assignment id and, if queue is set, appends payload.
+func (q *retryQueue) replace(id string, payload []byte, queue bool) error {
+	var kept []json.RawMessage
+	for _, queued := range q.payloads {
+		if queuedID, _ := submissionID(queued); queuedID != id {
+			kept = append(kept, queued)
+		}
+	}
+	if !queue && len(kept) == len(q.payloads) {
+		return nil
+	}
+	if queue {
+		kept = append(kept, append(json.RawMessage(nil), payload...))
+	}
+	q.payloads = kept
+	return q.save()
+}
+
+// save writes the payloads to a temporary file and renames it over the queue, so a crash never leaves a
+// partly written queue. The file holds students' work, so only the current user may read it.
+func (q *retryQueue) save() error {
+	var content bytes.Buffer
+	for _, payload := range q.payloads {
+		content.Write(payload)
+		content.WriteByte('\n')
+	}
+
+	dir := filepath.Dir(q.path)
+	temp, err := os.CreateTemp(dir, filepath.Base(q.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save retry queue: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	_, err = temp.Write(content.Bytes())
+	if err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), q.path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save retry queue: %w", err)
+	}
+	return nil
+}
+
+// submissionID returns the ID of the assignment a WorkSubmitted payload is for.
+func submissionID(payload []byte) (string, error) {
+	var work submittedWork
+	if err := json.Unmarshal(payload, &work); err != nil {
+		return "", fmt.Errorf("failed to parse submitted work: %w", err)
+	}
+	if work.ID == "" {
+		return "", errors.New("submitted work has no assignment ID")
+	}
+	return work.ID, nil
+}
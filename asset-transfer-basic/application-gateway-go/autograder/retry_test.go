@@ -0,0 +1,72 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestRetryQueue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "autograder.retry")
+	retries, err := openRetryQueue(path)
+	if err != nil {
+		t.Fatalf("failed to open retry queue: %v", err)
+	}
+
+	var graded []string
+	failing := func(payload []byte) error {
+		graded = append(graded, string(payload))
+		return errors.New("endorsement failed")
+	}
+	first := []byte(`{"ID":"hw1alice","Work":"London"}`)
+	second := []byte(`{"ID":"hw1bob","Work":"Paris"}`)
+	if err := retries.handle(first, failing); err != nil {
+		t.Fatalf("failed to queue submission: %v", err)
+	}
+	if err := retries.handle(second, failing); err != nil {
+		t.Fatalf("failed to queue submission: %v", err)
+	}
+	if err := retries.handle([]byte("not json"), failing); err != nil {
+		t.Fatalf("expected a malformed payload to be dropped, got %v", err)
+	}
+	if len(graded) != 2 {
+		t.Errorf("expected only well-formed payloads to be graded, got %v", graded)
+	}
+
+	// A resubmission replaces the queued failure, and the queue survives a restart
+	resubmitted := []byte(`{"ID":"hw1alice","Work":"Paris"}`)
+	if err := retries.handle(resubmitted, failing); err != nil {
+		t.Fatalf("failed to queue submission: %v", err)
+	}
+	retries, err = openRetryQueue(path)
+	if err != nil {
+		t.Fatalf("failed to reopen retry queue: %v", err)
+	}
+
+	graded = nil
+	succeeding := func(payload []byte) error {
+		graded = append(graded, string(payload))
+		return nil
+	}
+	if err := retries.retry(succeeding); err != nil {
+		t.Fatalf("failed to retry: %v", err)
+	}
+	expected := []string{string(second), string(resubmitted)}
+	if !reflect.DeepEqual(expected, graded) {
+		t.Errorf("expected %v to be retried, got %v", expected, graded)
+	}
+
+	retries, err = openRetryQueue(path)
+	if err != nil {
+		t.Fatalf("failed to reopen retry queue: %v", err)
+	}
+	if len(retries.payloads) != 0 {
+		t.Errorf("expected graded submissions to leave the queue, got %d", len(retries.payloads))
+	}
+}
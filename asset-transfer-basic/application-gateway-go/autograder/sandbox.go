@@ -0,0 +1,278 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxOutputBytes bounds how much output from a build or test run is kept.
+const maxOutputBytes = 64 * 1024
+
+// buildLimits bounds the compiler building a submission. It needs more memory and CPU time than a
+// submitted program is given, but runs under the same kinds of limit.
+var buildLimits = Limits{CPUTime: time.Minute, WallTime: time.Minute, MemoryMB: 1024}
+
+// maxProcesses bounds the processes and threads a submitted program may run at once, which stops fork bombs.
+// The Go runtime needs a handful of threads even with GOMAXPROCS=1.
+const maxProcesses = 64
+
+// errSandbox is returned by runProgram when the sandbox could not be set up, which is the autograder's
+// failure rather than the submission's.
+var errSandbox = errors.New("failed to start sandboxed program")
+
+// TestCase is one instructor-provided test of a programming submission: the program is run with Input on
+// stdin and passes when its stdout matches Output, ignoring trailing whitespace on each line.
+type TestCase struct {
+	Name   string `json:"name"`
+	Input  string `json:"input"`
+	Output string `json:"output"`
+	Points int    `json:"points"`
+}
+
+// TestSuite is the set of test cases for a programming assignment.
+type TestSuite struct {
+	Tests []TestCase `json:"tests"`
+}
+
+// Limits bounds the resources a submitted program may use for each test run.
+type Limits struct {
+	CPUTime  time.Duration
+	WallTime time.Duration
+	MemoryMB int
+}
+
+// GoProgramGrader builds a submitted Go program and runs it against a test suite in a resource-limited
+// subprocess. The program runs in a temporary directory with an empty environment, under CPU time, data
+// segment and process count limits set by the shell, and its whole process group is killed when the
+// wall-clock limit expires. On Linux it also runs without network access in its own PID namespace and,
+// when the autograder runs as root, as the nobody user; see isolate for what that does and does not
+// protect. Submitted programs are not run on other platforms.
+type GoProgramGrader struct {
+	Suite  TestSuite
+	Hash   string
+	Limits Limits
+}
+
+// loadGoProgramGrader reads a test suite file and records its SHA-256 content hash.
+func loadGoProgramGrader(filename string, limits Limits) (*GoProgramGrader, error) {
+	suiteJSON, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read test suite: %w", err)
+	}
+
+	var suite TestSuite
+	if err := json.Unmarshal(suiteJSON, &suite); err != nil {
+		return nil, fmt.Errorf("failed to parse test suite: %w", err)
+	}
+	if len(suite.Tests) == 0 {
+		return nil, errors.New("test suite has no tests")
+	}
+
+	hash := sha256.Sum256(suiteJSON)
+	return &GoProgramGrader{
+		Suite:  suite,
+		Hash:   hex.EncodeToString(hash[:]),
+		Limits: limits,
+	}, nil
+}
+
+// TestSuiteHash returns the content hash of the test suite, which must match the one on the assignment.
+func (g *GoProgramGrader) TestSuiteHash() string {
+	return g.Hash
+}
+
+// Grade implements Grader. A submission that fails to build scores zero; an error is returned only when the
+// grader itself cannot run.
+func (g *GoProgramGrader) Grade(work string) (int, string, error) {
+	dir, err := os.MkdirTemp("", "autograder")
+	if err != nil {
+		return 0, "", err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(work), 0o600); err != nil {
+		return 0, "", err
+	}
+	// The program may run as another user, which needs to reach its binary but nothing else in dir
+	if err := os.Chmod(dir, 0o711); err != nil {
+		return 0, "", err
+	}
+
+	var feedback strings.Builder
+	fmt.Fprintf(&feedback, "Test suite sha256:%s\n", g.Hash)
+
+	program, buildOutput, err := buildProgram(dir)
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) && !errors.Is(err, context.DeadlineExceeded) {
+			return 0, "", fmt.Errorf("failed to run go build: %w", err)
+		}
+		fmt.Fprintf(&feedback, "Build failed:\n%s", buildOutput)
+		return 0, feedback.String(), nil
+	}
+
+	score := 0
+	for _, test := range g.Suite.Tests {
+		output, err := runProgram(program, dir, test.Input, g.Limits)
+		switch {
+		case errors.Is(err, errSandbox):
+			return 0, "", err
+		case errors.Is(err, context.DeadlineExceeded):
+			fmt.Fprintf(&feedback, "FAIL %s: time limit exceeded\n", test.Name)
+		case err != nil:
+			fmt.Fprintf(&feedback, "FAIL %s: %v\n", test.Name, err)
+		case normalizeOutput(output) != normalizeOutput(test.Output):
+			fmt.Fprintf(&feedback, "FAIL %s: expected %q, got %q\n", test.Name, test.Output, output)
+		default:
+			score += test.Points
+			fmt.Fprintf(&feedback, "PASS %s (%d points)\n", test.Name, test.Points)
+		}
+	}
+
+	return score, feedback.String(), nil
+}
+
+// buildProgram compiles main.go in dir and returns the path of the binary and the compiler output. The
+// compiler runs under buildLimits with an environment of its own, so it sees none of the autograder's
+// variables and can not download modules or toolchains.
+func buildProgram(dir string) (string, string, error) {
+	goPath, err := exec.LookPath("go")
+	if err != nil {
+		return "", "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), buildLimits.WallTime)
+	defer cancel()
+
+	program := filepath.Join(dir, "program")
+	cmd := limitedCommand(ctx, buildLimits, goPath, "build", "-p", "1", "-o", program, "main.go")
+	cmd.Dir = dir
+	cmd.Env = []string{
+		"HOME=" + dir,
+		"GOPATH=" + filepath.Join(dir, "gopath"),
+		"GOCACHE=" + buildCache(),
+		"GOTOOLCHAIN=local",
+		"GOPROXY=off",
+		"GOFLAGS=",
+		"CGO_ENABLED=0",
+		"GOMAXPROCS=1",
+	}
+	output := &limitedBuffer{limit: maxOutputBytes}
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	err = cmd.Run()
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	return program, output.String(), err
+}
+
+// buildCache returns the Go build cache shared by every build, so the standard library is compiled once.
+func buildCache() string {
+	if cache := os.Getenv("GOCACHE"); cache != "" && cache != "off" {
+		return cache
+	}
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "go-build")
+	}
+	return filepath.Join(os.TempDir(), "autograder-go-build")
+}
+
+// limitedCommand returns a command that runs name with args under the CPU time, data segment and process
+// limits. The shell applies the limits to itself and then replaces itself with the program, so they are
+// inherited without affecting the autograder. RLIMIT_DATA is used rather than RLIMIT_AS because the Go
+// runtime reserves far more address space than it uses. Shells disagree on the flag for the process limit:
+// dash calls it -p, most others -u.
+func limitedCommand(ctx context.Context, limits Limits, name string, args ...string) *exec.Cmd {
+	cpuSeconds := int(limits.CPUTime.Round(time.Second) / time.Second)
+	if cpuSeconds < 1 {
+		cpuSeconds = 1
+	}
+	script := fmt.Sprintf(`ulimit -t %d && ulimit -d %d && { ulimit -u %d 2>/dev/null || ulimit -p %d; } && exec "$0" "$@"`,
+		cpuSeconds, limits.MemoryMB*1024, maxProcesses, maxProcesses)
+	return exec.CommandContext(ctx, "/bin/sh", append([]string{"-c", script, name}, args...)...)
+}
+
+// runProgram runs the compiled program once with input on stdin, under the given limits and isolated from
+// the autograder by isolate.
+func runProgram(program string, dir string, input string, limits Limits) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), limits.WallTime)
+	defer cancel()
+
+	cmd := limitedCommand(ctx, limits, program)
+	if err := isolate(cmd); err != nil {
+		return "", fmt.Errorf("%w: %v", errSandbox, err)
+	}
+	cmd.Dir = dir
+	cmd.Env = []string{"GOMAXPROCS=1"}
+	cmd.Stdin = strings.NewReader(input)
+	stdout := &limitedBuffer{limit: maxOutputBytes}
+	stderr := &limitedBuffer{limit: maxOutputBytes}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("%w: %v", errSandbox, err)
+	}
+	err := cmd.Wait()
+	killGroup(cmd)
+	if ctx.Err() != nil {
+		return stdout.String(), ctx.Err()
+	}
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if i := strings.IndexByte(message, '\n'); i >= 0 {
+			message = message[:i]
+		}
+		if message == "" {
+			return stdout.String(), err
+		}
+		return stdout.String(), fmt.Errorf("%v: %s", err, message)
+	}
+	return stdout.String(), nil
+}
+
+// normalizeOutput strips trailing whitespace from every line and trailing blank lines.
+func normalizeOutput(output string) string {
+	lines := strings.Split(output, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
+
+// limitedBuffer keeps at most limit bytes written to it and silently discards the rest.
+type limitedBuffer struct {
+	buffer bytes.Buffer
+	limit  int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.buffer.Len(); remaining > 0 {
+		if len(p) > remaining {
+			b.buffer.Write(p[:remaining])
+		} else {
+			b.buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buffer.String()
+}
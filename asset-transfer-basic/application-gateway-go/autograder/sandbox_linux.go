@@ -0,0 +1,53 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// sandboxUID and sandboxGID are the IDs submitted programs run as when the autograder runs as root: those
+// of the nobody user, which owns none of the autograder's files.
+const (
+	sandboxUID = 65534
+	sandboxGID = 65534
+)
+
+// isolate makes cmd run in its own process group and in new network, PID, IPC and UTS namespaces, so a
+// submitted program cannot reach the network or signal other processes, and every process it starts dies
+// with it. Run as root, the autograder also drops the program to the nobody user, so it cannot read the
+// autograder's signing key or class keys. Otherwise the program runs as the autograder's own user inside a
+// new user namespace, and only file permissions keep it from those files; run the autograder as root or
+// in a container to protect them.
+func isolate(cmd *exec.Cmd) error {
+	attr := &syscall.SysProcAttr{
+		Setpgid:    true,
+		Cloneflags: syscall.CLONE_NEWNET | syscall.CLONE_NEWPID | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+	}
+	if os.Geteuid() == 0 {
+		attr.Credential = &syscall.Credential{Uid: sandboxUID, Gid: sandboxGID, Groups: []uint32{}}
+	} else {
+		attr.Cloneflags |= syscall.CLONE_NEWUSER
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+	}
+	cmd.SysProcAttr = attr
+
+	// Kill the whole process group, not just the shell, when the wall-clock limit expires
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	return nil
+}
+
+// killGroup kills whatever is left of the process group of a finished command.
+func killGroup(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		_ = syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// probeGrader returns a grader whose single test expects the program to print "isolated".
+func probeGrader() *GoProgramGrader {
+	return &GoProgramGrader{
+		Suite:  TestSuite{Tests: []TestCase{{Name: "probe", Output: "isolated", Points: 1}}},
+		Limits: Limits{CPUTime: time.Second, WallTime: 3 * time.Second, MemoryMB: 64},
+	}
+}
+
+func TestGoProgramGraderKillsChildProcesses(t *testing.T) {
+	// The program leaves a child holding its output open and never exits itself
+	orphan := `package main
+
+import (
+	"os"
+	"os/exec"
+	"time"
+)
+
+func main() {
+	cmd := exec.Command("/bin/sleep", "60")
+	cmd.Stdout = os.Stdout
+	cmd.Start()
+	time.Sleep(time.Hour)
+}
+`
+	start := time.Now()
+	score, feedback, err := probeGrader().Grade(orphan)
+	if err != nil {
+		t.Fatalf("failed to grade: %v", err)
+	}
+	if score != 0 || !strings.Contains(feedback, "time limit exceeded") {
+		t.Errorf("expected the program to time out, got %d:\n%s", score, feedback)
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Second {
+		t.Errorf("expected the child process to be killed with the program, grading took %v", elapsed)
+	}
+}
+
+func TestGoProgramGraderIsolatesProgram(t *testing.T) {
+	// The program tries to connect to a port the autograder's host is listening on
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	network := fmt.Sprintf(`package main
+
+import (
+	"fmt"
+	"net"
+)
+
+func main() {
+	if _, err := net.Dial("tcp", %q); err != nil {
+		fmt.Println("isolated")
+		return
+	}
+	fmt.Println("network reachable")
+}
+`, listener.Addr().String())
+	score, feedback, err := probeGrader().Grade(network)
+	if err != nil {
+		t.Fatalf("failed to grade: %v", err)
+	}
+	if score != 1 {
+		t.Errorf("expected the program to have no network, got:\n%s", feedback)
+	}
+
+	if os.Geteuid() != 0 {
+		t.Skip("submitted programs only run as another user when the autograder runs as root")
+	}
+	secret := filepath.Join(t.TempDir(), "priv_sk")
+	if err := os.WriteFile(secret, []byte("signing key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	files := fmt.Sprintf(`package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	if _, err := os.ReadFile(%q); err != nil {
+		fmt.Println("isolated")
+		return
+	}
+	fmt.Println("key readable")
+}
+`, secret)
+	score, feedback, err = probeGrader().Grade(files)
+	if err != nil {
+		t.Fatalf("failed to grade: %v", err)
+	}
+	if score != 1 {
+		t.Errorf("expected the program not to read the autograder's files, got:\n%s", feedback)
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// isolate refuses to run submitted programs, which are only isolated from the autograder on Linux.
+func isolate(cmd *exec.Cmd) error {
+	return errors.New("running submitted programs requires Linux namespaces")
+}
+
+// killGroup does nothing, as isolate never lets a command start.
+func killGroup(cmd *exec.Cmd) {}
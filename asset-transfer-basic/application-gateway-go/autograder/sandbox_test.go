@@ -0,0 +1,148 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const wordCountSolution = `package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+)
+
+func main() {
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Split(bufio.ScanWords)
+	count := 0
+	for scanner.Scan() {
+		count++
+	}
+	fmt.Println(count)
+}
+`
+
+var testLimits = Limits{CPUTime: 2 * time.Second, WallTime: 10 * time.Second, MemoryMB: 256}
+
+func newWordCountGrader(t *testing.T) *GoProgramGrader {
+	grader, err := loadGoProgramGrader("wordcount.tests.json", testLimits)
+	if err != nil {
+		t.Fatalf("failed to load test suite: %v", err)
+	}
+	return grader
+}
+
+func TestGoProgramGraderPassesCorrectProgram(t *testing.T) {
+	grader := newWordCountGrader(t)
+	if len(grader.TestSuiteHash()) != 64 {
+		t.Errorf("expected a hex SHA-256 test suite hash, got %q", grader.TestSuiteHash())
+	}
+
+	score, feedback, err := grader.Grade(wordCountSolution)
+	if err != nil {
+		t.Fatalf("failed to grade: %v", err)
+	}
+	if score != 100 {
+		t.Errorf("expected full score, got %d:\n%s", score, feedback)
+	}
+	if !strings.Contains(feedback, grader.TestSuiteHash()) {
+		t.Errorf("expected feedback to reference the test suite hash:\n%s", feedback)
+	}
+}
+
+func TestGoProgramGraderScoresPartialCredit(t *testing.T) {
+	grader := newWordCountGrader(t)
+
+	lineCount := strings.Replace(wordCountSolution, "scanner.Split(bufio.ScanWords)\n", "", 1)
+	score, feedback, err := grader.Grade(lineCount)
+	if err != nil {
+		t.Fatalf("failed to grade: %v", err)
+	}
+	if score != 20 {
+		t.Errorf("expected only the empty input test to pass, got %d:\n%s", score, feedback)
+	}
+	if !strings.Contains(feedback, "FAIL single line") {
+		t.Errorf("expected per-test feedback:\n%s", feedback)
+	}
+}
+
+func TestGoProgramGraderRejectsBuildFailure(t *testing.T) {
+	grader := newWordCountGrader(t)
+
+	score, feedback, err := grader.Grade("package main\n\nfunc main() { undefined() }\n")
+	if err != nil {
+		t.Fatalf("failed to grade: %v", err)
+	}
+	if score != 0 || !strings.Contains(feedback, "Build failed") {
+		t.Errorf("expected build failure to score zero, got %d:\n%s", score, feedback)
+	}
+}
+
+func TestGoProgramGraderBuildsWithOwnEnvironment(t *testing.T) {
+	// A cross-compiled binary could not run, so the build must not see the autograder's environment
+	t.Setenv("GOOS", "plan9")
+	grader := newWordCountGrader(t)
+
+	score, feedback, err := grader.Grade(wordCountSolution)
+	if err != nil {
+		t.Fatalf("failed to grade: %v", err)
+	}
+	if score != 100 {
+		t.Errorf("expected full score, got %d:\n%s", score, feedback)
+	}
+}
+
+func TestGoProgramGraderEnforcesLimits(t *testing.T) {
+	grader := newWordCountGrader(t)
+	grader.Limits = Limits{CPUTime: time.Second, WallTime: 3 * time.Second, MemoryMB: 64}
+
+	spin := "package main\n\nfunc main() {\n\tfor {\n\t}\n}\n"
+	score, feedback, err := grader.Grade(spin)
+	if err != nil {
+		t.Fatalf("failed to grade: %v", err)
+	}
+	if score != 0 || strings.Contains(feedback, "PASS") {
+		t.Errorf("expected program exceeding the CPU limit to fail, got %d:\n%s", score, feedback)
+	}
+
+	hog := `package main
+
+import "fmt"
+
+func main() {
+	var blocks [][]byte
+	for i := 0; i < 1024; i++ {
+		block := make([]byte, 1<<20)
+		for j := range block {
+			block[j] = 1
+		}
+		blocks = append(blocks, block)
+	}
+	fmt.Println(len(blocks))
+}
+`
+	score, feedback, err = grader.Grade(hog)
+	if err != nil {
+		t.Fatalf("failed to grade: %v", err)
+	}
+	if score != 0 || strings.Contains(feedback, "PASS") {
+		t.Errorf("expected program exceeding the memory limit to fail, got %d:\n%s", score, feedback)
+	}
+}
+
+func TestNormalizeOutput(t *testing.T) {
+	if normalizeOutput("a  \r\nb\t\n\n") != normalizeOutput("a\nb") {
+		t.Error("expected trailing whitespace and blank lines to be ignored")
+	}
+	if normalizeOutput(" a") == normalizeOutput("a") {
+		t.Error("expected leading whitespace to be significant")
+	}
+}
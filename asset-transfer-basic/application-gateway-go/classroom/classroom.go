@@ -0,0 +1,836 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package classroom holds what the instructor and student programs do on the ledger: creating, submitting,
+// grading and releasing assignments, and managing the class encryption key. It talks to the grading
+// chaincode through Contract, which *client.Contract implements, so the same flows can run against the
+// in-process network in classroomtest. Transactions are submitted with an idempotency key when the contract
+// is a KeyedContract, such as outbox.Contract, so they can safely be submitted again after a network failure.
+//
+// Failures are returned as errors for the caller to report; errors from the gateway are wrapped, so
+// graderr.Classify still finds them.
+package classroom
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"assetTransfer/seal"
+	"assetTransfer/storage"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Contract evaluates and submits transactions of the grading chaincode. SubmitTransaction waits for the
+// transaction to commit.
+type Contract interface {
+	EvaluateTransaction(name string, args ...string) ([]byte, error)
+	SubmitTransaction(name string, args ...string) ([]byte, error)
+}
+
+// KeyedContract is a Contract that can submit a transaction with an idempotency key, which the chaincode
+// reads from the transaction's IdempotencyKeyField transient data.
+type KeyedContract interface {
+	Contract
+	SubmitWithKey(idempotencyKey string, name string, args ...string) ([]byte, error)
+}
+
+// submitAttempts is how many times a transaction with an idempotency key is submitted before a network
+// failure is reported
+const submitAttempts = 3
+
+// submitRetryDelay is the pause before a transaction is submitted again after a network failure
+var submitRetryDelay = time.Second
+
+// ErrNoClassKey is returned by SealWork and SubmitWork when the class has no published key to encrypt work
+// to. Work is only submitted unencrypted, where anyone on the channel can read it, if the caller chooses to
+// pass it to SubmitWorkCalls as it is.
+var ErrNoClassKey = errors.New("no encryption key")
+
+// IdempotencyKeyField is the transient data field the grading chaincode reads a transaction's idempotency
+// key from. It matches chaincode.IdempotencyKeyField, which can not be imported here: the chaincode is built
+// against protobuf packages that conflict with the gateway client's.
+const IdempotencyKeyField = "idempotencyKey"
+
+// Call is a transaction to submit: a chaincode function and its arguments. A call with an IdempotencyKey
+// can be submitted again after an unknown outcome; the chaincode returns the original result if the first
+// submission committed.
+type Call struct {
+	Name           string
+	Args           []string
+	IdempotencyKey string `json:",omitempty"`
+}
+
+// Asset is an assignment as the grading chaincode stores it. An assignment has one asset per student.
+type Asset struct {
+	ID           string
+	Title        string
+	Date         string
+	Description  string
+	InstructorID string
+	StudentID    string
+	GroupID      string
+	ClassID      string
+	Owner        string
+	Work         string
+	Grade        int
+	Feedback     string
+	Released     bool
+	MaxGrade     int
+	GradedBy     string
+	PendingGrade *PendingGrade
+	Attachments  []Attachment
+	Adjustments  []GradeAdjustment
+}
+
+// Assignee names who an assignment was created for: the student, or the group of a group assignment.
+func (a Asset) Assignee() string {
+	if a.GroupID != "" {
+		return "group " + a.GroupID
+	}
+	return a.StudentID
+}
+
+// Attachment is a file submitted with an assignment and kept in off-chain storage.
+type Attachment struct {
+	Name   string
+	SHA256 string
+	Size   int64
+}
+
+// GradeAdjustment moves one member's grade on a group assignment away from the group's grade.
+type GradeAdjustment struct {
+	Member string
+	Points int
+}
+
+// Group is a set of students of a class who work on group assignments together.
+type Group struct {
+	ClassID string
+	Name    string
+	Members []string
+}
+
+// MemberGrade is the grade of one member of a group on a group assignment.
+type MemberGrade struct {
+	Member     string
+	Grade      int
+	Adjustment int
+}
+
+// PendingGrade is a grade given by a teaching assistant that waits for the instructor's approval.
+type PendingGrade struct {
+	Grade    int
+	Feedback string
+	GradedBy string
+	TxID     string
+	// Adjustments are the member adjustments that wait with the grade; 0 Points removes one
+	Adjustments []GradeAdjustment
+}
+
+// Class is the public record of a class, holding the key students encrypt their work to and the teaching
+// assistants grading is delegated to.
+type Class struct {
+	ClassID              string
+	PublicKey            string
+	KeyVersion           int
+	Graders              []Grader
+	RequireGradeApproval bool
+}
+
+// Grader is a teaching assistant of a class and the parts of the class they may grade.
+type Grader struct {
+	ClientID string
+	Scopes   []GraderScope
+}
+
+// GraderScope is an assignment, or every assignment if Title is empty, delegated to a teaching assistant
+// for the listed students and groups, or for the whole class if there are none.
+type GraderScope struct {
+	Title    string
+	Students []string
+}
+
+// ClassTemplate is the definition of every assignment in a class, without students' work or grades, as
+// exported to run the class again in a later term.
+type ClassTemplate struct {
+	ClassID     string
+	Assignments []AssignmentTemplate
+}
+
+// AssignmentTemplate is the definition of one assignment of a ClassTemplate.
+type AssignmentTemplate struct {
+	Title         string
+	Date          string
+	Description   string
+	TestSuiteHash string
+	MaxGrade      int
+}
+
+// AssignmentStats summarizes the grades of the submissions of an assignment.
+type AssignmentStats struct {
+	ClassID        string
+	Title          string
+	Assigned       int
+	Count          int
+	Ungraded       int
+	SubmissionRate float64
+	Mean           float64
+	Median         float64
+	StdDev         float64
+	Min            int
+	Max            int
+	Percentiles    []Percentile
+	Histogram      []HistogramBin
+	// Suppressed is set when too few submissions are graded for anyone but the class's staff to see the
+	// figures derived from the grades, which are then left zero.
+	Suppressed bool
+}
+
+// Percentile is the grade below which the given percentage of submissions fall.
+type Percentile struct {
+	Percentile int
+	Grade      float64
+}
+
+// HistogramBin counts the submissions graded from Low up to High. The last bin also holds grades of High
+// and above.
+type HistogramBin struct {
+	Low   int
+	High  int
+	Count int
+}
+
+// AnomalyReport is the result of scanning the grade history of a class.
+type AnomalyReport struct {
+	AssetsScanned int
+	Anomalies     []Anomaly
+}
+
+// Anomaly is a suspicious grade change found in the history of a submission.
+type Anomaly struct {
+	AssetID   string
+	Kind      string
+	TxID      string
+	Timestamp time.Time
+	Detail    string
+}
+
+// InitLedger runs the chaincode's one-off initialisation.
+func InitLedger(contract Contract) error {
+	return submit(contract, "InitLedger")
+}
+
+// Student is the registration that lets a client act, in a class, as a student whose name its certificate
+// does not carry.
+type Student struct {
+	ClassID  string
+	Username string
+	ClientID string
+}
+
+// RegisterStudentCall returns the transaction that lets a client act as a student in a class, even when
+// the client is shared, like the test network's User1 of Org2. Only the instructor of the class may submit
+// it; students give the instructor the client ID returned by ClientID.
+func RegisterStudentCall(class string, username string, clientID string) Call {
+	return Call{Name: "RegisterStudent", Args: []string{class, username, clientID}}
+}
+
+// RegisterStudent lets a client act as a student in a class.
+func RegisterStudent(contract Contract, class string, username string, clientID string) error {
+	call := RegisterStudentCall(class, username, clientID)
+	return submit(contract, call.Name, call.Args...)
+}
+
+// Classes returns the classes username has assignments in.
+func Classes(contract Contract, username string) ([]string, error) {
+	var classes []string
+	if err := evaluate(contract, &classes, "GetAllClasses", username); err != nil {
+		return nil, err
+	}
+	return classes, nil
+}
+
+// Assets returns every asset of a class that username is the instructor or owner of.
+func Assets(contract Contract, username string, class string) ([]Asset, error) {
+	var assets []Asset
+	if err := evaluate(contract, &assets, "GetAllAssets", username, class); err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+// Assignments returns the assignments of a class that are waiting for username's work.
+func Assignments(contract Contract, username string, class string) ([]Asset, error) {
+	var assets []Asset
+	if err := evaluate(contract, &assets, "GetAllAssignments", username, class); err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+// SubmittedAssignments returns the assignments of a class that username has handed in.
+func SubmittedAssignments(contract Contract, username string, class string) ([]Asset, error) {
+	var assets []Asset
+	if err := evaluate(contract, &assets, "GetSubmittedAssignments", username, class); err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+// AssignmentID returns the ID of a student's copy of the titled assignment in a class.
+func AssignmentID(contract Contract, class string, title string, student string) (string, error) {
+	id, err := contract.EvaluateTransaction("AssignmentID", class, title, student)
+	if err != nil {
+		return "", fmt.Errorf("failed to evaluate transaction: %w", err)
+	}
+	return string(id), nil
+}
+
+// ReadAsset returns the asset with the given ID.
+func ReadAsset(contract Contract, id string) (*Asset, error) {
+	var asset Asset
+	if err := evaluate(contract, &asset, "ReadAsset", id); err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
+
+// ReadClass returns the record of a class, or nil if it has no assignments yet.
+func ReadClass(contract Contract, class string) (*Class, error) {
+	result, err := contract.EvaluateTransaction("ReadClass", class)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate transaction: %w", err)
+	}
+	if len(result) == 0 {
+		return nil, nil
+	}
+	var classRecord Class
+	if err := json.Unmarshal(result, &classRecord); err != nil {
+		return nil, fmt.Errorf("failed to parse class: %w", err)
+	}
+	return &classRecord, nil
+}
+
+// CreateAssignmentCalls returns the transactions that create a student's copy of an assignment, whose ID
+// is given by AssignmentID, and hand it to the student.
+func CreateAssignmentCalls(id string, class string, title string, instructor string, student string, date string, description string) []Call {
+	return []Call{
+		{Name: "CreateAssignment", Args: []string{class, title, instructor, student, date, description}},
+		{Name: "TransferAsset", Args: []string{id, student}},
+	}
+}
+
+// CreateAssignment creates a student's copy of an assignment and hands it to the student. It returns the
+// ID the chaincode gave the assignment.
+func CreateAssignment(contract Contract, class string, title string, instructor string, student string, date string, description string) (string, error) {
+	id, err := submitResult(contract, "CreateAssignment", class, title, instructor, student, date, description)
+	if err != nil {
+		return "", err
+	}
+	if err := submit(contract, "TransferAsset", string(id), student); err != nil {
+		return "", err
+	}
+	return string(id), nil
+}
+
+// GroupAssignmentID returns the ID of a group's copy of the titled assignment in a class.
+func GroupAssignmentID(contract Contract, class string, title string, group string) (string, error) {
+	id, err := contract.EvaluateTransaction("GroupAssignmentID", class, title, group)
+	if err != nil {
+		return "", fmt.Errorf("failed to evaluate transaction: %w", err)
+	}
+	return string(id), nil
+}
+
+// FindAssignment returns the assignment of a class with the given title that username holds or has handed
+// in, whether it is their own copy or their group's, or nil if there is none.
+func FindAssignment(contract Contract, username string, class string, title string) (*Asset, error) {
+	current, err := Assignments(contract, username, class)
+	if err != nil {
+		return nil, err
+	}
+	submitted, err := SubmittedAssignments(contract, username, class)
+	if err != nil {
+		return nil, err
+	}
+	for _, asset := range append(current, submitted...) {
+		if asset.Title == title {
+			return &asset, nil
+		}
+	}
+	return nil, nil
+}
+
+// CreateGroupAssignmentCalls returns the transactions that create a group's copy of an assignment, whose ID
+// is given by GroupAssignmentID, and hand it to the group.
+func CreateGroupAssignmentCalls(id string, class string, title string, instructor string, group string, date string, description string) []Call {
+	return []Call{
+		{Name: "CreateGroupAssignment", Args: []string{class, title, instructor, group, date, description}},
+		{Name: "TransferAsset", Args: []string{id, group}},
+	}
+}
+
+// CreateGroupAssignment creates a group's copy of an assignment and hands it to the group. It returns the ID
+// the chaincode gave the assignment.
+func CreateGroupAssignment(contract Contract, class string, title string, instructor string, group string, date string, description string) (string, error) {
+	id, err := submitResult(contract, "CreateGroupAssignment", class, title, instructor, group, date, description)
+	if err != nil {
+		return "", err
+	}
+	if err := submit(contract, "TransferAsset", string(id), group); err != nil {
+		return "", err
+	}
+	return string(id), nil
+}
+
+// SetGroupMembersCall returns the transaction that creates a group of a class or replaces its members.
+func SetGroupMembersCall(class string, group string, members []string) Call {
+	membersJSON, _ := json.Marshal(members)
+	return Call{Name: "SetGroupMembers", Args: []string{class, group, string(membersJSON)}}
+}
+
+// JoinGroupCall returns the transaction that signs a student up for a group of a class.
+func JoinGroupCall(class string, group string, student string) Call {
+	return Call{Name: "JoinGroup", Args: []string{class, group, student}}
+}
+
+// LeaveGroupCall returns the transaction that takes a student out of a group of a class.
+func LeaveGroupCall(class string, group string, student string) Call {
+	return Call{Name: "LeaveGroup", Args: []string{class, group, student}}
+}
+
+// ReadGroup returns a group of a class.
+func ReadGroup(contract Contract, class string, group string) (*Group, error) {
+	var record Group
+	if err := evaluate(contract, &record, "ReadGroup", class, group); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// AdjustMemberGradeCall returns the transaction that sets how many points one member's grade on a group
+// assignment differs from the group's grade. Its result is the adjustment it replaced.
+func AdjustMemberGradeCall(id string, member string, points int) Call {
+	return Call{Name: "AdjustMemberGrade", Args: []string{id, member, strconv.Itoa(points)}}
+}
+
+// MemberGrades returns the grade of each member of the group a group assignment belongs to.
+func MemberGrades(contract Contract, id string) ([]MemberGrade, error) {
+	var grades []MemberGrade
+	if err := evaluate(contract, &grades, "GetMemberGrades", id); err != nil {
+		return nil, err
+	}
+	return grades, nil
+}
+
+// ClientID returns the client ID the network knows the caller by, which teaching assistants and students
+// give their instructor to be added to a class.
+func ClientID(contract Contract) (string, error) {
+	id, err := contract.EvaluateTransaction("GetClientID")
+	if err != nil {
+		return "", fmt.Errorf("failed to evaluate transaction: %w", err)
+	}
+	return string(id), nil
+}
+
+// ClaimClassCall returns the transaction that makes the caller the instructor of a class whose assignments
+// predate class records, which can not change until it is claimed.
+func ClaimClassCall(class string) Call {
+	return Call{Name: "ClaimClass", Args: []string{class}}
+}
+
+// AddTACall returns the transaction that makes a client a teaching assistant of a class.
+func AddTACall(class string, clientID string) Call {
+	return Call{Name: "AddTA", Args: []string{class, clientID}}
+}
+
+// RemoveTACall returns the transaction that takes a teaching assistant and their grading away from a class.
+func RemoveTACall(class string, clientID string) Call {
+	return Call{Name: "RemoveTA", Args: []string{class, clientID}}
+}
+
+// AssignGraderCall returns the transaction that delegates grading of an assignment, or of every assignment
+// if title is empty, to a teaching assistant for the given students and groups, or for the whole class.
+func AssignGraderCall(class string, clientID string, title string, students []string) Call {
+	if students == nil {
+		students = []string{}
+	}
+	studentsJSON, _ := json.Marshal(students)
+	return Call{Name: "AssignGrader", Args: []string{class, clientID, title, string(studentsJSON)}}
+}
+
+// SetGradeApprovalCall returns the transaction that sets whether grades given by teaching assistants of a
+// class wait for the instructor's approval.
+func SetGradeApprovalCall(class string, required bool) Call {
+	return Call{Name: "SetGradeApproval", Args: []string{class, strconv.FormatBool(required)}}
+}
+
+// ApproveGradeCall returns the transaction that applies a teaching assistant's pending grade. Its result is
+// read by PreviousGrade.
+func ApproveGradeCall(id string) Call {
+	return Call{Name: "ApproveGrade", Args: []string{id}}
+}
+
+// RejectGradeCall returns the transaction that discards a teaching assistant's pending grade.
+func RejectGradeCall(id string) Call {
+	return Call{Name: "RejectGrade", Args: []string{id}}
+}
+
+// PendingGrades returns the submissions of a class with a teaching assistant's grade waiting for approval.
+func PendingGrades(contract Contract, class string) ([]Asset, error) {
+	var assets []Asset
+	if err := evaluate(contract, &assets, "GetPendingGrades", class); err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+// ExportClassTemplate returns the template of a class's assignments.
+func ExportClassTemplate(contract Contract, class string) (*ClassTemplate, error) {
+	var template ClassTemplate
+	if err := evaluate(contract, &template, "ExportClassTemplate", class); err != nil {
+		return nil, err
+	}
+	return &template, nil
+}
+
+// CreateClassFromTemplateCalls returns the transactions that create a copy of every assignment of a
+// template for each student of a new class, with due dates moved by offsetDays, and hand the copies to the
+// students. It asks the chaincode for the ID of each copy.
+func CreateClassFromTemplateCalls(contract Contract, template *ClassTemplate, class string, instructor string, students []string, offsetDays int) ([]Call, error) {
+	templateJSON, err := json.Marshal(template)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode template: %w", err)
+	}
+	studentsJSON, err := json.Marshal(students)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode students: %w", err)
+	}
+
+	calls := []Call{{Name: "CreateClassFromTemplate", Args: []string{class, string(templateJSON), instructor, string(studentsJSON), strconv.Itoa(offsetDays)}}}
+	for _, student := range students {
+		for _, assignment := range template.Assignments {
+			id, err := AssignmentID(contract, class, assignment.Title, student)
+			if err != nil {
+				return nil, err
+			}
+			calls = append(calls, Call{Name: "TransferAsset", Args: []string{id, student}})
+		}
+	}
+	return calls, nil
+}
+
+// CreateClassFromTemplate creates the assignments of a template for each student of a new class, with due
+// dates moved by offsetDays, and hands them to the students. It returns the IDs of the copies.
+func CreateClassFromTemplate(contract Contract, template *ClassTemplate, class string, instructor string, students []string, offsetDays int) ([]string, error) {
+	calls, err := CreateClassFromTemplateCalls(contract, template, class, instructor, students, offsetDays)
+	if err != nil {
+		return nil, err
+	}
+	result, err := submitResult(contract, calls[0].Name, calls[0].Args...)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	if err := json.Unmarshal(result, &ids); err != nil {
+		return nil, fmt.Errorf("failed to parse CreateClassFromTemplate result: %w", err)
+	}
+	for _, call := range calls[1:] {
+		if err := submit(contract, call.Name, call.Args...); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// SealWork encrypts a student's work on an assignment to the key of its class, read by ReadClass. It
+// returns the sealed work and the key version used, or ErrNoClassKey if the class has no key.
+func SealWork(classRecord *Class, asset *Asset, work string) (string, int, error) {
+	if classRecord == nil || classRecord.PublicKey == "" {
+		return "", 0, fmt.Errorf("class %s has %w, ask its instructor to publish one", asset.ClassID, ErrNoClassKey)
+	}
+	sealed, err := seal.Seal(classRecord.PublicKey, classRecord.KeyVersion, asset.ID, work)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to encrypt work: %w", err)
+	}
+	return sealed, classRecord.KeyVersion, nil
+}
+
+// SubmitWorkCalls returns the transactions that record work on an assignment and hand it back to its
+// instructor, to be submitted in order.
+func SubmitWorkCalls(asset *Asset, work string) []Call {
+	return []Call{
+		{Name: "SubmitAssignment", Args: []string{asset.ID, work}},
+		{Name: "TransferAsset", Args: []string{asset.ID, asset.InstructorID}},
+	}
+}
+
+// SubmitWork records a student's work on an assignment and hands the assignment back to its instructor.
+// The work is encrypted to the class key, and the key version used is returned; nothing is submitted if
+// the class has no key.
+func SubmitWork(contract Contract, asset *Asset, work string) (int, error) {
+	classRecord, err := ReadClass(contract, asset.ClassID)
+	if err != nil {
+		return 0, err
+	}
+	work, keyVersion, err := SealWork(classRecord, asset, work)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, call := range SubmitWorkCalls(asset, work) {
+		if err := submit(contract, call.Name, call.Args...); err != nil {
+			return 0, err
+		}
+	}
+	return keyVersion, nil
+}
+
+// GradeCall returns the transaction that sets the grade and feedback of a submission. Its result is read
+// by PreviousGrade.
+func GradeCall(id string, grade int, feedback string) Call {
+	return Call{Name: "GradeAssignment", Args: []string{id, strconv.Itoa(grade), feedback}}
+}
+
+// GradeAssignment sets the grade and feedback of a submission and returns the grade it replaced.
+func GradeAssignment(contract Contract, id string, grade int, feedback string) (int, error) {
+	call := GradeCall(id, grade, feedback)
+	result, err := submitResult(contract, call.Name, call.Args...)
+	if err != nil {
+		return 0, err
+	}
+	return PreviousGrade(result)
+}
+
+// PreviousGrade parses the result of a GradeAssignment transaction: the grade it replaced.
+func PreviousGrade(result []byte) (int, error) {
+	previous, err := strconv.Atoi(string(result))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected previous grade %q: %w", result, err)
+	}
+	return previous, nil
+}
+
+// ReleaseGradesCall returns the transaction that releases the grades of every submission of the titled
+// assignment in a class.
+func ReleaseGradesCall(class string, title string) Call {
+	return Call{Name: "ReleaseGrades", Args: []string{class, title}}
+}
+
+// SetMaxGradeCall returns the transaction that sets the grade awarded for full credit on every submission
+// of the titled assignment in a class.
+func SetMaxGradeCall(class string, title string, maxGrade int) Call {
+	return Call{Name: "SetMaxGrade", Args: []string{class, title, strconv.Itoa(maxGrade)}}
+}
+
+// ReleaseGrades releases the grades of every submission of the titled assignment in a class.
+func ReleaseGrades(contract Contract, class string, title string) error {
+	call := ReleaseGradesCall(class, title)
+	return submit(contract, call.Name, call.Args...)
+}
+
+// AttachFileCall returns the transaction that records a file kept in off-chain storage on an assignment.
+func AttachFileCall(id string, name string, hash string, size int64) Call {
+	return Call{Name: "AttachFile", Args: []string{id, name, hash, strconv.FormatInt(size, 10)}}
+}
+
+// AttachFile records a file kept in off-chain storage on an assignment.
+func AttachFile(contract Contract, id string, name string, hash string, size int64) error {
+	call := AttachFileCall(id, name, hash, size)
+	return submit(contract, call.Name, call.Args...)
+}
+
+// DownloadAttachment saves the attachment with the given name of an assignment from off-chain storage into
+// dir, checking it against the hash and size the ledger records. It returns the attachment and the path it
+// was saved to.
+func DownloadAttachment(ctx context.Context, contract Contract, store storage.Store, id string, name string, dir string) (*Attachment, string, error) {
+	asset, err := ReadAsset(contract, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, attachment := range asset.Attachments {
+		if attachment.Name != name {
+			continue
+		}
+		path := filepath.Join(dir, filepath.Base(attachment.Name))
+		if err := storage.DownloadFile(ctx, store, attachment.SHA256, attachment.Size, path); err != nil {
+			return nil, "", fmt.Errorf("download of %s rejected: %w", name, err)
+		}
+		return &attachment, path, nil
+	}
+	return nil, "", fmt.Errorf("no attachment named %s on %s", name, id)
+}
+
+// Stats returns the grade statistics of the titled assignment in a class. Students may read them once the
+// grades are released.
+func Stats(contract Contract, class string, title string) (*AssignmentStats, error) {
+	var stats AssignmentStats
+	if err := evaluate(contract, &stats, "GetAssignmentStats", class, title); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// HistogramLines draws the grade histogram of stats as text, one line per bin, with the fullest bin width
+// characters long.
+func HistogramLines(stats *AssignmentStats, width int) []string {
+	most := 0
+	for _, bin := range stats.Histogram {
+		if bin.Count > most {
+			most = bin.Count
+		}
+	}
+
+	lines := []string{}
+	for i, bin := range stats.Histogram {
+		high := bin.High - 1
+		if i == len(stats.Histogram)-1 {
+			high = bin.High
+		}
+		bar := 0
+		if most > 0 {
+			bar = (bin.Count*width + most - 1) / most
+		}
+		lines = append(lines, fmt.Sprintf("%3d-%3d | %-*s %d", bin.Low, high, width, strings.Repeat("#", bar), bin.Count))
+	}
+	return lines
+}
+
+// GradeAnomalies scans the grade history of a class, flagging submissions graded more than maxGradeChanges
+// times among other anomalies.
+func GradeAnomalies(contract Contract, class string, maxGradeChanges int) (*AnomalyReport, error) {
+	var report AnomalyReport
+	if err := evaluate(contract, &report, "GetGradeAnomalies", class, strconv.Itoa(maxGradeChanges)); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// RotateClassKey generates a new key pair for the class, keeps the private key in keyring and publishes the
+// public key for students to encrypt their work to. It returns the version of the new key.
+//
+// The private key is saved before the public key is published so work can never be sealed to a key the
+// instructor does not hold. Earlier keys stay in the keyring so work submitted before the rotation can
+// still be read.
+func RotateClassKey(contract Contract, keyring *seal.Keyring, class string) (int, error) {
+	classRecord, err := ReadClass(contract, class)
+	if err != nil {
+		return 0, err
+	}
+	expected := 1
+	if classRecord != nil {
+		expected = classRecord.KeyVersion + 1
+	}
+
+	key, err := seal.GenerateKey()
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate class key: %w", err)
+	}
+	if err := keyring.Save(class, expected, key); err != nil {
+		return 0, fmt.Errorf("failed to save class key: %w", err)
+	}
+
+	result, err := submitResult(contract, "RotateClassKey", class, seal.EncodePublicKey(key.PublicKey()))
+	if err != nil {
+		return 0, err
+	}
+	version, err := strconv.Atoi(string(result))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected key version %q: %w", result, err)
+	}
+	if version != expected {
+		// Another rotation committed in between, so file the key under the version it was published as
+		if err := keyring.Save(class, version, key); err != nil {
+			return 0, fmt.Errorf("failed to save class key: %w", err)
+		}
+	}
+	return version, nil
+}
+
+// evaluate evaluates a transaction and parses its JSON result into v. An empty result leaves v unchanged.
+func evaluate(contract Contract, v interface{}, name string, args ...string) error {
+	result, err := contract.EvaluateTransaction(name, args...)
+	if err != nil {
+		return fmt.Errorf("failed to evaluate transaction: %w", err)
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(result, v); err != nil {
+		return fmt.Errorf("failed to parse %s result: %w", name, err)
+	}
+	return nil
+}
+
+// submit submits a transaction whose result is not needed and waits for it to commit.
+func submit(contract Contract, name string, args ...string) error {
+	_, err := submitResult(contract, name, args...)
+	return err
+}
+
+// submitResult submits a transaction, waits for it to commit and returns its result. A KeyedContract
+// submits it with a new idempotency key, and submits it again with the same key, up to submitAttempts
+// times, while the network can not be reached or does not answer in time: the transaction may have
+// committed all the same, and the chaincode then returns its original result rather than applying it twice.
+func submitResult(contract Contract, name string, args ...string) ([]byte, error) {
+	keyed, ok := contract.(KeyedContract)
+	if !ok {
+		result, err := contract.SubmitTransaction(name, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to submit transaction: %w", err)
+		}
+		return result, nil
+	}
+
+	key, err := NewIdempotencyKey()
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 1; ; attempt++ {
+		result, err := keyed.SubmitWithKey(key, name, args...)
+		if err == nil {
+			return result, nil
+		}
+		if attempt == submitAttempts || !networkFailure(err) {
+			return nil, fmt.Errorf("failed to submit transaction: %w", err)
+		}
+		time.Sleep(submitRetryDelay)
+	}
+}
+
+// networkFailure reports whether err means the network could not be reached or did not answer in time,
+// which graderr classifies as Unavailable or Timeout. graderr is not used here so that classroomtest, which
+// can not be linked with it, can run these flows.
+func networkFailure(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var statusErr interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	code := statusErr.GRPCStatus().Code()
+	return code == codes.Unavailable || code == codes.DeadlineExceeded
+}
+
+// NewIdempotencyKey returns a random idempotency key for a transaction.
+func NewIdempotencyKey() (string, error) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return "", fmt.Errorf("failed to generate idempotency key: %w", err)
+	}
+	return hex.EncodeToString(key), nil
+}
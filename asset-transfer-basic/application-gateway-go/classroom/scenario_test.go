@@ -0,0 +1,661 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package classroom_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"assetTransfer/classroom"
+	"assetTransfer/classroom/classroomtest"
+	"assetTransfer/seal"
+	"assetTransfer/storage"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// connect returns the contract as seen by each of the given users.
+func connect(t *testing.T, network *classroomtest.Network, usernames ...string) map[string]*classroomtest.Contract {
+	t.Helper()
+	contracts := map[string]*classroomtest.Contract{}
+	for _, username := range usernames {
+		contract, err := network.Contract(username)
+		if err != nil {
+			t.Fatalf("failed to connect as %s: %v", username, err)
+		}
+		contracts[username] = contract
+	}
+	return contracts
+}
+
+// titles returns the titles of assets, sorted.
+func titles(assets []classroom.Asset) []string {
+	result := []string{}
+	for _, asset := range assets {
+		result = append(result, asset.Title)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func TestGradingScenario(t *testing.T) {
+	network, err := classroomtest.NewNetwork()
+	if err != nil {
+		t.Fatalf("failed to start network: %v", err)
+	}
+	users := connect(t, network, "instructor", "alice", "bob")
+	instructor := users["instructor"]
+	keyring := seal.NewKeyring(t.TempDir())
+	students := []string{"alice", "bob"}
+
+	// The instructor hands out the essay and publishes a class key
+	ids := map[string]string{}
+	for _, student := range students {
+		id, err := classroom.CreateAssignment(instructor, "cs101", "essay", "instructor", student, "4/24/2023", "Write an essay")
+		if err != nil {
+			t.Fatalf("failed to create assignment for %s: %v", student, err)
+		}
+		ids[student] = id
+	}
+	version, err := classroom.RotateClassKey(instructor, keyring, "cs101")
+	if err != nil || version != 1 {
+		t.Fatalf("expected key version 1, got %d, %v", version, err)
+	}
+	if ids["alice"] == ids["bob"] {
+		t.Fatal("expected every student to get their own copy of the assignment")
+	}
+
+	_, err = classroom.CreateAssignment(instructor, "cs101", "essay", "instructor", "alice", "", "")
+	if code := classroomtest.ErrorCode(err); code != "Conflict" {
+		t.Errorf("expected a duplicate assignment to be a conflict, got %s: %v", code, err)
+	}
+
+	// Each student sees the class and the assignment, and hands in their work
+	answers := map[string]string{"alice": "Alice's essay", "bob": "Bob's essay"}
+	for _, student := range students {
+		contract := users[student]
+		classes, err := classroom.Classes(contract, student)
+		if err != nil || !reflect.DeepEqual([]string{"cs101"}, classes) {
+			t.Fatalf("expected %s to be in cs101, got %v, %v", student, classes, err)
+		}
+		assignments, err := classroom.Assignments(contract, student, "cs101")
+		if err != nil || !reflect.DeepEqual([]string{"essay"}, titles(assignments)) {
+			t.Fatalf("expected %s to have the essay to do, got %v, %v", student, titles(assignments), err)
+		}
+
+		id, err := classroom.AssignmentID(contract, "cs101", "essay", student)
+		if err != nil || id != ids[student] {
+			t.Fatalf("expected %s's assignment ID %s, got %s, %v", student, ids[student], id, err)
+		}
+		asset, err := classroom.ReadAsset(contract, id)
+		if err != nil {
+			t.Fatalf("failed to read %s's assignment: %v", student, err)
+		}
+		keyVersion, err := classroom.SubmitWork(contract, asset, answers[student])
+		if err != nil || keyVersion != 1 {
+			t.Fatalf("expected %s's work to be sealed to key version 1, got %d, %v", student, keyVersion, err)
+		}
+
+		assignments, err = classroom.Assignments(contract, student, "cs101")
+		if err != nil || len(assignments) != 0 {
+			t.Errorf("expected nothing left to do for %s, got %v, %v", student, titles(assignments), err)
+		}
+		submitted, err := classroom.SubmittedAssignments(contract, student, "cs101")
+		if err != nil || !reflect.DeepEqual([]string{"essay"}, titles(submitted)) {
+			t.Errorf("expected %s to have handed in the essay, got %v, %v", student, titles(submitted), err)
+		}
+	}
+	if events := network.Events(); len(events) != len(students) {
+		t.Errorf("expected one WorkSubmitted event per student, got %d", len(events))
+	}
+
+	// The instructor reads and grades the work, then releases the grades
+	grades := map[string]int{"alice": 90, "bob": 75}
+	for _, student := range students {
+		asset, err := classroom.ReadAsset(instructor, ids[student])
+		if err != nil {
+			t.Fatalf("failed to read %s's submission: %v", student, err)
+		}
+		if asset.Owner != "instructor" {
+			t.Errorf("expected %s's submission to be handed back to the instructor, owner is %s", student, asset.Owner)
+		}
+		if asset.Work == answers[student] || !seal.IsSealed(asset.Work) {
+			t.Errorf("expected %s's work to be stored encrypted", student)
+		}
+		work, err := keyring.Open("cs101", asset.ID, asset.Work)
+		if err != nil || work != answers[student] {
+			t.Errorf("expected the instructor to read %q, got %q, %v", answers[student], work, err)
+		}
+
+		previous, err := classroom.GradeAssignment(instructor, asset.ID, grades[student], "Well argued")
+		if err != nil || previous != 0 {
+			t.Errorf("expected %s's first grade to replace 0, got %d, %v", student, previous, err)
+		}
+	}
+	if _, err := classroom.Stats(users["alice"], "cs101", "essay"); classroomtest.ErrorCode(err) != "Forbidden" {
+		t.Errorf("expected statistics to be hidden from students until release, got %v", err)
+	}
+	if err := classroom.ReleaseGrades(instructor, "cs101", "essay"); err != nil {
+		t.Fatalf("failed to release grades: %v", err)
+	}
+
+	// Two grades are too few for students to see anything derived from them without learning each other's
+	stats, err := classroom.Stats(users["alice"], "cs101", "essay")
+	if err != nil || !stats.Suppressed || stats.Count != 2 || stats.Mean != 0 || len(stats.Histogram) != 0 {
+		t.Errorf("expected students to see suppressed statistics, got %+v, %v", stats, err)
+	}
+	stats, err = classroom.Stats(instructor, "cs101", "essay")
+	if err != nil || stats.Suppressed || stats.Count != 2 || stats.Ungraded != 0 || stats.SubmissionRate != 1 || stats.Mean != 82.5 || stats.Min != 75 || stats.Max != 90 {
+		t.Fatalf("expected statistics of both grades, got %+v, %v", stats, err)
+	}
+	histogram := classroom.HistogramLines(stats, 4)
+	if len(histogram) != 10 || histogram[7] != " 70- 79 | #### 1" || histogram[9] != " 90-100 | #### 1" {
+		t.Errorf("unexpected histogram %q", histogram)
+	}
+
+	for _, student := range students {
+		asset, err := classroom.ReadAsset(users[student], ids[student])
+		if err != nil {
+			t.Fatalf("failed to read %s's grade: %v", student, err)
+		}
+		if !asset.Released || asset.Grade != grades[student] || asset.Feedback != "Well argued" {
+			t.Errorf("expected %s to see a released grade of %d, got %+v", student, grades[student], asset)
+		}
+	}
+
+	report, err := classroom.GradeAnomalies(instructor, "cs101", 3)
+	if err != nil {
+		t.Fatalf("failed to scan grade history: %v", err)
+	}
+	if report.AssetsScanned != len(students) || len(report.Anomalies) != 0 {
+		t.Errorf("expected a clean report for %d submissions, got %+v", len(students), report)
+	}
+}
+
+func TestRejectedTransactions(t *testing.T) {
+	network, err := classroomtest.NewNetwork()
+	if err != nil {
+		t.Fatalf("failed to start network: %v", err)
+	}
+	users := connect(t, network, "instructor", "alice", "mallory")
+
+	_, err = classroom.ReadAsset(users["instructor"], "missing")
+	if code := classroomtest.ErrorCode(err); code != "NotFound" {
+		t.Errorf("expected a missing asset to be not found, got %s: %v", code, err)
+	}
+
+	// Only the client that created the class's assignments may publish its key, even before they do
+	_, err = classroom.RotateClassKey(users["mallory"], seal.NewKeyring(t.TempDir()), "cs101")
+	if code := classroomtest.ErrorCode(err); code != "NotFound" {
+		t.Errorf("expected a class without assignments to have no key to rotate, got %s: %v", code, err)
+	}
+	id, err := classroom.CreateAssignment(users["instructor"], "cs101", "hw1", "instructor", "alice", "", "")
+	if err != nil {
+		t.Fatalf("failed to create assignment: %v", err)
+	}
+
+	// Work is not submitted in the clear while the class has no key
+	asset, err := classroom.ReadAsset(users["alice"], id)
+	if err != nil {
+		t.Fatalf("failed to read assignment: %v", err)
+	}
+	if _, err := classroom.SubmitWork(users["alice"], asset, "My answer"); !errors.Is(err, classroom.ErrNoClassKey) {
+		t.Errorf("expected work for a class without a key to be refused, got %v", err)
+	}
+	if asset, err := classroom.ReadAsset(users["alice"], id); err != nil || asset.Work != "" {
+		t.Errorf("expected no work to be recorded, got %+v, %v", asset, err)
+	}
+
+	if _, err := classroom.RotateClassKey(users["instructor"], seal.NewKeyring(t.TempDir()), "cs101"); err != nil {
+		t.Fatalf("failed to publish class key: %v", err)
+	}
+	_, err = classroom.RotateClassKey(users["mallory"], seal.NewKeyring(t.TempDir()), "cs101")
+	if code := classroomtest.ErrorCode(err); code != "Forbidden" {
+		t.Errorf("expected another client rotating the class key to be forbidden, got %s: %v", code, err)
+	}
+
+	_, err = classroom.CreateAssignment(users["instructor"], "cs101", "hw1", "instructor", "", "", "")
+	if code := classroomtest.ErrorCode(err); code != "Validation" {
+		t.Errorf("expected an assignment without a student to be rejected, got %s: %v", code, err)
+	}
+
+	// Rejected and evaluated transactions leave the ledger as it was
+	classRecord, err := classroom.ReadClass(users["instructor"], "cs101")
+	if err != nil || classRecord.KeyVersion != 1 {
+		t.Errorf("expected the class key to stay at version 1, got %+v, %v", classRecord, err)
+	}
+	assets, err := classroom.Assignments(users["instructor"], "alice", "cs101")
+	if err != nil || len(assets) != 1 {
+		t.Errorf("expected only the created assignment on the ledger, got %v, %v", assets, err)
+	}
+}
+
+// lostResponse is a contract whose first keyed submission commits but reports the network unavailable, as
+// when the connection to the gateway drops before the commit status arrives.
+type lostResponse struct {
+	*classroomtest.Contract
+	lost bool
+}
+
+func (c *lostResponse) SubmitWithKey(idempotencyKey string, name string, args ...string) ([]byte, error) {
+	result, err := c.Contract.SubmitWithKey(idempotencyKey, name, args...)
+	if err == nil && !c.lost {
+		c.lost = true
+		return nil, status.Error(codes.Unavailable, "connection lost")
+	}
+	return result, err
+}
+
+func TestSubmitRetriedAfterLostResponse(t *testing.T) {
+	network, err := classroomtest.NewNetwork()
+	if err != nil {
+		t.Fatalf("failed to start network: %v", err)
+	}
+	users := connect(t, network, "instructor", "alice")
+	instructor := users["instructor"]
+
+	id, err := classroom.CreateAssignment(instructor, "cs101", "hw1", "instructor", "alice", "", "")
+	if err != nil {
+		t.Fatalf("failed to create assignment: %v", err)
+	}
+	if _, err := classroom.RotateClassKey(instructor, seal.NewKeyring(t.TempDir()), "cs101"); err != nil {
+		t.Fatalf("failed to publish class key: %v", err)
+	}
+	asset, err := classroom.ReadAsset(users["alice"], id)
+	if err != nil {
+		t.Fatalf("failed to read assignment: %v", err)
+	}
+	if _, err := classroom.SubmitWork(users["alice"], asset, "My answer"); err != nil {
+		t.Fatalf("failed to submit work: %v", err)
+	}
+	if _, err := classroom.GradeAssignment(instructor, id, 80, "Good"); err != nil {
+		t.Fatalf("failed to grade: %v", err)
+	}
+
+	// The regrade commits, but its response is lost; submitted again with the same key, it returns the grade
+	// the first submission replaced instead of being applied twice
+	previous, err := classroom.GradeAssignment(&lostResponse{Contract: instructor}, id, 90, "Better")
+	if err != nil || previous != 80 {
+		t.Fatalf("expected the retried regrade to replace 80, got %d, %v", previous, err)
+	}
+	graded, err := classroom.ReadAsset(instructor, id)
+	if err != nil || graded.Grade != 90 {
+		t.Errorf("expected the regrade to be recorded, got %+v, %v", graded, err)
+	}
+}
+
+func TestCloneClassScenario(t *testing.T) {
+	network, err := classroomtest.NewNetwork()
+	if err != nil {
+		t.Fatalf("failed to start network: %v", err)
+	}
+	users := connect(t, network, "instructor", "alice", "carol")
+	instructor := users["instructor"]
+
+	// Last term, alice handed in the essay and was graded
+	for _, title := range []string{"essay", "quiz"} {
+		if _, err := classroom.CreateAssignment(instructor, "cs101-f23", title, "instructor", "alice", "9/15/2023", "Write an "+title); err != nil {
+			t.Fatalf("failed to create %s: %v", title, err)
+		}
+	}
+	essay, err := classroom.AssignmentID(instructor, "cs101-f23", "essay", "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := classroom.RotateClassKey(instructor, seal.NewKeyring(t.TempDir()), "cs101-f23"); err != nil {
+		t.Fatalf("failed to publish class key: %v", err)
+	}
+	if _, err := classroom.SubmitWork(users["alice"], &classroom.Asset{ID: essay, ClassID: "cs101-f23", InstructorID: "instructor"}, "my essay"); err != nil {
+		t.Fatalf("failed to submit work: %v", err)
+	}
+	if _, err := classroom.GradeAssignment(instructor, essay, 85, "Good"); err != nil {
+		t.Fatalf("failed to grade: %v", err)
+	}
+
+	template, err := classroom.ExportClassTemplate(instructor, "cs101-f23")
+	if err != nil {
+		t.Fatalf("failed to export template: %v", err)
+	}
+	ids, err := classroom.CreateClassFromTemplate(instructor, template, "cs101-s24", "instructor", []string{"carol"}, 140)
+	if err != nil {
+		t.Fatalf("failed to clone class: %v", err)
+	}
+	if len(ids) != 2 {
+		t.Fatalf("expected a copy of each assignment, got %v", ids)
+	}
+
+	assignments, err := classroom.Assignments(users["carol"], "carol", "cs101-s24")
+	if err != nil {
+		t.Fatalf("failed to list assignments: %v", err)
+	}
+	if got := titles(assignments); !reflect.DeepEqual(got, []string{"essay", "quiz"}) {
+		t.Errorf("expected carol to hold both assignments, got %v", got)
+	}
+	for _, assignment := range assignments {
+		if assignment.Date != "2/2/2024" || assignment.Work != "" || assignment.Grade != 0 {
+			t.Errorf("expected a fresh copy due 2/2/2024, got %+v", assignment)
+		}
+	}
+}
+
+func TestGroupScenario(t *testing.T) {
+	network, err := classroomtest.NewNetwork()
+	if err != nil {
+		t.Fatalf("failed to start network: %v", err)
+	}
+	users := connect(t, network, "instructor", "alice", "bob")
+	instructor := users["instructor"]
+
+	// The instructor forms the group and bob signs up to it, but may not redefine it or sign others up
+	for _, step := range []struct {
+		user string
+		call classroom.Call
+	}{
+		{"instructor", classroom.SetGroupMembersCall("cs101", "team1", []string{"alice"})},
+		{"bob", classroom.JoinGroupCall("cs101", "team1", "bob")},
+	} {
+		if _, err := users[step.user].SubmitTransaction(step.call.Name, step.call.Args...); err != nil {
+			t.Fatalf("failed to submit %s: %v", step.call.Name, err)
+		}
+	}
+	for _, call := range []classroom.Call{
+		classroom.SetGroupMembersCall("cs101", "team1", []string{"bob"}),
+		classroom.LeaveGroupCall("cs101", "team1", "alice"),
+	} {
+		if _, err := users["bob"].SubmitTransaction(call.Name, call.Args...); classroomtest.ErrorCode(err) != "Forbidden" {
+			t.Errorf("expected bob's %s to be forbidden, got %v", call.Name, err)
+		}
+	}
+	group, err := classroom.ReadGroup(instructor, "cs101", "team1")
+	if err != nil || !reflect.DeepEqual(group.Members, []string{"alice", "bob"}) {
+		t.Fatalf("expected alice and bob in team1, got %+v, %v", group, err)
+	}
+
+	id, err := classroom.CreateGroupAssignment(instructor, "cs101", "project", "instructor", "team1", "", "Build a compiler")
+	if err != nil {
+		t.Fatalf("failed to create group assignment: %v", err)
+	}
+	keyring := seal.NewKeyring(t.TempDir())
+	if _, err := classroom.RotateClassKey(instructor, keyring, "cs101"); err != nil {
+		t.Fatalf("failed to publish class key: %v", err)
+	}
+
+	// alice submits for the group, and bob sees the work among his past assignments
+	project, err := classroom.FindAssignment(users["alice"], "alice", "cs101", "project")
+	if err != nil || project == nil || project.ID != id || project.GroupID != "team1" {
+		t.Fatalf("expected alice to hold the group's project, got %+v, %v", project, err)
+	}
+	if _, err := classroom.SubmitWork(users["alice"], project, "our compiler"); err != nil {
+		t.Fatalf("failed to submit work: %v", err)
+	}
+	submitted, err := classroom.FindAssignment(users["bob"], "bob", "cs101", "project")
+	if err != nil || submitted == nil {
+		t.Fatalf("expected bob to see the group's work, got %+v, %v", submitted, err)
+	}
+	if work, err := keyring.Open("cs101", id, submitted.Work); err != nil || work != "our compiler" {
+		t.Fatalf("expected the group's work to open with the class key, got %q, %v", work, err)
+	}
+
+	if _, err := classroom.GradeAssignment(instructor, id, 90, "Great"); err != nil {
+		t.Fatalf("failed to grade: %v", err)
+	}
+	adjust := classroom.AdjustMemberGradeCall(id, "alice", 5)
+	if _, err := instructor.SubmitTransaction(adjust.Name, adjust.Args...); err != nil {
+		t.Fatalf("failed to adjust alice's grade: %v", err)
+	}
+	grades, err := classroom.MemberGrades(users["bob"], id)
+	if err != nil {
+		t.Fatalf("failed to read member grades: %v", err)
+	}
+	expected := []classroom.MemberGrade{{Member: "alice", Grade: 95, Adjustment: 5}, {Member: "bob", Grade: 90}}
+	if !reflect.DeepEqual(grades, expected) {
+		t.Errorf("expected member grades %+v, got %+v", expected, grades)
+	}
+
+	// Nobody can leave the graded group, or join it to share its grade
+	leave := classroom.LeaveGroupCall("cs101", "team1", "bob")
+	if _, err := users["bob"].SubmitTransaction(leave.Name, leave.Args...); classroomtest.ErrorCode(err) != "Conflict" {
+		t.Errorf("expected leaving the graded group to conflict, got %v", err)
+	}
+	join := classroom.JoinGroupCall("cs101", "team1", "carol")
+	if _, err := instructor.SubmitTransaction(join.Name, join.Args...); classroomtest.ErrorCode(err) != "Conflict" {
+		t.Errorf("expected joining the graded group to conflict, got %v", err)
+	}
+}
+
+func TestTeachingAssistantScenario(t *testing.T) {
+	network, err := classroomtest.NewNetwork()
+	if err != nil {
+		t.Fatalf("failed to start network: %v", err)
+	}
+	users := connect(t, network, "instructor", "ta", "alice")
+	instructor := users["instructor"]
+
+	id, err := classroom.CreateAssignment(instructor, "cs101", "hw1", "instructor", "alice", "", "Essay")
+	if err != nil {
+		t.Fatalf("failed to create assignment: %v", err)
+	}
+
+	// The TA tells the instructor their client ID, and is delegated hw1 with approval required
+	taID, err := classroom.ClientID(users["ta"])
+	if err != nil {
+		t.Fatalf("failed to read client ID: %v", err)
+	}
+	for _, call := range []classroom.Call{
+		classroom.AddTACall("cs101", taID),
+		classroom.AssignGraderCall("cs101", taID, "hw1", nil),
+		classroom.SetGradeApprovalCall("cs101", true),
+	} {
+		if _, err := instructor.SubmitTransaction(call.Name, call.Args...); err != nil {
+			t.Fatalf("failed to submit %s: %v", call.Name, err)
+		}
+	}
+	classRecord, err := classroom.ReadClass(instructor, "cs101")
+	if err != nil || !classRecord.RequireGradeApproval || len(classRecord.Graders) != 1 || classRecord.Graders[0].Scopes[0].Title != "hw1" {
+		t.Fatalf("expected the TA to be delegated hw1, got %+v, %v", classRecord, err)
+	}
+
+	if _, err := classroom.GradeAssignment(users["ta"], id, 85, "Solid"); err != nil {
+		t.Fatalf("failed to grade as TA: %v", err)
+	}
+	pending, err := classroom.PendingGrades(instructor, "cs101")
+	if err != nil || len(pending) != 1 || pending[0].PendingGrade == nil || pending[0].PendingGrade.Grade != 85 || pending[0].PendingGrade.GradedBy != taID {
+		t.Fatalf("expected the TA's grade to wait for approval, got %+v, %v", pending, err)
+	}
+
+	approve := classroom.ApproveGradeCall(id)
+	if _, err := users["ta"].SubmitTransaction(approve.Name, approve.Args...); classroomtest.ErrorCode(err) != "Forbidden" {
+		t.Errorf("expected the TA approving their own grade to be forbidden, got %v", err)
+	}
+	result, err := instructor.SubmitTransaction(approve.Name, approve.Args...)
+	if err != nil {
+		t.Fatalf("failed to approve grade: %v", err)
+	}
+	if previous, err := classroom.PreviousGrade(result); err != nil || previous != 0 {
+		t.Errorf("expected the approved grade to replace 0, got %d, %v", previous, err)
+	}
+	asset, err := classroom.ReadAsset(users["alice"], id)
+	if err != nil || asset.Grade != 85 || asset.GradedBy != taID || asset.PendingGrade != nil {
+		t.Errorf("expected the approved grade to be recorded as the TA's, got %+v, %v", asset, err)
+	}
+}
+
+func TestAutograderScenario(t *testing.T) {
+	network, err := classroomtest.NewNetwork()
+	if err != nil {
+		t.Fatalf("failed to start network: %v", err)
+	}
+	users := connect(t, network, "instructor", "autograder", "alice")
+	instructor := users["instructor"]
+	autograder := users["autograder"]
+
+	id, err := classroom.CreateAssignment(instructor, "cs101", "hw1", "instructor", "alice", "", "Capital of France")
+	if err != nil {
+		t.Fatalf("failed to create assignment: %v", err)
+	}
+	if _, err := classroom.GradeAssignment(autograder, id, 100, "Correct"); classroomtest.ErrorCode(err) != "Forbidden" {
+		t.Fatalf("expected an unregistered autograder to be forbidden, got %v", err)
+	}
+
+	// The instructor publishes the test suite and registers the autograder as autograder -publish does
+	autograderID, err := classroom.ClientID(autograder)
+	if err != nil {
+		t.Fatalf("failed to read client ID: %v", err)
+	}
+	for _, call := range []classroom.Call{
+		classroom.AddTACall("cs101", autograderID),
+		classroom.AssignGraderCall("cs101", autograderID, "hw1", nil),
+		{Name: "SetTestSuiteHash", Args: []string{"cs101", "hw1", "abc"}},
+	} {
+		if _, err := instructor.SubmitTransaction(call.Name, call.Args...); err != nil {
+			t.Fatalf("failed to submit %s: %v", call.Name, err)
+		}
+	}
+	if _, err := autograder.SubmitTransaction("SetTestSuiteHash", "cs101", "hw1", "def"); classroomtest.ErrorCode(err) != "Forbidden" {
+		t.Errorf("expected the autograder publishing a test suite to be forbidden, got %v", err)
+	}
+
+	if _, err := classroom.RotateClassKey(instructor, seal.NewKeyring(t.TempDir()), "cs101"); err != nil {
+		t.Fatalf("failed to publish class key: %v", err)
+	}
+	asset, err := classroom.ReadAsset(users["alice"], id)
+	if err != nil {
+		t.Fatalf("failed to read assignment: %v", err)
+	}
+	if _, err := classroom.SubmitWork(users["alice"], asset, "Paris"); err != nil {
+		t.Fatalf("failed to submit work: %v", err)
+	}
+	if _, err := classroom.GradeAssignment(autograder, id, 100, "Correct"); err != nil {
+		t.Fatalf("failed to grade as the autograder: %v", err)
+	}
+	if err := classroom.ReleaseGrades(autograder, "cs101", "hw1"); classroomtest.ErrorCode(err) != "Forbidden" {
+		t.Errorf("expected the autograder releasing grades to be forbidden, got %v", err)
+	}
+	if err := classroom.ReleaseGrades(instructor, "cs101", "hw1"); err != nil {
+		t.Fatalf("failed to release grades: %v", err)
+	}
+
+	asset, err = classroom.ReadAsset(users["alice"], id)
+	if err != nil || asset.Grade != 100 || asset.GradedBy != autograderID || !asset.Released {
+		t.Errorf("expected the autograder's grade to be released, got %+v, %v", asset, err)
+	}
+}
+
+func TestAttachmentScenario(t *testing.T) {
+	network, err := classroomtest.NewNetwork()
+	if err != nil {
+		t.Fatalf("failed to start network: %v", err)
+	}
+	users := connect(t, network, "instructor", "alice", "mallory")
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create file store: %v", err)
+	}
+	ctx := context.Background()
+
+	id, err := classroom.CreateAssignment(users["instructor"], "cs101", "essay", "instructor", "alice", "4/24/2023", "Write an essay")
+	if err != nil {
+		t.Fatalf("failed to create assignment: %v", err)
+	}
+	hash, size, err := storage.Upload(ctx, store, strings.NewReader("alice's essay"))
+	if err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+
+	// Only the student and the holder of the assignment may attach files to it
+	if err := classroom.AttachFile(users["mallory"], id, "essay.txt", hash, size); classroomtest.ErrorCode(err) != "Forbidden" {
+		t.Errorf("expected another student attaching a file to be forbidden, got %v", err)
+	}
+	if err := classroom.AttachFile(users["alice"], id, "essay.txt", hash, size); err != nil {
+		t.Fatalf("failed to attach file: %v", err)
+	}
+
+	dir := t.TempDir()
+	attachment, path, err := classroom.DownloadAttachment(ctx, users["instructor"], store, id, "essay.txt", dir)
+	if err != nil || attachment.SHA256 != hash || path != filepath.Join(dir, "essay.txt") {
+		t.Fatalf("expected the attachment to download, got %+v, %s, %v", attachment, path, err)
+	}
+	if content, err := os.ReadFile(path); err != nil || string(content) != "alice's essay" {
+		t.Errorf("expected the downloaded file to hold the essay, got %q, %v", content, err)
+	}
+	if _, _, err := classroom.DownloadAttachment(ctx, users["instructor"], store, id, "notes.txt", dir); err == nil {
+		t.Error("expected a missing attachment to fail")
+	}
+}
+
+func TestSharedIdentityScenario(t *testing.T) {
+	network, err := classroomtest.NewNetwork()
+	if err != nil {
+		t.Fatalf("failed to start network: %v", err)
+	}
+	// Like the student program on the test network, every student signs with the same identity
+	users := connect(t, network, "instructor", "User1@org2.example.com", "mallory")
+	shared := users["User1@org2.example.com"]
+	store, err := storage.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create file store: %v", err)
+	}
+	ctx := context.Background()
+
+	ids := map[string]string{}
+	for _, student := range []string{"alice", "bob"} {
+		id, err := classroom.CreateAssignment(users["instructor"], "cs101", "essay", "instructor", student, "", "Write an essay")
+		if err != nil {
+			t.Fatalf("failed to create assignment for %s: %v", student, err)
+		}
+		ids[student] = id
+	}
+	if _, err := classroom.RotateClassKey(users["instructor"], seal.NewKeyring(t.TempDir()), "cs101"); err != nil {
+		t.Fatalf("failed to publish class key: %v", err)
+	}
+	hash, size, err := storage.Upload(ctx, store, strings.NewReader("alice's essay"))
+	if err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+
+	// The shared certificate names none of the students, so it acts for them once the instructor registers them
+	if err := classroom.AttachFile(shared, ids["alice"], "essay.txt", hash, size); classroomtest.ErrorCode(err) != "Forbidden" {
+		t.Errorf("expected an unregistered shared identity to be forbidden, got %v", err)
+	}
+	sharedID, err := classroom.ClientID(shared)
+	if err != nil {
+		t.Fatalf("failed to read client ID: %v", err)
+	}
+	for _, student := range []string{"alice", "bob"} {
+		if err := classroom.RegisterStudent(users["instructor"], "cs101", student, sharedID); err != nil {
+			t.Fatalf("failed to register %s: %v", student, err)
+		}
+	}
+	if err := classroom.AttachFile(shared, ids["alice"], "essay.txt", hash, size); err != nil {
+		t.Fatalf("failed to attach file: %v", err)
+	}
+	asset, err := classroom.ReadAsset(shared, ids["bob"])
+	if err != nil {
+		t.Fatalf("failed to read bob's assignment: %v", err)
+	}
+	if _, err := classroom.SubmitWork(shared, asset, "Bob's essay"); err != nil {
+		t.Fatalf("failed to submit bob's work: %v", err)
+	}
+
+	// A classmate can neither register alice's name to their own client nor act for her
+	malloryID, err := classroom.ClientID(users["mallory"])
+	if err != nil {
+		t.Fatalf("failed to read client ID: %v", err)
+	}
+	if err := classroom.RegisterStudent(users["mallory"], "cs101", "alice", malloryID); classroomtest.ErrorCode(err) != "Forbidden" {
+		t.Errorf("expected a classmate registering alice to be forbidden, got %v", err)
+	}
+	if err := classroom.AttachFile(users["mallory"], ids["alice"], "essay.txt", hash, size); classroomtest.ErrorCode(err) != "Forbidden" {
+		t.Errorf("expected another client attaching a file to be forbidden, got %v", err)
+	}
+}
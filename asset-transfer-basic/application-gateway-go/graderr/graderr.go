@@ -0,0 +1,383 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package graderr classifies the errors returned by the Fabric Gateway client, so the grader CLIs and the
+// REST API report the same failure the same way: as a message for the user, a process exit code or an HTTP
+// status.
+//
+// A failure is classified by the stage of the transaction flow it happened in (endorse, submit, commit
+// status or commit), and by a Code. For chaincode failures the Code comes from the messages the peers attach
+// to the gateway error as gateway.ErrorDetail. The grading chaincode reports its errors as JSON with a code,
+// message and details; plain text messages from older chaincode are classified by their wording.
+package graderr
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Code is the kind of failure, independent of where it happened.
+type Code string
+
+const (
+	// Unknown is any failure that is not classified more precisely.
+	Unknown Code = "Unknown"
+	// NotFound means the assignment or record the request refers to does not exist.
+	NotFound Code = "NotFound"
+	// Forbidden means the caller may not perform the request.
+	Forbidden Code = "Forbidden"
+	// Conflict means the request clashes with the ledger state, such as a duplicate record or a concurrent
+	// update; the latter can be retried.
+	Conflict Code = "Conflict"
+	// Validation means the request's arguments were rejected.
+	Validation Code = "Validation"
+	// Unavailable means the gateway, peers or orderers could not be reached.
+	Unavailable Code = "Unavailable"
+	// Timeout means the network did not answer in time.
+	Timeout Code = "Timeout"
+)
+
+// Stage is the step of the transaction flow a failure happened in.
+type Stage string
+
+const (
+	StageEndorse      Stage = "endorse"
+	StageSubmit       Stage = "submit"
+	StageCommitStatus Stage = "commit status"
+	StageCommit       Stage = "commit"
+)
+
+// Detail is the error reported by one peer or orderer behind the gateway.
+type Detail struct {
+	Address string
+	MspID   string
+	Message string
+}
+
+// Error is a classified failure. It wraps the error it was classified from.
+type Error struct {
+	Code          Code
+	Stage         Stage
+	TransactionID string
+	// Message is the most specific description available: the chaincode's own error text when the
+	// chaincode rejected the request, otherwise the error itself
+	Message string
+	Details []Detail
+	// ChaincodeDetails are the details the chaincode attached to its error, such as the ID of a missing asset
+	ChaincodeDetails map[string]string
+	// ValidationCode is set for failures at the commit stage
+	ValidationCode peer.TxValidationCode
+
+	err error
+}
+
+func (e *Error) Error() string {
+	return e.err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// chaincodeCodes maps the error codes of the grading chaincode to the kind of failure they describe.
+var chaincodeCodes = map[string]Code{
+	"NotFound":   NotFound,
+	"Forbidden":  Forbidden,
+	"Conflict":   Conflict,
+	"Validation": Validation,
+	"Internal":   Unknown,
+}
+
+// chaincodePayload is the JSON error payload returned by the grading chaincode.
+type chaincodePayload struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details"`
+}
+
+// parseChaincodeError reads the JSON error payload from a peer's error message, which the peer prefixes with
+// the chaincode response status.
+func parseChaincodeError(message string) (*chaincodePayload, bool) {
+	start := strings.Index(message, "{")
+	if start < 0 {
+		return nil, false
+	}
+	var payload chaincodePayload
+	if err := json.Unmarshal([]byte(message[start:]), &payload); err != nil || payload.Code == "" {
+		return nil, false
+	}
+	return &payload, true
+}
+
+// messageCodes maps phrases used in chaincode error messages to the kind of failure they describe.
+var messageCodes = []struct {
+	phrase string
+	code   Code
+}{
+	{"does not exist", NotFound},
+	{"already exists", Conflict},
+	{"only the", Forbidden},
+	{"not allowed", Forbidden},
+	{"must", Validation},
+	{"invalid", Validation},
+	{"is not a", Validation},
+}
+
+// Classify returns the classification of err, or nil if err is nil. Errors that did not come from the
+// gateway are classified as Unknown.
+func Classify(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var classified *Error
+	if errors.As(err, &classified) {
+		return classified
+	}
+	classified = &Error{Code: Unknown, Message: err.Error(), err: err}
+
+	var commitErr *client.CommitError
+	if errors.As(err, &commitErr) {
+		classified.Stage = StageCommit
+		classified.TransactionID = commitErr.TransactionID
+		classified.ValidationCode = commitErr.Code
+		classified.Code = ForValidationCode(commitErr.Code)
+		return classified
+	}
+
+	var endorseErr *client.EndorseError
+	var submitErr *client.SubmitError
+	var commitStatusErr *client.CommitStatusError
+	switch {
+	case errors.As(err, &endorseErr):
+		classified.Stage = StageEndorse
+		classified.TransactionID = endorseErr.TransactionID
+	case errors.As(err, &submitErr):
+		classified.Stage = StageSubmit
+		classified.TransactionID = submitErr.TransactionID
+	case errors.As(err, &commitStatusErr):
+		classified.Stage = StageCommitStatus
+		classified.TransactionID = commitStatusErr.TransactionID
+	}
+
+	var statusErr interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &statusErr) {
+		if errors.Is(err, context.DeadlineExceeded) {
+			classified.Code = Timeout
+		}
+		return classified
+	}
+
+	grpcStatus := statusErr.GRPCStatus()
+	classified.Message = grpcStatus.Message()
+	var chaincodeErr *chaincodePayload
+	var messages []string
+	for _, detail := range grpcStatus.Details() {
+		if errorDetail, ok := detail.(*gateway.ErrorDetail); ok {
+			message := errorDetail.GetMessage()
+			if payload, ok := parseChaincodeError(message); ok {
+				chaincodeErr = payload
+				message = payload.Message
+			}
+			classified.Details = append(classified.Details, Detail{
+				Address: errorDetail.GetAddress(),
+				MspID:   errorDetail.GetMspId(),
+				Message: message,
+			})
+			messages = append(messages, message)
+		}
+	}
+	if len(messages) > 0 {
+		classified.Message = strings.Join(messages, "; ")
+	} else if payload, ok := parseChaincodeError(classified.Message); ok {
+		chaincodeErr = payload
+		classified.Message = payload.Message
+	}
+
+	switch grpcStatus.Code() {
+	case codes.Unavailable:
+		classified.Code = Unavailable
+	case codes.DeadlineExceeded:
+		classified.Code = Timeout
+	case codes.PermissionDenied:
+		classified.Code = Forbidden
+	case codes.NotFound:
+		classified.Code = NotFound
+	case codes.Aborted, codes.Unknown, codes.FailedPrecondition, codes.InvalidArgument:
+		// The chaincode rejected the request
+		if chaincodeErr != nil {
+			classified.Code = chaincodeCodes[chaincodeErr.Code]
+			if classified.Code == "" {
+				classified.Code = Unknown
+			}
+			classified.ChaincodeDetails = chaincodeErr.Details
+			break
+		}
+		classified.Code = ForMessage(classified.Message)
+		if classified.Code != Unknown {
+			break
+		}
+		// Otherwise only the status code is left: an aborted transaction may succeed if retried, and only
+		// an invalid argument is the caller's fault
+		switch grpcStatus.Code() {
+		case codes.Aborted:
+			classified.Code = Conflict
+		case codes.InvalidArgument:
+			classified.Code = Validation
+		}
+	}
+	return classified
+}
+
+// CommitFailed returns the error for a transaction whose commit status shows it failed validation.
+func CommitFailed(commitStatus *client.Status) *Error {
+	code := commitStatus.Code
+	return &Error{
+		Code:           ForValidationCode(code),
+		Stage:          StageCommit,
+		TransactionID:  commitStatus.TransactionID,
+		Message:        fmt.Sprintf("transaction %s failed to commit with status code %d (%s)", commitStatus.TransactionID, int32(code), code),
+		ValidationCode: code,
+		err:            fmt.Errorf("transaction %s failed to commit with status code %d (%s)", commitStatus.TransactionID, int32(code), code),
+	}
+}
+
+// ForMessage classifies a chaincode error message.
+func ForMessage(message string) Code {
+	for _, messageCode := range messageCodes {
+		if strings.Contains(message, messageCode.phrase) {
+			return messageCode.code
+		}
+	}
+	return Unknown
+}
+
+// ForValidationCode classifies a transaction that failed validation at commit.
+func ForValidationCode(code peer.TxValidationCode) Code {
+	switch code {
+	case peer.TxValidationCode_MVCC_READ_CONFLICT, peer.TxValidationCode_PHANTOM_READ_CONFLICT:
+		return Conflict
+	case peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE:
+		return Forbidden
+	default:
+		return Unknown
+	}
+}
+
+// HTTPStatus returns the HTTP status that describes a failure of this kind.
+func (code Code) HTTPStatus() int {
+	switch code {
+	case NotFound:
+		return http.StatusNotFound
+	case Forbidden:
+		return http.StatusForbidden
+	case Conflict:
+		return http.StatusConflict
+	case Validation:
+		return http.StatusBadRequest
+	case Unavailable:
+		return http.StatusServiceUnavailable
+	case Timeout:
+		return http.StatusGatewayTimeout
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// ExitCode returns the process exit code the CLIs use for a failure of this kind.
+func (code Code) ExitCode() int {
+	switch code {
+	case Validation:
+		return 2
+	case NotFound:
+		return 3
+	case Forbidden:
+		return 4
+	case Conflict:
+		return 5
+	case Unavailable:
+		return 6
+	case Timeout:
+		return 7
+	default:
+		return 1
+	}
+}
+
+// HTTPStatus returns the HTTP status for the failure. Unclassified failures to order a transaction or to
+// learn its commit status are blamed on the network behind the gateway peer rather than on the server.
+func (e *Error) HTTPStatus() int {
+	switch {
+	case e.Stage == StageSubmit && (e.Code == Unavailable || e.Code == Unknown):
+		return http.StatusBadGateway
+	case e.Stage == StageCommitStatus && e.Code == Unknown:
+		return http.StatusGatewayTimeout
+	default:
+		return e.Code.HTTPStatus()
+	}
+}
+
+// ExitCode returns the process exit code for the failure.
+func (e *Error) ExitCode() int {
+	return e.Code.ExitCode()
+}
+
+// UserMessage describes the failure for someone using the graders rather than developing them.
+func (e *Error) UserMessage() string {
+	switch e.Code {
+	case NotFound:
+		return "Not found: " + e.Message
+	case Forbidden:
+		return "Not allowed: " + e.Message
+	case Conflict:
+		if e.Stage == StageCommit {
+			return "Someone else changed the same records at the same time, please try again: " + e.Message
+		}
+		return "Conflict: " + e.Message
+	case Validation:
+		return "Invalid request: " + e.Message
+	case Unavailable:
+		return "The network is unavailable, please try again later: " + e.Message
+	case Timeout:
+		return "The network did not respond in time: " + e.Message
+	default:
+		return e.Message
+	}
+}
+
+// Report writes the user message for err, followed by the transaction and per-peer details.
+func Report(w io.Writer, err error) {
+	classified := Classify(err)
+	if classified == nil {
+		return
+	}
+	fmt.Fprintf(w, "Error: %s\n", classified.UserMessage())
+	if classified.TransactionID != "" {
+		fmt.Fprintf(w, "  transaction %s failed at %s\n", classified.TransactionID, classified.Stage)
+	}
+	for _, detail := range classified.Details {
+		fmt.Fprintf(w, "  - address: %s, mspId: %s, message: %s\n", detail.Address, detail.MspID, detail.Message)
+	}
+}
+
+// Exit reports err on standard error and exits with its exit code.
+func Exit(err error) {
+	if err == nil {
+		return
+	}
+	Report(os.Stderr, err)
+	os.Exit(Classify(err).ExitCode())
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package graderr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func chaincodeError(messages ...string) error {
+	grpcStatus := status.New(codes.Aborted, "failed to endorse transaction, see attached details for more info")
+	for i, message := range messages {
+		grpcStatus, _ = grpcStatus.WithDetails(&gateway.ErrorDetail{
+			Address: fmt.Sprintf("peer%d.org1.example.com:7051", i),
+			MspId:   "Org1MSP",
+			Message: message,
+		})
+	}
+	return grpcStatus.Err()
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		code       Code
+		message    string
+		httpStatus int
+		exitCode   int
+	}{
+		{"missing asset", chaincodeError("chaincode response 500, the asset hw1alice does not exist"), NotFound, "chaincode response 500, the asset hw1alice does not exist", http.StatusNotFound, 3},
+		{"duplicate asset", chaincodeError("the asset hw1alice already exists"), Conflict, "the asset hw1alice already exists", http.StatusConflict, 5},
+		{"wrong instructor", chaincodeError("only the instructor of class cs101 may rotate its key"), Forbidden, "only the instructor of class cs101 may rotate its key", http.StatusForbidden, 4},
+		{"bad argument", chaincodeError("the attachment size must not be negative"), Validation, "the attachment size must not be negative", http.StatusBadRequest, 2},
+		{"several peers", chaincodeError("the asset x does not exist", "the asset x does not exist"), NotFound, "the asset x does not exist; the asset x does not exist", http.StatusNotFound, 3},
+		{"aborted", chaincodeError("the grade is out of range"), Conflict, "the grade is out of range", http.StatusConflict, 5},
+		{"invalid argument", status.Error(codes.InvalidArgument, "the grade is out of range"), Validation, "the grade is out of range", http.StatusBadRequest, 2},
+		{"unknown status", status.Error(codes.Unknown, "the peer crashed"), Unknown, "the peer crashed", http.StatusInternalServerError, 1},
+		{"failed precondition", status.Error(codes.FailedPrecondition, "no peers available"), Unknown, "no peers available", http.StatusInternalServerError, 1},
+		{"coded not found", chaincodeError(`chaincode response 500, {"code":"NotFound","message":"the asset hw1alice does not exist","details":{"id":"hw1alice"}}`), NotFound, "the asset hw1alice does not exist", http.StatusNotFound, 3},
+		{"coded forbidden", chaincodeError(`chaincode response 500, {"code":"Forbidden","message":"the grade is locked","details":{"id":"hw1alice"}}`), Forbidden, "the grade is locked", http.StatusForbidden, 4},
+		{"coded internal", chaincodeError(`chaincode response 500, {"code":"Internal","message":"failed to read from world state: closed"}`), Unknown, "failed to read from world state: closed", http.StatusInternalServerError, 1},
+		{"unavailable", status.Error(codes.Unavailable, "connection refused"), Unavailable, "connection refused", http.StatusServiceUnavailable, 6},
+		{"deadline", status.Error(codes.DeadlineExceeded, "deadline exceeded"), Timeout, "deadline exceeded", http.StatusGatewayTimeout, 7},
+		{"context deadline", fmt.Errorf("waiting: %w", context.DeadlineExceeded), Timeout, "waiting: context deadline exceeded", http.StatusGatewayTimeout, 7},
+		{"plain error", errors.New("failed to parse asset"), Unknown, "failed to parse asset", http.StatusInternalServerError, 1},
+		{"wrapped chaincode error", fmt.Errorf("failed to evaluate transaction: %w", chaincodeError("the asset x does not exist")), NotFound, "the asset x does not exist", http.StatusNotFound, 3},
+		{"read conflict", &client.CommitError{TransactionID: "tx1", Code: peer.TxValidationCode_MVCC_READ_CONFLICT}, Conflict, "", http.StatusConflict, 5},
+		{"policy failure", &client.CommitError{TransactionID: "tx1", Code: peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE}, Forbidden, "", http.StatusForbidden, 4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			classified := Classify(test.err)
+			if classified.Code != test.code || classified.Message != test.message {
+				t.Errorf("expected %s %q, got %s %q", test.code, test.message, classified.Code, classified.Message)
+			}
+			if classified.HTTPStatus() != test.httpStatus || classified.ExitCode() != test.exitCode {
+				t.Errorf("expected HTTP %d and exit %d, got %d and %d", test.httpStatus, test.exitCode, classified.HTTPStatus(), classified.ExitCode())
+			}
+			if !errors.Is(classified, test.err) {
+				t.Error("expected the classified error to wrap the original")
+			}
+		})
+	}
+
+	coded := Classify(chaincodeError(`chaincode response 500, {"code":"Conflict","message":"the asset hw1alice already exists","details":{"id":"hw1alice"}}`))
+	if coded.ChaincodeDetails["id"] != "hw1alice" || coded.Details[0].Message != "the asset hw1alice already exists" {
+		t.Errorf("unexpected chaincode details %+v", coded)
+	}
+
+	if Classify(nil) != nil {
+		t.Error("expected nil to stay nil")
+	}
+	commit := Classify(&client.CommitError{TransactionID: "tx1", Code: peer.TxValidationCode_MVCC_READ_CONFLICT})
+	if commit.Stage != StageCommit || commit.TransactionID != "tx1" || commit.ValidationCode != peer.TxValidationCode_MVCC_READ_CONFLICT {
+		t.Errorf("unexpected commit classification %+v", commit)
+	}
+	if again := Classify(fmt.Errorf("retry: %w", commit)); again != commit {
+		t.Error("expected an already classified error to be returned as is")
+	}
+}
+
+func TestReport(t *testing.T) {
+	var out bytes.Buffer
+	Report(&out, fmt.Errorf("failed to evaluate transaction: %w", chaincodeError("the asset hw1alice does not exist")))
+	expected := "Error: Not found: the asset hw1alice does not exist\n" +
+		"  - address: peer0.org1.example.com:7051, mspId: Org1MSP, message: the asset hw1alice does not exist\n"
+	if out.String() != expected {
+		t.Errorf("unexpected report\n%s\nexpected\n%s", out.String(), expected)
+	}
+
+	out.Reset()
+	Report(&out, &client.CommitError{TransactionID: "tx1", Code: peer.TxValidationCode_MVCC_READ_CONFLICT})
+	if !strings.Contains(out.String(), "please try again") || !strings.Contains(out.String(), "transaction tx1 failed at commit") {
+		t.Errorf("unexpected report %q", out.String())
+	}
+}
@@ -15,15 +15,21 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"assetTransfer/classroom"
+	"assetTransfer/graderr"
+	"assetTransfer/outbox"
+	"assetTransfer/seal"
+	"assetTransfer/storage"
+
 	"github.com/hyperledger/fabric-gateway/pkg/client"
 	"github.com/hyperledger/fabric-gateway/pkg/identity"
-	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/status"
 )
 
 const (
@@ -36,8 +42,11 @@ const (
 	gatewayPeer  = "peer0.org1.example.com"
 )
 
-var now = time.Now()
-var assetId = fmt.Sprintf("asset%d", now.Unix()*1e3+int64(now.Nanosecond())/1e6)
+// maxGradeChanges is the number of grade changes to a submission above which the audit flags it
+const maxGradeChanges = 3
+
+// histogramWidth is the length in characters of the longest bar of a grade histogram
+const histogramWidth = 40
 
 func main() {
 
@@ -79,11 +88,23 @@ func main() {
 
 	network := gw.GetNetwork(channelName)
 	contract := network.GetContract(chaincodeName)
+
+	// Transactions that can not reach the network wait in the outbox until it is reachable again
+	outboxPath := "instructor-outbox.jsonl"
+	if path := os.Getenv("OUTBOX_PATH"); path != "" {
+		outboxPath = path
+	}
+	box, err := outbox.Open(outboxPath, outbox.NewGateway(gw, contract))
+	if err != nil {
+		panic(err)
+	}
+
 	initLedger(contract)
 	quit := false
 	print := true
 	class := ""
 	for !quit {
+		flushOutbox(box)
 		if class == "" {
 			printClasses(contract, username)
 			args := strings.Fields(getInput("Join or create class: "))
@@ -104,12 +125,28 @@ func main() {
 				if args[1] == "all" {
 					getAllAssets(contract, username, class)
 				} else {
-					// viewSubmission(contract, args[1])
-					readAssetByID(contract, args[1])
+					viewSubmission(contract, class, args[1])
 				}
 			case "g": // grade assignment
 				fmt.Println("Grading assignment", args[1])
-				gradeAssignment(contract, args[1])
+				gradeAssignment(contract, box, args[1])
+			case "r": // release grades
+				fmt.Println("Releasing grades for", args[1])
+				releaseGrades(box, class, args[1])
+			case "max": // set the grade awarded for full credit
+				fmt.Println("Setting the maximum grade of", args[1])
+				setMaxGrade(box, class, args[1])
+			case "m": // set the members of a group
+				fmt.Println("Setting members of group", args[1])
+				setGroupMembers(box, class, args[1])
+			case "t": // clone the class into a new term
+				fmt.Println("Cloning", class, "into", args[1])
+				cloneClass(contract, box, username, class, args[1])
+			case "grader": // delegate grading to a teaching assistant
+				fmt.Println("Delegating grading to", args[1])
+				assignGrader(box, class, args[1])
+			case "approval": // require approval of teaching assistants' grades, on or off
+				setGradeApproval(box, class, args[1])
 			case "b":
 				class = ""
 			default:
@@ -119,8 +156,27 @@ func main() {
 			switch args[0] {
 			case "c": // create new assignment (and post)
 				fmt.Println("Creating new assignment")
-				createAssignment(contract, username, class)
+				createAssignment(contract, box, username, class)
 				// createAsset(contract)
+			case "cg": // create new group assignment (and post)
+				fmt.Println("Creating new group assignment")
+				createGroupAssignment(contract, box, username, class)
+			case "claim": // become the instructor of a class created before class records
+				claimClass(box, class)
+			case "k": // rotate the class encryption key
+				fmt.Println("Rotating encryption key for", class)
+				rotateClassKey(contract, class)
+			case "a": // audit grade history
+				print = false
+				printGradeAnomalies(contract, class)
+			case "s": // grade statistics of every assignment
+				print = false
+				printClassStats(contract, username, class)
+			case "p": // review grades waiting for approval
+				reviewPendingGrades(contract, box, class)
+			case "id": // show the client ID to give an instructor when becoming a teaching assistant
+				print = false
+				printClientID(contract)
 			case "b":
 				class = ""
 			case "q":
@@ -129,6 +185,26 @@ func main() {
 			default:
 				fmt.Println("Unrecognized command, please try again.")
 			}
+		} else if len(args) == 3 {
+			switch args[0] {
+			case "v": // view a student's submission by assignment title
+				print = false
+				viewSubmission(contract, class, assignmentID(contract, class, args[1], args[2]))
+			case "g": // grade a student's submission by assignment title
+				fmt.Println("Grading assignment", args[1], "for", args[2])
+				gradeAssignment(contract, box, assignmentID(contract, class, args[1], args[2]))
+			case "download": // fetch a file attached to a submission
+				downloadAttachment(contract, args[1], args[2])
+			case "adjust": // adjust one member's grade on a group assignment
+				fmt.Println("Adjusting grade of", args[2], "on", args[1])
+				adjustMemberGrade(contract, box, args[1], args[2])
+			case "ta": // add or remove a teaching assistant
+				changeTA(box, class, args[1], args[2])
+			case "enroll": // let the client ID a student signs with act for them
+				registerStudent(box, class, args[1], args[2])
+			default:
+				fmt.Println("Unrecognized command, please try again.")
+			}
 		} else {
 			fmt.Println("Invalid command, please try again.")
 		}
@@ -145,41 +221,275 @@ func main() {
 func printClasses(contract *client.Contract, username string) {
 	fmt.Println("\n--> Evaluate Transaction: GetAllAssets, function returns all the current assets on the ledger")
 
-	evaluateResult, err := contract.EvaluateTransaction("GetAllClasses", username)
+	classes, err := classroom.Classes(contract, username)
 	if err != nil {
-		panic(fmt.Errorf("failed to evaluate transaction: %w", err))
-	}
-	var result string
-	if evaluateResult != nil {
-		result = formatJSON(evaluateResult)
+		graderr.Exit(err)
 	}
 
 	fmt.Println("Classes:")
-	fmt.Println(result)
+	for _, class := range classes {
+		fmt.Println(class)
+	}
 }
 
-func gradeAssignment(contract *client.Contract, assetId string) {
-	grade := getInput("Grade: ")
+func gradeAssignment(contract *client.Contract, box *outbox.Outbox, assetId string) {
+	grade, err := strconv.Atoi(getInput("Grade: "))
+	if err != nil {
+		fmt.Println("The grade must be a whole number, please try again.")
+		return
+	}
+	feedback := getInput("Feedback: ")
 
-	fmt.Printf("\n--> Async Submit Transaction: TransferAsset, updates existing asset grade")
+	fmt.Printf("\n--> Submit Transaction: GradeAssignment, updates existing asset grade and feedback\n")
 
-	submitResult, commit, err := contract.SubmitAsync("GradeAssignment", client.WithArguments(assetId, grade))
+	results, ok := submitQueued(box, "the grade for "+assetId, classroom.GradeCall(assetId, grade, feedback))
+	if !ok {
+		return
+	}
+	previous, err := classroom.PreviousGrade(results[0])
 	if err != nil {
-		panic(fmt.Errorf("failed to submit transaction asynchronously: %w", err))
+		graderr.Exit(err)
 	}
 
-	fmt.Printf("\n*** Successfully submitted transaction to transfer ownership from %s to Mark. \n", string(submitResult))
-	fmt.Println("*** Waiting for transaction commit.")
+	// A teaching assistant's grade may be held until the instructor approves it
+	if asset, err := classroom.ReadAsset(contract, assetId); err == nil && asset.PendingGrade != nil && asset.Grade == previous {
+		fmt.Printf("*** Transaction committed successfully, grade %d is awaiting the instructor's approval\n", grade)
+		return
+	}
+	fmt.Printf("*** Transaction committed successfully, grade changed from %d to %d\n", previous, grade)
+}
 
-	if commitStatus, err := commit.Status(); err != nil {
-		panic(fmt.Errorf("failed to get commit status: %w", err))
-	} else if !commitStatus.Successful {
-		panic(fmt.Errorf("transaction %s failed to commit with status: %d", commitStatus.TransactionID, int32(commitStatus.Code)))
+// printClientID shows the client ID the network knows the user by.
+func printClientID(contract *client.Contract) {
+	clientID, err := classroom.ClientID(contract)
+	if err != nil {
+		graderr.Exit(err)
+	}
+	fmt.Println("Client ID:", clientID)
+}
+
+// changeTA adds or removes a teaching assistant of the class by client ID.
+func changeTA(box *outbox.Outbox, class string, action string, clientID string) {
+	var call classroom.Call
+	switch action {
+	case "add":
+		call = classroom.AddTACall(class, clientID)
+	case "remove":
+		call = classroom.RemoveTACall(class, clientID)
+	default:
+		fmt.Println("Use ta add <client ID> or ta remove <client ID>, please try again.")
+		return
+	}
+
+	fmt.Printf("\n--> Submit Transaction: %s, updates the teaching assistants of the class\n", call.Name)
+
+	if _, ok := submitQueued(box, "the "+action+" of teaching assistant "+clientID, call); !ok {
+		return
+	}
+
+	fmt.Printf("*** Transaction committed successfully\n")
+}
+
+// registerStudent lets the client a student signs with, given by its client ID, act for the student in the
+// class.
+func registerStudent(box *outbox.Outbox, class string, username string, clientID string) {
+	fmt.Printf("\n--> Submit Transaction: RegisterStudent, lets the client act for the student\n")
+
+	if _, ok := submitQueued(box, "the registration of "+username, classroom.RegisterStudentCall(class, username, clientID)); !ok {
+		return
+	}
+
+	fmt.Printf("*** Transaction committed successfully\n")
+}
+
+// assignGrader delegates grading of an assignment, for the students and groups the instructor enters, to
+// a teaching assistant. An empty title delegates every assignment and no students the whole class.
+func assignGrader(box *outbox.Outbox, class string, clientID string) {
+	title := getInput("Assignment title (empty for all): ")
+	students := strings.FieldsFunc(getInput("Students or groups (comma separated, empty for all): "), func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+
+	fmt.Printf("\n--> Submit Transaction: AssignGrader, delegates grading to the teaching assistant\n")
+
+	if _, ok := submitQueued(box, "the delegation to "+clientID, classroom.AssignGraderCall(class, clientID, title, students)); !ok {
+		return
+	}
+
+	fmt.Printf("*** Transaction committed successfully\n")
+}
+
+// setGradeApproval turns on or off the instructor's approval of grades given by teaching assistants.
+func setGradeApproval(box *outbox.Outbox, class string, setting string) {
+	if setting != "on" && setting != "off" {
+		fmt.Println("Use approval on or approval off, please try again.")
+		return
+	}
+
+	fmt.Printf("\n--> Submit Transaction: SetGradeApproval, sets whether TA grades need approval\n")
+
+	if _, ok := submitQueued(box, "grade approval "+setting, classroom.SetGradeApprovalCall(class, setting == "on")); !ok {
+		return
+	}
+
+	fmt.Printf("*** Transaction committed successfully, grade approval is %s\n", setting)
+}
+
+// reviewPendingGrades walks through the grades teaching assistants gave in the class that wait for
+// approval, asking the instructor to approve, reject or skip each.
+func reviewPendingGrades(contract *client.Contract, box *outbox.Outbox, class string) {
+	fmt.Println("\n--> Evaluate Transaction: GetPendingGrades, function returns grades waiting for approval")
+
+	pending, err := classroom.PendingGrades(contract, class)
+	if err != nil {
+		graderr.Exit(err)
+	}
+
+	fmt.Printf("%d grade(s) waiting for approval\n", len(pending))
+	for _, asset := range pending {
+		fmt.Printf("\n%s for %s (%s)\n", asset.Title, asset.Assignee(), asset.ID)
+		fmt.Printf("Graded by %s: %d -> %d\n", asset.PendingGrade.GradedBy, asset.Grade, asset.PendingGrade.Grade)
+		fmt.Println("Feedback:", asset.PendingGrade.Feedback)
+		for _, change := range asset.PendingGrade.Adjustments {
+			fmt.Printf("Adjustment for %s: %+d\n", change.Member, change.Points)
+		}
+
+		var call classroom.Call
+		switch getInput("Approve, reject or skip (a/r/s): ") {
+		case "a":
+			call = classroom.ApproveGradeCall(asset.ID)
+		case "r":
+			call = classroom.RejectGradeCall(asset.ID)
+		default:
+			continue
+		}
+		if _, ok := submitQueued(box, "the review of "+asset.ID, call); ok {
+			fmt.Printf("*** Transaction committed successfully\n")
+		}
+	}
+}
+
+// setMaxGrade sets the grade awarded for full credit on every submission of an assignment.
+func setMaxGrade(box *outbox.Outbox, class string, title string) {
+	maxGrade, err := strconv.Atoi(getInput("Points for full credit: "))
+	if err != nil || maxGrade <= 0 {
+		fmt.Println("The maximum grade must be a positive whole number, please try again.")
+		return
+	}
+
+	fmt.Printf("\n--> Submit Transaction: SetMaxGrade, sets the grade awarded for full credit\n")
+
+	if _, ok := submitQueued(box, "the maximum grade of "+title, classroom.SetMaxGradeCall(class, title, maxGrade)); !ok {
+		return
+	}
+
+	fmt.Printf("*** Transaction committed successfully, %s is out of %d\n", title, maxGrade)
+}
+
+func releaseGrades(box *outbox.Outbox, class string, title string) {
+	fmt.Printf("\n--> Submit Transaction: ReleaseGrades, releases the grades of every submission of an assignment\n")
+
+	if _, ok := submitQueued(box, "the release of "+title, classroom.ReleaseGradesCall(class, title)); !ok {
+		return
+	}
+
+	fmt.Printf("*** Transaction committed successfully\n")
+}
+
+// printClassStats shows the grade statistics and a text histogram for each assignment of the class.
+func printClassStats(contract *client.Contract, username string, class string) {
+	assets, err := classroom.Assets(contract, username, class)
+	if err != nil {
+		graderr.Exit(err)
+	}
+	titles := []string{}
+	seen := map[string]bool{}
+	for _, asset := range assets {
+		if !seen[asset.Title] {
+			seen[asset.Title] = true
+			titles = append(titles, asset.Title)
+		}
+	}
+	sort.Strings(titles)
+
+	for _, title := range titles {
+		fmt.Printf("\n--> Evaluate Transaction: GetAssignmentStats, function summarizes the grades of %s\n", title)
+
+		stats, err := classroom.Stats(contract, class, title)
+		if err != nil {
+			graderr.Report(os.Stdout, err)
+			continue
+		}
+		fmt.Printf("%s: %d of %d submitted (%.0f%%), %d graded, %d awaiting grading\n", title, stats.Count+stats.Ungraded, stats.Assigned,
+			stats.SubmissionRate*100, stats.Count, stats.Ungraded)
+		if stats.Count == 0 {
+			continue
+		}
+		fmt.Printf("Mean %.1f  Median %.1f  Std dev %.1f  Min %d  Max %d\n", stats.Mean, stats.Median, stats.StdDev, stats.Min, stats.Max)
+		for _, percentile := range stats.Percentiles {
+			fmt.Printf("P%d %.1f  ", percentile.Percentile, percentile.Grade)
+		}
+		fmt.Println()
+		for _, line := range classroom.HistogramLines(stats, histogramWidth) {
+			fmt.Println(line)
+		}
+	}
+}
+
+func printGradeAnomalies(contract *client.Contract, class string) {
+	fmt.Println("\n--> Evaluate Transaction: GetGradeAnomalies, function scans the grade history of the class")
+
+	report, err := classroom.GradeAnomalies(contract, class, maxGradeChanges)
+	if err != nil {
+		graderr.Exit(err)
+	}
+
+	fmt.Printf("Scanned %d submissions, found %d anomalies\n", report.AssetsScanned, len(report.Anomalies))
+	for _, anomaly := range report.Anomalies {
+		fmt.Printf("%s  %-22s %s: %s (tx %s)\n", anomaly.Timestamp.Format(time.RFC3339), anomaly.Kind, anomaly.AssetID, anomaly.Detail, anomaly.TxID)
+	}
+}
+
+func downloadAttachment(contract *client.Contract, assetId string, name string) {
+	store, err := storage.FromEnv()
+	if err != nil {
+		panic(fmt.Errorf("failed to open file storage: %w", err))
+	}
+
+	fmt.Printf("\n--> Evaluate Transaction: ReadAsset, function returns asset attributes\n")
+
+	attachment, path, err := classroom.DownloadAttachment(context.Background(), contract, store, assetId, name, ".")
+	if err != nil {
+		graderr.Report(os.Stdout, err)
+		return
+	}
+
+	fmt.Printf("*** Saved %s (%d bytes), sha256 %s verified against the ledger\n", path, attachment.Size, attachment.SHA256)
+}
+
+// claimClass makes the user the instructor of a class whose assignments predate class records.
+func claimClass(box *outbox.Outbox, class string) {
+	fmt.Printf("\n--> Submit Transaction: ClaimClass, makes the caller the instructor of the class\n")
+
+	if _, ok := submitQueued(box, "the claim of "+class, classroom.ClaimClassCall(class)); !ok {
+		return
 	}
 
 	fmt.Printf("*** Transaction committed successfully\n")
 }
 
+// rotateClassKey publishes a new encryption key for the class, keeping its private key in the local keyring.
+func rotateClassKey(contract *client.Contract, class string) {
+	fmt.Printf("\n--> Submit Transaction: RotateClassKey, publishes a new encryption key for the class\n")
+
+	version, err := classroom.RotateClassKey(outbox.Contract{Contract: contract}, seal.KeyringFromEnv(), class)
+	if err != nil {
+		graderr.Exit(err)
+	}
+
+	fmt.Printf("*** Transaction committed successfully, class key is now version %d\n", version)
+}
+
 func login() string {
 	fmt.Println("Please first login.")
 	var username string
@@ -205,36 +515,128 @@ func getInput(prompt string) string {
 	return input[:len(input)-1] // strip trailing '\n'
 }
 
-func createAssignment(contract *client.Contract, username string, class string) {
+func createAssignment(contract *client.Contract, box *outbox.Outbox, username string, class string) {
 	title := getInput("Assignment title: ")
 	date := getInput("Assignment due date: ")
 	desc := getInput("Assignment description: ")
 	student := getInput("For student: ")
 
-	_, err := contract.SubmitTransaction("CreateAsset", title+student, title, "0", username, date, desc, class)
+	fmt.Printf("\n--> Submit Transaction: CreateAssignment, creates the assignment and hands it to the student\n")
+
+	id := assignmentID(contract, class, title, student)
+	calls := classroom.CreateAssignmentCalls(id, class, title, username, student, date, desc)
+	if _, ok := submitQueued(box, title+" for "+student, calls...); !ok {
+		return
+	}
+
+	fmt.Printf("*** Transaction committed successfully, assignment ID %s\n", id)
+}
+
+func createGroupAssignment(contract *client.Contract, box *outbox.Outbox, username string, class string) {
+	title := getInput("Assignment title: ")
+	date := getInput("Assignment due date: ")
+	desc := getInput("Assignment description: ")
+	group := getInput("For group: ")
+
+	fmt.Printf("\n--> Submit Transaction: CreateGroupAssignment, creates the assignment and hands it to the group\n")
+
+	id, err := classroom.GroupAssignmentID(contract, class, title, group)
 	if err != nil {
-		panic(fmt.Errorf("failed to submit transaction: %w", err))
+		graderr.Exit(err)
+	}
+	calls := classroom.CreateGroupAssignmentCalls(id, class, title, username, group, date, desc)
+	if _, ok := submitQueued(box, title+" for group "+group, calls...); !ok {
+		return
 	}
 
-	fmt.Printf("*** Transaction committed successfully\n")
+	fmt.Printf("*** Transaction committed successfully, assignment ID %s\n", id)
+}
 
-	fmt.Printf("\n--> Async Submit Transaction: TransferAsset, updates existing asset owner")
+// setGroupMembers replaces the members of a group of the class with the students the instructor enters.
+func setGroupMembers(box *outbox.Outbox, class string, group string) {
+	members := strings.FieldsFunc(getInput("Members (comma separated): "), func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+
+	fmt.Printf("\n--> Submit Transaction: SetGroupMembers, records the members of the group\n")
+
+	if _, ok := submitQueued(box, "the members of "+group, classroom.SetGroupMembersCall(class, group, members)); !ok {
+		return
+	}
 
-	submitResult, commit, err := contract.SubmitAsync("TransferAsset", client.WithArguments(title+student, student))
+	fmt.Printf("*** Transaction committed successfully, %s has %d members\n", group, len(members))
+}
+
+// adjustMemberGrade moves one member's grade on a group assignment away from the group's grade.
+func adjustMemberGrade(contract *client.Contract, box *outbox.Outbox, assetId string, member string) {
+	points, err := strconv.Atoi(getInput("Points to add (negative to deduct, 0 to clear): "))
 	if err != nil {
-		panic(fmt.Errorf("failed to submit transaction asynchronously: %w", err))
+		fmt.Println("The adjustment must be a whole number, please try again.")
+		return
 	}
 
-	fmt.Printf("\n*** Successfully submitted transaction to transfer ownership from %s to Mark. \n", string(submitResult))
-	fmt.Println("*** Waiting for transaction commit.")
+	fmt.Printf("\n--> Submit Transaction: AdjustMemberGrade, records the member's grade adjustment\n")
 
-	if commitStatus, err := commit.Status(); err != nil {
-		panic(fmt.Errorf("failed to get commit status: %w", err))
-	} else if !commitStatus.Successful {
-		panic(fmt.Errorf("transaction %s failed to commit with status: %d", commitStatus.TransactionID, int32(commitStatus.Code)))
+	results, ok := submitQueued(box, "the adjustment for "+member+" on "+assetId, classroom.AdjustMemberGradeCall(assetId, member, points))
+	if !ok {
+		return
+	}
+	previous, err := strconv.Atoi(string(results[0]))
+	if err != nil {
+		graderr.Exit(fmt.Errorf("unexpected previous adjustment %q: %w", results[0], err))
 	}
 
-	fmt.Printf("*** Transaction committed successfully\n")
+	// A teaching assistant's adjustment may be held until the instructor approves it
+	if asset, err := classroom.ReadAsset(contract, assetId); err == nil && asset.PendingGrade != nil {
+		for _, change := range asset.PendingGrade.Adjustments {
+			if change.Member == member && change.Points == points {
+				fmt.Printf("*** Transaction committed successfully, adjustment %+d is awaiting the instructor's approval\n", points)
+				return
+			}
+		}
+	}
+	fmt.Printf("*** Transaction committed successfully, adjustment changed from %+d to %+d\n", previous, points)
+}
+
+// cloneClass copies the assignments of a class, without work or grades, into a new class for the next term,
+// moving due dates by the number of days the instructor enters.
+func cloneClass(contract *client.Contract, box *outbox.Outbox, username string, class string, newClass string) {
+	offsetDays, err := strconv.Atoi(getInput("Move due dates by days: "))
+	if err != nil {
+		fmt.Println("The offset must be a whole number of days, please try again.")
+		return
+	}
+	students := strings.FieldsFunc(getInput("Students (comma separated): "), func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+
+	fmt.Printf("\n--> Evaluate Transaction: ExportClassTemplate, returns the assignment definitions of the class\n")
+
+	template, err := classroom.ExportClassTemplate(contract, class)
+	if err != nil {
+		graderr.Exit(err)
+	}
+
+	fmt.Printf("\n--> Submit Transaction: CreateClassFromTemplate, creates %d assignments for %d students and hands them out\n", len(template.Assignments), len(students))
+
+	calls, err := classroom.CreateClassFromTemplateCalls(contract, template, newClass, username, students, offsetDays)
+	if err != nil {
+		graderr.Exit(err)
+	}
+	if _, ok := submitQueued(box, "the clone of "+class+" into "+newClass, calls...); !ok {
+		return
+	}
+
+	fmt.Printf("*** Transaction committed successfully, %s has %d assignments\n", newClass, len(template.Assignments))
+}
+
+// assignmentID asks the chaincode for the ID of a student's copy of the titled assignment in a class.
+func assignmentID(contract *client.Contract, class string, title string, student string) string {
+	id, err := classroom.AssignmentID(contract, class, title, student)
+	if err != nil {
+		graderr.Exit(err)
+	}
+	return id
 }
 
 // newGrpcConnection creates a gRPC connection to the Gateway server.
@@ -309,9 +711,8 @@ func newSign() identity.Sign {
 func initLedger(contract *client.Contract) {
 	fmt.Printf("\n--> Submit Transaction: InitLedger, function creates the initial set of assets on the ledger \n")
 
-	_, err := contract.SubmitTransaction("InitLedger")
-	if err != nil {
-		panic(fmt.Errorf("failed to submit transaction: %w", err))
+	if err := classroom.InitLedger(outbox.Contract{Contract: contract}); err != nil {
+		graderr.Exit(err)
 	}
 
 	fmt.Printf("*** Transaction committed successfully\n")
@@ -323,7 +724,7 @@ func getAllAssets(contract *client.Contract, username string, class string) {
 
 	evaluateResult, err := contract.EvaluateTransaction("GetAllAssets", username, class)
 	if err != nil {
-		panic(fmt.Errorf("failed to evaluate transaction: %w", err))
+		graderr.Exit(fmt.Errorf("failed to evaluate transaction: %w", err))
 	}
 	result := ""
 	if evaluateResult != nil {
@@ -334,27 +735,24 @@ func getAllAssets(contract *client.Contract, username string, class string) {
 }
 
 func printAssignments(contract *client.Contract, username string, class string) {
-	evaluateResult, err := contract.EvaluateTransaction("GetAllAssets", username, class)
+	assets, err := classroom.Assets(contract, username, class)
 	if err != nil {
-		//panic(fmt.Errorf("failed to evaluate transaction: %w", err))
+		graderr.Report(os.Stdout, err)
 	}
-	//result := formatJSON(evaluateResult)
 	fmt.Println("Class: ", class)
 	fmt.Println("Submissions:")
-	var parsedResult []map[string]interface{}
-	json.Unmarshal(evaluateResult, &parsedResult)
-	for _, asset := range parsedResult {
-		fmt.Println(asset["ID"].(string))
+	for _, asset := range assets {
+		fmt.Println(asset.Title, asset.Assignee(), asset.ID)
 	}
 }
 
 // Submit a transaction synchronously, blocking until it has been committed to the ledger.
-func createAsset(contract *client.Contract) {
+func createAsset(contract *client.Contract, assetId string) {
 	fmt.Printf("\n--> Submit Transaction: CreateAsset, creates new asset with ID, Color, Size, Owner and AppraisedValue arguments \n")
 
 	_, err := contract.SubmitTransaction("CreateAsset", assetId, "yellow", "5", "Tom", "1300")
 	if err != nil {
-		panic(fmt.Errorf("failed to submit transaction: %w", err))
+		graderr.Exit(fmt.Errorf("failed to submit transaction: %w", err))
 	}
 
 	fmt.Printf("*** Transaction committed successfully\n")
@@ -367,41 +765,7 @@ func readAssetByID(contract *client.Contract, assetId string) {
 	evaluateResult, err := contract.EvaluateTransaction("ReadAsset", assetId)
 	result := ""
 	if err != nil {
-		// panic(fmt.Errorf("failed to evaluate transaction: %w", err))
-		switch err := err.(type) {
-		case *client.EndorseError:
-			fmt.Printf("Endorse error for transaction %s with gRPC status %v: %s\n", err.TransactionID, status.Code(err), err)
-		case *client.SubmitError:
-			fmt.Printf("Submit error for transaction %s with gRPC status %v: %s\n", err.TransactionID, status.Code(err), err)
-		case *client.CommitStatusError:
-			if errors.Is(err, context.DeadlineExceeded) {
-				fmt.Printf("Timeout waiting for transaction %s commit status: %s", err.TransactionID, err)
-			} else {
-				fmt.Printf("Error obtaining commit status for transaction %s with gRPC status %v: %s\n", err.TransactionID, status.Code(err), err)
-			}
-		case *client.CommitError:
-			fmt.Printf("Transaction %s failed to commit with status %d: %s\n", err.TransactionID, int32(err.Code), err)
-		default:
-			// panic(fmt.Errorf("unexpected error type %T: %w", err, err))
-			// fmt.Printf("unexpected error type %T: %w", err, err)
-			fmt.Printf("The submission ID: " + assetId + " does not exist!\n")
-		}
-		// Any error that originates from a peer or orderer node external to the gateway will have its details
-		// embedded within the gRPC status error. The following code shows how to extract that.
-		// statusErr := status.Convert(err)
-
-		// details := statusErr.Details()
-		// if len(details) > 0 {
-		// 	fmt.Println("Error Details:")
-
-		// 	for _, detail := range details {
-		// 		switch detail := detail.(type) {
-		// 		case *gateway.ErrorDetail:
-		// 			fmt.Printf("- address: %s, mspId: %s, message: %s\n", detail.Address, detail.MspId, detail.Message)
-		// 		}
-		// 	}
-		// }
-
+		graderr.Report(os.Stdout, err)
 	} else {
 		result = formatJSON(evaluateResult)
 	}
@@ -409,75 +773,66 @@ func readAssetByID(contract *client.Contract, assetId string) {
 	fmt.Printf("*** Result:%s\n", result)
 }
 
-func viewSubmission(contract *client.Contract, assetId string) {
+func viewSubmission(contract *client.Contract, class string, assetId string) {
 	fmt.Printf("\n--> Evaluate Transaction: ReadAsset, function returns asset attributes\n")
 
-	evaluateResult, err := contract.EvaluateTransaction("ReadAsset", assetId)
+	asset, err := classroom.ReadAsset(contract, assetId)
 	if err != nil {
-		// panic(fmt.Errorf("failed to evaluate transaction: %w", err))
-		switch err := err.(type) {
-		case *client.EndorseError:
-			fmt.Printf("Endorse error for transaction %s with gRPC status %v: %s\n", err.TransactionID, status.Code(err), err)
-		case *client.SubmitError:
-			fmt.Printf("Submit error for transaction %s with gRPC status %v: %s\n", err.TransactionID, status.Code(err), err)
-		case *client.CommitStatusError:
-			if errors.Is(err, context.DeadlineExceeded) {
-				fmt.Printf("Timeout waiting for transaction %s commit status: %s", err.TransactionID, err)
-			} else {
-				fmt.Printf("Error obtaining commit status for transaction %s with gRPC status %v: %s\n", err.TransactionID, status.Code(err), err)
-			}
-		case *client.CommitError:
-			fmt.Printf("Transaction %s failed to commit with status %d: %s\n", err.TransactionID, int32(err.Code), err)
-		default:
-			// panic(fmt.Errorf("unexpected error type %T: %w", err, err))
-			// fmt.Printf("unexpected error type %T: %w", err, err)
-			fmt.Printf("The submission ID: " + assetId + " does not exist!\n")
-		}
-		// Any error that originates from a peer or orderer node external to the gateway will have its details
-		// embedded within the gRPC status error. The following code shows how to extract that.
-		// statusErr := status.Convert(err)
-
-		// details := statusErr.Details()
-		// if len(details) > 0 {
-		// 	fmt.Println("Error Details:")
-
-		// 	for _, detail := range details {
-		// 		switch detail := detail.(type) {
-		// 		case *gateway.ErrorDetail:
-		// 			fmt.Printf("- address: %s, mspId: %s, message: %s\n", detail.Address, detail.MspId, detail.Message)
-		// 		}
-		// 	}
-		// }
-
+		graderr.Report(os.Stdout, err)
 	} else {
-		var parsedResult []map[string]interface{}
-		json.Unmarshal(evaluateResult, &parsedResult)
-		for _, asset := range parsedResult {
-			fmt.Println(asset["Title"].(string))
-			fmt.Println(asset["Description"].(string))
-			fmt.Println("Student response:", asset["Work"].(string))
-			fmt.Println("Grade:", asset["Grade"].(string))
+		work, err := seal.KeyringFromEnv().Open(class, assetId, asset.Work)
+		if err != nil {
+			work = fmt.Sprintf("<unable to decrypt: %v>", err)
+		}
+		fmt.Println(asset.Title)
+		fmt.Println(asset.Description)
+		fmt.Println("Student response:", work)
+		fmt.Println("Grade:", asset.Grade)
+		fmt.Println("Feedback:", asset.Feedback)
+		if asset.GradedBy != "" {
+			fmt.Println("Graded by:", asset.GradedBy)
+		}
+		if asset.PendingGrade != nil {
+			fmt.Printf("Awaiting approval: %d from %s\n", asset.PendingGrade.Grade, asset.PendingGrade.GradedBy)
+		}
+		if asset.GroupID != "" {
+			printMemberGrades(contract, asset)
 		}
 	}
 }
 
+// printMemberGrades lists the grade of each member of the group a group assignment belongs to.
+func printMemberGrades(contract *client.Contract, asset *classroom.Asset) {
+	fmt.Printf("\n--> Evaluate Transaction: GetMemberGrades, function returns the grade of each group member\n")
+
+	grades, err := classroom.MemberGrades(contract, asset.ID)
+	if err != nil {
+		graderr.Report(os.Stdout, err)
+		return
+	}
+	fmt.Println("Group", asset.GroupID+":")
+	for _, grade := range grades {
+		fmt.Printf("  %-16s %3d (%+d)\n", grade.Member, grade.Grade, grade.Adjustment)
+	}
+}
+
 // Submit transaction asynchronously, blocking until the transaction has been sent to the orderer, and allowing
 // this thread to process the chaincode response (e.g. update a UI) without waiting for the commit notification
-func transferAssetAsync(contract *client.Contract) {
+func transferAssetAsync(contract *client.Contract, assetId string) {
 	fmt.Printf("\n--> Async Submit Transaction: TransferAsset, updates existing asset owner")
 
 	submitResult, commit, err := contract.SubmitAsync("TransferAsset", client.WithArguments(assetId, "Mark"))
 	if err != nil {
-		panic(fmt.Errorf("failed to submit transaction asynchronously: %w", err))
+		graderr.Exit(fmt.Errorf("failed to submit transaction asynchronously: %w", err))
 	}
 
 	fmt.Printf("\n*** Successfully submitted transaction to transfer ownership from %s to Mark. \n", string(submitResult))
 	fmt.Println("*** Waiting for transaction commit.")
 
 	if commitStatus, err := commit.Status(); err != nil {
-		panic(fmt.Errorf("failed to get commit status: %w", err))
+		graderr.Exit(fmt.Errorf("failed to get commit status: %w", err))
 	} else if !commitStatus.Successful {
-		panic(fmt.Errorf("transaction %s failed to commit with status: %d", commitStatus.TransactionID, int32(commitStatus.Code)))
+		graderr.Exit(graderr.CommitFailed(commitStatus))
 	}
 
 	fmt.Printf("*** Transaction committed successfully\n")
@@ -493,39 +848,7 @@ func exampleErrorHandling(contract *client.Contract) {
 	}
 
 	fmt.Println("*** Successfully caught the error:")
-
-	switch err := err.(type) {
-	case *client.EndorseError:
-		fmt.Printf("Endorse error for transaction %s with gRPC status %v: %s\n", err.TransactionID, status.Code(err), err)
-	case *client.SubmitError:
-		fmt.Printf("Submit error for transaction %s with gRPC status %v: %s\n", err.TransactionID, status.Code(err), err)
-	case *client.CommitStatusError:
-		if errors.Is(err, context.DeadlineExceeded) {
-			fmt.Printf("Timeout waiting for transaction %s commit status: %s", err.TransactionID, err)
-		} else {
-			fmt.Printf("Error obtaining commit status for transaction %s with gRPC status %v: %s\n", err.TransactionID, status.Code(err), err)
-		}
-	case *client.CommitError:
-		fmt.Printf("Transaction %s failed to commit with status %d: %s\n", err.TransactionID, int32(err.Code), err)
-	default:
-		panic(fmt.Errorf("unexpected error type %T: %w", err, err))
-	}
-
-	// Any error that originates from a peer or orderer node external to the gateway will have its details
-	// embedded within the gRPC status error. The following code shows how to extract that.
-	statusErr := status.Convert(err)
-
-	details := statusErr.Details()
-	if len(details) > 0 {
-		fmt.Println("Error Details:")
-
-		for _, detail := range details {
-			switch detail := detail.(type) {
-			case *gateway.ErrorDetail:
-				fmt.Printf("- address: %s, mspId: %s, message: %s\n", detail.Address, detail.MspId, detail.Message)
-			}
-		}
-	}
+	graderr.Report(os.Stdout, err)
 }
 
 // Format JSON data
@@ -536,3 +859,33 @@ func formatJSON(data []byte) string {
 	}
 	return prettyJSON.String()
 }
+
+// submitQueued submits calls through the outbox and returns the result of each once they have committed.
+// If the network can not be reached the calls stay queued, to be retried on a later command, and ok is false.
+func submitQueued(box *outbox.Outbox, description string, calls ...classroom.Call) (results [][]byte, ok bool) {
+	entry, err := box.Submit(description, calls...)
+	if errors.Is(err, outbox.ErrPending) {
+		fmt.Printf("*** The network can not be reached, %s is queued and will be submitted when it can\n", description)
+		graderr.Report(os.Stdout, err)
+		return nil, false
+	}
+	if err != nil {
+		graderr.Exit(err)
+	}
+	return entry.Results, true
+}
+
+// flushOutbox retries the transactions waiting in the outbox and reports those that finished.
+func flushOutbox(box *outbox.Outbox) {
+	for _, entry := range box.Flush() {
+		if entry.Err != nil {
+			fmt.Printf("*** Queued %s was rejected\n", entry.Description)
+			graderr.Report(os.Stdout, entry.Err)
+			continue
+		}
+		fmt.Printf("*** Queued %s committed successfully\n", entry.Description)
+	}
+	if pending := len(box.Pending()); pending > 0 {
+		fmt.Printf("*** %d queued transaction(s) waiting for the network\n", pending)
+	}
+}
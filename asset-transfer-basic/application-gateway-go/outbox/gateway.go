@@ -0,0 +1,95 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package outbox
+
+import (
+	"assetTransfer/classroom"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// Gateway implements Network for a contract reached through a Fabric Gateway connection. Proposals are
+// signed with the gateway's identity, so an outbox must always be flushed by the user who filled it.
+type Gateway struct {
+	gateway  *client.Gateway
+	contract *client.Contract
+}
+
+// NewGateway returns the Network for a contract of gateway.
+func NewGateway(gateway *client.Gateway, contract *client.Contract) *Gateway {
+	return &Gateway{gateway: gateway, contract: contract}
+}
+
+// Propose implements Network.
+func (g *Gateway) Propose(call classroom.Call) (string, []byte, error) {
+	options := []client.ProposalOption{client.WithArguments(call.Args...)}
+	if call.IdempotencyKey != "" {
+		options = append(options, withIdempotencyKey(call.IdempotencyKey))
+	}
+	proposal, err := g.contract.NewProposal(call.Name, options...)
+	if err != nil {
+		return "", nil, err
+	}
+	proposalBytes, err := proposal.Bytes()
+	if err != nil {
+		return "", nil, err
+	}
+	return proposal.TransactionID(), proposalBytes, nil
+}
+
+// Endorse implements Network.
+func (g *Gateway) Endorse(proposalBytes []byte) ([]byte, []byte, error) {
+	proposal, err := g.gateway.NewProposal(proposalBytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	transaction, err := proposal.Endorse()
+	if err != nil {
+		return nil, nil, err
+	}
+	transactionBytes, err := transaction.Bytes()
+	if err != nil {
+		return nil, nil, err
+	}
+	return transactionBytes, transaction.Result(), nil
+}
+
+// Submit implements Network.
+func (g *Gateway) Submit(transactionBytes []byte) ([]byte, error) {
+	transaction, err := g.gateway.NewTransaction(transactionBytes)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := transaction.Submit()
+	if err != nil {
+		return nil, err
+	}
+	return commit.Bytes()
+}
+
+// Status implements Network.
+func (g *Gateway) Status(commitBytes []byte) (*client.Status, error) {
+	commit, err := g.gateway.NewCommit(commitBytes)
+	if err != nil {
+		return nil, err
+	}
+	return commit.Status()
+}
+
+// Contract adapts a gateway contract to classroom.KeyedContract, so the classroom helpers that submit
+// transactions directly send them with an idempotency key too.
+type Contract struct {
+	*client.Contract
+}
+
+// SubmitWithKey implements classroom.KeyedContract.
+func (c Contract) SubmitWithKey(idempotencyKey string, name string, args ...string) ([]byte, error) {
+	return c.Submit(name, client.WithArguments(args...), withIdempotencyKey(idempotencyKey))
+}
+
+func withIdempotencyKey(key string) client.ProposalOption {
+	return client.WithTransient(map[string][]byte{classroom.IdempotencyKeyField: []byte(key)})
+}
@@ -0,0 +1,382 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package outbox keeps transactions that could not reach the network in a local file and submits them once
+// it is reachable again, so a student's answer or an instructor's grade is never lost to a dropped
+// connection.
+//
+// Each queued transaction is created once, and its serialized proposal, endorsed transaction and commit
+// status request are saved as it moves through the transaction flow. A retry picks up from the last saved
+// step with the same bytes, so the transaction ID never changes: a transaction that was submitted before
+// the connection dropped either committed already, in which case the commit status says so, or is
+// rejected by the peers as a duplicate. Retrying after an unknown outcome can not apply it twice.
+//
+// Each call is also given an idempotency key when it is queued, saved with it, for the rare case where a
+// transaction has to be proposed again under a new transaction ID, such as after a read conflict: the
+// chaincode then returns the result recorded for the key rather than running the call a second time.
+//
+// The file holds one JSON entry per line and is rewritten in full, through a temporary file, on every
+// change.
+package outbox
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"assetTransfer/classroom"
+	"assetTransfer/graderr"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+const (
+	// minBackoff is the delay before the first retry, doubled on each further attempt up to maxBackoff
+	minBackoff = 5 * time.Second
+	maxBackoff = 5 * time.Minute
+)
+
+// ErrPending is returned, wrapping the network error, when a transaction could not be committed yet and
+// stays in the outbox to be retried.
+var ErrPending = errors.New("transaction queued for retry")
+
+// Network runs the steps of the transaction flow on serialized transactions. Gateway implements it for a
+// Fabric Gateway connection.
+type Network interface {
+	// Propose creates a signed proposal for call without contacting the network.
+	Propose(call classroom.Call) (transactionID string, proposal []byte, err error)
+	// Endorse endorses a proposal, returning the transaction to submit and the transaction result.
+	Endorse(proposal []byte) (transaction []byte, result []byte, err error)
+	// Submit sends an endorsed transaction to the orderer, returning the request for its commit status.
+	Submit(transaction []byte) (commit []byte, err error)
+	// Status waits for the commit status of a submitted transaction.
+	Status(commit []byte) (*client.Status, error)
+}
+
+// State is the step of the transaction flow an entry's current transaction has reached.
+type State string
+
+const (
+	// Queued means no proposal has been made for the current transaction yet.
+	Queued State = "queued"
+	// Proposed means the proposal is signed but not endorsed.
+	Proposed State = "proposed"
+	// Endorsed means the transaction is endorsed and may or may not have reached the orderer.
+	Endorsed State = "endorsed"
+	// Submitted means the orderer accepted the transaction and its commit status is not known yet.
+	Submitted State = "submitted"
+)
+
+// Entry is a queued sequence of transactions, such as a submission followed by the transfer back to the
+// instructor, committed one after another in order.
+type Entry struct {
+	// ID identifies the entry; it is the transaction ID of its first transaction
+	ID          string
+	Description string
+	Calls       []classroom.Call
+	// Step is the index in Calls of the transaction being submitted
+	Step          int
+	State         State
+	TransactionID string
+	Proposal      []byte `json:",omitempty"`
+	Transaction   []byte `json:",omitempty"`
+	Commit        []byte `json:",omitempty"`
+	// Result is the endorsed result of the current transaction
+	Result []byte `json:",omitempty"`
+	// Results holds the result of each committed transaction
+	Results [][]byte `json:",omitempty"`
+	// TransactionIDs holds the ID each transaction committed under, which differs from ID after a conflict
+	TransactionIDs []string `json:",omitempty"`
+	Attempts       int
+	NextAttempt    time.Time
+	LastError      string `json:",omitempty"`
+	Created        time.Time
+
+	// Err is why a finished entry failed, or nil if all its transactions committed
+	Err error `json:"-"`
+}
+
+// Outbox is a file of transactions waiting to be committed. It is safe for concurrent use.
+type Outbox struct {
+	mu      sync.Mutex
+	path    string
+	network Network
+	entries []*Entry
+	// finished holds the entries that finished outside Flush, to be returned by the next one
+	finished []*Entry
+	now      func() time.Time
+}
+
+// Open returns the outbox stored at path, which is created when the first transaction is queued.
+func Open(path string, network Network) (*Outbox, error) {
+	o := &Outbox{path: path, network: network, now: time.Now}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read outbox: %w", err)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, len(content)+1)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("malformed outbox entry on line %d of %s: %w", line, path, err)
+		}
+		o.entries = append(o.entries, &entry)
+	}
+	return o, scanner.Err()
+}
+
+// Pending returns copies of the entries still waiting, oldest first.
+func (o *Outbox) Pending() []Entry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	pending := make([]Entry, len(o.entries))
+	for i, entry := range o.entries {
+		pending[i] = *entry
+	}
+	return pending
+}
+
+// Submit queues calls as one entry and tries to commit it straight away, after any entries queued before
+// it so transactions commit in the order they were queued. The entry is returned with the result of each
+// transaction once they have all committed. Earlier entries that finish on the way are returned by the
+// next Flush.
+//
+// If the network could not be reached the error wraps ErrPending and the entry stays queued for Flush to
+// retry. Any other error means the transactions were rejected and the entry has been dropped.
+func (o *Outbox) Submit(description string, calls ...classroom.Call) (*Entry, error) {
+	if len(calls) == 0 {
+		return nil, errors.New("no transactions to queue")
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	calls = append([]classroom.Call(nil), calls...)
+	for i := range calls {
+		if calls[i].IdempotencyKey == "" {
+			key, err := classroom.NewIdempotencyKey()
+			if err != nil {
+				return nil, err
+			}
+			calls[i].IdempotencyKey = key
+		}
+	}
+
+	entry := &Entry{Description: description, Calls: calls, State: Queued, Created: o.now()}
+	// Sign the first proposal before anything is sent, so the entry has its transaction ID from the start
+	if err := o.advance(entry); err != nil {
+		return nil, err
+	}
+	entry.ID = entry.TransactionID
+	o.entries = append(o.entries, entry)
+	if err := o.save(); err != nil {
+		return nil, err
+	}
+
+	for _, queued := range append([]*Entry(nil), o.entries...) {
+		err := o.run(queued)
+		if queued == entry {
+			return entry, err
+		}
+		if errors.Is(err, ErrPending) {
+			return entry, fmt.Errorf("waiting behind %q: %w", queued.Description, err)
+		}
+		queued.Err = err
+		o.finished = append(o.finished, queued)
+	}
+	return entry, nil
+}
+
+// Flush retries the entries whose backoff has expired, oldest first, and returns those that finished since
+// the last Flush: committed, or rejected with Err set. It stops at the first entry that still can not
+// reach the network.
+func (o *Outbox) Flush() []*Entry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for _, entry := range append([]*Entry(nil), o.entries...) {
+		if entry.NextAttempt.After(o.now()) {
+			break
+		}
+		err := o.run(entry)
+		if errors.Is(err, ErrPending) {
+			break
+		}
+		entry.Err = err
+		o.finished = append(o.finished, entry)
+	}
+	finished := o.finished
+	o.finished = nil
+	return finished
+}
+
+// run takes an entry through the transaction flow until all its transactions have committed, removing it
+// from the outbox, or until a step fails. A network failure leaves the entry queued with its backoff
+// extended and returns an error wrapping ErrPending; a rejection removes it.
+func (o *Outbox) run(entry *Entry) error {
+	for entry.Step < len(entry.Calls) {
+		if err := o.advance(entry); err != nil {
+			if !retryable(entry, err) {
+				o.remove(entry)
+				return err
+			}
+			entry.Attempts++
+			entry.NextAttempt = o.now().Add(backoff(entry.Attempts))
+			entry.LastError = err.Error()
+			if saveErr := o.save(); saveErr != nil {
+				return fmt.Errorf("%w: %w", ErrPending, saveErr)
+			}
+			return fmt.Errorf("%w: %w", ErrPending, err)
+		}
+		if err := o.save(); err != nil {
+			// The step is kept in memory, and saved with the next change that can be
+			return fmt.Errorf("%w: %w", ErrPending, err)
+		}
+	}
+	o.remove(entry)
+	return nil
+}
+
+// advance takes the entry's current transaction one step through the transaction flow. Each step starts
+// from the bytes saved by the previous one, so a retry repeats the failed step of the same transaction.
+func (o *Outbox) advance(entry *Entry) error {
+	switch entry.State {
+	case Queued:
+		transactionID, proposal, err := o.network.Propose(entry.Calls[entry.Step])
+		if err != nil {
+			return fmt.Errorf("failed to create proposal: %w", err)
+		}
+		entry.TransactionID, entry.Proposal, entry.State = transactionID, proposal, Proposed
+	case Proposed:
+		transaction, result, err := o.network.Endorse(entry.Proposal)
+		if err != nil {
+			return fmt.Errorf("failed to endorse transaction: %w", err)
+		}
+		entry.Transaction, entry.Result, entry.State = transaction, result, Endorsed
+	case Endorsed:
+		commit, err := o.network.Submit(entry.Transaction)
+		if err != nil {
+			return fmt.Errorf("failed to submit transaction: %w", err)
+		}
+		entry.Commit, entry.State = commit, Submitted
+	case Submitted:
+		status, err := o.network.Status(entry.Commit)
+		if err != nil {
+			return fmt.Errorf("failed to get commit status: %w", err)
+		}
+		// A duplicate means an earlier submission of this same transaction is the one that committed
+		if !status.Successful && status.Code != peer.TxValidationCode_DUPLICATE_TXID {
+			if graderr.ForValidationCode(status.Code) == graderr.Conflict {
+				// A concurrent update invalidated the transaction, so it is proposed again as a new one
+				entry.State = Queued
+			}
+			return graderr.CommitFailed(status)
+		}
+		entry.Results = append(entry.Results, entry.Result)
+		entry.TransactionIDs = append(entry.TransactionIDs, entry.TransactionID)
+		entry.Step++
+		entry.State = Queued
+		entry.TransactionID, entry.Proposal, entry.Transaction, entry.Commit, entry.Result = "", nil, nil, nil, nil
+	}
+	return nil
+}
+
+// retryable reports whether the entry should stay queued after err. A transaction that committed as
+// invalid is retried only after a conflict, which has already put it back in the Queued state. Once a
+// transaction is endorsed it may have reached the orderer, so it is never dropped before its commit status
+// is known: that can only be learned by submitting the same transaction again or asking for its status.
+// Before that it is retried only if the network could not be reached.
+func retryable(entry *Entry, err error) bool {
+	classified := graderr.Classify(err)
+	if classified.Stage == graderr.StageCommit {
+		return entry.State == Queued
+	}
+	switch entry.State {
+	case Endorsed, Submitted:
+		return true
+	case Proposed:
+		return classified.Code == graderr.Unavailable || classified.Code == graderr.Timeout
+	}
+	return false
+}
+
+// backoff returns the delay before retry attempt n.
+func backoff(attempts int) time.Duration {
+	delay := minBackoff
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+func (o *Outbox) remove(entry *Entry) {
+	for i, queued := range o.entries {
+		if queued == entry {
+			o.entries = append(o.entries[:i], o.entries[i+1:]...)
+			break
+		}
+	}
+	if err := o.save(); err != nil {
+		entry.LastError = err.Error()
+	}
+}
+
+// save writes the entries to a temporary file and renames it over the outbox, so a crash never leaves a
+// partly written outbox. The file holds queued work and grades, so only the current user may read it.
+func (o *Outbox) save() error {
+	var content bytes.Buffer
+	for _, entry := range o.entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("failed to encode outbox entry: %w", err)
+		}
+		content.Write(line)
+		content.WriteByte('\n')
+	}
+
+	dir := filepath.Dir(o.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create outbox directory: %w", err)
+	}
+	temp, err := os.CreateTemp(dir, filepath.Base(o.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to save outbox: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	_, err = temp.Write(content.Bytes())
+	if err == nil {
+		err = temp.Sync()
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), o.path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save outbox: %w", err)
+	}
+	return nil
+}
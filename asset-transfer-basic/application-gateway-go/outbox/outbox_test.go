@@ -0,0 +1,334 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package outbox
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"assetTransfer/classroom"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeNetwork serializes each transaction as its ID, and commits a transaction the first time it reaches
+// the orderer; later copies are invalidated as duplicates. failures makes the named steps fail with a gRPC
+// error of the given code.
+type fakeNetwork struct {
+	proposals int
+	// applied counts how many times each call took effect on the ledger
+	applied   map[string]int
+	calls     map[string]classroom.Call
+	committed map[string]peer.TxValidationCode
+	failures  map[string]codes.Code
+	// lostReplies makes a failing Submit reach the orderer first, as when the connection drops before the
+	// orderer's reply arrives
+	lostReplies bool
+	reject      string
+	// conflicts is the number of transactions to invalidate with a read conflict
+	conflicts int
+}
+
+func newFakeNetwork() *fakeNetwork {
+	return &fakeNetwork{
+		applied:   map[string]int{},
+		calls:     map[string]classroom.Call{},
+		committed: map[string]peer.TxValidationCode{},
+		failures:  map[string]codes.Code{},
+	}
+}
+
+func (n *fakeNetwork) fail(step string) error {
+	if code, ok := n.failures[step]; ok {
+		return status.Error(code, step+" failed")
+	}
+	return nil
+}
+
+func (n *fakeNetwork) Propose(call classroom.Call) (string, []byte, error) {
+	n.proposals++
+	id := fmt.Sprintf("tx%d", n.proposals)
+	n.calls[id] = call
+	return id, []byte(id), nil
+}
+
+func (n *fakeNetwork) Endorse(proposal []byte) ([]byte, []byte, error) {
+	if err := n.fail("endorse"); err != nil {
+		return nil, nil, err
+	}
+	call := n.calls[string(proposal)]
+	if call.Name == n.reject {
+		return nil, nil, status.Error(codes.Aborted, `chaincode response 500, {"code":"Validation","message":"rejected"}`)
+	}
+	return proposal, []byte("result of " + call.Name), nil
+}
+
+func (n *fakeNetwork) Submit(transaction []byte) ([]byte, error) {
+	err := n.fail("submit")
+	if err != nil && !n.lostReplies {
+		return nil, err
+	}
+	id := string(transaction)
+	if _, ok := n.committed[id]; ok {
+		// The ledger already holds the transaction ID, so this copy is invalidated at commit
+		if err != nil {
+			return nil, err
+		}
+		return []byte(id + " duplicate"), nil
+	}
+	if n.conflicts > 0 {
+		n.conflicts--
+		n.committed[id] = peer.TxValidationCode_MVCC_READ_CONFLICT
+		return transaction, err
+	}
+	n.committed[id] = peer.TxValidationCode_VALID
+	n.applied[n.calls[id].Name+" "+strings.Join(n.calls[id].Args, " ")]++
+	if err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+func (n *fakeNetwork) Status(commit []byte) (*client.Status, error) {
+	if err := n.fail("status"); err != nil {
+		return nil, err
+	}
+	id, duplicate := strings.CutSuffix(string(commit), " duplicate")
+	code := n.committed[id]
+	if duplicate {
+		code = peer.TxValidationCode_DUPLICATE_TXID
+	}
+	return &client.Status{Code: code, Successful: code == peer.TxValidationCode_VALID, TransactionID: id}, nil
+}
+
+// clock is a time source the tests move forward to expire the backoff.
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) time() time.Time {
+	return c.now
+}
+
+func openOutbox(t *testing.T, path string, network Network, c *clock) *Outbox {
+	t.Helper()
+	o, err := Open(path, network)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	o.now = c.time
+	return o
+}
+
+var submitWork = []classroom.Call{
+	{Name: "SubmitAssignment", Args: []string{"hw1alice", "answer"}},
+	{Name: "TransferAsset", Args: []string{"hw1alice", "instructor"}},
+}
+
+func TestSubmitCommits(t *testing.T) {
+	network := newFakeNetwork()
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	o := openOutbox(t, path, network, &clock{now: time.Now()})
+
+	entry, err := o.Submit("hw1", submitWork...)
+	if err != nil {
+		t.Fatalf("Submit() error = %v", err)
+	}
+	if len(entry.Results) != 2 || string(entry.Results[1]) != "result of TransferAsset" {
+		t.Errorf("Results = %q", entry.Results)
+	}
+	if entry.ID != "tx1" {
+		t.Errorf("ID = %q, want the first transaction ID", entry.ID)
+	}
+	if !reflect.DeepEqual(entry.TransactionIDs, []string{"tx1", "tx2"}) {
+		t.Errorf("TransactionIDs = %q, want both committed transactions", entry.TransactionIDs)
+	}
+	if pending := o.Pending(); len(pending) != 0 {
+		t.Errorf("Pending() = %d entries, want none", len(pending))
+	}
+	reopened := openOutbox(t, path, network, &clock{})
+	if pending := reopened.Pending(); len(pending) != 0 {
+		t.Errorf("reopened outbox has %d entries, want none", len(pending))
+	}
+}
+
+func TestOfflineSubmitSurvivesRestart(t *testing.T) {
+	network := newFakeNetwork()
+	network.failures["endorse"] = codes.Unavailable
+	path := filepath.Join(t.TempDir(), "outbox.jsonl")
+	c := &clock{now: time.Now()}
+	o := openOutbox(t, path, network, c)
+
+	if _, err := o.Submit("hw1", submitWork...); !errors.Is(err, ErrPending) {
+		t.Fatalf("Submit() error = %v, want ErrPending", err)
+	}
+
+	// The program exits and is started again once the peer is back
+	delete(network.failures, "endorse")
+	o = openOutbox(t, path, network, c)
+	pending := o.Pending()
+	if len(pending) != 1 || pending[0].State != Proposed || pending[0].Attempts != 1 {
+		t.Fatalf("Pending() = %+v, want one proposed entry", pending)
+	}
+	if finished := o.Flush(); len(finished) != 0 {
+		t.Errorf("Flush() before the backoff expired finished %d entries", len(finished))
+	}
+
+	c.now = c.now.Add(minBackoff)
+	finished := o.Flush()
+	if len(finished) != 1 || finished[0].Err != nil || finished[0].Description != "hw1" {
+		t.Fatalf("Flush() = %+v, want hw1 committed", finished)
+	}
+	if network.applied["SubmitAssignment hw1alice answer"] != 1 || network.applied["TransferAsset hw1alice instructor"] != 1 {
+		t.Errorf("applied = %v, want each call once", network.applied)
+	}
+	if network.proposals != 2 {
+		t.Errorf("made %d proposals, want one per call", network.proposals)
+	}
+}
+
+func TestUnknownOutcomeIsNotDuplicated(t *testing.T) {
+	tests := []struct {
+		name        string
+		step        string
+		code        codes.Code
+		lostReplies bool
+	}{
+		{"commit status timeout", "status", codes.DeadlineExceeded, false},
+		{"submit reply lost", "submit", codes.Unavailable, true},
+		{"submit rejected", "submit", codes.Unknown, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			network := newFakeNetwork()
+			network.failures[tt.step] = tt.code
+			network.lostReplies = tt.lostReplies
+			c := &clock{now: time.Now()}
+			o := openOutbox(t, filepath.Join(t.TempDir(), "outbox.jsonl"), network, c)
+
+			grade := classroom.GradeCall("hw1alice", 90, "Good")
+			if _, err := o.Submit("grade", grade); !errors.Is(err, ErrPending) {
+				t.Fatalf("Submit() error = %v, want ErrPending", err)
+			}
+			for attempt := 0; attempt < 3; attempt++ {
+				c.now = c.now.Add(maxBackoff)
+				if finished := o.Flush(); len(finished) != 0 {
+					t.Fatalf("Flush() finished %+v while the network is failing", finished)
+				}
+			}
+
+			delete(network.failures, tt.step)
+			c.now = c.now.Add(maxBackoff)
+			finished := o.Flush()
+			if len(finished) != 1 || finished[0].Err != nil {
+				t.Fatalf("Flush() = %+v, want the grade committed", finished)
+			}
+			if got := network.applied["GradeAssignment hw1alice 90 Good"]; got != 1 {
+				t.Errorf("grade applied %d times, want once", got)
+			}
+			if network.proposals != 1 {
+				t.Errorf("made %d proposals, want the same transaction retried", network.proposals)
+			}
+		})
+	}
+}
+
+func TestConflictProposesAgainWithSameKey(t *testing.T) {
+	network := newFakeNetwork()
+	network.conflicts = 1
+	c := &clock{now: time.Now()}
+	o := openOutbox(t, filepath.Join(t.TempDir(), "outbox.jsonl"), network, c)
+
+	_, err := o.Submit("grade", classroom.GradeCall("hw1alice", 90, ""))
+	if !errors.Is(err, ErrPending) {
+		t.Fatalf("Submit() error = %v, want ErrPending after the conflict", err)
+	}
+	pending := o.Pending()
+	if len(pending) != 1 || pending[0].State != Queued || pending[0].Calls[0].IdempotencyKey == "" {
+		t.Fatalf("Pending() = %+v, want the grade queued to be proposed again with its key", pending)
+	}
+
+	c.now = c.now.Add(minBackoff)
+	finished := o.Flush()
+	if len(finished) != 1 || finished[0].Err != nil {
+		t.Fatalf("Flush() = %+v, want the grade committed", finished)
+	}
+	if !reflect.DeepEqual(finished[0].TransactionIDs, []string{"tx2"}) {
+		t.Errorf("TransactionIDs = %q, want the transaction proposed again", finished[0].TransactionIDs)
+	}
+	if network.proposals != 2 {
+		t.Errorf("made %d proposals, want a new one after the conflict", network.proposals)
+	}
+	if first, second := network.calls["tx1"].IdempotencyKey, network.calls["tx2"].IdempotencyKey; first == "" || first != second {
+		t.Errorf("idempotency keys %q and %q, want the same key for both proposals", first, second)
+	}
+}
+
+func TestRejectedEntryIsDropped(t *testing.T) {
+	network := newFakeNetwork()
+	network.reject = "SubmitAssignment"
+	o := openOutbox(t, filepath.Join(t.TempDir(), "outbox.jsonl"), network, &clock{now: time.Now()})
+
+	_, err := o.Submit("hw1", submitWork...)
+	if err == nil || errors.Is(err, ErrPending) {
+		t.Fatalf("Submit() error = %v, want the rejection", err)
+	}
+	if pending := o.Pending(); len(pending) != 0 {
+		t.Errorf("Pending() = %d entries, want the rejected entry dropped", len(pending))
+	}
+}
+
+func TestEntriesCommitInOrder(t *testing.T) {
+	network := newFakeNetwork()
+	network.failures["endorse"] = codes.Unavailable
+	c := &clock{now: time.Now()}
+	o := openOutbox(t, filepath.Join(t.TempDir(), "outbox.jsonl"), network, c)
+
+	if _, err := o.Submit("grade", classroom.GradeCall("hw1alice", 90, "")); !errors.Is(err, ErrPending) {
+		t.Fatalf("Submit() error = %v, want ErrPending", err)
+	}
+	delete(network.failures, "endorse")
+	network.failures["submit"] = codes.Unavailable
+	_, err := o.Submit("release", classroom.Call{Name: "ReleaseGrades", Args: []string{"cs101", "hw1"}})
+	if !errors.Is(err, ErrPending) || !strings.Contains(err.Error(), `behind "grade"`) {
+		t.Fatalf("Submit() error = %v, want release queued behind the grade", err)
+	}
+	if pending := o.Pending(); len(pending) != 2 || pending[1].State != Proposed {
+		t.Fatalf("Pending() = %+v, want release not yet endorsed", pending)
+	}
+
+	delete(network.failures, "submit")
+	c.now = c.now.Add(maxBackoff)
+	finished := o.Flush()
+	if len(finished) != 2 || finished[0].Description != "grade" || finished[1].Description != "release" {
+		t.Fatalf("Flush() = %+v, want grade then release", finished)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, minBackoff},
+		{2, 2 * minBackoff},
+		{3, 4 * minBackoff},
+		{20, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"assetTransfer/seal"
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// Report is the result of a similarity check across all submissions for an assignment.
+type Report struct {
+	ClassID     string           `json:"classId"`
+	Title       string           `json:"title"`
+	Submissions int              `json:"submissions"`
+	ShingleSize int              `json:"shingleSize"`
+	Threshold   float64          `json:"threshold"`
+	Pairs       []SuspiciousPair `json:"pairs"`
+}
+
+// main compares every submission for an assignment and prints the ranked suspicious pairs. Run it from the
+// application-gateway-go directory so the test-network crypto paths resolve, for example:
+//
+//	go run ./plagiarism -class cs101 -assignment hw1 -record
+func main() {
+	class := flag.String("class", "", "class containing the assignment")
+	title := flag.String("assignment", "", "title of the assignment to check")
+	k := flag.Int("k", 3, "number of words per shingle")
+	threshold := flag.Float64("threshold", 0.5, "minimum Jaccard similarity reported")
+	record := flag.Bool("record", false, "anchor the report hash on the ledger with RecordIntegrityReport")
+	flag.Parse()
+
+	if *class == "" || *title == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	clientConnection := newGrpcConnection()
+	defer clientConnection.Close()
+
+	id := newIdentity()
+	sign := newSign()
+
+	gw, err := client.Connect(
+		id,
+		client.WithSign(sign),
+		client.WithClientConnection(clientConnection),
+		client.WithEvaluateTimeout(5*time.Second),
+		client.WithEndorseTimeout(15*time.Second),
+		client.WithSubmitTimeout(5*time.Second),
+		client.WithCommitStatusTimeout(1*time.Minute),
+	)
+	if err != nil {
+		panic(err)
+	}
+	defer gw.Close()
+
+	chaincodeName := "basic"
+	if ccname := os.Getenv("CHAINCODE_NAME"); ccname != "" {
+		chaincodeName = ccname
+	}
+
+	channelName := "mychannel"
+	if cname := os.Getenv("CHANNEL_NAME"); cname != "" {
+		channelName = cname
+	}
+
+	network := gw.GetNetwork(channelName)
+	contract := network.GetContract(chaincodeName)
+
+	submissions := getSubmissions(contract, *class, *title)
+	report := Report{
+		ClassID:     *class,
+		Title:       *title,
+		Submissions: len(submissions),
+		ShingleSize: *k,
+		Threshold:   *threshold,
+		Pairs:       rankSuspiciousPairs(submissions, *k, *threshold),
+	}
+
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		panic(err)
+	}
+	reportHash := sha256.Sum256(reportJSON)
+
+	printReport(report)
+	fmt.Printf("\nReport SHA-256: %s\n", hex.EncodeToString(reportHash[:]))
+	fmt.Println(formatJSON(reportJSON))
+
+	if *record {
+		recordIntegrityReport(contract, *class, *title, hex.EncodeToString(reportHash[:]))
+	}
+}
+
+func getSubmissions(contract *client.Contract, class string, title string) []Submission {
+	fmt.Printf("\n--> Evaluate Transaction: GetAssignmentSubmissions, function returns all submissions for %s in %s\n", title, class)
+
+	evaluateResult, err := contract.EvaluateTransaction("GetAssignmentSubmissions", class, title)
+	if err != nil {
+		panic(fmt.Errorf("failed to evaluate transaction: %w", err))
+	}
+
+	var assets []struct {
+		ID    string
+		Title string
+		Work  string
+	}
+	if err := json.Unmarshal(evaluateResult, &assets); err != nil {
+		panic(fmt.Errorf("failed to parse submissions: %w", err))
+	}
+
+	// Encrypted work is opened with the instructor's class keys so the comparison sees the plaintext
+	keyring := seal.KeyringFromEnv()
+	submissions := make([]Submission, 0, len(assets))
+	for _, asset := range assets {
+		work, err := keyring.Open(class, asset.ID, asset.Work)
+		if err != nil {
+			panic(fmt.Errorf("failed to open work for %s: %w", asset.ID, err))
+		}
+		submissions = append(submissions, Submission{
+			ID:   asset.ID,
+			Work: work,
+		})
+	}
+	return submissions
+}
+
+func printReport(report Report) {
+	fmt.Printf("\nCompared %d submissions for %s in %s\n", report.Submissions, report.Title, report.ClassID)
+	if len(report.Pairs) == 0 {
+		fmt.Printf("No pairs at or above %.2f similarity\n", report.Threshold)
+		return
+	}
+	fmt.Println(strings.Repeat("-", 60))
+	for i, pair := range report.Pairs {
+		fmt.Printf("%3d. %-20s %-20s %6.2f%%\n", i+1, pair.First, pair.Second, pair.Similarity*100)
+	}
+}
+
+func recordIntegrityReport(contract *client.Contract, class string, title string, reportHash string) {
+	fmt.Printf("\n--> Submit Transaction: RecordIntegrityReport, anchors the report hash on the ledger\n")
+
+	_, err := contract.SubmitTransaction("RecordIntegrityReport", class, title, reportHash)
+	if err != nil {
+		panic(fmt.Errorf("failed to submit transaction: %w", err))
+	}
+
+	fmt.Printf("*** Transaction committed successfully\n")
+}
+
+// Format JSON data
+func formatJSON(data []byte) string {
+	var prettyJSON bytes.Buffer
+	if err := json.Indent(&prettyJSON, data, "", "  "); err != nil {
+		panic(fmt.Errorf("failed to parse JSON: %w", err))
+	}
+	return prettyJSON.String()
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Submission is the work handed in by one student for an assignment.
+type Submission struct {
+	ID   string
+	Work string
+}
+
+// SuspiciousPair is a pair of submissions whose work is similar enough to be reviewed.
+type SuspiciousPair struct {
+	First      string  `json:"first"`
+	Second     string  `json:"second"`
+	Similarity float64 `json:"similarity"`
+}
+
+// tokenize splits work into lower-case words, ignoring punctuation and whitespace.
+func tokenize(work string) []string {
+	return strings.FieldsFunc(strings.ToLower(work), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// shingles returns the set of k-word shingles in work. Work shorter than k words is a single shingle.
+func shingles(work string, k int) map[string]struct{} {
+	tokens := tokenize(work)
+	result := make(map[string]struct{})
+	if len(tokens) == 0 {
+		return result
+	}
+	if len(tokens) < k {
+		result[strings.Join(tokens, " ")] = struct{}{}
+		return result
+	}
+	for i := 0; i+k <= len(tokens); i++ {
+		result[strings.Join(tokens[i:i+k], " ")] = struct{}{}
+	}
+	return result
+}
+
+// jaccard returns the Jaccard similarity of two shingle sets.
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 0
+	}
+	intersection := 0
+	for shingle := range a {
+		if _, ok := b[shingle]; ok {
+			intersection++
+		}
+	}
+	return float64(intersection) / float64(len(a)+len(b)-intersection)
+}
+
+// rankSuspiciousPairs compares every pair of submissions and returns those at or above the threshold,
+// most similar first.
+func rankSuspiciousPairs(submissions []Submission, k int, threshold float64) []SuspiciousPair {
+	sets := make([]map[string]struct{}, len(submissions))
+	for i, submission := range submissions {
+		sets[i] = shingles(submission.Work, k)
+	}
+
+	pairs := []SuspiciousPair{}
+	for i := 0; i < len(submissions); i++ {
+		for j := i + 1; j < len(submissions); j++ {
+			similarity := jaccard(sets[i], sets[j])
+			if similarity < threshold {
+				continue
+			}
+			pairs = append(pairs, SuspiciousPair{
+				First:      submissions[i].ID,
+				Second:     submissions[j].ID,
+				Similarity: similarity,
+			})
+		}
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		return pairs[i].Similarity > pairs[j].Similarity
+	})
+	return pairs
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"testing"
+)
+
+func TestShingles(t *testing.T) {
+	set := shingles("The quick, brown fox. The quick brown", 3)
+	for _, expected := range []string{"the quick brown", "quick brown fox", "brown fox the", "fox the quick"} {
+		if _, ok := set[expected]; !ok {
+			t.Errorf("missing shingle %q in %v", expected, set)
+		}
+	}
+	if len(set) != 4 {
+		t.Errorf("expected 4 shingles, got %d", len(set))
+	}
+
+	if short := shingles("two words", 3); len(short) != 1 {
+		t.Errorf("expected work shorter than k to be a single shingle, got %v", short)
+	}
+	if empty := shingles(" ... ", 3); len(empty) != 0 {
+		t.Errorf("expected no shingles for empty work, got %v", empty)
+	}
+}
+
+func TestJaccard(t *testing.T) {
+	a := shingles("a b c d", 2)
+	b := shingles("a b c e", 2)
+	if similarity := jaccard(a, b); similarity != 0.5 {
+		t.Errorf("expected similarity 0.5, got %v", similarity)
+	}
+	if similarity := jaccard(a, a); similarity != 1 {
+		t.Errorf("expected identical work to have similarity 1, got %v", similarity)
+	}
+	if similarity := jaccard(shingles("", 2), shingles("", 2)); similarity != 0 {
+		t.Errorf("expected empty work to have similarity 0, got %v", similarity)
+	}
+}
+
+func TestRankSuspiciousPairs(t *testing.T) {
+	submissions := []Submission{
+		{ID: "hw1alice", Work: "The derivative of x squared is two x"},
+		{ID: "hw1bob", Work: "the derivative of x squared is 2 x"},
+		{ID: "hw1carol", Work: "Integrate by parts and simplify the result"},
+		{ID: "hw1dave", Work: "The derivative of x squared is two x!"},
+	}
+
+	pairs := rankSuspiciousPairs(submissions, 3, 0.4)
+	if len(pairs) != 3 {
+		t.Fatalf("expected 3 suspicious pairs, got %v", pairs)
+	}
+	if pairs[0].First != "hw1alice" || pairs[0].Second != "hw1dave" || pairs[0].Similarity != 1 {
+		t.Errorf("expected identical work to rank first, got %v", pairs[0])
+	}
+	for i := 1; i < len(pairs); i++ {
+		if pairs[i].Similarity > pairs[i-1].Similarity {
+			t.Errorf("pairs not ranked by similarity: %v", pairs)
+		}
+		if pairs[i].First == "hw1carol" || pairs[i].Second == "hw1carol" {
+			t.Errorf("unrelated work reported as suspicious: %v", pairs[i])
+		}
+	}
+
+	if pairs := rankSuspiciousPairs(submissions, 3, 1.1); len(pairs) != 0 {
+		t.Errorf("expected no pairs above threshold, got %v", pairs)
+	}
+}
@@ -0,0 +1,276 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package receipt gives students proof that their submission is on the ledger. A receipt names the block a
+// submission was committed in, with the block's hash and the hash of the work as it was recorded, and can
+// later be checked against the block fetched from any peer of the channel.
+//
+// A block is taken as containing a submission when its header hash matches the receipt, its data hashes to
+// the data hash in its header, and the transaction in its data was marked valid by the peers and wrote the
+// submitted work to the assignment.
+package receipt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// Receipt records where a submission was committed on the ledger.
+type Receipt struct {
+	ChannelID     string
+	TransactionID string
+	BlockNumber   uint64
+	// BlockHash is the hex SHA-256 hash of the block header, which chains it to the following block
+	BlockHash string
+	// Timestamp is when the student's client created the transaction
+	Timestamp    time.Time
+	AssignmentID string
+	// ContentHash is the hex SHA-256 hash of the work as written to the ledger, sealed if the class has a key
+	ContentHash string
+}
+
+// Ledger evaluates transactions of the qscc system chaincode. *client.Contract implements it.
+type Ledger interface {
+	EvaluateTransaction(name string, args ...string) ([]byte, error)
+}
+
+// ContentHash returns the hex SHA-256 hash of submitted work.
+func ContentHash(work string) string {
+	sum := sha256.Sum256([]byte(work))
+	return hex.EncodeToString(sum[:])
+}
+
+// BlockByTransaction fetches the block of a channel holding a transaction.
+func BlockByTransaction(qscc Ledger, channel string, transactionID string) (*common.Block, error) {
+	return fetchBlock(qscc, "GetBlockByTxID", channel, transactionID)
+}
+
+// BlockByNumber fetches a block of a channel.
+func BlockByNumber(qscc Ledger, channel string, number uint64) (*common.Block, error) {
+	return fetchBlock(qscc, "GetBlockByNumber", channel, strconv.FormatUint(number, 10))
+}
+
+func fetchBlock(qscc Ledger, name string, args ...string) (*common.Block, error) {
+	blockBytes, err := qscc.EvaluateTransaction(name, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch block: %w", err)
+	}
+	block := &common.Block{}
+	if err := proto.Unmarshal(blockBytes, block); err != nil {
+		return nil, fmt.Errorf("failed to parse block: %w", err)
+	}
+	return block, nil
+}
+
+// New returns the receipt for the submission of work to an assignment by a transaction in block.
+func New(block *common.Block, channel string, transactionID string, assignmentID string) (*Receipt, error) {
+	if err := checkBlock(block); err != nil {
+		return nil, err
+	}
+	timestamp, work, err := findSubmission(block, channel, transactionID, assignmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Receipt{
+		ChannelID:     channel,
+		TransactionID: transactionID,
+		BlockNumber:   block.Header.Number,
+		BlockHash:     hex.EncodeToString(HeaderHash(block.Header)),
+		Timestamp:     timestamp,
+		AssignmentID:  assignmentID,
+		ContentHash:   ContentHash(work),
+	}, nil
+}
+
+// Verify checks that block is the one the receipt names and that it holds the submission as recorded.
+func Verify(r *Receipt, block *common.Block) error {
+	if err := checkBlock(block); err != nil {
+		return err
+	}
+	if block.Header.Number != r.BlockNumber {
+		return fmt.Errorf("block %d is not block %d of the receipt", block.Header.Number, r.BlockNumber)
+	}
+	if hash := hex.EncodeToString(HeaderHash(block.Header)); hash != r.BlockHash {
+		return fmt.Errorf("block %d has hash %s, the receipt records %s", r.BlockNumber, hash, r.BlockHash)
+	}
+
+	timestamp, work, err := findSubmission(block, r.ChannelID, r.TransactionID, r.AssignmentID)
+	if err != nil {
+		return err
+	}
+	if !timestamp.Equal(r.Timestamp) {
+		return fmt.Errorf("transaction %s has timestamp %s, the receipt records %s", r.TransactionID, timestamp.Format(time.RFC3339Nano), r.Timestamp.Format(time.RFC3339Nano))
+	}
+	if hash := ContentHash(work); hash != r.ContentHash {
+		return fmt.Errorf("transaction %s recorded work with hash %s, the receipt records %s", r.TransactionID, hash, r.ContentHash)
+	}
+	return nil
+}
+
+// Save writes the receipt to path as JSON, creating its directory if needed.
+func (r *Receipt) Save(path string) error {
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode receipt: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return fmt.Errorf("failed to create receipt directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(content, '\n'), 0o600); err != nil {
+		return fmt.Errorf("failed to write receipt: %w", err)
+	}
+	return nil
+}
+
+// Load reads a receipt saved by Save.
+func Load(path string) (*Receipt, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read receipt: %w", err)
+	}
+	var r Receipt
+	if err := json.Unmarshal(content, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse receipt: %w", err)
+	}
+	return &r, nil
+}
+
+// HeaderHash returns the hash of a block header the way peers compute it: the SHA-256 hash of the ASN.1
+// encoding of its number, previous hash and data hash.
+func HeaderHash(header *common.BlockHeader) []byte {
+	encoded, _ := asn1.Marshal(struct {
+		Number       *big.Int
+		PreviousHash []byte
+		DataHash     []byte
+	}{new(big.Int).SetUint64(header.Number), header.PreviousHash, header.DataHash})
+	sum := sha256.Sum256(encoded)
+	return sum[:]
+}
+
+// DataHash returns the hash of the transactions of a block, which its header records.
+func DataHash(data *common.BlockData) []byte {
+	sum := sha256.Sum256(bytes.Join(data.GetData(), nil))
+	return sum[:]
+}
+
+// checkBlock checks that a block is complete and that its data matches its header.
+func checkBlock(block *common.Block) error {
+	if block.GetHeader() == nil || block.GetData() == nil {
+		return fmt.Errorf("the block is incomplete")
+	}
+	if !bytes.Equal(DataHash(block.Data), block.Header.DataHash) {
+		return fmt.Errorf("the data of block %d does not match its header", block.Header.Number)
+	}
+	return nil
+}
+
+// findSubmission finds a transaction in a block, checks that the peers marked it valid and returns its
+// timestamp and the work it wrote to the assignment.
+func findSubmission(block *common.Block, channel string, transactionID string, assignmentID string) (time.Time, string, error) {
+	for i, envelopeBytes := range block.Data.Data {
+		envelope := &common.Envelope{}
+		if err := proto.Unmarshal(envelopeBytes, envelope); err != nil {
+			return time.Time{}, "", fmt.Errorf("failed to parse transaction envelope: %w", err)
+		}
+		payload := &common.Payload{}
+		if err := proto.Unmarshal(envelope.Payload, payload); err != nil {
+			return time.Time{}, "", fmt.Errorf("failed to parse transaction payload: %w", err)
+		}
+		channelHeader := &common.ChannelHeader{}
+		if err := proto.Unmarshal(payload.GetHeader().GetChannelHeader(), channelHeader); err != nil {
+			return time.Time{}, "", fmt.Errorf("failed to parse channel header: %w", err)
+		}
+		if channelHeader.TxId != transactionID {
+			continue
+		}
+
+		if channelHeader.ChannelId != channel {
+			return time.Time{}, "", fmt.Errorf("transaction %s is on channel %s, not %s", transactionID, channelHeader.ChannelId, channel)
+		}
+		if code := validationCode(block, i); code != peer.TxValidationCode_VALID {
+			return time.Time{}, "", fmt.Errorf("transaction %s is in block %d but was marked %s", transactionID, block.Header.Number, code)
+		}
+		work, err := writtenWork(payload.Data, assignmentID)
+		if err != nil {
+			return time.Time{}, "", fmt.Errorf("transaction %s: %w", transactionID, err)
+		}
+		return channelHeader.GetTimestamp().AsTime(), work, nil
+	}
+	return time.Time{}, "", fmt.Errorf("transaction %s is not in block %d", transactionID, block.Header.Number)
+}
+
+// validationCode returns the validation code the peers recorded for the transaction at index i of a block.
+func validationCode(block *common.Block, i int) peer.TxValidationCode {
+	metadata := block.GetMetadata().GetMetadata()
+	if len(metadata) <= int(common.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		return peer.TxValidationCode_NOT_VALIDATED
+	}
+	filter := metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER]
+	if i >= len(filter) {
+		return peer.TxValidationCode_NOT_VALIDATED
+	}
+	return peer.TxValidationCode(filter[i])
+}
+
+// writtenWork returns the work a transaction wrote to an assignment, from the assignment JSON in its write
+// set.
+func writtenWork(transactionBytes []byte, assignmentID string) (string, error) {
+	transaction := &peer.Transaction{}
+	if err := proto.Unmarshal(transactionBytes, transaction); err != nil {
+		return "", fmt.Errorf("failed to parse transaction: %w", err)
+	}
+	for _, action := range transaction.Actions {
+		actionPayload := &peer.ChaincodeActionPayload{}
+		if err := proto.Unmarshal(action.Payload, actionPayload); err != nil {
+			return "", fmt.Errorf("failed to parse action: %w", err)
+		}
+		responsePayload := &peer.ProposalResponsePayload{}
+		if err := proto.Unmarshal(actionPayload.GetAction().GetProposalResponsePayload(), responsePayload); err != nil {
+			return "", fmt.Errorf("failed to parse proposal response: %w", err)
+		}
+		chaincodeAction := &peer.ChaincodeAction{}
+		if err := proto.Unmarshal(responsePayload.Extension, chaincodeAction); err != nil {
+			return "", fmt.Errorf("failed to parse chaincode action: %w", err)
+		}
+		readWriteSet := &rwset.TxReadWriteSet{}
+		if err := proto.Unmarshal(chaincodeAction.Results, readWriteSet); err != nil {
+			return "", fmt.Errorf("failed to parse read-write set: %w", err)
+		}
+
+		for _, namespace := range readWriteSet.NsRwset {
+			kvSet := &kvrwset.KVRWSet{}
+			if err := proto.Unmarshal(namespace.Rwset, kvSet); err != nil {
+				return "", fmt.Errorf("failed to parse read-write set: %w", err)
+			}
+			for _, write := range kvSet.Writes {
+				if write.Key != assignmentID || write.IsDelete {
+					continue
+				}
+				var asset struct{ Work string }
+				if err := json.Unmarshal(write.Value, &asset); err != nil {
+					return "", fmt.Errorf("failed to parse assignment %s: %w", assignmentID, err)
+				}
+				return asset.Work, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no write to assignment %s", assignmentID)
+}
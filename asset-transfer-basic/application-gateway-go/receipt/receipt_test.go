@@ -0,0 +1,157 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package receipt
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var submittedAt = time.Date(2023, 4, 24, 12, 0, 0, 0, time.UTC)
+
+// envelope serializes a transaction on mychannel that writes key with value.
+func envelope(t *testing.T, transactionID string, key string, value string) []byte {
+	t.Helper()
+	marshal := func(m proto.Message) []byte {
+		b, err := proto.Marshal(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	kvSet := marshal(&kvrwset.KVRWSet{Writes: []*kvrwset.KVWrite{{Key: key, Value: []byte(value)}}})
+	results := marshal(&rwset.TxReadWriteSet{NsRwset: []*rwset.NsReadWriteSet{{Namespace: "basic", Rwset: kvSet}}})
+	extension := marshal(&peer.ChaincodeAction{Results: results})
+	responsePayload := marshal(&peer.ProposalResponsePayload{Extension: extension})
+	actionPayload := marshal(&peer.ChaincodeActionPayload{Action: &peer.ChaincodeEndorsedAction{ProposalResponsePayload: responsePayload}})
+	transaction := marshal(&peer.Transaction{Actions: []*peer.TransactionAction{{Payload: actionPayload}}})
+	channelHeader := marshal(&common.ChannelHeader{ChannelId: "mychannel", TxId: transactionID, Timestamp: timestamppb.New(submittedAt)})
+	payload := marshal(&common.Payload{Header: &common.Header{ChannelHeader: channelHeader}, Data: transaction})
+	return marshal(&common.Envelope{Payload: payload})
+}
+
+// newBlock returns block 7 holding an unrelated transaction and alice's submission, marked with codes.
+func newBlock(t *testing.T, codes ...peer.TxValidationCode) *common.Block {
+	t.Helper()
+	data := &common.BlockData{Data: [][]byte{
+		envelope(t, "tx1", "hw1bob", `{"Work":"bob's answer"}`),
+		envelope(t, "tx2", "hw1alice", `{"ID":"hw1alice","Work":"alice's answer"}`),
+	}}
+	filter := make([]byte, len(codes))
+	for i, code := range codes {
+		filter[i] = byte(code)
+	}
+	metadata := make([][]byte, common.BlockMetadataIndex_TRANSACTIONS_FILTER+1)
+	metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER] = filter
+	return &common.Block{
+		Header:   &common.BlockHeader{Number: 7, PreviousHash: []byte("previous"), DataHash: DataHash(data)},
+		Data:     data,
+		Metadata: &common.BlockMetadata{Metadata: metadata},
+	}
+}
+
+// fakeQSCC serves blocks as qscc does, by transaction ID and by number.
+type fakeQSCC struct {
+	block *common.Block
+}
+
+func (q *fakeQSCC) EvaluateTransaction(name string, args ...string) ([]byte, error) {
+	if args[0] != "mychannel" {
+		return nil, errors.New("unknown channel " + args[0])
+	}
+	if (name == "GetBlockByTxID" && args[1] == "tx2") || (name == "GetBlockByNumber" && args[1] == "7") {
+		return proto.Marshal(q.block)
+	}
+	return nil, errors.New("block not found")
+}
+
+func TestReceiptVerifies(t *testing.T) {
+	qscc := &fakeQSCC{block: newBlock(t, peer.TxValidationCode_VALID, peer.TxValidationCode_VALID)}
+	block, err := BlockByTransaction(qscc, "mychannel", "tx2")
+	if err != nil {
+		t.Fatalf("BlockByTransaction() error = %v", err)
+	}
+	r, err := New(block, "mychannel", "tx2", "hw1alice")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if r.BlockNumber != 7 || !r.Timestamp.Equal(submittedAt) || r.ContentHash != ContentHash("alice's answer") || len(r.BlockHash) != 64 {
+		t.Errorf("New() = %+v", r)
+	}
+
+	path := filepath.Join(t.TempDir(), "receipts", "hw1alice.json")
+	if err := r.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	block, err = BlockByNumber(qscc, "mychannel", loaded.BlockNumber)
+	if err != nil {
+		t.Fatalf("BlockByNumber() error = %v", err)
+	}
+	if err := Verify(loaded, block); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+}
+
+func TestReceiptRejects(t *testing.T) {
+	valid := newBlock(t, peer.TxValidationCode_VALID, peer.TxValidationCode_VALID)
+	r, err := New(valid, "mychannel", "tx2", "hw1alice")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tampered := newBlock(t, peer.TxValidationCode_VALID, peer.TxValidationCode_VALID)
+	tampered.Data.Data[1] = envelope(t, "tx2", "hw1alice", `{"Work":"someone else's answer"}`)
+	rehashed := newBlock(t, peer.TxValidationCode_VALID, peer.TxValidationCode_VALID)
+	rehashed.Data = tampered.Data
+	rehashed.Header.DataHash = DataHash(tampered.Data)
+	otherWork := *r
+	otherWork.ContentHash = ContentHash("another answer")
+	otherBlock := *r
+	otherBlock.BlockNumber = 8
+
+	tests := []struct {
+		name    string
+		receipt *Receipt
+		block   *common.Block
+		want    string
+	}{
+		{"invalid transaction", r, newBlock(t, peer.TxValidationCode_VALID, peer.TxValidationCode_MVCC_READ_CONFLICT), "marked MVCC_READ_CONFLICT"},
+		{"data changed", r, tampered, "does not match its header"},
+		{"header changed", r, rehashed, "the receipt records"},
+		{"other work", &otherWork, valid, "recorded work with hash"},
+		{"other block", &otherBlock, valid, "is not block 8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.receipt, tt.block)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Verify() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := New(valid, "mychannel", "tx3", "hw1alice"); err == nil || !strings.Contains(err.Error(), "is not in block 7") {
+		t.Errorf("New() for a missing transaction error = %v", err)
+	}
+	if _, err := New(valid, "mychannel", "tx1", "hw1alice"); err == nil || !strings.Contains(err.Error(), "no write to assignment hw1alice") {
+		t.Errorf("New() for another assignment's transaction error = %v", err)
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package seal
+
+import (
+	"crypto/ecdh"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Keyring holds the instructor's class private keys, one file per class and key version, so work sealed
+// to a key that has since been rotated can still be opened.
+type Keyring struct {
+	dir string
+}
+
+// NewKeyring returns the keyring stored in dir.
+func NewKeyring(dir string) *Keyring {
+	return &Keyring{dir: dir}
+}
+
+// KeyringFromEnv returns the keyring in the CLASS_KEY_DIR directory, or "class-keys" if it is not set.
+func KeyringFromEnv() *Keyring {
+	dir := os.Getenv("CLASS_KEY_DIR")
+	if dir == "" {
+		dir = "class-keys"
+	}
+	return NewKeyring(dir)
+}
+
+func (k *Keyring) path(class string, version int) string {
+	// Class names are chosen by users, so keep them from escaping the keyring directory
+	name := strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(class)
+	return filepath.Join(k.dir, fmt.Sprintf("%s.v%d.key", name, version))
+}
+
+// Save stores a class private key, readable only by the current user.
+func (k *Keyring) Save(class string, version int, key *ecdh.PrivateKey) error {
+	if err := os.MkdirAll(k.dir, 0o700); err != nil {
+		return err
+	}
+	encoded := base64.StdEncoding.EncodeToString(key.Bytes())
+	return os.WriteFile(k.path(class, version), []byte(encoded+"\n"), 0o600)
+}
+
+// Load returns the class private key with the given version.
+func (k *Keyring) Load(class string, version int) (*ecdh.PrivateKey, error) {
+	encoded, err := os.ReadFile(k.path(class, version))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no private key for version %d of class %s in %s", version, class, k.dir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	keyBytes, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return nil, fmt.Errorf("malformed class key: %w", err)
+	}
+	return ecdh.X25519().NewPrivateKey(keyBytes)
+}
+
+// Open returns the plaintext of work submitted for assetID in class. Work that was not sealed is returned
+// unchanged.
+func (k *Keyring) Open(class string, assetID string, work string) (string, error) {
+	if !IsSealed(work) {
+		return work, nil
+	}
+	version, envelope, err := parse(work)
+	if err != nil {
+		return "", err
+	}
+	key, err := k.Load(class, version)
+	if err != nil {
+		return "", err
+	}
+	return open(key, assetID, envelope)
+}
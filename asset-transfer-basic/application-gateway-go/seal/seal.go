@@ -0,0 +1,145 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package seal encrypts submitted work on the student's machine so that only the instructor can read it,
+// even though the ciphertext is stored on a ledger every peer administrator can see.
+//
+// Work is sealed to the X25519 public key published on the class record: a fresh ephemeral key pair is
+// generated for every submission, the X25519 shared secret is hashed with both public keys into an AES-256
+// key, and the work is encrypted with AES-GCM using the submission ID as additional data so ciphertext
+// cannot be moved to another student's submission.
+package seal
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// prefix marks sealed work, and is followed by the class key version and the base64 envelope.
+const prefix = "sealed:x25519-aes256gcm:v"
+
+// kdfLabel separates keys derived by this scheme from any other use of the same shared secret.
+const kdfLabel = "cryptograder seal v1"
+
+// GenerateKey creates a new class key pair.
+func GenerateKey() (*ecdh.PrivateKey, error) {
+	return ecdh.X25519().GenerateKey(rand.Reader)
+}
+
+// EncodePublicKey returns the base64 form of a public key, as published on the class record.
+func EncodePublicKey(key *ecdh.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(key.Bytes())
+}
+
+// IsSealed reports whether work was produced by Seal.
+func IsSealed(work string) bool {
+	return strings.HasPrefix(work, prefix)
+}
+
+// Seal encrypts work for the submission assetID to the class public key with the given version.
+func Seal(publicKey string, keyVersion int, assetID string, work string) (string, error) {
+	recipientBytes, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return "", fmt.Errorf("invalid class key: %w", err)
+	}
+	recipient, err := ecdh.X25519().NewPublicKey(recipientBytes)
+	if err != nil {
+		return "", fmt.Errorf("invalid class key: %w", err)
+	}
+
+	ephemeral, err := GenerateKey()
+	if err != nil {
+		return "", err
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAEAD(shared, ephemeral.PublicKey().Bytes(), recipientBytes)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	envelope := append(ephemeral.PublicKey().Bytes(), nonce...)
+	envelope = aead.Seal(envelope, nonce, []byte(work), []byte(assetID))
+	return prefix + strconv.Itoa(keyVersion) + ":" + base64.StdEncoding.EncodeToString(envelope), nil
+}
+
+// parse splits sealed work into the class key version and the decoded envelope.
+func parse(sealed string) (int, []byte, error) {
+	if !IsSealed(sealed) {
+		return 0, nil, errors.New("work is not sealed")
+	}
+	version, encoded, ok := strings.Cut(strings.TrimPrefix(sealed, prefix), ":")
+	if !ok {
+		return 0, nil, errors.New("malformed sealed work")
+	}
+	keyVersion, err := strconv.Atoi(version)
+	if err != nil {
+		return 0, nil, fmt.Errorf("malformed key version: %w", err)
+	}
+	envelope, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return 0, nil, fmt.Errorf("malformed sealed work: %w", err)
+	}
+	return keyVersion, envelope, nil
+}
+
+// open decrypts an envelope with the class private key.
+func open(key *ecdh.PrivateKey, assetID string, envelope []byte) (string, error) {
+	const keySize = 32
+	if len(envelope) < keySize {
+		return "", errors.New("malformed sealed work")
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(envelope[:keySize])
+	if err != nil {
+		return "", err
+	}
+	shared, err := key.ECDH(ephemeral)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := newAEAD(shared, envelope[:keySize], key.PublicKey().Bytes())
+	if err != nil {
+		return "", err
+	}
+	rest := envelope[keySize:]
+	if len(rest) < aead.NonceSize() {
+		return "", errors.New("malformed sealed work")
+	}
+	work, err := aead.Open(nil, rest[:aead.NonceSize()], rest[aead.NonceSize():], []byte(assetID))
+	if err != nil {
+		return "", errors.New("unable to decrypt work: wrong key or tampered ciphertext")
+	}
+	return string(work), nil
+}
+
+func newAEAD(shared []byte, ephemeral []byte, recipient []byte) (cipher.AEAD, error) {
+	digest := sha256.New()
+	digest.Write([]byte(kdfLabel))
+	digest.Write(shared)
+	digest.Write(ephemeral)
+	digest.Write(recipient)
+
+	block, err := aes.NewCipher(digest.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
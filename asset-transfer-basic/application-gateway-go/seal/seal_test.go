@@ -0,0 +1,86 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package seal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSealAndOpen(t *testing.T) {
+	keyring := NewKeyring(t.TempDir())
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	if err := keyring.Save("cs101", 1, key); err != nil {
+		t.Fatalf("failed to save key: %v", err)
+	}
+
+	sealed, err := Seal(EncodePublicKey(key.PublicKey()), 1, "hw1alice", "my answer")
+	if err != nil {
+		t.Fatalf("failed to seal: %v", err)
+	}
+	if !IsSealed(sealed) || strings.Contains(sealed, "my answer") {
+		t.Fatalf("expected sealed work to hide the answer, got %q", sealed)
+	}
+
+	work, err := keyring.Open("cs101", "hw1alice", sealed)
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	if work != "my answer" {
+		t.Errorf("expected %q, got %q", "my answer", work)
+	}
+
+	if _, err := keyring.Open("cs101", "hw1bob", sealed); err == nil {
+		t.Error("expected work moved to another submission to be rejected")
+	}
+
+	tampered := sealed[:len(sealed)-4] + "AAA="
+	if _, err := keyring.Open("cs101", "hw1alice", tampered); err == nil {
+		t.Error("expected tampered work to be rejected")
+	}
+
+	if work, err := keyring.Open("cs101", "hw1alice", "plain answer"); err != nil || work != "plain answer" {
+		t.Errorf("expected unsealed work to be returned unchanged, got %q, %v", work, err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	keyring := NewKeyring(t.TempDir())
+	oldKey, _ := GenerateKey()
+	newKey, _ := GenerateKey()
+	if err := keyring.Save("cs101", 1, oldKey); err != nil {
+		t.Fatalf("failed to save key: %v", err)
+	}
+	if err := keyring.Save("cs101", 2, newKey); err != nil {
+		t.Fatalf("failed to save key: %v", err)
+	}
+
+	before, _ := Seal(EncodePublicKey(oldKey.PublicKey()), 1, "hw1alice", "before rotation")
+	after, _ := Seal(EncodePublicKey(newKey.PublicKey()), 2, "hw2alice", "after rotation")
+
+	if work, err := keyring.Open("cs101", "hw1alice", before); err != nil || work != "before rotation" {
+		t.Errorf("expected work sealed to the old key to open, got %q, %v", work, err)
+	}
+	if work, err := keyring.Open("cs101", "hw2alice", after); err != nil || work != "after rotation" {
+		t.Errorf("expected work sealed to the new key to open, got %q, %v", work, err)
+	}
+
+	if _, err := NewKeyring(t.TempDir()).Open("cs101", "hw1alice", before); err == nil {
+		t.Error("expected opening without the private key to fail")
+	}
+}
+
+func TestSealRejectsInvalidKey(t *testing.T) {
+	if _, err := Seal("not base64!", 1, "hw1alice", "work"); err == nil {
+		t.Error("expected invalid key to be rejected")
+	}
+	if _, err := Seal("c2hvcnQ=", 1, "hw1alice", "work"); err == nil {
+		t.Error("expected short key to be rejected")
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FileStore keeps content in a local directory, sharded by the first two characters of the hash.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a store rooted at dir, creating the directory if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(hash string) string {
+	return filepath.Join(s.dir, hash[:2], hash)
+}
+
+// Put implements Store. Content is written to a temporary file and renamed into place, so a reader never
+// sees a partially written file.
+func (s *FileStore) Put(ctx context.Context, hash string, size int64, r io.Reader) error {
+	if !ValidHash(hash) {
+		return fmt.Errorf("invalid content hash %q", hash)
+	}
+
+	target := s.path(hash)
+	if _, err := os.Stat(target); err == nil {
+		return nil // content-addressed, so identical content is already stored
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+		return err
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(target), hash+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+
+	written, err := io.Copy(temp, r)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if written != size {
+		return fmt.Errorf("wrote %d bytes but expected %d", written, size)
+	}
+
+	return os.Rename(temp.Name(), target)
+}
+
+// Get implements Store.
+func (s *FileStore) Get(ctx context.Context, hash string) (io.ReadCloser, error) {
+	if !ValidHash(hash) {
+		return nil, fmt.Errorf("invalid content hash %q", hash)
+	}
+
+	file, err := os.Open(s.path(hash))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package storage keeps submission attachments in an off-chain content-addressed store. Files are addressed
+// by the hex SHA-256 of their content, which is the only thing recorded on the ledger alongside their size,
+// so anything read back can be checked against the ledger.
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// ErrNotFound is returned when the store has no content with the requested hash.
+var ErrNotFound = errors.New("content not found")
+
+// Store is a content-addressed blob store.
+type Store interface {
+	// Put stores size bytes read from r under hash. Implementations may assume the caller has already
+	// computed hash from the content.
+	Put(ctx context.Context, hash string, size int64, r io.Reader) error
+	// Get returns the content stored under hash, or ErrNotFound.
+	Get(ctx context.Context, hash string) (io.ReadCloser, error)
+}
+
+var hashPattern = regexp.MustCompile("^[0-9a-f]{64}$")
+
+// ValidHash reports whether hash is a lower-case hex SHA-256 digest.
+func ValidHash(hash string) bool {
+	return hashPattern.MatchString(hash)
+}
+
+// Upload stores the content of r and returns its SHA-256 hash and size. The content is spooled to a
+// temporary file first so the hash is known before anything is written to the store.
+func Upload(ctx context.Context, store Store, r io.Reader) (string, int64, error) {
+	spool, err := os.CreateTemp("", "upload")
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	digest := sha256.New()
+	size, err := io.Copy(io.MultiWriter(spool, digest), r)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read upload: %w", err)
+	}
+	hash := hex.EncodeToString(digest.Sum(nil))
+
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+	if err := store.Put(ctx, hash, size, spool); err != nil {
+		return "", 0, fmt.Errorf("failed to store %s: %w", hash, err)
+	}
+	return hash, size, nil
+}
+
+// Download copies the content stored under hash to w, failing if what the store returns does not have
+// the expected hash and size. Callers should write to a temporary location and only keep it on success.
+func Download(ctx context.Context, store Store, hash string, size int64, w io.Writer) error {
+	if !ValidHash(hash) {
+		return fmt.Errorf("invalid content hash %q", hash)
+	}
+
+	content, err := store.Get(ctx, hash)
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
+	digest := sha256.New()
+	// Read one byte past the expected size so oversized content is detected without reading all of it
+	written, err := io.Copy(io.MultiWriter(w, digest), io.LimitReader(content, size+1))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", hash, err)
+	}
+	if written != size {
+		return fmt.Errorf("content %s has size %d but the ledger records %d", hash, written, size)
+	}
+	if actual := hex.EncodeToString(digest.Sum(nil)); actual != hash {
+		return fmt.Errorf("content %s has hash %s and does not match the ledger", hash, actual)
+	}
+	return nil
+}
+
+// DownloadFile saves the content stored under hash to path. It downloads next to path and only keeps the
+// file once it has the expected hash and size, so a rejected download leaves nothing behind.
+func DownloadFile(ctx context.Context, store Store, hash string, size int64, path string) error {
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.part")
+	if err != nil {
+		return fmt.Errorf("failed to create download file: %w", err)
+	}
+	err = Download(ctx, store, hash, size, temp)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		return err
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		os.Remove(temp.Name())
+		return fmt.Errorf("failed to save %s: %w", path, err)
+	}
+	return nil
+}
+
+// FromEnv creates the store selected by the STORAGE_BACKEND environment variable: "file" (the default) keeps
+// files under STORAGE_DIR, and "s3" uses the bucket S3_BUCKET at S3_ENDPOINT with S3_REGION, S3_ACCESS_KEY
+// and S3_SECRET_KEY. Any S3-compatible server, such as a local MinIO, can stand in for S3.
+func FromEnv() (Store, error) {
+	switch backend := os.Getenv("STORAGE_BACKEND"); backend {
+	case "", "file":
+		dir := os.Getenv("STORAGE_DIR")
+		if dir == "" {
+			dir = "submission-files"
+		}
+		return NewFileStore(dir)
+	case "s3":
+		region := os.Getenv("S3_REGION")
+		if region == "" {
+			region = "us-east-1"
+		}
+		return NewS3Store(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Region:    region,
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", backend)
+	}
+}
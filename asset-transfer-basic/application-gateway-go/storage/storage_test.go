@@ -0,0 +1,172 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 is a minimal local stand-in for an S3-compatible server. Like S3, it rejects uploads whose body
+// does not match the signed payload hash.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+		http.Error(w, "AccessDenied", http.StatusForbidden)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		hash := sha256.Sum256(body)
+		if hex.EncodeToString(hash[:]) != r.Header.Get("x-amz-content-sha256") {
+			http.Error(w, "XAmzContentSHA256Mismatch", http.StatusBadRequest)
+			return
+		}
+		f.objects[r.URL.Path] = body
+	case http.MethodGet:
+		body, ok := f.objects[r.URL.Path]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	}
+}
+
+func newStores(t *testing.T) map[string]Store {
+	fileStore, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create file store: %v", err)
+	}
+
+	server := httptest.NewServer(&fakeS3{objects: map[string][]byte{}})
+	t.Cleanup(server.Close)
+	s3Store, err := NewS3Store(S3Config{Endpoint: server.URL, Bucket: "submissions", Region: "us-east-1", AccessKey: "access", SecretKey: "secret"})
+	if err != nil {
+		t.Fatalf("failed to create S3 store: %v", err)
+	}
+
+	return map[string]Store{"file": fileStore, "s3": s3Store}
+}
+
+func TestUploadDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("submission "), 10000)
+	expected := sha256.Sum256(content)
+
+	for name, store := range newStores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			hash, size, err := Upload(ctx, store, bytes.NewReader(content))
+			if err != nil {
+				t.Fatalf("failed to upload: %v", err)
+			}
+			if hash != hex.EncodeToString(expected[:]) || size != int64(len(content)) {
+				t.Errorf("unexpected hash %s and size %d", hash, size)
+			}
+
+			// uploading identical content again is a no-op
+			if _, _, err := Upload(ctx, store, bytes.NewReader(content)); err != nil {
+				t.Fatalf("failed to upload again: %v", err)
+			}
+
+			var downloaded bytes.Buffer
+			if err := Download(ctx, store, hash, size, &downloaded); err != nil {
+				t.Fatalf("failed to download: %v", err)
+			}
+			if !bytes.Equal(content, downloaded.Bytes()) {
+				t.Error("downloaded content differs from upload")
+			}
+
+			if err := Download(ctx, store, hash, size-1, io.Discard); err == nil {
+				t.Error("expected size mismatch with the ledger to fail")
+			}
+
+			missing := strings.Repeat("0", 64)
+			if err := Download(ctx, store, missing, 0, io.Discard); !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected ErrNotFound, got %v", err)
+			}
+			if err := Download(ctx, store, "../../etc/passwd", 0, io.Discard); err == nil {
+				t.Error("expected invalid hash to be rejected")
+			}
+		})
+	}
+}
+
+func TestDownloadDetectsTampering(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("failed to create file store: %v", err)
+	}
+
+	ctx := context.Background()
+	hash, size, err := Upload(ctx, store, strings.NewReader("original work"))
+	if err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, hash[:2], hash), []byte("altered work!"), 0o600); err != nil {
+		t.Fatalf("failed to tamper with file: %v", err)
+	}
+
+	if err := Download(ctx, store, hash, size, io.Discard); err == nil || !strings.Contains(err.Error(), "does not match the ledger") {
+		t.Errorf("expected tampered content to be rejected, got %v", err)
+	}
+	target := filepath.Join(t.TempDir(), "work.txt")
+	if err := DownloadFile(ctx, store, hash, size, target); err == nil {
+		t.Error("expected tampered content to be rejected")
+	}
+	if entries, err := os.ReadDir(filepath.Dir(target)); err != nil || len(entries) != 0 {
+		t.Errorf("expected a rejected download to leave nothing behind, got %v, %v", entries, err)
+	}
+}
+
+func TestS3StoreRejectsMismatchedContent(t *testing.T) {
+	store := newStores(t)["s3"]
+	wrongHash := strings.Repeat("a", 64)
+	err := store.Put(context.Background(), wrongHash, 4, strings.NewReader("work"))
+	if err == nil || !strings.Contains(err.Error(), "XAmzContentSHA256Mismatch") {
+		t.Errorf("expected server to reject content not matching its hash, got %v", err)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("STORAGE_BACKEND", "file")
+	t.Setenv("STORAGE_DIR", t.TempDir())
+	if store, err := FromEnv(); err != nil {
+		t.Errorf("failed to create file store: %v", err)
+	} else if _, ok := store.(*FileStore); !ok {
+		t.Errorf("expected file store, got %T", store)
+	}
+
+	t.Setenv("STORAGE_BACKEND", "s3")
+	if _, err := FromEnv(); err == nil {
+		t.Error("expected S3 store without endpoint to be rejected")
+	}
+
+	t.Setenv("STORAGE_BACKEND", "ftp")
+	if _, err := FromEnv(); err == nil {
+		t.Error("expected unknown backend to be rejected")
+	}
+}
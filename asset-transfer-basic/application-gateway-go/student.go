@@ -15,15 +15,20 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
 	"strings"
 	"time"
 
+	"assetTransfer/classroom"
+	"assetTransfer/graderr"
+	"assetTransfer/outbox"
+	"assetTransfer/receipt"
+	"assetTransfer/storage"
+
 	"github.com/hyperledger/fabric-gateway/pkg/client"
 	"github.com/hyperledger/fabric-gateway/pkg/identity"
-	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/status"
 )
 
 const (
@@ -36,8 +41,8 @@ const (
 	gatewayPeer  = "peer0.org2.example.com"
 )
 
-var now = time.Now()
-var assetId = fmt.Sprintf("asset%d", now.Unix()*1e3+int64(now.Nanosecond())/1e6)
+// histogramWidth is the length in characters of the longest bar of a grade histogram
+const histogramWidth = 20
 
 func main() {
 
@@ -79,11 +84,31 @@ func main() {
 
 	network := gw.GetNetwork(channelName)
 	contract := network.GetContract(chaincodeName)
+	// Blocks for submission receipts are fetched through the ledger query system chaincode
+	qscc := network.GetContract("qscc")
+
+	// Transactions that can not reach the network wait in the outbox until it is reachable again
+	outboxPath := "student-outbox.jsonl"
+	if path := os.Getenv("OUTBOX_PATH"); path != "" {
+		outboxPath = path
+	}
+	box, err := outbox.Open(outboxPath, outbox.NewGateway(gw, contract))
+	if err != nil {
+		panic(err)
+	}
+
+	// Every student signs with the same identity, which acts for a username once the instructor registers it
+	clientID, err := classroom.ClientID(contract)
+	if err != nil {
+		graderr.Exit(err)
+	}
+	fmt.Printf("Client ID: %s\nAsk your instructor to register %s to it in each class you take.\n", clientID, username)
 
 	quit := false
 	print := true
 	class := ""
 	for !quit {
+		flushOutbox(box, qscc, channelName)
 		if class == "" {
 			printClasses(contract, username)
 			args := strings.Fields(getInput("Join or create class: "))
@@ -104,11 +129,25 @@ func main() {
 				if args[1] == "all" {
 					getAllAssets(contract, username, class)
 				} else {
-					readAssetByID(contract, args[1], username)
+					id := assignmentID(contract, class, args[1], username)
+					readAssetByID(contract, id)
+					printMemberGrade(contract, username, id)
 				}
 			case "s": // submit assignment
 				fmt.Println("Submitting assignment", args[1])
-				submitAssignment(contract, args[1], username)
+				submitAssignment(contract, box, qscc, channelName, assignmentID(contract, class, args[1], username))
+			case "verify": // check the receipt of a submission against its block
+				print = false
+				verifyReceipt(qscc, assignmentID(contract, class, args[1], username))
+			case "stats": // class statistics of an assignment, once its grades are released
+				print = false
+				printStats(contract, class, args[1])
+			case "join": // sign up for a group
+				fmt.Println("Joining group", args[1])
+				changeGroup(box, "the sign-up for "+args[1], classroom.JoinGroupCall(class, args[1], username))
+			case "leave": // leave a group
+				fmt.Println("Leaving group", args[1])
+				changeGroup(box, "leaving "+args[1], classroom.LeaveGroupCall(class, args[1], username))
 			case "b":
 				class = ""
 			default:
@@ -124,6 +163,15 @@ func main() {
 			default:
 				fmt.Println("Unrecognized command, please try again.")
 			}
+		} else if len(args) == 3 {
+			switch args[0] {
+			case "upload": // attach a file to an assignment
+				uploadAttachment(box, assignmentID(contract, class, args[1], username), args[2])
+			case "download": // fetch an attached file
+				downloadAttachment(contract, assignmentID(contract, class, args[1], username), args[2])
+			default:
+				fmt.Println("Unrecognized command, please try again.")
+			}
 		} else {
 			fmt.Println("Invalid command, please try again.")
 		}
@@ -140,17 +188,59 @@ func main() {
 func printClasses(contract *client.Contract, username string) {
 	fmt.Println("\n--> Evaluate Transaction: GetAllAssets, function returns all the current assets on the ledger")
 
-	evaluateResult, err := contract.EvaluateTransaction("GetAllClasses", username)
+	classes, err := classroom.Classes(contract, username)
 	if err != nil {
-		panic(fmt.Errorf("failed to evaluate transaction: %w", err))
-	}
-	var result string
-	if evaluateResult != nil {
-		result = formatJSON(evaluateResult)
+		graderr.Exit(err)
 	}
 
 	fmt.Println("Classes:")
-	fmt.Println(result)
+	for _, class := range classes {
+		fmt.Println(class)
+	}
+}
+
+func uploadAttachment(box *outbox.Outbox, assetId string, filename string) {
+	store, err := storage.FromEnv()
+	if err != nil {
+		panic(fmt.Errorf("failed to open file storage: %w", err))
+	}
+
+	file, err := os.Open(filename)
+	if err != nil {
+		fmt.Println("Unable to open", filename+":", err)
+		return
+	}
+	defer file.Close()
+
+	hash, size, err := storage.Upload(context.Background(), store, file)
+	if err != nil {
+		panic(fmt.Errorf("failed to upload file: %w", err))
+	}
+
+	fmt.Printf("\n--> Submit Transaction: AttachFile, records %s (%d bytes, sha256 %s)\n", filepath.Base(filename), size, hash)
+
+	if _, ok := submitQueued(box, "the attachment "+filepath.Base(filename), classroom.AttachFileCall(assetId, filepath.Base(filename), hash, size)); !ok {
+		return
+	}
+
+	fmt.Printf("*** Transaction committed successfully\n")
+}
+
+func downloadAttachment(contract *client.Contract, assetId string, name string) {
+	store, err := storage.FromEnv()
+	if err != nil {
+		panic(fmt.Errorf("failed to open file storage: %w", err))
+	}
+
+	fmt.Printf("\n--> Evaluate Transaction: ReadAsset, function returns asset attributes\n")
+
+	attachment, path, err := classroom.DownloadAttachment(context.Background(), contract, store, assetId, name, ".")
+	if err != nil {
+		graderr.Report(os.Stdout, err)
+		return
+	}
+
+	fmt.Printf("*** Saved %s (%d bytes), sha256 %s verified against the ledger\n", path, attachment.Size, attachment.SHA256)
 }
 
 func login() string {
@@ -178,59 +268,188 @@ func getInput(prompt string) string {
 	return input[:len(input)-1] // strip trailing '\n'
 }
 
-func submitAssignment(contract *client.Contract, assignmentId, username string) {
+func submitAssignment(contract *client.Contract, box *outbox.Outbox, qscc *client.Contract, channel string, assignmentId string) {
 
 	fmt.Printf("\n--> Evaluate Transaction: ReadAsset, function returns asset attributes\n")
 
-	evaluateResult, err := contract.EvaluateTransaction("ReadAsset", assignmentId+username)
+	asset, err := classroom.ReadAsset(contract, assignmentId)
 	if err != nil {
-		panic(fmt.Errorf("failed to evaluate transaction: %w", err))
+		graderr.Exit(err)
+	}
+	// Read the class key now, so the answer can be sealed and queued even if the network drops while it is typed
+	classRecord, err := classroom.ReadClass(contract, asset.ClassID)
+	if err != nil {
+		graderr.Exit(err)
 	}
-	var parsedResult map[string]interface{}
-	json.Unmarshal(evaluateResult, &parsedResult)
 
-	fmt.Printf("\n--> Async Submit Transaction: TransferAsset, updates existing asset work")
+	fmt.Println(asset.Title)
+	fmt.Println(asset.Date)
+	fmt.Println(asset.Description)
 
-	fmt.Println(parsedResult["Title"].(string))
-	fmt.Println(parsedResult["Date"].(string))
-	fmt.Println(parsedResult["Description"].(string))
+	answer := getInput("Answer: ")
+	work, keyVersion, err := classroom.SealWork(classRecord, asset, answer)
+	switch {
+	case errors.Is(err, classroom.ErrNoClassKey):
+		fmt.Println("*** Class", asset.ClassID, "has no encryption key, so anyone on the channel could read your work")
+		if !strings.EqualFold(getInput("Submit it unencrypted anyway? [y/N]: "), "y") {
+			fmt.Println("*** Work not submitted, ask the instructor to publish a class key")
+			return
+		}
+		work = answer
+		fmt.Println("*** Work is submitted unencrypted")
+	case err != nil:
+		graderr.Exit(err)
+	default:
+		fmt.Printf("*** Work encrypted to version %d of the class key\n", keyVersion)
+	}
 
-	work := getInput("Answer: ")
-	submitResult, commit, err := contract.SubmitAsync("SubmitAssignment", client.WithArguments(assignmentId+username, work))
+	fmt.Printf("\n--> Submit Transaction: SubmitAssignment, records the work and hands the assignment back\n")
+
+	entry, ok := submitQueued(box, "your answer to "+asset.Title, classroom.SubmitWorkCalls(asset, work)...)
+	if !ok {
+		return
+	}
+
+	fmt.Printf("*** Transaction committed successfully\n")
+	saveReceipt(qscc, channel, entry)
+}
+
+// receiptPath returns where the receipt for a submission to an assignment is kept.
+func receiptPath(assignmentId string) string {
+	dir := "receipts"
+	if path := os.Getenv("RECEIPT_DIR"); path != "" {
+		dir = path
+	}
+	return filepath.Join(dir, assignmentId+".json")
+}
+
+// saveReceipt writes a receipt for a committed entry that submitted work, naming the block the submission
+// is in. A receipt that can not be made is reported, since the work itself is already on the ledger.
+func saveReceipt(qscc *client.Contract, channel string, entry *outbox.Entry) {
+	if len(entry.Calls) == 0 || entry.Calls[0].Name != "SubmitAssignment" || len(entry.TransactionIDs) == 0 {
+		return
+	}
+	assignmentId, transactionId := entry.Calls[0].Args[0], entry.TransactionIDs[0]
+
+	fmt.Printf("\n--> Evaluate Transaction: GetBlockByTxID, function returns the block holding the submission\n")
+
+	block, err := receipt.BlockByTransaction(qscc, channel, transactionId)
 	if err != nil {
-		panic(fmt.Errorf("failed to submit transaction asynchronously: %w", err))
+		fmt.Println("*** Unable to fetch the block for a receipt")
+		graderr.Report(os.Stdout, err)
+		return
+	}
+	r, err := receipt.New(block, channel, transactionId, assignmentId)
+	if err != nil {
+		fmt.Println("*** Unable to make a receipt:", err)
+		return
+	}
+	path := receiptPath(assignmentId)
+	if err := r.Save(path); err != nil {
+		fmt.Println("*** Unable to save the receipt:", err)
+		return
 	}
 
-	fmt.Printf("\n*** Successfully submitted transaction to transfer ownership from %s to Mark. \n", string(submitResult))
-	fmt.Println("*** Waiting for transaction commit.")
+	fmt.Printf("*** Receipt saved to %s\n", path)
+	fmt.Printf("Transaction %s in block %d (hash %s) at %s\n", r.TransactionID, r.BlockNumber, r.BlockHash, r.Timestamp.Format(time.RFC3339))
+	fmt.Println("Content hash:", r.ContentHash)
+}
 
-	if commitStatus, err := commit.Status(); err != nil {
-		panic(fmt.Errorf("failed to get commit status: %w", err))
-	} else if !commitStatus.Successful {
-		panic(fmt.Errorf("transaction %s failed to commit with status: %d", commitStatus.TransactionID, int32(commitStatus.Code)))
+// verifyReceipt fetches the block named by the receipt of a submission and checks that the submission is
+// in it as a valid transaction.
+func verifyReceipt(qscc *client.Contract, assignmentId string) {
+	r, err := receipt.Load(receiptPath(assignmentId))
+	if err != nil {
+		fmt.Println("*** No receipt for", assignmentId+":", err)
+		return
 	}
 
-	fmt.Printf("*** Transaction committed successfully\n")
+	fmt.Printf("\n--> Evaluate Transaction: GetBlockByNumber, function returns block %d\n", r.BlockNumber)
 
-	fmt.Printf("\n--> Async Submit Transaction: TransferAsset, updates existing asset owner")
+	block, err := receipt.BlockByNumber(qscc, r.ChannelID, r.BlockNumber)
+	if err != nil {
+		graderr.Report(os.Stdout, err)
+		return
+	}
+	if err := receipt.Verify(r, block); err != nil {
+		fmt.Println("*** Receipt verification FAILED:", err)
+		return
+	}
+
+	fmt.Printf("*** Receipt verified: transaction %s is valid in block %d (hash %s)\n", r.TransactionID, r.BlockNumber, r.BlockHash)
+	fmt.Println("Content hash:", r.ContentHash)
+}
 
-	submitResult, commit, err = contract.SubmitAsync("TransferAsset", client.WithArguments(assignmentId+username, parsedResult["InstructorID"].(string)))
+// assignmentID returns the ID of the titled assignment in a class that the student holds or handed in, which
+// may be their group's, falling back to the ID of the student's own copy.
+func assignmentID(contract *client.Contract, class string, title string, student string) string {
+	asset, err := classroom.FindAssignment(contract, student, class, title)
+	if err != nil {
+		graderr.Exit(err)
+	}
+	if asset != nil {
+		return asset.ID
+	}
+	id, err := classroom.AssignmentID(contract, class, title, student)
 	if err != nil {
-		panic(fmt.Errorf("failed to submit transaction asynchronously: %w", err))
+		graderr.Exit(err)
 	}
+	return id
+}
 
-	fmt.Printf("\n*** Successfully submitted transaction to transfer ownership from %s to Mark. \n", string(submitResult))
-	fmt.Println("*** Waiting for transaction commit.")
+// changeGroup submits a change to the student's group membership.
+func changeGroup(box *outbox.Outbox, description string, call classroom.Call) {
+	fmt.Printf("\n--> Submit Transaction: %s, records the change in the group's history\n", call.Name)
 
-	if commitStatus, err := commit.Status(); err != nil {
-		panic(fmt.Errorf("failed to get commit status: %w", err))
-	} else if !commitStatus.Successful {
-		panic(fmt.Errorf("transaction %s failed to commit with status: %d", commitStatus.TransactionID, int32(commitStatus.Code)))
+	if _, ok := submitQueued(box, description, call); !ok {
+		return
 	}
 
 	fmt.Printf("*** Transaction committed successfully\n")
 }
 
+// printStats shows the aggregate grade statistics of an assignment, which the chaincode reveals once its
+// grades are released.
+func printStats(contract *client.Contract, class string, title string) {
+	fmt.Printf("\n--> Evaluate Transaction: GetAssignmentStats, function summarizes the grades of %s\n", title)
+
+	stats, err := classroom.Stats(contract, class, title)
+	if err != nil {
+		graderr.Report(os.Stdout, err)
+		return
+	}
+	fmt.Printf("%s: %d of %d submitted, %d graded\n", title, stats.Count+stats.Ungraded, stats.Assigned, stats.Count)
+	if stats.Suppressed {
+		fmt.Println("Too few submissions are graded to show their statistics")
+		return
+	}
+	if stats.Count == 0 {
+		return
+	}
+	fmt.Printf("Mean %.1f  Median %.1f  Std dev %.1f  Min %d  Max %d\n", stats.Mean, stats.Median, stats.StdDev, stats.Min, stats.Max)
+	for _, line := range classroom.HistogramLines(stats, histogramWidth) {
+		fmt.Println(line)
+	}
+}
+
+// printMemberGrade prints the student's own grade on a group assignment, which may differ from the group's.
+func printMemberGrade(contract *client.Contract, username string, assetId string) {
+	asset, err := classroom.ReadAsset(contract, assetId)
+	if err != nil || asset.GroupID == "" {
+		return
+	}
+	grades, err := classroom.MemberGrades(contract, assetId)
+	if err != nil {
+		graderr.Report(os.Stdout, err)
+		return
+	}
+	for _, grade := range grades {
+		if grade.Member == username {
+			fmt.Printf("Your grade in group %s: %d (%+d)\n", asset.GroupID, grade.Grade, grade.Adjustment)
+		}
+	}
+}
+
 func createAssignment(contract *client.Contract, username string) {
 	title := getInput("Assignment title: ")
 	date := getInput("Assignment due date: ")
@@ -238,7 +457,7 @@ func createAssignment(contract *client.Contract, username string) {
 
 	_, err := contract.SubmitTransaction("CreateAsset", title, date, "0", username, "0")
 	if err != nil {
-		panic(fmt.Errorf("failed to submit transaction: %w", err))
+		graderr.Exit(fmt.Errorf("failed to submit transaction: %w", err))
 	}
 
 	fmt.Printf("*** Transaction committed successfully\n")
@@ -316,39 +535,31 @@ func newSign() identity.Sign {
 func initLedger(contract *client.Contract) {
 	fmt.Printf("\n--> Submit Transaction: InitLedger, function creates the initial set of assets on the ledger \n")
 
-	_, err := contract.SubmitTransaction("InitLedger")
-	if err != nil {
-		panic(fmt.Errorf("failed to submit transaction: %w", err))
+	if err := classroom.InitLedger(outbox.Contract{Contract: contract}); err != nil {
+		graderr.Exit(err)
 	}
 
 	fmt.Printf("*** Transaction committed successfully\n")
 }
 
 func printAssignments(contract *client.Contract, username string, class string) {
-	evaluateResult, err := contract.EvaluateTransaction("GetAllAssignments", username, class)
+	assignments, err := classroom.Assignments(contract, username, class)
 	if err != nil {
-		//panic(fmt.Errorf("failed to evaluate transaction: %w", err))
+		graderr.Report(os.Stdout, err)
 	}
-	//result := formatJSON(evaluateResult)
 	fmt.Println("Class: ", class)
 	fmt.Println("Current Assignments:")
-	var parsedResult []map[string]interface{}
-	json.Unmarshal(evaluateResult, &parsedResult)
-	for _, asset := range parsedResult {
-		fmt.Println(asset["Title"].(string))
+	for _, asset := range assignments {
+		fmt.Println(asset.Title)
 	}
 
-	evaluateResult, err = contract.EvaluateTransaction("GetSubmittedAssignments", username, class)
+	submitted, err := classroom.SubmittedAssignments(contract, username, class)
 	if err != nil {
-		//panic(fmt.Errorf("failed to evaluate transaction: %w", err))
+		graderr.Report(os.Stdout, err)
 	}
-	//result := formatJSON(evaluateResult)
-
 	fmt.Println("Past Assignments:")
-	var parsedResult2 []map[string]interface{}
-	json.Unmarshal(evaluateResult, &parsedResult2)
-	for _, asset := range parsedResult2 {
-		fmt.Println(asset["Title"].(string))
+	for _, asset := range submitted {
+		fmt.Println(asset.Title)
 	}
 }
 
@@ -358,7 +569,7 @@ func getAllAssets(contract *client.Contract, username string, class string) {
 
 	evaluateResult, err := contract.EvaluateTransaction("GetAllAssignments", username, class)
 	if err != nil {
-		panic(fmt.Errorf("failed to evaluate transaction: %w", err))
+		graderr.Exit(fmt.Errorf("failed to evaluate transaction: %w", err))
 	}
 	result := ""
 	if evaluateResult != nil {
@@ -375,24 +586,24 @@ func getAllAssets(contract *client.Contract, username string, class string) {
 }
 
 // Submit a transaction synchronously, blocking until it has been committed to the ledger.
-func createAsset(contract *client.Contract) {
+func createAsset(contract *client.Contract, assetId string) {
 	fmt.Printf("\n--> Submit Transaction: CreateAsset, creates new asset with ID, Color, Size, Owner and AppraisedValue arguments \n")
 
 	_, err := contract.SubmitTransaction("CreateAsset", assetId, "yellow", "5", "Tom", "1300")
 	if err != nil {
-		panic(fmt.Errorf("failed to submit transaction: %w", err))
+		graderr.Exit(fmt.Errorf("failed to submit transaction: %w", err))
 	}
 
 	fmt.Printf("*** Transaction committed successfully\n")
 }
 
 // Evaluate a transaction by assetID to query ledger state.
-func readAssetByID(contract *client.Contract, assetId, username string) {
+func readAssetByID(contract *client.Contract, assetId string) {
 	fmt.Printf("\n--> Evaluate Transaction: ReadAsset, function returns asset attributes\n")
 
-	evaluateResult, err := contract.EvaluateTransaction("ReadAsset", assetId+username)
+	evaluateResult, err := contract.EvaluateTransaction("ReadAsset", assetId)
 	if err != nil {
-		panic(fmt.Errorf("failed to evaluate transaction: %w", err))
+		graderr.Exit(fmt.Errorf("failed to evaluate transaction: %w", err))
 	}
 	result := formatJSON(evaluateResult)
 
@@ -401,21 +612,21 @@ func readAssetByID(contract *client.Contract, assetId, username string) {
 
 // Submit transaction asynchronously, blocking until the transaction has been sent to the orderer, and allowing
 // this thread to process the chaincode response (e.g. update a UI) without waiting for the commit notification
-func transferAssetAsync(contract *client.Contract) {
+func transferAssetAsync(contract *client.Contract, assetId string) {
 	fmt.Printf("\n--> Async Submit Transaction: TransferAsset, updates existing asset owner")
 
 	submitResult, commit, err := contract.SubmitAsync("TransferAsset", client.WithArguments(assetId, "Mark"))
 	if err != nil {
-		panic(fmt.Errorf("failed to submit transaction asynchronously: %w", err))
+		graderr.Exit(fmt.Errorf("failed to submit transaction asynchronously: %w", err))
 	}
 
 	fmt.Printf("\n*** Successfully submitted transaction to transfer ownership from %s to Mark. \n", string(submitResult))
 	fmt.Println("*** Waiting for transaction commit.")
 
 	if commitStatus, err := commit.Status(); err != nil {
-		panic(fmt.Errorf("failed to get commit status: %w", err))
+		graderr.Exit(fmt.Errorf("failed to get commit status: %w", err))
 	} else if !commitStatus.Successful {
-		panic(fmt.Errorf("transaction %s failed to commit with status: %d", commitStatus.TransactionID, int32(commitStatus.Code)))
+		graderr.Exit(graderr.CommitFailed(commitStatus))
 	}
 
 	fmt.Printf("*** Transaction committed successfully\n")
@@ -431,39 +642,7 @@ func exampleErrorHandling(contract *client.Contract) {
 	}
 
 	fmt.Println("*** Successfully caught the error:")
-
-	switch err := err.(type) {
-	case *client.EndorseError:
-		fmt.Printf("Endorse error for transaction %s with gRPC status %v: %s\n", err.TransactionID, status.Code(err), err)
-	case *client.SubmitError:
-		fmt.Printf("Submit error for transaction %s with gRPC status %v: %s\n", err.TransactionID, status.Code(err), err)
-	case *client.CommitStatusError:
-		if errors.Is(err, context.DeadlineExceeded) {
-			fmt.Printf("Timeout waiting for transaction %s commit status: %s", err.TransactionID, err)
-		} else {
-			fmt.Printf("Error obtaining commit status for transaction %s with gRPC status %v: %s\n", err.TransactionID, status.Code(err), err)
-		}
-	case *client.CommitError:
-		fmt.Printf("Transaction %s failed to commit with status %d: %s\n", err.TransactionID, int32(err.Code), err)
-	default:
-		panic(fmt.Errorf("unexpected error type %T: %w", err, err))
-	}
-
-	// Any error that originates from a peer or orderer node external to the gateway will have its details
-	// embedded within the gRPC status error. The following code shows how to extract that.
-	statusErr := status.Convert(err)
-
-	details := statusErr.Details()
-	if len(details) > 0 {
-		fmt.Println("Error Details:")
-
-		for _, detail := range details {
-			switch detail := detail.(type) {
-			case *gateway.ErrorDetail:
-				fmt.Printf("- address: %s, mspId: %s, message: %s\n", detail.Address, detail.MspId, detail.Message)
-			}
-		}
-	}
+	graderr.Report(os.Stdout, err)
 }
 
 // Format JSON data
@@ -474,3 +653,35 @@ func formatJSON(data []byte) string {
 	}
 	return prettyJSON.String()
 }
+
+// submitQueued submits calls through the outbox and returns the entry once they have all committed. If the
+// network can not be reached the calls stay queued, to be retried on a later command, and ok is false.
+func submitQueued(box *outbox.Outbox, description string, calls ...classroom.Call) (entry *outbox.Entry, ok bool) {
+	entry, err := box.Submit(description, calls...)
+	if errors.Is(err, outbox.ErrPending) {
+		fmt.Printf("*** The network can not be reached, %s is queued and will be submitted when it can\n", description)
+		graderr.Report(os.Stdout, err)
+		return nil, false
+	}
+	if err != nil {
+		graderr.Exit(err)
+	}
+	return entry, true
+}
+
+// flushOutbox retries the transactions waiting in the outbox and reports those that finished, saving a
+// receipt for each queued submission that committed.
+func flushOutbox(box *outbox.Outbox, qscc *client.Contract, channel string) {
+	for _, entry := range box.Flush() {
+		if entry.Err != nil {
+			fmt.Printf("*** Queued %s was rejected\n", entry.Description)
+			graderr.Report(os.Stdout, entry.Err)
+			continue
+		}
+		fmt.Printf("*** Queued %s committed successfully\n", entry.Description)
+		saveReceipt(qscc, channel, entry)
+	}
+	if pending := len(box.Pending()); pending > 0 {
+		fmt.Printf("*** %d queued transaction(s) waiting for the network\n", pending)
+	}
+}
@@ -0,0 +1,145 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"assetTransfer/classroom"
+	"assetTransfer/graderr"
+	"assetTransfer/outbox"
+	"assetTransfer/seal"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// main runs the full-screen grader client. Run it from the application-gateway-go directory so the
+// test-network crypto paths resolve, for example:
+//
+//	go run ./tui -user instructor -rubric "Content:60,Style:40"
+//	go run ./tui -user alice -role student
+func main() {
+	username := flag.String("user", "", "username to sign in as")
+	role := flag.String("role", "instructor", "instructor or student")
+	rubricSpec := flag.String("rubric", "Grade:100", "rubric for grading, as comma-separated name:points pairs")
+	flag.Parse()
+
+	org, ok := organizations[*role]
+	if *username == "" || !ok {
+		flag.Usage()
+		os.Exit(2)
+	}
+	rubric, err := parseRubric(*rubricSpec)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	clientConnection := newGrpcConnection(org)
+	defer clientConnection.Close()
+
+	gw, err := client.Connect(
+		newIdentity(org),
+		client.WithSign(newSign(org)),
+		client.WithClientConnection(clientConnection),
+		client.WithEvaluateTimeout(5*time.Second),
+		client.WithEndorseTimeout(15*time.Second),
+		client.WithSubmitTimeout(5*time.Second),
+		client.WithCommitStatusTimeout(1*time.Minute),
+	)
+	if err != nil {
+		panic(err)
+	}
+	defer gw.Close()
+
+	chaincodeName := "basic"
+	if ccname := os.Getenv("CHAINCODE_NAME"); ccname != "" {
+		chaincodeName = ccname
+	}
+
+	channelName := "mychannel"
+	if cname := os.Getenv("CHANNEL_NAME"); cname != "" {
+		channelName = cname
+	}
+
+	network := gw.GetNetwork(channelName)
+	m := &model{
+		contract:   outbox.Contract{Contract: network.GetContract(chaincodeName)},
+		username:   *username,
+		instructor: *role == "instructor",
+		keyring:    seal.KeyringFromEnv(),
+		rubric:     rubric,
+	}
+
+	// Students share their organization's identity, which acts for a username once the instructor registers it
+	if !m.instructor {
+		clientID, err := classroom.ClientID(m.contract)
+		if err != nil {
+			graderr.Exit(err)
+		}
+		m.status = "Ask your instructor to register " + m.username + " to client ID " + clientID
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Refresh whenever a transaction of the grading chaincode emits an event
+	events, err := network.ChaincodeEvents(ctx, chaincodeName)
+	if err != nil {
+		graderr.Exit(fmt.Errorf("failed to start chaincode event listening: %w", err))
+	}
+
+	if err := run(m, events); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run draws the model and applies key presses, chaincode events and terminal resizes to it until the user
+// quits.
+func run(m *model, events <-chan *client.ChaincodeEvent) error {
+	term, err := openTerminal()
+	if err != nil {
+		return err
+	}
+	defer term.close()
+
+	keys := make(chan key)
+	go term.readKeys(keys)
+
+	resize := make(chan os.Signal, 1)
+	signal.Notify(resize, syscall.SIGWINCH)
+	defer signal.Stop(resize)
+
+	m.refresh()
+	width, height := term.size()
+	for !m.quit {
+		term.draw(m.view(width, height))
+		select {
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			m.handleKey(k)
+		case event, ok := <-events:
+			if !ok {
+				events = nil
+				m.status = "Stopped receiving chaincode events, press Ctrl-R to refresh"
+				continue
+			}
+			m.handleEvent(event.EventName, event.Payload)
+		case <-resize:
+			width, height = term.size()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// organization is where a role's users are enrolled on the test network: instructors in Org1 and students
+// in Org2, as for the instructor and student programs.
+type organization struct {
+	mspID        string
+	cryptoPath   string
+	user         string
+	peerEndpoint string
+	gatewayPeer  string
+}
+
+var organizations = map[string]organization{
+	"instructor": {
+		mspID:        "Org1MSP",
+		cryptoPath:   "../../test-network/organizations/peerOrganizations/org1.example.com",
+		user:         "User1@org1.example.com",
+		peerEndpoint: "localhost:7051",
+		gatewayPeer:  "peer0.org1.example.com",
+	},
+	"student": {
+		mspID:        "Org2MSP",
+		cryptoPath:   "../../test-network/organizations/peerOrganizations/org2.example.com",
+		user:         "User1@org2.example.com",
+		peerEndpoint: "localhost:9051",
+		gatewayPeer:  "peer0.org2.example.com",
+	},
+}
+
+func (org organization) certPath() string {
+	return org.cryptoPath + "/users/" + org.user + "/msp/signcerts/cert.pem"
+}
+
+func (org organization) keyPath() string {
+	return org.cryptoPath + "/users/" + org.user + "/msp/keystore/"
+}
+
+func (org organization) tlsCertPath() string {
+	return org.cryptoPath + "/peers/" + org.gatewayPeer + "/tls/ca.crt"
+}
+
+// newGrpcConnection creates a gRPC connection to the Gateway server.
+func newGrpcConnection(org organization) *grpc.ClientConn {
+	certificate, err := loadCertificate(org.tlsCertPath())
+	if err != nil {
+		panic(err)
+	}
+
+	certPool := x509.NewCertPool()
+	certPool.AddCert(certificate)
+	transportCredentials := credentials.NewClientTLSFromCert(certPool, org.gatewayPeer)
+
+	connection, err := grpc.Dial(org.peerEndpoint, grpc.WithTransportCredentials(transportCredentials))
+	if err != nil {
+		panic(fmt.Errorf("failed to create gRPC connection: %w", err))
+	}
+
+	return connection
+}
+
+// newIdentity creates a client identity for this Gateway connection using an X.509 certificate.
+func newIdentity(org organization) *identity.X509Identity {
+	certificate, err := loadCertificate(org.certPath())
+	if err != nil {
+		panic(err)
+	}
+
+	id, err := identity.NewX509Identity(org.mspID, certificate)
+	if err != nil {
+		panic(err)
+	}
+
+	return id
+}
+
+func loadCertificate(filename string) (*x509.Certificate, error) {
+	certificatePEM, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate file: %w", err)
+	}
+	return identity.CertificateFromPEM(certificatePEM)
+}
+
+// newSign creates a function that generates a digital signature from a message digest using a private key.
+func newSign(org organization) identity.Sign {
+	files, err := os.ReadDir(org.keyPath())
+	if err != nil {
+		panic(fmt.Errorf("failed to read private key directory: %w", err))
+	}
+	privateKeyPEM, err := os.ReadFile(path.Join(org.keyPath(), files[0].Name()))
+
+	if err != nil {
+		panic(fmt.Errorf("failed to read private key file: %w", err))
+	}
+
+	privateKey, err := identity.PrivateKeyFromPEM(privateKeyPEM)
+	if err != nil {
+		panic(err)
+	}
+
+	sign, err := identity.NewPrivateKeySign(privateKey)
+	if err != nil {
+		panic(err)
+	}
+
+	return sign
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// criterion is one part of a rubric, scored from 0 to Points.
+type criterion struct {
+	Name   string
+	Points int
+}
+
+// parseRubric reads a rubric written as comma-separated name:points pairs, such as "Content:60,Style:40".
+func parseRubric(spec string) ([]criterion, error) {
+	var rubric []criterion
+	for _, part := range strings.Split(spec, ",") {
+		name, points, found := strings.Cut(strings.TrimSpace(part), ":")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("rubric criterion %q must be written as name:points", part)
+		}
+		value, err := strconv.Atoi(strings.TrimSpace(points))
+		if err != nil || value <= 0 {
+			return nil, fmt.Errorf("rubric criterion %q must have a positive number of points", part)
+		}
+		rubric = append(rubric, criterion{Name: strings.TrimSpace(name), Points: value})
+	}
+	return rubric, nil
+}
+
+// field is a line of text being edited in a form.
+type field struct {
+	label string
+	value []rune
+	// points is the maximum score for a rubric field, or 0 for free text
+	points int
+}
+
+// form is a set of fields edited one at a time, submitted with Enter on the last field or Ctrl-S.
+type form struct {
+	title  string
+	fields []*field
+	focus  int
+}
+
+// formResult is what a key press did to a form.
+type formResult int
+
+const (
+	formEditing formResult = iota
+	formSubmitted
+	formCancelled
+)
+
+// newGradeForm returns a form scoring an asset against rubric, with a comment field for free-form feedback.
+func newGradeForm(assetTitle string, rubric []criterion) *form {
+	f := &form{title: "Grade " + assetTitle}
+	for _, c := range rubric {
+		f.fields = append(f.fields, &field{label: c.Name, points: c.Points})
+	}
+	f.fields = append(f.fields, &field{label: "Comment"})
+	return f
+}
+
+// newAnswerForm returns a form for a student's answer to an assignment.
+func newAnswerForm(assetTitle string) *form {
+	return &form{title: "Submit " + assetTitle, fields: []*field{{label: "Answer"}}}
+}
+
+// handleKey applies a key press to the form.
+func (f *form) handleKey(k key) formResult {
+	current := f.fields[f.focus]
+	switch k.code {
+	case keyEscape, keyCtrlC:
+		return formCancelled
+	case keyCtrlS:
+		return formSubmitted
+	case keyEnter:
+		if f.focus == len(f.fields)-1 {
+			return formSubmitted
+		}
+		f.focus++
+	case keyTab, keyDown:
+		f.focus = (f.focus + 1) % len(f.fields)
+	case keyBackTab, keyUp:
+		f.focus = (f.focus + len(f.fields) - 1) % len(f.fields)
+	case keyBackspace:
+		if len(current.value) > 0 {
+			current.value = current.value[:len(current.value)-1]
+		}
+	case keyRune:
+		if current.points > 0 && (k.r < '0' || k.r > '9') {
+			break
+		}
+		current.value = append(current.value, k.r)
+	}
+	return formEditing
+}
+
+// grade returns the total score and the feedback recorded for a grade form: the score for each criterion
+// followed by the comment, on one line as the chaincode does not accept line breaks in feedback.
+func (f *form) grade() (int, string, error) {
+	total := 0
+	var parts []string
+	comment := ""
+	for _, field := range f.fields {
+		if field.points == 0 {
+			comment = strings.TrimSpace(string(field.value))
+			continue
+		}
+		score, err := strconv.Atoi(string(field.value))
+		if err != nil {
+			return 0, "", fmt.Errorf("enter a score for %s", field.label)
+		}
+		if score > field.points {
+			return 0, "", fmt.Errorf("the score for %s must not be more than %d", field.label, field.points)
+		}
+		total += score
+		parts = append(parts, fmt.Sprintf("%s %d/%d", field.label, score, field.points))
+	}
+	feedback := strings.Join(parts, "; ")
+	if comment != "" {
+		if feedback != "" {
+			feedback += ". "
+		}
+		feedback += comment
+	}
+	return total, feedback, nil
+}
+
+// answer returns the text entered in an answer form.
+func (f *form) answer() string {
+	return string(f.fields[0].value)
+}
+
+// lines renders the form, marking the focused field.
+func (f *form) lines() []string {
+	lines := []string{f.title, ""}
+	for i, field := range f.fields {
+		marker := "  "
+		if i == f.focus {
+			marker = "> "
+		}
+		label := field.label
+		if field.points > 0 {
+			label = fmt.Sprintf("%s (/%d)", field.label, field.points)
+		}
+		lines = append(lines, fmt.Sprintf("%s%-20s %s", marker, label+":", string(field.value)))
+	}
+	return append(lines, "", "Enter: next field, Ctrl-S: save, Esc: cancel")
+}
@@ -0,0 +1,286 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"assetTransfer/classroom"
+	"assetTransfer/graderr"
+	"assetTransfer/seal"
+)
+
+// pane is one of the three columns of the screen.
+type pane int
+
+const (
+	classesPane pane = iota
+	assignmentsPane
+	detailPane
+)
+
+// model is the state of the TUI. Key presses and chaincode events change it, and the screen is redrawn
+// from it after each one. Every ledger call goes through the classroom package.
+type model struct {
+	contract classroom.Contract
+	username string
+	// instructor is true when the user grades the class, and false when they are a student in it
+	instructor bool
+	keyring    *seal.Keyring
+	rubric     []criterion
+
+	focus      pane
+	classes    []string
+	classIndex int
+	class      string
+	assets     []classroom.Asset
+	assetIndex int
+	detail     *classroom.Asset
+	// work is the detail asset's work, decrypted when the keyring holds the class key
+	work   string
+	editor *form
+	status string
+	quit   bool
+}
+
+// refresh reloads the class list, the assets of the open class and the asset in the detail pane, keeping
+// the selection where possible.
+func (m *model) refresh() {
+	classes, err := classroom.Classes(m.contract, m.username)
+	if err != nil {
+		m.fail(err)
+		return
+	}
+	sort.Strings(classes)
+	m.classes = classes
+	m.classIndex = clamp(m.classIndex, len(m.classes))
+
+	if m.class != "" {
+		m.loadAssets()
+	}
+	if m.detail != nil {
+		m.loadDetail(m.detail.ID)
+	}
+}
+
+// loadAssets loads the assets of the open class: every submission for an instructor, and the student's own
+// assignments, both outstanding and handed in, for a student.
+func (m *model) loadAssets() {
+	var assets []classroom.Asset
+	var err error
+	if m.instructor {
+		assets, err = classroom.Assets(m.contract, m.username, m.class)
+	} else {
+		assets, err = classroom.Assignments(m.contract, m.username, m.class)
+		if err == nil {
+			var submitted []classroom.Asset
+			submitted, err = classroom.SubmittedAssignments(m.contract, m.username, m.class)
+			assets = append(assets, submitted...)
+		}
+	}
+	if err != nil {
+		m.fail(err)
+		return
+	}
+	sort.Slice(assets, func(i, j int) bool {
+		if assets[i].Title != assets[j].Title {
+			return assets[i].Title < assets[j].Title
+		}
+		return assets[i].Assignee() < assets[j].Assignee()
+	})
+	m.assets = assets
+	m.assetIndex = clamp(m.assetIndex, len(m.assets))
+}
+
+// loadDetail shows the asset with the given ID in the detail pane.
+func (m *model) loadDetail(id string) {
+	asset, err := classroom.ReadAsset(m.contract, id)
+	if err != nil {
+		m.fail(err)
+		return
+	}
+	m.detail = asset
+	m.work = asset.Work
+	if m.instructor && m.keyring != nil && seal.IsSealed(asset.Work) {
+		work, err := m.keyring.Open(asset.ClassID, asset.ID, asset.Work)
+		if err != nil {
+			work = fmt.Sprintf("<unable to decrypt: %v>", err)
+		}
+		m.work = work
+	}
+}
+
+// handleKey applies a key press: to the editor while one is open, otherwise to the focused pane.
+func (m *model) handleKey(k key) {
+	if m.editor != nil {
+		switch m.editor.handleKey(k) {
+		case formSubmitted:
+			m.save()
+		case formCancelled:
+			m.editor = nil
+			m.status = "Cancelled"
+		}
+		return
+	}
+
+	switch {
+	case k.code == keyCtrlC || k.code == keyRune && k.r == 'q':
+		m.quit = true
+	case k.code == keyUp || k.code == keyRune && k.r == 'k':
+		m.move(-1)
+	case k.code == keyDown || k.code == keyRune && k.r == 'j':
+		m.move(1)
+	case k.code == keyLeft || k.code == keyBackTab || k.code == keyRune && k.r == 'h':
+		if m.focus > classesPane {
+			m.focus--
+		}
+	case k.code == keyRight || k.code == keyTab || k.code == keyRune && k.r == 'l':
+		if m.focus < detailPane {
+			m.focus++
+		}
+	case k.code == keyEnter:
+		m.open()
+	case k.code == keyCtrlR:
+		m.refresh()
+		m.status = "Refreshed"
+	case k.code == keyRune && k.r == 'g' && m.instructor:
+		if asset := m.selected(); asset != nil {
+			m.editor = newGradeForm(asset.Title+" for "+asset.Assignee(), m.rubric)
+		}
+	case k.code == keyRune && k.r == 'r' && m.instructor:
+		m.release()
+	case k.code == keyRune && k.r == 's' && !m.instructor:
+		if asset := m.selected(); asset != nil {
+			m.editor = newAnswerForm(asset.Title)
+		}
+	}
+}
+
+// move changes the selection in the focused pane by delta.
+func (m *model) move(delta int) {
+	switch m.focus {
+	case classesPane:
+		m.classIndex = clamp(m.classIndex+delta, len(m.classes))
+	case assignmentsPane:
+		m.assetIndex = clamp(m.assetIndex+delta, len(m.assets))
+	}
+}
+
+// open opens the selected class or asset in the pane to the right.
+func (m *model) open() {
+	switch m.focus {
+	case classesPane:
+		if len(m.classes) == 0 {
+			return
+		}
+		m.class = m.classes[m.classIndex]
+		m.assetIndex = 0
+		m.detail = nil
+		m.loadAssets()
+		m.focus = assignmentsPane
+	case assignmentsPane:
+		if asset := m.selected(); asset != nil {
+			m.loadDetail(asset.ID)
+			m.focus = detailPane
+		}
+	}
+}
+
+// selected returns the asset to act on: the one in the detail pane when it has focus, otherwise the one
+// selected in the assignments pane.
+func (m *model) selected() *classroom.Asset {
+	if m.focus == detailPane && m.detail != nil {
+		return m.detail
+	}
+	if m.focus == assignmentsPane && m.assetIndex < len(m.assets) {
+		return &m.assets[m.assetIndex]
+	}
+	return nil
+}
+
+// save submits the open editor: a grade for an instructor, an answer for a student. The editor stays open
+// when the input is rejected so it can be corrected.
+func (m *model) save() {
+	asset := m.selected()
+	if asset == nil {
+		m.editor = nil
+		return
+	}
+	if m.instructor {
+		grade, feedback, err := m.editor.grade()
+		if err != nil {
+			m.status = err.Error()
+			return
+		}
+		previous, err := classroom.GradeAssignment(m.contract, asset.ID, grade, feedback)
+		if err != nil {
+			m.fail(err)
+			return
+		}
+		m.status = fmt.Sprintf("Graded %s for %s: %d (was %d)", asset.Title, asset.Assignee(), grade, previous)
+	} else {
+		keyVersion, err := classroom.SubmitWork(m.contract, asset, m.editor.answer())
+		if err != nil {
+			m.fail(err)
+			return
+		}
+		m.status = fmt.Sprintf("Submitted %s, encrypted to version %d of the class key", asset.Title, keyVersion)
+	}
+	id := asset.ID
+	m.editor = nil
+	m.refresh()
+	m.loadDetail(id)
+}
+
+// release releases the grades of the selected asset's assignment to every student.
+func (m *model) release() {
+	asset := m.selected()
+	if asset == nil {
+		return
+	}
+	if err := classroom.ReleaseGrades(m.contract, m.class, asset.Title); err != nil {
+		m.fail(err)
+		return
+	}
+	m.status = "Released grades for " + asset.Title
+	m.refresh()
+}
+
+// handleEvent refreshes the screen for a chaincode event. Submitted work carries the assignment and other
+// grading events name the assignment or group that changed, which the status line shows.
+func (m *model) handleEvent(name string, payload []byte) {
+	var asset classroom.Asset
+	if err := json.Unmarshal(payload, &asset); err != nil || asset.ClassID == "" {
+		m.status = name
+	} else if name == "WorkSubmitted" {
+		m.status = fmt.Sprintf("%s: %s by %s", name, asset.Title, asset.Assignee())
+	} else if asset.ID != "" {
+		m.status = fmt.Sprintf("%s: %s for %s", name, asset.Title, asset.Assignee())
+	} else if asset.Title != "" {
+		m.status = fmt.Sprintf("%s: %s", name, asset.Title)
+	} else {
+		m.status = fmt.Sprintf("%s: group %s", name, asset.GroupID)
+	}
+	m.refresh()
+}
+
+// fail shows a classified error in the status line.
+func (m *model) fail(err error) {
+	m.status = "Error: " + graderr.Classify(err).UserMessage()
+}
+
+// clamp keeps an index within a list of length n.
+func clamp(index int, n int) int {
+	if index >= n {
+		index = n - 1
+	}
+	if index < 0 {
+		index = 0
+	}
+	return index
+}
@@ -0,0 +1,175 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeContract answers evaluations with canned JSON by transaction name and records submissions.
+type fakeContract struct {
+	results   map[string]string
+	submitted [][]string
+}
+
+func (f *fakeContract) EvaluateTransaction(name string, args ...string) ([]byte, error) {
+	return []byte(f.results[name]), nil
+}
+
+func (f *fakeContract) SubmitTransaction(name string, args ...string) ([]byte, error) {
+	f.submitted = append(f.submitted, append([]string{name}, args...))
+	return []byte(f.results[name]), nil
+}
+
+func typeKeys(m *model, text string) {
+	for _, r := range text {
+		m.handleKey(key{code: keyRune, r: r})
+	}
+}
+
+func newTestModel() (*model, *fakeContract) {
+	contract := &fakeContract{results: map[string]string{
+		"GetAllClasses":   `["cs102","cs101"]`,
+		"GetAllAssets":    `[{"ID":"b2","Title":"hw1","StudentID":"bob","ClassID":"cs101","Work":"Bob's work"},{"ID":"a1","Title":"hw1","StudentID":"alice","ClassID":"cs101"}]`,
+		"ReadAsset":       `{"ID":"a1","Title":"hw1","StudentID":"alice","ClassID":"cs101","Description":"Essay","Work":"Alice's work"}`,
+		"GradeAssignment": "0",
+	}}
+	m := &model{contract: contract, username: "instructor", instructor: true, rubric: []criterion{{"Content", 60}, {"Style", 40}}}
+	m.refresh()
+	return m, contract
+}
+
+func TestNavigation(t *testing.T) {
+	m, _ := newTestModel()
+	if !reflect.DeepEqual([]string{"cs101", "cs102"}, m.classes) {
+		t.Fatalf("expected sorted classes, got %v", m.classes)
+	}
+
+	m.handleKey(key{code: keyEnter})
+	if m.class != "cs101" || m.focus != assignmentsPane {
+		t.Fatalf("expected cs101 open with the assignments focused, got %q and pane %d", m.class, m.focus)
+	}
+	if len(m.assets) != 2 || m.assets[0].StudentID != "alice" {
+		t.Fatalf("expected submissions sorted by title and student, got %+v", m.assets)
+	}
+
+	m.handleKey(key{code: keyDown})
+	m.handleKey(key{code: keyDown})
+	if m.assetIndex != 1 {
+		t.Errorf("expected the selection to stop at the last submission, got %d", m.assetIndex)
+	}
+	m.handleKey(key{code: keyRune, r: 'k'})
+	m.handleKey(key{code: keyEnter})
+	if m.detail == nil || m.detail.ID != "a1" || m.focus != detailPane {
+		t.Fatalf("expected alice's submission in the detail pane, got %+v", m.detail)
+	}
+
+	screen := strings.Join(m.view(100, 20), "\n")
+	for _, expected := range []string{"cs101", "alice", "Essay", "Alice's work", "g: grade"} {
+		if !strings.Contains(screen, expected) {
+			t.Errorf("expected the screen to show %q:\n%s", expected, screen)
+		}
+	}
+	if lines := m.view(100, 20); len(lines) != 20 {
+		t.Errorf("expected 20 lines, got %d", len(lines))
+	}
+
+	m.handleKey(key{code: keyRune, r: 'q'})
+	if !m.quit {
+		t.Error("expected q to quit")
+	}
+}
+
+func TestGradeEditor(t *testing.T) {
+	m, contract := newTestModel()
+	m.handleKey(key{code: keyEnter})
+	m.handleKey(key{code: keyEnter})
+
+	m.handleKey(key{code: keyRune, r: 'g'})
+	if m.editor == nil {
+		t.Fatal("expected g to open the grade editor")
+	}
+	typeKeys(m, "5x0")
+	m.handleKey(key{code: keyEnter})
+	typeKeys(m, "45")
+	m.handleKey(key{code: keyEnter})
+	typeKeys(m, "Good")
+	m.handleKey(key{code: keyEnter})
+	if m.editor == nil || !strings.Contains(m.status, "Style must not be more than 40") {
+		t.Fatalf("expected an out of range score to keep the editor open, got status %q", m.status)
+	}
+	if len(contract.submitted) != 0 {
+		t.Fatalf("expected nothing submitted, got %v", contract.submitted)
+	}
+
+	m.handleKey(key{code: keyUp})
+	m.handleKey(key{code: keyBackspace})
+	typeKeys(m, "0")
+	m.handleKey(key{code: keyCtrlS})
+	if m.editor != nil {
+		t.Fatalf("expected the editor to close after saving, status %q", m.status)
+	}
+	expected := [][]string{{"GradeAssignment", "a1", "90", "Content 50/60; Style 40/40. Good"}}
+	if !reflect.DeepEqual(expected, contract.submitted) {
+		t.Errorf("expected %v, got %v", expected, contract.submitted)
+	}
+
+	m.handleKey(key{code: keyRune, r: 'g'})
+	m.handleKey(key{code: keyEscape})
+	if m.editor != nil || len(contract.submitted) != 1 {
+		t.Error("expected Esc to cancel without submitting")
+	}
+}
+
+func TestEventRefresh(t *testing.T) {
+	m, contract := newTestModel()
+	m.handleKey(key{code: keyEnter})
+	contract.results["GetAllAssets"] = `[{"ID":"a1","Title":"hw1","StudentID":"alice","ClassID":"cs101","Work":"done"}]`
+
+	m.handleEvent("WorkSubmitted", []byte(`{"ID":"a1","Title":"hw1","StudentID":"alice","ClassID":"cs101"}`))
+	if len(m.assets) != 1 || m.assets[0].Work != "done" {
+		t.Errorf("expected the event to reload the submissions, got %+v", m.assets)
+	}
+	if m.status != "WorkSubmitted: hw1 by alice" {
+		t.Errorf("unexpected status %q", m.status)
+	}
+
+	// Grades, releases and group changes refresh the screen too
+	contract.results["GetAllAssets"] = `[{"ID":"a1","Title":"hw1","StudentID":"alice","ClassID":"cs101","Work":"done","Grade":90}]`
+	m.handleEvent("GradeChanged", []byte(`{"ID":"a1","ClassID":"cs101","Title":"hw1","StudentID":"alice"}`))
+	if len(m.assets) != 1 || m.assets[0].Grade != 90 || m.status != "GradeChanged: hw1 for alice" {
+		t.Errorf("expected the grade to reload, got %+v, %q", m.assets, m.status)
+	}
+	m.handleEvent("GradesReleased", []byte(`{"ClassID":"cs101","Title":"hw1"}`))
+	if m.status != "GradesReleased: hw1" {
+		t.Errorf("unexpected status %q", m.status)
+	}
+	m.handleEvent("GroupChanged", []byte(`{"ClassID":"cs101","GroupID":"team1"}`))
+	if m.status != "GroupChanged: group team1" {
+		t.Errorf("unexpected status %q", m.status)
+	}
+}
+
+func TestParseRubric(t *testing.T) {
+	rubric, err := parseRubric("Content:60, Style : 40")
+	if err != nil || !reflect.DeepEqual([]criterion{{"Content", 60}, {"Style", 40}}, rubric) {
+		t.Errorf("unexpected rubric %v, %v", rubric, err)
+	}
+	for _, spec := range []string{"", "Content", "Content:0", ":10", "Content:ten"} {
+		if _, err := parseRubric(spec); err == nil {
+			t.Errorf("expected rubric %q to be rejected", spec)
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	expected := []string{"the quick", "brown fox", "abcdefghij", "kl"}
+	if lines := wrap("the quick brown fox abcdefghijkl", 10); !reflect.DeepEqual(expected, lines) {
+		t.Errorf("expected %q, got %q", expected, lines)
+	}
+}
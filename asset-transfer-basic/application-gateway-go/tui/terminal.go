@@ -0,0 +1,191 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/sys/unix"
+)
+
+// keyCode identifies a key press; printable characters are keyRune with the character in key.r.
+type keyCode int
+
+const (
+	keyRune keyCode = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyEnter
+	keyTab
+	keyBackTab
+	keyBackspace
+	keyEscape
+	keyCtrlC
+	keyCtrlR
+	keyCtrlS
+)
+
+// key is a single key press.
+type key struct {
+	code keyCode
+	r    rune
+}
+
+// terminal is the controlling terminal switched into raw mode, drawn on through the alternate screen.
+type terminal struct {
+	in       *os.File
+	out      *bufio.Writer
+	original unix.Termios
+}
+
+// openTerminal puts stdin into raw mode, so keys arrive as they are pressed and are not echoed, and switches
+// to the alternate screen. close restores both.
+func openTerminal() (*terminal, error) {
+	fd := int(os.Stdin.Fd())
+	original, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, fmt.Errorf("stdin is not a terminal: %w", err)
+	}
+
+	raw := *original
+	raw.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON
+	raw.Oflag &^= unix.OPOST
+	raw.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Cflag &^= unix.CSIZE | unix.PARENB
+	raw.Cflag |= unix.CS8
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, &raw); err != nil {
+		return nil, fmt.Errorf("failed to switch terminal to raw mode: %w", err)
+	}
+
+	t := &terminal{in: os.Stdin, out: bufio.NewWriter(os.Stdout), original: *original}
+	t.out.WriteString("\x1b[?1049h\x1b[?25l")
+	t.out.Flush()
+	return t, nil
+}
+
+// close leaves the alternate screen and restores the terminal mode.
+func (t *terminal) close() {
+	t.out.WriteString("\x1b[?25h\x1b[?1049l")
+	t.out.Flush()
+	unix.IoctlSetTermios(int(t.in.Fd()), ioctlSetTermios, &t.original)
+}
+
+// size returns the width and height of the terminal in characters.
+func (t *terminal) size() (int, int) {
+	winsize, err := unix.IoctlGetWinsize(int(t.in.Fd()), unix.TIOCGWINSZ)
+	if err != nil || winsize.Col == 0 || winsize.Row == 0 {
+		return 80, 24
+	}
+	return int(winsize.Col), int(winsize.Row)
+}
+
+// draw replaces the screen with lines.
+func (t *terminal) draw(lines []string) {
+	t.out.WriteString("\x1b[H")
+	for i, line := range lines {
+		if i > 0 {
+			t.out.WriteString("\r\n")
+		}
+		t.out.WriteString(line)
+		t.out.WriteString("\x1b[K")
+	}
+	t.out.WriteString("\x1b[J")
+	t.out.Flush()
+}
+
+// readKeys sends the keys pressed on the terminal to keys, closing it when reading fails.
+func (t *terminal) readKeys(keys chan<- key) {
+	buffer := make([]byte, 64)
+	for {
+		n, err := t.in.Read(buffer)
+		if err != nil {
+			close(keys)
+			return
+		}
+		for _, k := range decodeKeys(buffer[:n]) {
+			keys <- k
+		}
+	}
+}
+
+// escapeSequences maps the escape sequences terminals send for special keys to those keys.
+var escapeSequences = map[string]keyCode{
+	"\x1b[A": keyUp,
+	"\x1b[B": keyDown,
+	"\x1b[C": keyRight,
+	"\x1b[D": keyLeft,
+	"\x1bOA": keyUp,
+	"\x1bOB": keyDown,
+	"\x1bOC": keyRight,
+	"\x1bOD": keyLeft,
+	"\x1b[Z": keyBackTab,
+}
+
+// decodeKeys splits the bytes read from a raw mode terminal into key presses. Unknown escape sequences are
+// dropped; an escape that starts no known sequence is the Escape key.
+func decodeKeys(input []byte) []key {
+	var keys []key
+	for len(input) > 0 {
+		if input[0] == 0x1b {
+			matched := false
+			for sequence, code := range escapeSequences {
+				if strings.HasPrefix(string(input), sequence) {
+					keys = append(keys, key{code: code})
+					input = input[len(sequence):]
+					matched = true
+					break
+				}
+			}
+			if matched {
+				continue
+			}
+			if len(input) > 2 && input[1] == '[' {
+				// Skip an unknown CSI sequence up to its final byte
+				end := 2
+				for end < len(input)-1 && (input[end] < 0x40 || input[end] > 0x7e) {
+					end++
+				}
+				input = input[end+1:]
+				continue
+			}
+			keys = append(keys, key{code: keyEscape})
+			input = input[1:]
+			continue
+		}
+
+		switch input[0] {
+		case '\r', '\n':
+			keys = append(keys, key{code: keyEnter})
+		case '\t':
+			keys = append(keys, key{code: keyTab})
+		case 0x7f, 0x08:
+			keys = append(keys, key{code: keyBackspace})
+		case 0x03:
+			keys = append(keys, key{code: keyCtrlC})
+		case 0x12:
+			keys = append(keys, key{code: keyCtrlR})
+		case 0x13:
+			keys = append(keys, key{code: keyCtrlS})
+		default:
+			r, size := utf8.DecodeRune(input)
+			if r != utf8.RuneError && r >= ' ' {
+				keys = append(keys, key{code: keyRune, r: r})
+			}
+			input = input[size:]
+			continue
+		}
+		input = input[1:]
+	}
+	return keys
+}
@@ -0,0 +1,32 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeKeys(t *testing.T) {
+	input := []byte("a\x1b[A\x1b[B\r\t\x7f\x1b[Z\x1b\x13\x03é\x1b[1;5Pz")
+	expected := []key{
+		{code: keyRune, r: 'a'},
+		{code: keyUp},
+		{code: keyDown},
+		{code: keyEnter},
+		{code: keyTab},
+		{code: keyBackspace},
+		{code: keyBackTab},
+		{code: keyEscape},
+		{code: keyCtrlS},
+		{code: keyCtrlC},
+		{code: keyRune, r: 'é'},
+		{code: keyRune, r: 'z'},
+	}
+	if keys := decodeKeys(input); !reflect.DeepEqual(expected, keys) {
+		t.Errorf("expected %v, got %v", expected, keys)
+	}
+}
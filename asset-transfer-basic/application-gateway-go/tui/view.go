@@ -0,0 +1,189 @@
+/*
+Copyright 2021 IBM All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// reverse and reset are the escape sequences that highlight the selected line
+	reverse = "\x1b[7m"
+	reset   = "\x1b[0m"
+)
+
+// view renders the model as width by height lines of text: a title line, the three panes side by side, and
+// a status and help line at the bottom.
+func (m *model) view(width int, height int) []string {
+	role := "student"
+	if m.instructor {
+		role = "instructor"
+	}
+	title := fmt.Sprintf(" CryptoGrader - %s (%s)", m.username, role)
+	if m.class != "" {
+		title += " - " + m.class
+	}
+
+	classesWidth := width / 5
+	assetsWidth := width * 2 / 5
+	detailWidth := width - classesWidth - assetsWidth - 2
+	paneHeight := height - 3
+	if paneHeight < 1 {
+		paneHeight = 1
+	}
+
+	classes := m.paneLines(classesPane, "Classes", m.classLines(), m.classIndex, classesWidth, paneHeight)
+	assets := m.paneLines(assignmentsPane, "Assignments", m.assetLines(), m.assetIndex, assetsWidth, paneHeight)
+	detail := m.paneLines(detailPane, "Detail", m.detailLines(detailWidth), -1, detailWidth, paneHeight)
+
+	lines := []string{fit(title, width)}
+	for i := 0; i < paneHeight; i++ {
+		lines = append(lines, classes[i]+"|"+assets[i]+"|"+detail[i])
+	}
+	return append(lines, fit(" "+m.status, width), fit(" "+m.help(), width))
+}
+
+// paneLines lays out a pane as height lines of exactly width characters, with a heading and the selected
+// line highlighted. The items scroll to keep the selection visible.
+func (m *model) paneLines(p pane, heading string, items []string, selected int, width int, height int) []string {
+	if m.focus == p {
+		heading = "[" + heading + "]"
+	}
+	lines := []string{fit(" "+heading, width)}
+
+	first := 0
+	if selected >= height-1 {
+		first = selected - (height - 2)
+	}
+	for i := first; len(lines) < height; i++ {
+		if i >= len(items) {
+			lines = append(lines, strings.Repeat(" ", width))
+			continue
+		}
+		line := fit(" "+items[i], width)
+		if i == selected && m.focus == p {
+			line = reverse + line + reset
+		} else if i == selected {
+			line = fit(">"+items[i], width)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (m *model) classLines() []string {
+	return m.classes
+}
+
+// assetLines lists the open class's assets: each submission with its student for an instructor, and each
+// assignment with its state for a student.
+func (m *model) assetLines() []string {
+	var lines []string
+	for _, asset := range m.assets {
+		state := "to do"
+		switch {
+		case asset.Released:
+			state = fmt.Sprintf("graded %d", asset.Grade)
+		case asset.Work != "":
+			state = "submitted"
+		}
+		if m.instructor {
+			lines = append(lines, fmt.Sprintf("%-12s %-10s %s", asset.Title, asset.Assignee(), state))
+		} else {
+			lines = append(lines, fmt.Sprintf("%-16s %s", asset.Title, state))
+		}
+	}
+	return lines
+}
+
+// detailLines shows the open editor, or else the asset in the detail pane, wrapped to width.
+func (m *model) detailLines(width int) []string {
+	if m.editor != nil {
+		return m.editor.lines()
+	}
+	if m.detail == nil {
+		return []string{"Select an assignment and press Enter"}
+	}
+	asset := m.detail
+	lines := []string{
+		asset.Title,
+		"Due: " + asset.Date,
+		"For: " + asset.Assignee(),
+		"Owner: " + asset.Owner,
+		"",
+	}
+	lines = append(lines, wrap(asset.Description, width-2)...)
+	lines = append(lines, "", "Work:")
+	lines = append(lines, wrap(m.work, width-2)...)
+	lines = append(lines, "")
+	if asset.Released || m.instructor {
+		lines = append(lines, fmt.Sprintf("Grade: %d", asset.Grade))
+		lines = append(lines, wrap("Feedback: "+asset.Feedback, width-2)...)
+	} else {
+		lines = append(lines, "Grade: not released")
+	}
+	for _, attachment := range asset.Attachments {
+		lines = append(lines, fmt.Sprintf("Attachment: %s (%d bytes)", attachment.Name, attachment.Size))
+	}
+	return lines
+}
+
+// help lists the keys available in the current state.
+func (m *model) help() string {
+	switch {
+	case m.editor != nil:
+		return "Tab/Up/Down: field  Enter: next  Ctrl-S: save  Esc: cancel"
+	case m.instructor:
+		return "Arrows/hjkl: move  Enter: open  g: grade  r: release grades  Ctrl-R: refresh  q: quit"
+	default:
+		return "Arrows/hjkl: move  Enter: open  s: submit work  Ctrl-R: refresh  q: quit"
+	}
+}
+
+// fit pads or truncates s to exactly width characters.
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	runes := []rune(s)
+	if len(runes) > width {
+		return string(runes[:width])
+	}
+	return s + strings.Repeat(" ", width-len(runes))
+}
+
+// wrap breaks text into lines of at most width characters, at spaces where possible.
+func wrap(text string, width int) []string {
+	if width < 1 {
+		width = 1
+	}
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			for len([]rune(word)) > width {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				lines = append(lines, string([]rune(word)[:width]))
+				word = string([]rune(word)[width:])
+			}
+			switch {
+			case line == "":
+				line = word
+			case len([]rune(line))+1+len([]rune(word)) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
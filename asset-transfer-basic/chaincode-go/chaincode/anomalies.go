@@ -0,0 +1,327 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Kinds of anomaly reported by GetGradeAnomalies
+const (
+	AnomalyExcessiveGradeChanges = "ExcessiveGradeChanges"
+	AnomalyChangedAfterRelease   = "ChangedAfterRelease"
+	AnomalyUnauthorizedChange    = "UnauthorizedChange"
+	AnomalyLateFullCredit        = "LateFullCredit"
+)
+
+// defaultMaxGrade is the grade awarded for full credit on assignments that do not set their own MaxGrade
+const defaultMaxGrade = 100
+
+// gradeAuditorAttribute is the certificate attribute that, set to "true", lets a client audit the grades
+// of any class
+const gradeAuditorAttribute = "grade.auditor"
+
+// dueDateLayouts are the date formats accepted for an assignment due date
+var dueDateLayouts = []string{
+	"1/2/2006",
+	"2006-01-02",
+	time.RFC3339,
+}
+
+// GradeAnomaly describes a single suspicious change found in the history of a submission
+type GradeAnomaly struct {
+	AssetID   string    `json:"AssetID"`
+	Kind      string    `json:"Kind"`
+	TxID      string    `json:"TxID"`
+	Timestamp time.Time `json:"Timestamp"`
+	Detail    string    `json:"Detail"`
+}
+
+// GradeAnomalyReport is the result of scanning the grade history of a class
+type GradeAnomalyReport struct {
+	ClassID       string          `json:"ClassID"`
+	AssetsScanned int             `json:"AssetsScanned"`
+	Anomalies     []*GradeAnomaly `json:"Anomalies"`
+}
+
+// assetVersion is a single entry from the history of an asset
+type assetVersion struct {
+	asset     Asset
+	txID      string
+	timestamp time.Time
+}
+
+// classVersion is a single entry from the history of a class record
+type classVersion struct {
+	record    Class
+	timestamp time.Time
+}
+
+// GetGradeAnomalies scans the history of every asset in a class and reports grades changed more than
+// maxGradeChanges times after first being given, grades changed after release, grades changed by a client
+// that was neither the instructor nor a teaching assistant of the class at the time, and late submissions
+// that still received full credit. maxGradeChanges must be at least 1. Only the instructor of the class and
+// clients with the grade.auditor attribute may scan it.
+func (s *SmartContract) GetGradeAnomalies(ctx contractapi.TransactionContextInterface, class string, maxGradeChanges int) (*GradeAnomalyReport, error) {
+	if maxGradeChanges < 1 {
+		return nil, validationError("maxGradeChanges", "the maximum number of grade changes must be at least 1")
+	}
+	if err := s.authorizeAudit(ctx, class, "scan its grades"); err != nil {
+		return nil, err
+	}
+	classHistory, err := getClassVersions(ctx, class)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return nil, internalError("failed to read from world state", err)
+	}
+	defer resultsIterator.Close()
+
+	report := &GradeAnomalyReport{
+		ClassID:   class,
+		Anomalies: []*GradeAnomaly{},
+	}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, internalError("failed to read from world state", err)
+		}
+
+		var asset Asset
+		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return nil, internalError("failed to parse asset", err)
+		}
+		if asset.ClassID != class {
+			continue
+		}
+
+		history, err := getAssetVersions(ctx, asset.ID)
+		if err != nil {
+			return nil, err
+		}
+		report.AssetsScanned++
+		report.Anomalies = append(report.Anomalies, findGradeAnomalies(asset.ID, history, maxGradeChanges, classHistory)...)
+	}
+
+	return report, nil
+}
+
+// authorizeAudit checks that the submitting client is the instructor of a class or a grade auditor, naming
+// what it was refused in the error.
+func (s *SmartContract) authorizeAudit(ctx contractapi.TransactionContextInterface, class string, action string) error {
+	if err := validateName("class", "class ID", class); err != nil {
+		return err
+	}
+	if ctx.GetClientIdentity().AssertAttributeValue(gradeAuditorAttribute, "true") == nil {
+		return nil
+	}
+
+	instructor, err := s.isInstructor(ctx, class)
+	if err != nil {
+		return err
+	}
+	if !instructor {
+		return newContractError(ErrForbidden, map[string]string{"class": class},
+			"only the instructor of class %s or a grade auditor may %s", class, action)
+	}
+	return nil
+}
+
+// getClassVersions returns the history of a class record ordered from oldest to newest, skipping deletions.
+func getClassVersions(ctx contractapi.TransactionContextInterface, class string) ([]classVersion, error) {
+	classKey, err := ctx.GetStub().CreateCompositeKey(classObjectType, []string{class})
+	if err != nil {
+		return nil, internalError("failed to create composite key", err)
+	}
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(classKey)
+	if err != nil {
+		return nil, internalError(fmt.Sprintf("failed to read history of class %s", class), err)
+	}
+	defer resultsIterator.Close()
+
+	var versions []classVersion
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, internalError(fmt.Sprintf("failed to read history of class %s", class), err)
+		}
+		if response.IsDelete || len(response.Value) == 0 {
+			continue
+		}
+
+		var record Class
+		err = json.Unmarshal(response.Value, &record)
+		if err != nil {
+			return nil, internalError("failed to parse class", err)
+		}
+
+		versions = append(versions, classVersion{record: record, timestamp: response.Timestamp.AsTime()})
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].timestamp.Before(versions[j].timestamp)
+	})
+
+	return versions, nil
+}
+
+// getAssetVersions returns the history of an asset ordered from oldest to newest, skipping deletions.
+func getAssetVersions(ctx contractapi.TransactionContextInterface, id string) ([]assetVersion, error) {
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(id)
+	if err != nil {
+		return nil, internalError(fmt.Sprintf("failed to read history of asset %s", id), err)
+	}
+	defer resultsIterator.Close()
+
+	var versions []assetVersion
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, internalError(fmt.Sprintf("failed to read history of asset %s", id), err)
+		}
+		if response.IsDelete || len(response.Value) == 0 {
+			continue
+		}
+
+		var asset Asset
+		err = json.Unmarshal(response.Value, &asset)
+		if err != nil {
+			return nil, internalError("failed to parse asset", err)
+		}
+
+		versions = append(versions, assetVersion{
+			asset:     asset,
+			txID:      response.TxId,
+			timestamp: response.Timestamp.AsTime(),
+		})
+	}
+
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].timestamp.Before(versions[j].timestamp)
+	})
+
+	return versions, nil
+}
+
+// findGradeAnomalies applies each anomaly rule to the ordered history of a single asset. classHistory is
+// the ordered history of its class record, which tells who could grade at the time of each change.
+func findGradeAnomalies(id string, history []assetVersion, maxGradeChanges int, classHistory []classVersion) []*GradeAnomaly {
+	var anomalies []*GradeAnomaly
+	if len(history) == 0 {
+		return anomalies
+	}
+
+	creator := history[0].asset.ModifiedBy
+	// The first grade given replaces the 0 every assignment starts with, and is no change of mind
+	graded := history[0].asset.Grade != 0
+	gradeChanges := 0
+	var submittedAt time.Time
+	for i := 1; i < len(history); i++ {
+		previous, current := history[i-1], history[i]
+
+		if current.asset.Work != previous.asset.Work {
+			submittedAt = current.timestamp
+		}
+		if current.asset.Grade == previous.asset.Grade {
+			continue
+		}
+
+		if graded {
+			gradeChanges++
+			if gradeChanges == maxGradeChanges+1 {
+				anomalies = append(anomalies, &GradeAnomaly{
+					AssetID:   id,
+					Kind:      AnomalyExcessiveGradeChanges,
+					TxID:      current.txID,
+					Timestamp: current.timestamp,
+					Detail:    fmt.Sprintf("grade changed more than %d times", maxGradeChanges),
+				})
+			}
+		}
+		graded = graded || current.asset.Grade != 0
+		if previous.asset.Released {
+			anomalies = append(anomalies, &GradeAnomaly{
+				AssetID:   id,
+				Kind:      AnomalyChangedAfterRelease,
+				TxID:      current.txID,
+				Timestamp: current.timestamp,
+				Detail:    fmt.Sprintf("grade changed from %d to %d after release", previous.asset.Grade, current.asset.Grade),
+			})
+		}
+		instructor, graded := gradersAt(classHistory, current.timestamp, creator, current.asset.ModifiedBy)
+		if instructor != "" && current.asset.ModifiedBy != "" && !graded {
+			anomalies = append(anomalies, &GradeAnomaly{
+				AssetID:   id,
+				Kind:      AnomalyUnauthorizedChange,
+				TxID:      current.txID,
+				Timestamp: current.timestamp,
+				Detail:    fmt.Sprintf("grade changed by %s instead of the instructor %s", current.asset.ModifiedBy, instructor),
+			})
+		}
+	}
+
+	latest := history[len(history)-1]
+	dueDate, ok := parseDueDate(latest.asset.Date)
+	if ok && !submittedAt.IsZero() && submittedAt.After(dueDate) && latest.asset.Grade >= maxGrade(&latest.asset) {
+		anomalies = append(anomalies, &GradeAnomaly{
+			AssetID:   id,
+			Kind:      AnomalyLateFullCredit,
+			TxID:      latest.txID,
+			Timestamp: submittedAt,
+			Detail:    fmt.Sprintf("submitted after the due date %s but graded %d", latest.asset.Date, latest.asset.Grade),
+		})
+	}
+
+	return anomalies
+}
+
+// gradersAt returns the instructor of a class at a time and whether clientID was its instructor or one of
+// its teaching assistants then. Before the class had a record, creator, the client that created the
+// assignment, is taken as its instructor.
+func gradersAt(classHistory []classVersion, at time.Time, creator string, clientID string) (instructor string, graded bool) {
+	var record *Class
+	for i := range classHistory {
+		if classHistory[i].timestamp.After(at) {
+			break
+		}
+		record = &classHistory[i].record
+	}
+
+	instructor = creator
+	if record != nil && record.InstructorClient != "" {
+		instructor = record.InstructorClient
+	}
+	graded = clientID == instructor || (record != nil && findGrader(record, clientID) != nil)
+	return instructor, graded
+}
+
+// maxGrade returns the grade awarded for full credit on an assignment.
+func maxGrade(asset *Asset) int {
+	if asset.MaxGrade > 0 {
+		return asset.MaxGrade
+	}
+	return defaultMaxGrade
+}
+
+// parseDueDate parses an assignment due date, treating a date without a time as due at the end of that day.
+func parseDueDate(date string) (time.Time, bool) {
+	for _, layout := range dueDateLayouts {
+		dueDate, err := time.Parse(layout, date)
+		if err != nil {
+			continue
+		}
+		if layout != time.RFC3339 {
+			dueDate = dueDate.Add(24*time.Hour - time.Nanosecond)
+		}
+		return dueDate, true
+	}
+
+	return time.Time{}, false
+}
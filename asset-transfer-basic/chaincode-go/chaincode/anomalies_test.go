@@ -0,0 +1,201 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestGetGradeAnomalies(t *testing.T) {
+	dueDate := time.Date(2023, 4, 24, 0, 0, 0, 0, time.UTC)
+	base := chaincode.Asset{ID: "hw1alice", Title: "hw1", Date: "4/24/2023", ClassID: "cs101", ModifiedBy: "instructor", MaxGrade: 50}
+
+	created := base
+	submitted := base
+	submitted.Work = "answer"
+	submitted.ModifiedBy = "alice"
+	graded := submitted
+	graded.Grade = 45
+	graded.ModifiedBy = "ta"
+	corrected := graded
+	corrected.Grade = 40
+	released := corrected
+	released.Released = true
+	released.ModifiedBy = "instructor"
+	regraded := released
+	regraded.Grade = 50
+	regraded.ModifiedBy = "mallory"
+
+	// history is returned newest first, as the peer does
+	versions := []chaincode.Asset{regraded, released, corrected, graded, submitted, created}
+	times := []time.Time{
+		dueDate.Add(96 * time.Hour),
+		dueDate.Add(72 * time.Hour),
+		dueDate.Add(66 * time.Hour),
+		dueDate.Add(60 * time.Hour),
+		dueDate.Add(48 * time.Hour),
+		dueDate.Add(-48 * time.Hour),
+	}
+
+	historyIterator := &mocks.HistoryQueryIterator{}
+	for i, version := range versions {
+		bytes, err := json.Marshal(version)
+		require.NoError(t, err)
+		historyIterator.HasNextReturnsOnCall(i, true)
+		historyIterator.NextReturnsOnCall(i, &queryresult.KeyModification{
+			TxId:      fmt.Sprintf("tx%d", len(versions)-i),
+			Value:     bytes,
+			Timestamp: timestamppb.New(times[i]),
+		}, nil)
+	}
+	historyIterator.HasNextReturnsOnCall(len(versions), false)
+
+	// ta grades while a teaching assistant and is removed before the audit; mallory is added only after
+	// changing the grade
+	ta := &chaincode.Grader{ClientID: "ta", Scopes: []*chaincode.GraderScope{{}}}
+	mallory := &chaincode.Grader{ClientID: "mallory", Scopes: []*chaincode.GraderScope{{}}}
+	classes := []chaincode.Class{
+		{ClassID: "cs101", InstructorClient: "instructor", Graders: []*chaincode.Grader{mallory}},
+		{ClassID: "cs101", InstructorClient: "instructor"},
+		{ClassID: "cs101", InstructorClient: "instructor", Graders: []*chaincode.Grader{ta}},
+	}
+	classTimes := []time.Time{dueDate.Add(100 * time.Hour), dueDate.Add(80 * time.Hour), dueDate.Add(-72 * time.Hour)}
+	classIterator := &mocks.HistoryQueryIterator{}
+	for i, class := range classes {
+		bytes, err := json.Marshal(class)
+		require.NoError(t, err)
+		classIterator.HasNextReturnsOnCall(i, true)
+		classIterator.NextReturnsOnCall(i, &queryresult.KeyModification{Value: bytes, Timestamp: timestamppb.New(classTimes[i])}, nil)
+	}
+	classIterator.HasNextReturnsOnCall(len(classes), false)
+
+	current, err := json.Marshal(regraded)
+	require.NoError(t, err)
+	other, err := json.Marshal(chaincode.Asset{ID: "hw1bob", ClassID: "cs102"})
+	require.NoError(t, err)
+	stateIterator := &mocks.StateQueryIterator{}
+	stateIterator.HasNextReturnsOnCall(0, true)
+	stateIterator.HasNextReturnsOnCall(1, true)
+	stateIterator.HasNextReturnsOnCall(2, false)
+	stateIterator.NextReturnsOnCall(0, &queryresult.KV{Value: current}, nil)
+	stateIterator.NextReturnsOnCall(1, &queryresult.KV{Value: other}, nil)
+
+	const classKey = "\x00Class\x00cs101\x00"
+	chaincodeStub := &mocks.ChaincodeStub{}
+	chaincodeStub.CreateCompositeKeyReturns(classKey, nil)
+	chaincodeStub.GetStateByRangeReturns(stateIterator, nil)
+	chaincodeStub.GetHistoryForKeyCalls(func(key string) (shim.HistoryQueryIteratorInterface, error) {
+		if key == classKey {
+			return classIterator, nil
+		}
+		return historyIterator, nil
+	})
+	clientIdentity := &mocks.ClientIdentity{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	transactionContext.GetClientIdentityReturns(clientIdentity)
+
+	assetTransfer := chaincode.SmartContract{}
+	report, err := assetTransfer.GetGradeAnomalies(transactionContext, "cs101", 1)
+	require.NoError(t, err)
+	require.Equal(t, "cs101", report.ClassID)
+	require.Equal(t, 1, report.AssetsScanned)
+	require.Equal(t, 2, chaincodeStub.GetHistoryForKeyCallCount())
+	name, value := clientIdentity.AssertAttributeValueArgsForCall(0)
+	require.Equal(t, "grade.auditor", name)
+	require.Equal(t, "true", value)
+
+	var kinds []string
+	for _, anomaly := range report.Anomalies {
+		require.Equal(t, "hw1alice", anomaly.AssetID)
+		kinds = append(kinds, anomaly.Kind)
+	}
+	require.Equal(t, []string{
+		chaincode.AnomalyExcessiveGradeChanges,
+		chaincode.AnomalyChangedAfterRelease,
+		chaincode.AnomalyUnauthorizedChange,
+		chaincode.AnomalyLateFullCredit,
+	}, kinds)
+	require.Equal(t, "tx6", report.Anomalies[0].TxID, "the first grading is not a change")
+	require.Equal(t, "grade changed by mallory instead of the instructor instructor", report.Anomalies[2].Detail)
+	require.Equal(t, times[4], report.Anomalies[3].Timestamp)
+
+	chaincodeStub.GetStateByRangeReturns(nil, fmt.Errorf("failed retrieving all assets"))
+	report, err = assetTransfer.GetGradeAnomalies(transactionContext, "cs101", 1)
+	requireContractError(t, err, chaincode.ErrInternal, "failed to read from world state: failed retrieving all assets")
+	require.Nil(t, report)
+
+	// Clients without the auditor attribute must be the instructor
+	classJSON, err := json.Marshal(classes[0])
+	require.NoError(t, err)
+	chaincodeStub.GetStateReturns(classJSON, nil)
+	clientIdentity.AssertAttributeValueReturns(fmt.Errorf("attribute 'grade.auditor' was not found"))
+	clientIdentity.GetIDReturns("mallory", nil)
+	report, err = assetTransfer.GetGradeAnomalies(transactionContext, "cs101", 1)
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 or a grade auditor may scan its grades")
+	require.Nil(t, report)
+}
+
+func TestGetGradeAnomaliesNoAnomalies(t *testing.T) {
+	// A single grading replaces the initial 0, which is no change even with the lowest limit
+	created := chaincode.Asset{ID: "hw1alice", Date: "not a date", ClassID: "cs101", ModifiedBy: "instructor"}
+	asset := created
+	asset.Grade = 100
+	createdBytes, err := json.Marshal(created)
+	require.NoError(t, err)
+	bytes, err := json.Marshal(asset)
+	require.NoError(t, err)
+
+	historyIterator := &mocks.HistoryQueryIterator{}
+	historyIterator.HasNextReturnsOnCall(0, true)
+	historyIterator.HasNextReturnsOnCall(1, true)
+	historyIterator.HasNextReturnsOnCall(2, false)
+	historyIterator.NextReturnsOnCall(0, &queryresult.KeyModification{TxId: "tx2", Value: bytes, Timestamp: timestamppb.Now()}, nil)
+	historyIterator.NextReturnsOnCall(1, &queryresult.KeyModification{TxId: "tx1", Value: createdBytes, Timestamp: timestamppb.New(time.Now().Add(-time.Hour))}, nil)
+
+	stateIterator := &mocks.StateQueryIterator{}
+	stateIterator.HasNextReturnsOnCall(0, true)
+	stateIterator.HasNextReturnsOnCall(1, false)
+	stateIterator.NextReturns(&queryresult.KV{Value: bytes}, nil)
+
+	chaincodeStub := &mocks.ChaincodeStub{}
+	chaincodeStub.CreateCompositeKeyReturns("\x00Class\x00cs101\x00", nil)
+	chaincodeStub.GetStateByRangeReturns(stateIterator, nil)
+	chaincodeStub.GetHistoryForKeyCalls(func(key string) (shim.HistoryQueryIteratorInterface, error) {
+		if key == "\x00Class\x00cs101\x00" {
+			return &mocks.HistoryQueryIterator{}, nil
+		}
+		return historyIterator, nil
+	})
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	transactionContext.GetClientIdentityReturns(&mocks.ClientIdentity{})
+
+	assetTransfer := chaincode.SmartContract{}
+	report, err := assetTransfer.GetGradeAnomalies(transactionContext, "cs101", 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, report.AssetsScanned)
+	require.Empty(t, report.Anomalies)
+
+	_, err = assetTransfer.GetGradeAnomalies(transactionContext, "cs101", 0)
+	requireContractError(t, err, chaincode.ErrValidation, "the maximum number of grade changes must be at least 1")
+
+	stateIterator.HasNextReturnsOnCall(2, true)
+	stateIterator.HasNextReturnsOnCall(3, false)
+	chaincodeStub.GetHistoryForKeyCalls(func(key string) (shim.HistoryQueryIteratorInterface, error) {
+		if key == "\x00Class\x00cs101\x00" {
+			return &mocks.HistoryQueryIterator{}, nil
+		}
+		return nil, fmt.Errorf("history database disabled")
+	})
+	_, err = assetTransfer.GetGradeAnomalies(transactionContext, "cs101", 3)
+	requireContractError(t, err, chaincode.ErrInternal, "failed to read history of asset hw1alice: history database disabled")
+}
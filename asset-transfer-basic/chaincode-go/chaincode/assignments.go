@@ -0,0 +1,115 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// assignmentID derives the ID of a student's copy of an assignment from the class, assignment title and
+// student. The fields are hashed with a separator that cannot occur in them, so different combinations never
+// produce the same ID, as "hw1"+"alice" and "hw1a"+"lice" did when IDs were concatenated. A composite key
+// would also be unambiguous, but composite keys are left out of the range queries the contract lists
+// assignments with.
+func assignmentID(class string, title string, student string) string {
+	hash := sha256.Sum256([]byte(strings.Join([]string{class, title, student}, "\x00")))
+	return hex.EncodeToString(hash[:])
+}
+
+// AssignmentID returns the ID of a student's copy of the titled assignment in a class, whether or not it has
+// been created yet.
+func (s *SmartContract) AssignmentID(ctx contractapi.TransactionContextInterface, class string, title string, student string) (string, error) {
+	if err := validateAssignmentKey(class, title, student); err != nil {
+		return "", err
+	}
+	return assignmentID(class, title, student), nil
+}
+
+// CreateAssignment creates a student's copy of the titled assignment in a class, held by the instructor
+// until it is transferred to the student, and returns its generated ID.
+func (s *SmartContract) CreateAssignment(ctx contractapi.TransactionContextInterface, class string, title string, instructor string, student string, date string, description string) (string, error) {
+	return idempotent(ctx, func() (string, error) {
+		asset, err := s.newAssignment(ctx, class, title, instructor, student, date, description)
+		if err != nil {
+			return "", err
+		}
+
+		err = putAsset(ctx, asset)
+		if err != nil {
+			return "", err
+		}
+
+		return asset.ID, nil
+	})
+}
+
+// newAssignment validates a student's copy of the titled assignment in a class and checks that it does not
+// exist yet, returning the asset to write. The first assignment of a class binds the class to its creator,
+// and later ones must be created by them.
+func (s *SmartContract) newAssignment(ctx contractapi.TransactionContextInterface, class string, title string, instructor string, student string, date string, description string) (*Asset, error) {
+	if err := validateAssignmentKey(class, title, student); err != nil {
+		return nil, err
+	}
+
+	id := assignmentID(class, title, student)
+	asset := Asset{
+		ID:           id,
+		Title:        title,
+		Date:         date,
+		Description:  description,
+		InstructorID: instructor,
+		StudentID:    student,
+		Owner:        instructor,
+		ClassID:      class,
+	}
+	err := validateAsset(&asset)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := s.AssetExists(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, newContractError(ErrConflict, map[string]string{"id": id, "class": class, "title": title, "student": student},
+			"the assignment %s already exists for student %s in class %s", title, student, class)
+	}
+
+	asset.ModifiedBy, err = submittingClientID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = s.claimClass(ctx, class, asset.ModifiedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	return &asset, nil
+}
+
+// validateAssignmentKey checks the fields an assignment ID is derived from.
+func validateAssignmentKey(class string, title string, student string) error {
+	if err := validateName("class", "class ID", class); err != nil {
+		return err
+	}
+	if err := validateTitle(title); err != nil {
+		return err
+	}
+	return validateName("student", "student", student)
+}
+
+// isStudentOf reports whether username is the student an asset was created for. Assets created before
+// StudentID was recorded only carry the student in the suffix of their ID. Group assignments belong to no
+// single student.
+func isStudentOf(asset *Asset, username string) bool {
+	if asset.GroupID != "" {
+		return false
+	}
+	if asset.StudentID != "" {
+		return asset.StudentID == username
+	}
+	return strings.HasSuffix(asset.ID, username)
+}
@@ -0,0 +1,110 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssignmentID(t *testing.T) {
+	transactionContext := &mocks.TransactionContext{}
+	assetTransfer := chaincode.SmartContract{}
+
+	id, err := assetTransfer.AssignmentID(transactionContext, "cs101", "hw1", "alice")
+	require.NoError(t, err)
+	require.Len(t, id, 64)
+
+	again, err := assetTransfer.AssignmentID(transactionContext, "cs101", "hw1", "alice")
+	require.NoError(t, err)
+	require.Equal(t, id, again)
+
+	shifted, err := assetTransfer.AssignmentID(transactionContext, "cs101", "hw1a", "lice")
+	require.NoError(t, err)
+	require.NotEqual(t, id, shifted)
+
+	otherClass, err := assetTransfer.AssignmentID(transactionContext, "cs102", "hw1", "alice")
+	require.NoError(t, err)
+	require.NotEqual(t, id, otherClass)
+
+	_, err = assetTransfer.AssignmentID(transactionContext, "cs101", "hw1", "")
+	requireContractError(t, err, chaincode.ErrValidation, "the student must not be empty")
+}
+
+func TestCreateAssignment(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	chaincodeStub.CreateCompositeKeyReturns("\x00Class\x00cs101\x00", nil)
+	chaincodeStub.GetStateByRangeReturns(&mocks.StateQueryIterator{}, nil)
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	clientIdentity := &mocks.ClientIdentity{}
+	clientIdentity.GetIDReturns("x509::CN=instructor", nil)
+	transactionContext.GetClientIdentityReturns(clientIdentity)
+
+	assetTransfer := chaincode.SmartContract{}
+	id, err := assetTransfer.CreateAssignment(transactionContext, "cs101", "hw1", "instructor", "alice", "4/24/2023", "Essay")
+	require.NoError(t, err)
+	expectedID, err := assetTransfer.AssignmentID(transactionContext, "cs101", "hw1", "alice")
+	require.NoError(t, err)
+	require.Equal(t, expectedID, id)
+
+	// The first assignment of a class binds the class to its creator
+	key, classJSON := chaincodeStub.PutStateArgsForCall(0)
+	require.Equal(t, "\x00Class\x00cs101\x00", key)
+	var class chaincode.Class
+	require.NoError(t, json.Unmarshal(classJSON, &class))
+	require.Equal(t, chaincode.Class{ClassID: "cs101", InstructorClient: "x509::CN=instructor"}, class)
+
+	key, assetJSON := chaincodeStub.PutStateArgsForCall(1)
+	require.Equal(t, id, key)
+	var created chaincode.Asset
+	require.NoError(t, json.Unmarshal(assetJSON, &created))
+	require.Equal(t, chaincode.Asset{
+		ID:           id,
+		Title:        "hw1",
+		Date:         "4/24/2023",
+		Description:  "Essay",
+		InstructorID: "instructor",
+		StudentID:    "alice",
+		Owner:        "instructor",
+		ClassID:      "cs101",
+		ModifiedBy:   "x509::CN=instructor",
+	}, created)
+
+	chaincodeStub.GetStateReturns(assetJSON, nil)
+	_, err = assetTransfer.CreateAssignment(transactionContext, "cs101", "hw1", "instructor", "alice", "", "")
+	requireContractError(t, err, chaincode.ErrConflict, "the assignment hw1 already exists for student alice in class cs101")
+
+	_, err = assetTransfer.CreateAssignment(transactionContext, "cs101", "hw1", "", "alice", "", "")
+	requireContractError(t, err, chaincode.ErrValidation, "the owner must not be empty")
+}
+
+func TestGetAllClasses(t *testing.T) {
+	assets := []chaincode.Asset{
+		{ID: "hw1alice", InstructorID: "instructor", ClassID: "cs100"},
+		{ID: "0a1b", InstructorID: "instructor", StudentID: "alice", ClassID: "cs101"},
+		{ID: "2c3dalice", InstructorID: "instructor", StudentID: "malice", ClassID: "cs102"},
+		{ID: "a", InstructorID: "instructor", ClassID: "cs103"},
+	}
+	iterator := &mocks.StateQueryIterator{}
+	for i, asset := range assets {
+		assetJSON, err := json.Marshal(asset)
+		require.NoError(t, err)
+		iterator.HasNextReturnsOnCall(i, true)
+		iterator.NextReturnsOnCall(i, &queryresult.KV{Key: asset.ID, Value: assetJSON}, nil)
+	}
+	iterator.HasNextReturnsOnCall(len(assets), false)
+
+	chaincodeStub := &mocks.ChaincodeStub{}
+	chaincodeStub.GetStateByRangeReturns(iterator, nil)
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+
+	assetTransfer := chaincode.SmartContract{}
+	classes, err := assetTransfer.GetAllClasses(transactionContext, "alice")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"cs100", "cs101"}, classes)
+}
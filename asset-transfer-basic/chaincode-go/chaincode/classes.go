@@ -0,0 +1,297 @@
+package chaincode
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// classObjectType is the composite key prefix under which class records are stored
+const classObjectType = "Class"
+
+// classKeySize is the length in bytes of an X25519 public key
+const classKeySize = 32
+
+// Class holds the settings shared by every assignment in a class. Students encrypt their work to PublicKey,
+// a base64 X25519 key whose private half only the instructor holds.
+type Class struct {
+	ClassID          string `json:"ClassID"`
+	InstructorClient string `json:"InstructorClient"`
+	PublicKey        string `json:"PublicKey"`
+	KeyVersion       int    `json:"KeyVersion"`
+	// Graders are the teaching assistants the instructor delegated grading to
+	Graders []*Grader `json:"Graders,omitempty" metadata:"Graders,optional"`
+	// RequireGradeApproval holds grades given by teaching assistants until the instructor approves them
+	RequireGradeApproval bool `json:"RequireGradeApproval,omitempty" metadata:"RequireGradeApproval,optional"`
+}
+
+// RotateClassKey publishes a new submission encryption key for a class and returns its version. Only the
+// instructor of the class, the client that created its first assignment or claimed it with ClaimClass, may
+// publish its key. Work already submitted stays encrypted to the key version recorded with it.
+func (s *SmartContract) RotateClassKey(ctx contractapi.TransactionContextInterface, class string, publicKey string) (int, error) {
+	return idempotent(ctx, func() (int, error) {
+		key, err := base64.StdEncoding.DecodeString(publicKey)
+		if err != nil || len(key) != classKeySize {
+			return -1, validationError("publicKey", "the class key must be a base64 encoded %d byte X25519 public key", classKeySize)
+		}
+
+		clientID, err := submittingClientID(ctx)
+		if err != nil {
+			return -1, err
+		}
+
+		record, err := s.boundClass(ctx, class)
+		if err != nil {
+			return -1, err
+		}
+		if record == nil {
+			return -1, missingClass(ctx, class)
+		}
+		if record.InstructorClient != clientID {
+			return -1, newContractError(ErrForbidden, map[string]string{"class": class}, "only the instructor of class %s may rotate its key", class)
+		}
+
+		record.PublicKey = publicKey
+		record.KeyVersion++
+
+		err = putClass(ctx, record)
+		if err != nil {
+			return -1, err
+		}
+
+		return record.KeyVersion, nil
+	})
+}
+
+// ReadClass returns the record of a class, or nil if it has no assignments yet.
+func (s *SmartContract) ReadClass(ctx contractapi.TransactionContextInterface, class string) (*Class, error) {
+	classKey, err := ctx.GetStub().CreateCompositeKey(classObjectType, []string{class})
+	if err != nil {
+		return nil, internalError("failed to create composite key", err)
+	}
+
+	recordJSON, err := ctx.GetStub().GetState(classKey)
+	if err != nil {
+		return nil, internalError("failed to read from world state", err)
+	}
+	if recordJSON == nil {
+		return nil, nil
+	}
+
+	var record Class
+	err = json.Unmarshal(recordJSON, &record)
+	if err != nil {
+		return nil, internalError("failed to parse class", err)
+	}
+
+	return &record, nil
+}
+
+// ClaimClass makes the submitting client the instructor of a class whose assignments predate class
+// records. New classes are bound to the creator of their first assignment or group instead, and a class
+// that predates class records can get no new assignments, groups or grades until it is claimed. The claim
+// belongs to the client that created the oldest of the class's assignments or, if no creator was recorded
+// for any of them, to a client whose certificate is issued to the instructor named on every one of them.
+func (s *SmartContract) ClaimClass(ctx contractapi.TransactionContextInterface, class string) error {
+	return idempotentError(ctx, func() error {
+		if err := validateName("class", "class ID", class); err != nil {
+			return err
+		}
+		clientID, err := submittingClientID(ctx)
+		if err != nil {
+			return err
+		}
+
+		record, err := s.ReadClass(ctx, class)
+		if err != nil {
+			return err
+		}
+		if record != nil && record.InstructorClient != "" {
+			if record.InstructorClient != clientID {
+				return newContractError(ErrConflict, map[string]string{"class": class}, "the class %s already has an instructor", class)
+			}
+			return nil
+		}
+
+		creator, instructors, err := legacyClassCreator(ctx, class)
+		if err != nil {
+			return err
+		}
+		if creator == "" && len(instructors) == 0 {
+			return newContractError(ErrNotFound, map[string]string{"class": class}, "the class %s has no assignments", class)
+		}
+		claims := creator == clientID
+		if creator == "" && len(instructors) == 1 {
+			username, err := submittingUsername(ctx)
+			if err != nil {
+				return err
+			}
+			claims = username != "" && instructors[username]
+		}
+		if !claims {
+			return newContractError(ErrForbidden, map[string]string{"class": class},
+				"only the creator of the first assignment of class %s or the instructor it names may claim it", class)
+		}
+
+		if record == nil {
+			record = &Class{ClassID: class}
+		}
+		record.InstructorClient = clientID
+		return putClass(ctx, record)
+	})
+}
+
+// claimClass checks that clientID is the instructor of a class before it gets another assignment or group.
+// A new class is bound to clientID, the creator of its first assignment or group, so only that client may
+// later manage the class. A class whose assignments predate class records must be claimed with ClaimClass.
+func (s *SmartContract) claimClass(ctx contractapi.TransactionContextInterface, class string, clientID string) error {
+	record, err := s.boundClass(ctx, class)
+	if err != nil {
+		return err
+	}
+	if record != nil {
+		if record.InstructorClient != clientID {
+			return newContractError(ErrForbidden, map[string]string{"class": class}, "only the instructor of class %s may add assignments or groups to it", class)
+		}
+		return nil
+	}
+
+	legacy, err := hasAssignments(ctx, class)
+	if err != nil {
+		return err
+	}
+	if legacy {
+		return unclaimedClass(class)
+	}
+
+	record, err = s.ReadClass(ctx, class)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		record = &Class{ClassID: class}
+	}
+	record.InstructorClient = clientID
+	return putClass(ctx, record)
+}
+
+// boundClass returns the record of a class if it has an instructor, or nil if the class is new or its
+// assignments predate class records and it is not claimed yet.
+func (s *SmartContract) boundClass(ctx contractapi.TransactionContextInterface, class string) (*Class, error) {
+	record, err := s.ReadClass(ctx, class)
+	if err != nil || record == nil || record.InstructorClient == "" {
+		return nil, err
+	}
+	return record, nil
+}
+
+// missingClass returns the error for managing a class without an instructor: it has no assignments yet,
+// or they predate class records and the class is not claimed.
+func missingClass(ctx contractapi.TransactionContextInterface, class string) error {
+	legacy, err := hasAssignments(ctx, class)
+	if err != nil {
+		return err
+	}
+	if legacy {
+		return unclaimedClass(class)
+	}
+	return newContractError(ErrNotFound, map[string]string{"class": class}, "the class %s has no assignments", class)
+}
+
+// unclaimedClass returns the error for changing a class whose assignments predate class records before its
+// instructor claims it.
+func unclaimedClass(class string) error {
+	return newContractError(ErrConflict, map[string]string{"class": class},
+		"the class %s predates class records, so its instructor must claim it with ClaimClass first", class)
+}
+
+// hasAssignments reports whether any assignment of a class is on the ledger.
+func hasAssignments(ctx contractapi.TransactionContextInterface, class string) (bool, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return false, internalError("failed to read from world state", err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return false, internalError("failed to read from world state", err)
+		}
+
+		var asset Asset
+		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return false, internalError("failed to parse asset", err)
+		}
+		if asset.ClassID == class {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// legacyClassCreator returns the client recorded as creating the oldest assignment of a class, or "" if no
+// creator was recorded for any of them, and the instructor usernames its assignments name.
+func legacyClassCreator(ctx contractapi.TransactionContextInterface, class string) (string, map[string]bool, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return "", nil, internalError("failed to read from world state", err)
+	}
+	defer resultsIterator.Close()
+
+	var creator string
+	var created time.Time
+	instructors := map[string]bool{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return "", nil, internalError("failed to read from world state", err)
+		}
+
+		var asset Asset
+		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return "", nil, internalError("failed to parse asset", err)
+		}
+		if asset.ClassID != class {
+			continue
+		}
+		instructors[asset.InstructorID] = true
+
+		versions, err := getAssetVersions(ctx, asset.ID)
+		if err != nil {
+			return "", nil, err
+		}
+		if len(versions) == 0 || versions[0].asset.ModifiedBy == "" {
+			continue
+		}
+		if creator == "" || versions[0].timestamp.Before(created) {
+			creator = versions[0].asset.ModifiedBy
+			created = versions[0].timestamp
+		}
+	}
+
+	return creator, instructors, nil
+}
+
+// putClass writes a class record.
+func putClass(ctx contractapi.TransactionContextInterface, record *Class) error {
+	classKey, err := ctx.GetStub().CreateCompositeKey(classObjectType, []string{record.ClassID})
+	if err != nil {
+		return internalError("failed to create composite key", err)
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return internalError("failed to encode class", err)
+	}
+	err = ctx.GetStub().PutState(classKey, recordJSON)
+	if err != nil {
+		return internalError("failed to write to world state", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package chaincode_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/simulator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotateClassKey(t *testing.T) {
+	firstKey := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	secondKey := base64.StdEncoding.EncodeToString(append(make([]byte, 31), 1))
+
+	// The class record is created by its first assignment
+	created, err := json.Marshal(&chaincode.Class{ClassID: "cs101", InstructorClient: "x509::CN=instructor"})
+	require.NoError(t, err)
+	chaincodeStub := &mocks.ChaincodeStub{}
+	chaincodeStub.CreateCompositeKeyReturns("\x00Class\x00cs101\x00", nil)
+	chaincodeStub.GetStateReturns(created, nil)
+	clientIdentity := &mocks.ClientIdentity{}
+	clientIdentity.GetIDReturns("x509::CN=instructor", nil)
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	transactionContext.GetClientIdentityReturns(clientIdentity)
+
+	assetTransfer := chaincode.SmartContract{}
+	version, err := assetTransfer.RotateClassKey(transactionContext, "cs101", firstKey)
+	require.NoError(t, err)
+	require.Equal(t, 1, version)
+
+	key, stored := chaincodeStub.PutStateArgsForCall(0)
+	require.Equal(t, "\x00Class\x00cs101\x00", key)
+	var class chaincode.Class
+	require.NoError(t, json.Unmarshal(stored, &class))
+	require.Equal(t, chaincode.Class{ClassID: "cs101", InstructorClient: "x509::CN=instructor", PublicKey: firstKey, KeyVersion: 1}, class)
+
+	chaincodeStub.GetStateReturns(stored, nil)
+	version, err = assetTransfer.RotateClassKey(transactionContext, "cs101", secondKey)
+	require.NoError(t, err)
+	require.Equal(t, 2, version)
+
+	_, stored = chaincodeStub.PutStateArgsForCall(1)
+	require.NoError(t, json.Unmarshal(stored, &class))
+	require.Equal(t, secondKey, class.PublicKey)
+
+	clientIdentity.GetIDReturns("x509::CN=student", nil)
+	_, err = assetTransfer.RotateClassKey(transactionContext, "cs101", secondKey)
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 may rotate its key")
+
+	_, err = assetTransfer.RotateClassKey(transactionContext, "cs101", "c2hvcnQ=")
+	requireContractError(t, err, chaincode.ErrValidation, "the class key must be a base64 encoded 32 byte X25519 public key")
+}
+
+func TestRotateClassKeyBeforeInstructor(t *testing.T) {
+	sim := simulator.New("mychannel")
+	instructor := simulator.NewClientIdentity("Org1MSP", "x509::CN=instructor")
+	student := simulator.NewClientIdentity("Org1MSP", "x509::CN=alice")
+	contract := chaincode.SmartContract{}
+	studentKey := base64.StdEncoding.EncodeToString(append(make([]byte, 31), 7))
+
+	// A student cannot claim a class by publishing a key before or after the instructor creates it
+	_, err := contract.RotateClassKey(sim.Transaction(student), "cs101", studentKey)
+	requireContractError(t, err, chaincode.ErrNotFound, "the class cs101 has no assignments")
+	_, err = contract.CreateAssignment(sim.Transaction(instructor), "cs101", "hw1", "instructor", "alice", "", "Essay")
+	require.NoError(t, err)
+	_, err = contract.RotateClassKey(sim.Transaction(student), "cs101", studentKey)
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 may rotate its key")
+
+	version, err := contract.RotateClassKey(sim.Transaction(instructor), "cs101", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	require.NoError(t, err)
+	require.Equal(t, 1, version)
+	record, err := contract.ReadClass(sim.Transaction(student), "cs101")
+	require.NoError(t, err)
+	require.Equal(t, "x509::CN=instructor", record.InstructorClient)
+	require.NotEqual(t, studentKey, record.PublicKey)
+}
+
+func TestClaimedClassAssignments(t *testing.T) {
+	sim := simulator.New("mychannel")
+	instructor := simulator.NewClientIdentity("Org1MSP", "x509::CN=instructor")
+	other := simulator.NewClientIdentity("Org1MSP", "x509::CN=other")
+	contract := chaincode.SmartContract{}
+
+	_, err := contract.CreateAssignment(sim.Transaction(instructor), "cs101", "hw1", "instructor", "alice", "", "")
+	require.NoError(t, err)
+	require.NoError(t, contract.SetGroupMembers(sim.Transaction(instructor), "cs101", "team1", []string{"alice"}))
+
+	// Nobody but the instructor may add assignments to the class once it is claimed
+	forbidden := "only the instructor of class cs101 may add assignments or groups to it"
+	_, err = contract.CreateAssignment(sim.Transaction(other), "cs101", "hw2", "other", "alice", "", "")
+	requireContractError(t, err, chaincode.ErrForbidden, forbidden)
+	err = contract.CreateAsset(sim.Transaction(other), "hw2alice", "hw2", 0, "other", "", "", "cs101")
+	requireContractError(t, err, chaincode.ErrForbidden, forbidden)
+	_, err = contract.CreateClassFromTemplate(sim.Transaction(other), "cs101", `{"Assignments":[{"Title":"hw2"}]}`, "other", []string{"alice"}, 0)
+	requireContractError(t, err, chaincode.ErrForbidden, forbidden)
+	_, err = contract.CreateGroupAssignment(sim.Transaction(other), "cs101", "project", "other", "team1", "", "")
+	requireContractError(t, err, chaincode.ErrForbidden, forbidden)
+
+	_, err = contract.CreateClassFromTemplate(sim.Transaction(instructor), "cs101", `{"Assignments":[{"Title":"hw2"}]}`, "instructor", []string{"alice"}, 0)
+	require.NoError(t, err)
+	_, err = contract.CreateGroupAssignment(sim.Transaction(instructor), "cs101", "project", "instructor", "team1", "", "")
+	require.NoError(t, err)
+	require.NoError(t, contract.CreateAsset(sim.Transaction(other), "hw1bob", "hw1", 0, "other", "", "", "cs202"))
+	record, err := contract.ReadClass(sim.Transaction(other), "cs202")
+	require.NoError(t, err)
+	require.Equal(t, "x509::CN=other", record.InstructorClient)
+}
+
+func TestReadClass(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+
+	assetTransfer := chaincode.SmartContract{}
+	class, err := assetTransfer.ReadClass(transactionContext, "cs101")
+	require.NoError(t, err)
+	require.Nil(t, class)
+
+	expected := &chaincode.Class{ClassID: "cs101", PublicKey: "key", KeyVersion: 3}
+	bytes, err := json.Marshal(expected)
+	require.NoError(t, err)
+	chaincodeStub.GetStateReturns(bytes, nil)
+	class, err = assetTransfer.ReadClass(transactionContext, "cs101")
+	require.NoError(t, err)
+	require.Equal(t, expected, class)
+}
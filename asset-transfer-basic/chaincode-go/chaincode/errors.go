@@ -0,0 +1,72 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrorCode is the kind of failure reported by a ContractError. Codes are stable, so clients can branch on
+// them rather than on the wording of the message.
+type ErrorCode string
+
+const (
+	// ErrNotFound means the asset or record the request refers to does not exist
+	ErrNotFound ErrorCode = "NotFound"
+	// ErrForbidden means the submitting client may not perform the request
+	ErrForbidden ErrorCode = "Forbidden"
+	// ErrConflict means the request clashes with the ledger state, such as an asset that already exists
+	ErrConflict ErrorCode = "Conflict"
+	// ErrValidation means an argument of the request was rejected
+	ErrValidation ErrorCode = "Validation"
+	// ErrInternal means the chaincode could not read or write the world state
+	ErrInternal ErrorCode = "Internal"
+)
+
+// ContractError is the error returned by the grading contract. The peers pass on its JSON encoding as the
+// chaincode error message, for example
+//
+//	{"code":"NotFound","message":"the asset hw1alice does not exist","details":{"id":"hw1alice"}}
+type ContractError struct {
+	Code    ErrorCode         `json:"code"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+func (e *ContractError) Error() string {
+	errorJSON, err := json.Marshal(e)
+	if err != nil {
+		return e.Message
+	}
+	return string(errorJSON)
+}
+
+// newContractError returns a ContractError with a formatted message.
+func newContractError(code ErrorCode, details map[string]string, format string, args ...interface{}) *ContractError {
+	return &ContractError{Code: code, Message: fmt.Sprintf(format, args...), Details: details}
+}
+
+// assetNotFound reports a missing asset.
+func assetNotFound(id string) error {
+	return newContractError(ErrNotFound, map[string]string{"id": id}, "the asset %s does not exist", id)
+}
+
+// assetAlreadyExists reports an asset that cannot be created because its ID is taken.
+func assetAlreadyExists(id string) error {
+	return newContractError(ErrConflict, map[string]string{"id": id}, "the asset %s already exists", id)
+}
+
+// validationError reports a rejected argument, named in the details.
+func validationError(argument string, format string, args ...interface{}) error {
+	return newContractError(ErrValidation, map[string]string{"argument": argument}, format, args...)
+}
+
+// internalError reports a failure of the world state or of the transaction context, described by action.
+// Errors that already are ContractErrors are returned unchanged.
+func internalError(action string, err error) error {
+	var contractErr *ContractError
+	if errors.As(err, &contractErr) {
+		return err
+	}
+	return newContractError(ErrInternal, nil, "%s: %v", action, err)
+}
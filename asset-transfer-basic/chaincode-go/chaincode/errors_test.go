@@ -0,0 +1,38 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+// requireContractError checks that err is a ContractError with the given code and message.
+func requireContractError(t *testing.T, err error, code chaincode.ErrorCode, message string) {
+	t.Helper()
+	var contractErr *chaincode.ContractError
+	require.ErrorAs(t, err, &contractErr)
+	require.Equal(t, code, contractErr.Code)
+	require.Equal(t, message, contractErr.Message)
+}
+
+func TestContractErrorJSON(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+
+	assetTransfer := chaincode.SmartContract{}
+	_, err := assetTransfer.ReadAsset(transactionContext, "hw1alice")
+	require.Error(t, err)
+
+	// The peers pass the error text on to clients, which decode it as JSON
+	var decoded chaincode.ContractError
+	require.NoError(t, json.Unmarshal([]byte(err.Error()), &decoded))
+	require.Equal(t, chaincode.ContractError{
+		Code:    chaincode.ErrNotFound,
+		Message: "the asset hw1alice does not exist",
+		Details: map[string]string{"id": "hw1alice"},
+	}, decoded)
+}
@@ -0,0 +1,338 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Grader is a teaching assistant of a class, identified by the client ID returned by GetClientID, and the
+// parts of the class they may grade.
+type Grader struct {
+	ClientID string         `json:"ClientID"`
+	Scopes   []*GraderScope `json:"Scopes"`
+}
+
+// GraderScope is a part of a class delegated to a teaching assistant. An empty Title covers every
+// assignment, and empty Students covers every student; Students may also name groups.
+type GraderScope struct {
+	Title    string   `json:"Title"`
+	Students []string `json:"Students"`
+}
+
+// PendingGrade is a grade given by a teaching assistant that waits for the instructor's approval
+type PendingGrade struct {
+	Grade    int    `json:"Grade"`
+	Feedback string `json:"Feedback"`
+	GradedBy string `json:"GradedBy"`
+	TxID     string `json:"TxID"`
+	// Adjustments are the changes to group members' adjustments that wait with the grade; 0 Points removes
+	// a member's adjustment
+	Adjustments []GradeAdjustment `json:"Adjustments,omitempty" metadata:"Adjustments,optional"`
+}
+
+// GetClientID returns the client ID of the caller, which instructors pass to AddTA and AssignGrader.
+func (s *SmartContract) GetClientID(ctx contractapi.TransactionContextInterface) (string, error) {
+	return submittingClientID(ctx)
+}
+
+// AddTA makes a client a teaching assistant of a class. A new teaching assistant may grade nothing until
+// AssignGrader delegates part of the class to them.
+func (s *SmartContract) AddTA(ctx contractapi.TransactionContextInterface, class string, clientID string) error {
+	return idempotentError(ctx, func() error {
+		if clientID == "" {
+			return validationError("clientID", "the client ID must not be empty")
+		}
+		record, err := s.instructorClass(ctx, class)
+		if err != nil {
+			return err
+		}
+		if findGrader(record, clientID) != nil {
+			return newContractError(ErrConflict, map[string]string{"class": class, "client": clientID},
+				"the client %s is already a teaching assistant of class %s", clientID, class)
+		}
+
+		record.Graders = append(record.Graders, &Grader{ClientID: clientID, Scopes: []*GraderScope{}})
+		return putClass(ctx, record)
+	})
+}
+
+// RemoveTA takes away a teaching assistant and every part of the class delegated to them. Grades they gave
+// that are still waiting for approval stay pending until the instructor approves or rejects them.
+func (s *SmartContract) RemoveTA(ctx contractapi.TransactionContextInterface, class string, clientID string) error {
+	return idempotentError(ctx, func() error {
+		record, err := s.instructorClass(ctx, class)
+		if err != nil {
+			return err
+		}
+
+		graders := []*Grader{}
+		for _, grader := range record.Graders {
+			if grader.ClientID != clientID {
+				graders = append(graders, grader)
+			}
+		}
+		if len(graders) == len(record.Graders) {
+			return graderNotFound(class, clientID)
+		}
+
+		record.Graders = graders
+		return putClass(ctx, record)
+	})
+}
+
+// AssignGrader delegates grading of an assignment to a teaching assistant, for the given students or groups
+// or, if there are none, for the whole class. An empty title delegates every assignment. Delegating a part
+// of the class the teaching assistant already grades changes nothing.
+func (s *SmartContract) AssignGrader(ctx contractapi.TransactionContextInterface, class string, clientID string, title string, students []string) error {
+	return idempotentError(ctx, func() error {
+		if title != "" {
+			if err := validateTitle(title); err != nil {
+				return err
+			}
+		}
+		for _, student := range students {
+			if err := validateName("students", "student ID", student); err != nil {
+				return err
+			}
+		}
+
+		record, err := s.instructorClass(ctx, class)
+		if err != nil {
+			return err
+		}
+		grader := findGrader(record, clientID)
+		if grader == nil {
+			return graderNotFound(class, clientID)
+		}
+
+		if students == nil {
+			students = []string{}
+		}
+		for _, scope := range grader.Scopes {
+			if scope.Title == title && reflect.DeepEqual(scope.Students, students) {
+				return nil
+			}
+		}
+		grader.Scopes = append(grader.Scopes, &GraderScope{Title: title, Students: students})
+		return putClass(ctx, record)
+	})
+}
+
+// SetGradeApproval sets whether grades given by teaching assistants of a class wait for the instructor's
+// approval before they replace the current grade.
+func (s *SmartContract) SetGradeApproval(ctx contractapi.TransactionContextInterface, class string, required bool) error {
+	return idempotentError(ctx, func() error {
+		record, err := s.instructorClass(ctx, class)
+		if err != nil {
+			return err
+		}
+
+		record.RequireGradeApproval = required
+		return putClass(ctx, record)
+	})
+}
+
+// ApproveGrade applies the grade and member adjustments a teaching assistant gave an assignment and returns
+// the grade it replaced.
+func (s *SmartContract) ApproveGrade(ctx contractapi.TransactionContextInterface, id string) (int, error) {
+	return idempotent(ctx, func() (int, error) {
+		asset, err := s.pendingGrade(ctx, id)
+		if err != nil {
+			return -1, err
+		}
+
+		oldGrade := asset.Grade
+		asset.Grade = asset.PendingGrade.Grade
+		asset.Feedback = asset.PendingGrade.Feedback
+		asset.GradedBy = asset.PendingGrade.GradedBy
+		for _, change := range asset.PendingGrade.Adjustments {
+			_, asset.Adjustments = adjustMember(asset.Adjustments, change.Member, change.Points)
+		}
+		asset.PendingGrade = nil
+		asset.ModifiedBy, err = submittingClientID(ctx)
+		if err != nil {
+			return -1, err
+		}
+
+		err = putAsset(ctx, asset)
+		if err != nil {
+			return -1, err
+		}
+		err = assetGradeChanged(ctx, asset)
+		if err != nil {
+			return -1, err
+		}
+
+		return oldGrade, nil
+	})
+}
+
+// RejectGrade discards the grade and member adjustments a teaching assistant gave an assignment, keeping the
+// current ones.
+func (s *SmartContract) RejectGrade(ctx contractapi.TransactionContextInterface, id string) error {
+	return idempotentError(ctx, func() error {
+		asset, err := s.pendingGrade(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		asset.PendingGrade = nil
+		asset.ModifiedBy, err = submittingClientID(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = putAsset(ctx, asset)
+		if err != nil {
+			return err
+		}
+		return assetGradeChanged(ctx, asset)
+	})
+}
+
+// GetPendingGrades returns the assignments of a class with a grade waiting for the instructor's approval.
+func (s *SmartContract) GetPendingGrades(ctx contractapi.TransactionContextInterface, class string) ([]*Asset, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return nil, internalError("failed to read from world state", err)
+	}
+	defer resultsIterator.Close()
+
+	var assets []*Asset
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, internalError("failed to read from world state", err)
+		}
+
+		var asset Asset
+		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return nil, internalError("failed to parse asset", err)
+		}
+		if asset.ClassID == class && asset.PendingGrade != nil {
+			assets = append(assets, &asset)
+		}
+	}
+
+	return assets, nil
+}
+
+// authorizeGrading checks that the submitting client may grade an assignment and returns its client ID:
+// the instructor of its class or a teaching assistant assigned to it. Assignments of a class that predates
+// class records can not be graded until the class is claimed. pending reports that the client is a
+// teaching assistant whose grades need the instructor's approval.
+func (s *SmartContract) authorizeGrading(ctx contractapi.TransactionContextInterface, asset *Asset) (clientID string, pending bool, err error) {
+	clientID, err = submittingClientID(ctx)
+	if err != nil {
+		return "", false, err
+	}
+
+	record, err := s.boundClass(ctx, asset.ClassID)
+	if err != nil {
+		return "", false, err
+	}
+	if record == nil {
+		return "", false, unclaimedClass(asset.ClassID)
+	}
+	if record.InstructorClient == clientID {
+		return clientID, false, nil
+	}
+	if grader := findGrader(record, clientID); grader != nil && grader.covers(asset) {
+		return clientID, record.RequireGradeApproval, nil
+	}
+
+	return "", false, newContractError(ErrForbidden, map[string]string{"class": asset.ClassID, "id": asset.ID},
+		"only the instructor of class %s or a teaching assistant assigned to it may grade the assignment %s", asset.ClassID, asset.ID)
+}
+
+// instructorClass returns the record of a class after checking that the submitting client is its
+// instructor, the client that created its first assignment or claimed it with ClaimClass.
+func (s *SmartContract) instructorClass(ctx contractapi.TransactionContextInterface, class string) (*Class, error) {
+	if err := validateName("class", "class ID", class); err != nil {
+		return nil, err
+	}
+	clientID, err := submittingClientID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := s.boundClass(ctx, class)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, missingClass(ctx, class)
+	}
+	if record.InstructorClient != clientID {
+		return nil, newContractError(ErrForbidden, map[string]string{"class": class}, "only the instructor of class %s may manage its grading", class)
+	}
+
+	return record, nil
+}
+
+// isInstructor reports whether the submitting client is the instructor of a class.
+func (s *SmartContract) isInstructor(ctx contractapi.TransactionContextInterface, class string) (bool, error) {
+	clientID, err := submittingClientID(ctx)
+	if err != nil {
+		return false, err
+	}
+	record, err := s.boundClass(ctx, class)
+	if err != nil {
+		return false, err
+	}
+
+	return record != nil && record.InstructorClient == clientID, nil
+}
+
+// pendingGrade reads an assignment with a grade waiting for approval, after checking that the submitting
+// client is the instructor of its class.
+func (s *SmartContract) pendingGrade(ctx contractapi.TransactionContextInterface, id string) (*Asset, error) {
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if asset.PendingGrade == nil {
+		return nil, newContractError(ErrNotFound, map[string]string{"id": id}, "the assignment %s has no grade waiting for approval", id)
+	}
+	if _, err := s.instructorClass(ctx, asset.ClassID); err != nil {
+		return nil, err
+	}
+
+	return asset, nil
+}
+
+// findGrader returns the teaching assistant of a class with the given client ID, or nil.
+func findGrader(record *Class, clientID string) *Grader {
+	for _, grader := range record.Graders {
+		if grader.ClientID == clientID {
+			return grader
+		}
+	}
+	return nil
+}
+
+// covers reports whether an assignment is in one of the parts of the class delegated to the grader.
+func (g *Grader) covers(asset *Asset) bool {
+	for _, scope := range g.Scopes {
+		if scope.Title != "" && scope.Title != asset.Title {
+			continue
+		}
+		if len(scope.Students) == 0 {
+			return true
+		}
+		for _, student := range scope.Students {
+			if (asset.StudentID != "" && student == asset.StudentID) || (asset.GroupID != "" && student == asset.GroupID) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func graderNotFound(class string, clientID string) error {
+	return newContractError(ErrNotFound, map[string]string{"class": class, "client": clientID},
+		"the client %s is not a teaching assistant of class %s", clientID, class)
+}
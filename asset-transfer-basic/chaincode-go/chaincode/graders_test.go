@@ -0,0 +1,178 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/simulator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTeachingAssistantGrading(t *testing.T) {
+	sim := simulator.New("mychannel")
+	instructor := simulator.NewClientIdentity("Org1MSP", "x509::CN=instructor")
+	ta := simulator.NewClientIdentity("Org1MSP", "x509::CN=ta")
+	contract := chaincode.SmartContract{}
+
+	taID, err := contract.GetClientID(sim.Transaction(ta))
+	require.NoError(t, err)
+	aliceID, err := contract.CreateAssignment(sim.Transaction(instructor), "cs101", "hw1", "instructor", "alice", "", "Essay")
+	require.NoError(t, err)
+	bobID, err := contract.CreateAssignment(sim.Transaction(instructor), "cs101", "hw1", "instructor", "bob", "", "Essay")
+	require.NoError(t, err)
+
+	// The TA may grade alice's copy of hw1 only
+	require.NoError(t, contract.AddTA(sim.Transaction(instructor), "cs101", taID))
+	requireContractError(t, contract.AddTA(sim.Transaction(instructor), "cs101", taID), chaincode.ErrConflict, "the client x509::CN=ta is already a teaching assistant of class cs101")
+	requireContractError(t, contract.AddTA(sim.Transaction(ta), "cs101", "x509::CN=carol"), chaincode.ErrForbidden, "only the instructor of class cs101 may manage its grading")
+	_, err = contract.GradeAssignment(sim.Transaction(ta), aliceID, 80, "Fine")
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 or a teaching assistant assigned to it may grade the assignment "+aliceID)
+	require.NoError(t, contract.AssignGrader(sim.Transaction(instructor), "cs101", taID, "hw1", []string{"alice"}))
+	require.NoError(t, contract.AssignGrader(sim.Transaction(instructor), "cs101", taID, "hw1", []string{"alice"}))
+	record, err := contract.ReadClass(sim.Transaction(instructor), "cs101")
+	require.NoError(t, err)
+	require.Len(t, record.Graders[0].Scopes, 1, "delegating the same part twice must not add a scope")
+
+	_, err = contract.GradeAssignment(sim.Transaction(ta), aliceID, 80, "Fine")
+	require.NoError(t, err)
+	_, err = contract.GradeAssignment(sim.Transaction(ta), bobID, 80, "Fine")
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 or a teaching assistant assigned to it may grade the assignment "+bobID)
+	asset, err := contract.ReadAsset(sim.Transaction(instructor), aliceID)
+	require.NoError(t, err)
+	require.Equal(t, 80, asset.Grade)
+	require.Equal(t, taID, asset.GradedBy)
+
+	// With approval required, the TA's grade waits for the instructor
+	require.NoError(t, contract.SetGradeApproval(sim.Transaction(instructor), "cs101", true))
+	previous, err := contract.GradeAssignment(sim.Transaction(ta), aliceID, 90, "Better")
+	require.NoError(t, err)
+	require.Equal(t, 80, previous)
+	pending, err := contract.GetPendingGrades(sim.Transaction(instructor), "cs101")
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.Equal(t, 80, pending[0].Grade)
+	require.Equal(t, &chaincode.PendingGrade{Grade: 90, Feedback: "Better", GradedBy: taID, TxID: pending[0].PendingGrade.TxID}, pending[0].PendingGrade)
+
+	_, err = contract.ApproveGrade(sim.Transaction(ta), aliceID)
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 may manage its grading")
+	previous, err = contract.ApproveGrade(sim.Transaction(instructor), aliceID)
+	require.NoError(t, err)
+	require.Equal(t, 80, previous)
+	asset, err = contract.ReadAsset(sim.Transaction(instructor), aliceID)
+	require.NoError(t, err)
+	require.Equal(t, 90, asset.Grade)
+	require.Equal(t, "Better", asset.Feedback)
+	require.Nil(t, asset.PendingGrade)
+
+	_, err = contract.GradeAssignment(sim.Transaction(ta), aliceID, 10, "Oops")
+	require.NoError(t, err)
+	require.NoError(t, contract.RejectGrade(sim.Transaction(instructor), aliceID))
+	requireContractError(t, contract.RejectGrade(sim.Transaction(instructor), aliceID), chaincode.ErrNotFound, "the assignment "+aliceID+" has no grade waiting for approval")
+	asset, err = contract.ReadAsset(sim.Transaction(instructor), aliceID)
+	require.NoError(t, err)
+	require.Equal(t, 90, asset.Grade)
+
+	// A removed TA may no longer grade
+	require.NoError(t, contract.RemoveTA(sim.Transaction(instructor), "cs101", taID))
+	requireContractError(t, contract.RemoveTA(sim.Transaction(instructor), "cs101", taID), chaincode.ErrNotFound, "the client x509::CN=ta is not a teaching assistant of class cs101")
+	_, err = contract.GradeAssignment(sim.Transaction(ta), aliceID, 95, "Late regrade")
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 or a teaching assistant assigned to it may grade the assignment "+aliceID)
+}
+
+func TestClassBoundToAssignmentCreator(t *testing.T) {
+	sim := simulator.New("mychannel")
+	instructor := simulator.NewClientIdentity("Org1MSP", "x509::CN=instructor")
+	student := simulator.NewClientIdentity("Org1MSP", "x509::CN=alice")
+	contract := chaincode.SmartContract{}
+
+	requireContractError(t, contract.AddTA(sim.Transaction(student), "cs101", "x509::CN=alice"), chaincode.ErrNotFound, "the class cs101 has no assignments")
+	id, err := contract.CreateAssignment(sim.Transaction(instructor), "cs101", "hw1", "instructor", "alice", "", "Essay")
+	require.NoError(t, err)
+	record, err := contract.ReadClass(sim.Transaction(student), "cs101")
+	require.NoError(t, err)
+	require.Equal(t, "x509::CN=instructor", record.InstructorClient)
+
+	// A student can neither take over the class nor grade their own work
+	requireContractError(t, contract.AddTA(sim.Transaction(student), "cs101", "x509::CN=alice"), chaincode.ErrForbidden, "only the instructor of class cs101 may manage its grading")
+	requireContractError(t, contract.SetGradeApproval(sim.Transaction(student), "cs101", false), chaincode.ErrForbidden, "only the instructor of class cs101 may manage its grading")
+	_, err = contract.GradeAssignment(sim.Transaction(student), id, 100, "")
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 or a teaching assistant assigned to it may grade the assignment "+id)
+
+	// Classes whose assignments predate class records stay locked until the creator of the oldest one
+	// claims them
+	sim.Transaction(instructor)
+	require.NoError(t, sim.Stub.PutState("hw1bob", []byte(`{"ID":"hw1bob","ClassID":"cs100","StudentID":"bob","Owner":"instructor","ModifiedBy":"x509::CN=instructor"}`)))
+	sim.Transaction(student)
+	require.NoError(t, sim.Stub.PutState("hw2bob", []byte(`{"ID":"hw2bob","ClassID":"cs100","StudentID":"bob","Owner":"instructor","ModifiedBy":"x509::CN=alice"}`)))
+	unclaimed := "the class cs100 predates class records, so its instructor must claim it with ClaimClass first"
+	requireContractError(t, contract.AddTA(sim.Transaction(student), "cs100", "x509::CN=alice"), chaincode.ErrConflict, unclaimed)
+	_, err = contract.CreateAssignment(sim.Transaction(student), "cs100", "hw3", "alice", "bob", "", "")
+	requireContractError(t, err, chaincode.ErrConflict, unclaimed)
+	_, err = contract.GradeAssignment(sim.Transaction(student), "hw2bob", 100, "")
+	requireContractError(t, err, chaincode.ErrConflict, unclaimed)
+	requireContractError(t, contract.ClaimClass(sim.Transaction(student), "cs100"), chaincode.ErrForbidden, "only the creator of the first assignment of class cs100 or the instructor it names may claim it")
+	require.NoError(t, contract.ClaimClass(sim.Transaction(instructor), "cs100"))
+	require.NoError(t, contract.ClaimClass(sim.Transaction(instructor), "cs100"))
+	requireContractError(t, contract.ClaimClass(sim.Transaction(student), "cs100"), chaincode.ErrConflict, "the class cs100 already has an instructor")
+	requireContractError(t, contract.AddTA(sim.Transaction(student), "cs100", "x509::CN=alice"), chaincode.ErrForbidden, "only the instructor of class cs100 may manage its grading")
+	require.NoError(t, contract.AddTA(sim.Transaction(instructor), "cs100", "x509::CN=ta"))
+
+	// Without a recorded creator, a class can only be claimed by a client whose certificate names the
+	// instructor of its assignments, never by whoever asks first
+	require.NoError(t, sim.Stub.PutState("hw1carol", []byte(`{"ID":"hw1carol","ClassID":"cs99","InstructorID":"prof","Owner":"carol"}`)))
+	mallory, err := simulator.NewX509ClientIdentity("Org1MSP", "mallory")
+	require.NoError(t, err)
+	prof, err := simulator.NewX509ClientIdentity("Org1MSP", "prof")
+	require.NoError(t, err)
+	requireContractError(t, contract.ClaimClass(sim.Transaction(mallory), "cs99"), chaincode.ErrForbidden, "only the creator of the first assignment of class cs99 or the instructor it names may claim it")
+	require.NoError(t, contract.ClaimClass(sim.Transaction(prof), "cs99"))
+	record, err = contract.ReadClass(sim.Transaction(prof), "cs99")
+	require.NoError(t, err)
+	require.Equal(t, prof.ID, record.InstructorClient)
+	requireContractError(t, contract.ClaimClass(sim.Transaction(prof), "cs98"), chaincode.ErrNotFound, "the class cs98 has no assignments")
+}
+
+func TestTeachingAssistantAdjustmentApproval(t *testing.T) {
+	sim := simulator.New("mychannel")
+	instructor := simulator.NewClientIdentity("Org1MSP", "x509::CN=instructor")
+	ta := simulator.NewClientIdentity("Org1MSP", "x509::CN=ta")
+	contract := chaincode.SmartContract{}
+
+	require.NoError(t, contract.SetGroupMembers(sim.Transaction(instructor), "cs101", "team1", []string{"alice", "bob"}))
+	id, err := contract.CreateGroupAssignment(sim.Transaction(instructor), "cs101", "project", "instructor", "team1", "", "Build a compiler")
+	require.NoError(t, err)
+	_, err = contract.GradeAssignment(sim.Transaction(instructor), id, 80, "Works")
+	require.NoError(t, err)
+	require.NoError(t, contract.AddTA(sim.Transaction(instructor), "cs101", ta.ID))
+	require.NoError(t, contract.AssignGrader(sim.Transaction(instructor), "cs101", ta.ID, "project", nil))
+	require.NoError(t, contract.SetGradeApproval(sim.Transaction(instructor), "cs101", true))
+
+	// The TA's adjustment is held back with their grade until the instructor approves both
+	previous, err := contract.AdjustMemberGrade(sim.Transaction(ta), id, "bob", -10)
+	require.NoError(t, err)
+	require.Equal(t, 0, previous)
+	_, err = contract.GradeAssignment(sim.Transaction(ta), id, 85, "Works well")
+	require.NoError(t, err)
+	grades, err := contract.GetMemberGrades(sim.Transaction(instructor), id)
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.MemberGrade{{Member: "alice", Grade: 80}, {Member: "bob", Grade: 80}}, grades)
+	asset, err := contract.ReadAsset(sim.Transaction(instructor), id)
+	require.NoError(t, err)
+	require.Equal(t, []chaincode.GradeAdjustment{{Member: "bob", Points: -10}}, asset.PendingGrade.Adjustments)
+
+	_, err = contract.ApproveGrade(sim.Transaction(instructor), id)
+	require.NoError(t, err)
+	grades, err = contract.GetMemberGrades(sim.Transaction(instructor), id)
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.MemberGrade{{Member: "alice", Grade: 85}, {Member: "bob", Grade: 75, Adjustment: -10}}, grades)
+
+	// Removing the adjustment waits for approval too, and a rejection keeps it
+	previous, err = contract.AdjustMemberGrade(sim.Transaction(ta), id, "bob", 0)
+	require.NoError(t, err)
+	require.Equal(t, -10, previous)
+	require.NoError(t, contract.RejectGrade(sim.Transaction(instructor), id))
+	asset, err = contract.ReadAsset(sim.Transaction(instructor), id)
+	require.NoError(t, err)
+	require.Equal(t, []chaincode.GradeAdjustment{{Member: "bob", Points: -10}}, asset.Adjustments)
+	require.Nil(t, asset.PendingGrade)
+}
@@ -0,0 +1,587 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// groupObjectType is the composite key prefix under which group records are stored
+const groupObjectType = "Group"
+
+// Group is a set of students of a class who work on group assignments together. Each change of membership
+// is a new version of the record, so the ledger history shows who was in the group when.
+type Group struct {
+	ClassID    string   `json:"ClassID"`
+	Name       string   `json:"Name"`
+	Members    []string `json:"Members"`
+	ModifiedBy string   `json:"ModifiedBy"`
+}
+
+// GroupChange is one version of a group from its history.
+type GroupChange struct {
+	Members    []string  `json:"Members"`
+	ModifiedBy string    `json:"ModifiedBy"`
+	TxID       string    `json:"TxID"`
+	Timestamp  time.Time `json:"Timestamp"`
+}
+
+// GradeAdjustment moves one member's grade on a group assignment away from the group's grade
+type GradeAdjustment struct {
+	Member string `json:"Member"`
+	Points int    `json:"Points"`
+}
+
+// MemberGrade is the grade of one member of a group on a group assignment
+type MemberGrade struct {
+	Member     string `json:"Member"`
+	Grade      int    `json:"Grade"`
+	Adjustment int    `json:"Adjustment"`
+}
+
+// groupAssignmentID derives the ID of a group's copy of an assignment. The empty field keeps the IDs apart
+// from those of students' copies, even for a group named like a student.
+func groupAssignmentID(class string, title string, group string) string {
+	hash := sha256.Sum256([]byte(strings.Join([]string{class, title, "", group}, "\x00")))
+	return hex.EncodeToString(hash[:])
+}
+
+// GroupAssignmentID returns the ID of a group's copy of the titled assignment in a class, whether or not it
+// has been created yet.
+func (s *SmartContract) GroupAssignmentID(ctx contractapi.TransactionContextInterface, class string, title string, group string) (string, error) {
+	if err := validateGroupAssignmentKey(class, title, group); err != nil {
+		return "", err
+	}
+	return groupAssignmentID(class, title, group), nil
+}
+
+// SetGroupMembers defines the members of a group in a class, creating the group or replacing its members.
+// A student can be in only one group of a class. Only the instructor of the class may define its groups;
+// like its first assignment, the first group of a class binds the class to its creator.
+func (s *SmartContract) SetGroupMembers(ctx contractapi.TransactionContextInterface, class string, group string, members []string) error {
+	return idempotentError(ctx, func() error {
+		if err := validateGroupKey(class, group); err != nil {
+			return err
+		}
+		clientID, err := submittingClientID(ctx)
+		if err != nil {
+			return err
+		}
+		if err := s.claimClass(ctx, class, clientID); err != nil {
+			return err
+		}
+		if err := checkGroupNotGraded(ctx, class, group); err != nil {
+			return err
+		}
+
+		listed := map[string]bool{}
+		for _, member := range members {
+			if err := validateName("members", "member", member); err != nil {
+				return err
+			}
+			if listed[member] {
+				return validationError("members", "the member %s is listed more than once", member)
+			}
+			listed[member] = true
+			if err := checkNotInOtherGroup(ctx, class, group, member); err != nil {
+				return err
+			}
+		}
+
+		return putGroup(ctx, &Group{ClassID: class, Name: group, Members: members})
+	})
+}
+
+// JoinGroup adds a student to a group of a class, creating the group if it does not exist yet, so students
+// can sign up for groups themselves. Only the student and the instructor of the class may add the student,
+// and not once an assignment of the group is graded.
+func (s *SmartContract) JoinGroup(ctx contractapi.TransactionContextInterface, class string, group string, student string) error {
+	return idempotentError(ctx, func() error {
+		if err := validateGroupKey(class, group); err != nil {
+			return err
+		}
+		if err := validateName("student", "student", student); err != nil {
+			return err
+		}
+		if err := s.authorizeMembershipChange(ctx, class, group, student); err != nil {
+			return err
+		}
+		if err := checkNotInOtherGroup(ctx, class, group, student); err != nil {
+			return err
+		}
+
+		record, err := readGroup(ctx, class, group)
+		if err != nil {
+			return err
+		}
+		if record == nil {
+			record = &Group{ClassID: class, Name: group}
+		}
+		if isMember(record, student) {
+			return nil
+		}
+		record.Members = append(record.Members, student)
+
+		return putGroup(ctx, record)
+	})
+}
+
+// LeaveGroup removes a student from a group of a class. The group's assignments stay with the group. Only
+// the student and the instructor of the class may remove the student, and not once an assignment of the
+// group is graded.
+func (s *SmartContract) LeaveGroup(ctx contractapi.TransactionContextInterface, class string, group string, student string) error {
+	return idempotentError(ctx, func() error {
+		record, err := s.ReadGroup(ctx, class, group)
+		if err != nil {
+			return err
+		}
+		if err := s.authorizeMembershipChange(ctx, class, group, student); err != nil {
+			return err
+		}
+		if !isMember(record, student) {
+			return newContractError(ErrNotFound, map[string]string{"class": class, "group": group, "student": student},
+				"the student %s is not a member of group %s in class %s", student, group, class)
+		}
+
+		members := []string{}
+		for _, member := range record.Members {
+			if member != student {
+				members = append(members, member)
+			}
+		}
+		record.Members = members
+
+		return putGroup(ctx, record)
+	})
+}
+
+// ReadGroup returns a group of a class.
+func (s *SmartContract) ReadGroup(ctx contractapi.TransactionContextInterface, class string, group string) (*Group, error) {
+	record, err := readGroup(ctx, class, group)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		return nil, newContractError(ErrNotFound, map[string]string{"class": class, "group": group}, "the group %s does not exist in class %s", group, class)
+	}
+	return record, nil
+}
+
+// GetGroupHistory returns every membership of a group of a class, oldest first.
+func (s *SmartContract) GetGroupHistory(ctx contractapi.TransactionContextInterface, class string, group string) ([]*GroupChange, error) {
+	key, err := groupKey(ctx, class, group)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(key)
+	if err != nil {
+		return nil, internalError(fmt.Sprintf("failed to read history of group %s", group), err)
+	}
+	defer resultsIterator.Close()
+
+	changes := []*GroupChange{}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, internalError(fmt.Sprintf("failed to read history of group %s", group), err)
+		}
+		if response.IsDelete || len(response.Value) == 0 {
+			continue
+		}
+
+		var record Group
+		err = json.Unmarshal(response.Value, &record)
+		if err != nil {
+			return nil, internalError("failed to parse group", err)
+		}
+		changes = append(changes, &GroupChange{
+			Members:    record.Members,
+			ModifiedBy: record.ModifiedBy,
+			TxID:       response.TxId,
+			Timestamp:  response.Timestamp.AsTime(),
+		})
+	}
+
+	// The history database returns the newest version first
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Timestamp.Before(changes[j].Timestamp)
+	})
+
+	return changes, nil
+}
+
+// CreateGroupAssignment creates a group's copy of the titled assignment in a class, held by the instructor
+// until it is transferred to the group, and returns its generated ID. Any member can submit the work, every
+// member sees it, and the grade applies to all of them.
+func (s *SmartContract) CreateGroupAssignment(ctx contractapi.TransactionContextInterface, class string, title string, instructor string, group string, date string, description string) (string, error) {
+	return idempotent(ctx, func() (string, error) {
+		if err := validateGroupAssignmentKey(class, title, group); err != nil {
+			return "", err
+		}
+		if _, err := s.ReadGroup(ctx, class, group); err != nil {
+			return "", err
+		}
+
+		id := groupAssignmentID(class, title, group)
+		asset := Asset{
+			ID:           id,
+			Title:        title,
+			Date:         date,
+			Description:  description,
+			InstructorID: instructor,
+			GroupID:      group,
+			Owner:        instructor,
+			ClassID:      class,
+		}
+		err := validateAsset(&asset)
+		if err != nil {
+			return "", err
+		}
+
+		exists, err := s.AssetExists(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		if exists {
+			return "", newContractError(ErrConflict, map[string]string{"id": id, "class": class, "title": title, "group": group},
+				"the assignment %s already exists for group %s in class %s", title, group, class)
+		}
+
+		asset.ModifiedBy, err = submittingClientID(ctx)
+		if err != nil {
+			return "", err
+		}
+		err = s.claimClass(ctx, class, asset.ModifiedBy)
+		if err != nil {
+			return "", err
+		}
+
+		err = putAsset(ctx, &asset)
+		if err != nil {
+			return "", err
+		}
+
+		return id, nil
+	})
+}
+
+// AdjustMemberGrade sets how many points one member's grade on a group assignment differs from the group's
+// grade, and returns the adjustment it replaced. An adjustment of 0 removes it. Like a grade, an adjustment
+// by a teaching assistant waits for the instructor's approval when the class requires it.
+func (s *SmartContract) AdjustMemberGrade(ctx contractapi.TransactionContextInterface, id string, member string, points int) (int, error) {
+	return idempotent(ctx, func() (int, error) {
+		asset, err := s.ReadAsset(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		if asset.GroupID == "" {
+			return 0, validationError("id", "the assignment %s is not a group assignment", id)
+		}
+		record, err := s.ReadGroup(ctx, asset.ClassID, asset.GroupID)
+		if err != nil {
+			return 0, err
+		}
+		if !isMember(record, member) {
+			return 0, newContractError(ErrNotFound, map[string]string{"class": asset.ClassID, "group": asset.GroupID, "student": member},
+				"the student %s is not a member of group %s in class %s", member, asset.GroupID, asset.ClassID)
+		}
+
+		modifiedBy, pending, err := s.authorizeGrading(ctx, asset)
+		if err != nil {
+			return 0, err
+		}
+
+		asset.ModifiedBy = modifiedBy
+		previous, adjustments := adjustMember(asset.Adjustments, member, points)
+		if pending {
+			if asset.PendingGrade == nil {
+				asset.PendingGrade = &PendingGrade{Grade: asset.Grade, Feedback: asset.Feedback}
+			}
+			changes := []GradeAdjustment{}
+			for _, change := range asset.PendingGrade.Adjustments {
+				if change.Member != member {
+					changes = append(changes, change)
+				}
+			}
+			asset.PendingGrade.Adjustments = append(changes, GradeAdjustment{Member: member, Points: points})
+			asset.PendingGrade.GradedBy = modifiedBy
+			asset.PendingGrade.TxID = ctx.GetStub().GetTxID()
+		} else {
+			asset.Adjustments = adjustments
+		}
+
+		err = putAsset(ctx, asset)
+		if err != nil {
+			return 0, err
+		}
+		err = assetGradeChanged(ctx, asset)
+		if err != nil {
+			return 0, err
+		}
+
+		return previous, nil
+	})
+}
+
+// GetMemberGrades returns the grade of each current member of the group an assignment belongs to: the
+// group's grade plus the member's adjustment, but never below zero.
+func (s *SmartContract) GetMemberGrades(ctx contractapi.TransactionContextInterface, id string) ([]*MemberGrade, error) {
+	asset, err := s.ReadAsset(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if asset.GroupID == "" {
+		return nil, validationError("id", "the assignment %s is not a group assignment", id)
+	}
+	record, err := s.ReadGroup(ctx, asset.ClassID, asset.GroupID)
+	if err != nil {
+		return nil, err
+	}
+
+	grades := []*MemberGrade{}
+	for _, member := range record.Members {
+		grade := &MemberGrade{Member: member, Grade: asset.Grade}
+		for _, adjustment := range asset.Adjustments {
+			if adjustment.Member == member {
+				grade.Adjustment = adjustment.Points
+			}
+		}
+		grade.Grade += grade.Adjustment
+		if grade.Grade < 0 {
+			grade.Grade = 0
+		}
+		grades = append(grades, grade)
+	}
+
+	return grades, nil
+}
+
+// adjustMember returns a member's adjustment in adjustments and the adjustments with it set to points, or
+// removed if points is 0.
+func adjustMember(adjustments []GradeAdjustment, member string, points int) (int, []GradeAdjustment) {
+	previous := 0
+	result := []GradeAdjustment{}
+	for _, adjustment := range adjustments {
+		if adjustment.Member == member {
+			previous = adjustment.Points
+		} else {
+			result = append(result, adjustment)
+		}
+	}
+	if points != 0 {
+		result = append(result, GradeAdjustment{Member: member, Points: points})
+	}
+	return previous, result
+}
+
+// validateGroupKey checks the fields a group record is stored under.
+func validateGroupKey(class string, group string) error {
+	if err := validateName("class", "class ID", class); err != nil {
+		return err
+	}
+	return validateName("group", "group", group)
+}
+
+// validateGroupAssignmentKey checks the fields a group assignment ID is derived from.
+func validateGroupAssignmentKey(class string, title string, group string) error {
+	if err := validateGroupKey(class, group); err != nil {
+		return err
+	}
+	return validateTitle(title)
+}
+
+// groupKey returns the world state key of a group record.
+func groupKey(ctx contractapi.TransactionContextInterface, class string, group string) (string, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(groupObjectType, []string{class, group})
+	if err != nil {
+		return "", internalError("failed to create composite key", err)
+	}
+	return key, nil
+}
+
+// readGroup returns a group of a class, or nil if it does not exist.
+func readGroup(ctx contractapi.TransactionContextInterface, class string, group string) (*Group, error) {
+	key, err := groupKey(ctx, class, group)
+	if err != nil {
+		return nil, err
+	}
+
+	recordJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, internalError("failed to read from world state", err)
+	}
+	if recordJSON == nil {
+		return nil, nil
+	}
+
+	var record Group
+	err = json.Unmarshal(recordJSON, &record)
+	if err != nil {
+		return nil, internalError("failed to parse group", err)
+	}
+
+	return &record, nil
+}
+
+// putGroup writes a group record, marking it as modified by the submitting client, and sets the
+// GroupChanged event.
+func putGroup(ctx contractapi.TransactionContextInterface, record *Group) error {
+	modifiedBy, err := submittingClientID(ctx)
+	if err != nil {
+		return err
+	}
+	record.ModifiedBy = modifiedBy
+
+	key, err := groupKey(ctx, record.ClassID, record.Name)
+	if err != nil {
+		return err
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return internalError("failed to encode group", err)
+	}
+	err = ctx.GetStub().PutState(key, recordJSON)
+	if err != nil {
+		return internalError("failed to write to world state", err)
+	}
+
+	return setGradingEvent(ctx, GroupChangedEvent, &GradingEvent{ClassID: record.ClassID, GroupID: record.Name})
+}
+
+// authorizeMembershipChange checks that the submitting client may add a student to or remove them from a
+// group: it acts as the student or is the instructor of the class, and no assignment of the group is graded
+// yet.
+func (s *SmartContract) authorizeMembershipChange(ctx contractapi.TransactionContextInterface, class string, group string, student string) error {
+	allowed, err := s.actsAs(ctx, class, student)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		allowed, err = s.isInstructor(ctx, class)
+		if err != nil {
+			return err
+		}
+	}
+	if !allowed {
+		return newContractError(ErrForbidden, map[string]string{"class": class, "group": group, "student": student},
+			"only the student %s or the instructor of class %s may change their group", student, class)
+	}
+
+	return checkGroupNotGraded(ctx, class, group)
+}
+
+// checkGroupNotGraded returns a conflict if an assignment of a group has a grade, given or waiting for
+// approval. Its members get the group's grade, so they are fixed from then on.
+func checkGroupNotGraded(ctx contractapi.TransactionContextInterface, class string, group string) error {
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return internalError("failed to read from world state", err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return internalError("failed to read from world state", err)
+		}
+
+		var asset Asset
+		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return internalError("failed to parse asset", err)
+		}
+		if asset.ClassID != class || asset.GroupID != group {
+			continue
+		}
+		if asset.GradedBy != "" || asset.PendingGrade != nil || asset.Grade != 0 {
+			return newContractError(ErrConflict, map[string]string{"class": class, "group": group, "id": asset.ID},
+				"the members of group %s in class %s can not change once its assignment %s is graded", group, class, asset.Title)
+		}
+	}
+
+	return nil
+}
+
+// checkNotInOtherGroup returns a conflict if a student is a member of a group of the class other than group.
+func checkNotInOtherGroup(ctx contractapi.TransactionContextInterface, class string, group string, student string) error {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(groupObjectType, []string{class})
+	if err != nil {
+		return internalError("failed to read from world state", err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return internalError("failed to read from world state", err)
+		}
+
+		var record Group
+		err = json.Unmarshal(queryResponse.Value, &record)
+		if err != nil {
+			return internalError("failed to parse group", err)
+		}
+		if record.Name != group && isMember(&record, student) {
+			return newContractError(ErrConflict, map[string]string{"class": class, "group": record.Name, "student": student},
+				"the student %s is already a member of group %s in class %s", student, record.Name, class)
+		}
+	}
+
+	return nil
+}
+
+// isMember reports whether username is a member of a group.
+func isMember(record *Group, username string) bool {
+	for _, member := range record.Members {
+		if member == username {
+			return true
+		}
+	}
+	return false
+}
+
+// groupMembers answers membership questions during a range query, reading each group at most once.
+type groupMembers struct {
+	ctx    contractapi.TransactionContextInterface
+	groups map[string]*Group
+}
+
+func newGroupMembers(ctx contractapi.TransactionContextInterface) *groupMembers {
+	return &groupMembers{ctx: ctx, groups: map[string]*Group{}}
+}
+
+// isAssignee reports whether username is the student an asset was created for or, for a group assignment,
+// a current member of its group.
+func (m *groupMembers) isAssignee(asset *Asset, username string) (bool, error) {
+	if asset.GroupID == "" {
+		return isStudentOf(asset, username), nil
+	}
+
+	key := asset.ClassID + "\x00" + asset.GroupID
+	record, ok := m.groups[key]
+	if !ok {
+		var err error
+		record, err = readGroup(m.ctx, asset.ClassID, asset.GroupID)
+		if err != nil {
+			return false, err
+		}
+		m.groups[key] = record
+	}
+	return record != nil && isMember(record, username), nil
+}
+
+// holds reports whether an asset is waiting for username's work: it is owned by username or, for a group
+// assignment, handed to a group username is a member of.
+func (m *groupMembers) holds(asset *Asset, username string) (bool, error) {
+	if asset.GroupID != "" && asset.Owner == asset.GroupID {
+		return m.isAssignee(asset, username)
+	}
+	return asset.Owner == username, nil
+}
@@ -0,0 +1,142 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/simulator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupAssignment(t *testing.T) {
+	sim := simulator.New("mychannel")
+	instructor := simulator.NewClientIdentity("Org1MSP", "x509::CN=instructor")
+	alice, err := simulator.NewX509ClientIdentity("Org1MSP", "alice")
+	require.NoError(t, err)
+	contract := chaincode.SmartContract{}
+
+	// The instructor puts alice and bob in a group, and carol signs up herself
+	require.NoError(t, contract.SetGroupMembers(sim.Transaction(instructor), "cs101", "team1", []string{"alice", "bob"}))
+	carol, err := simulator.NewX509ClientIdentity("Org1MSP", "carol")
+	require.NoError(t, err)
+	require.NoError(t, contract.JoinGroup(sim.Transaction(carol), "cs101", "team1", "carol"))
+	events := sim.Stub.Events()
+	require.Len(t, events, 2)
+	require.Equal(t, chaincode.GroupChangedEvent, events[1].Name)
+	require.JSONEq(t, `{"ClassID":"cs101","GroupID":"team1"}`, string(events[1].Payload))
+
+	id, err := contract.CreateGroupAssignment(sim.Transaction(instructor), "cs101", "project", "instructor", "team1", "", "Build a compiler")
+	require.NoError(t, err)
+	expectedID, err := contract.GroupAssignmentID(sim.Transaction(instructor), "cs101", "project", "team1")
+	require.NoError(t, err)
+	require.Equal(t, expectedID, id)
+	studentID, err := contract.AssignmentID(sim.Transaction(instructor), "cs101", "project", "team1")
+	require.NoError(t, err)
+	require.NotEqual(t, studentID, id, "group and student copies must not share IDs")
+	_, err = contract.TransferAsset(sim.Transaction(instructor), id, "team1")
+	require.NoError(t, err)
+
+	// Every member is waiting for the work, and one of them submits it for the group
+	for _, member := range []string{"alice", "bob", "carol"} {
+		assignments, err := contract.GetAllAssignments(sim.Transaction(instructor), member, "cs101")
+		require.NoError(t, err)
+		require.Len(t, assignments, 1, member)
+	}
+	dave, err := simulator.NewX509ClientIdentity("Org1MSP", "dave")
+	require.NoError(t, err)
+	err = contract.SubmitAssignment(sim.Transaction(dave), id, "not our compiler")
+	requireContractError(t, err, chaincode.ErrForbidden, "only the holder or student of the assignment "+id+" may submit work for it")
+	require.NoError(t, contract.SubmitAssignment(sim.Transaction(alice), id, "our compiler"))
+	_, err = contract.TransferAsset(sim.Transaction(alice), id, "instructor")
+	require.NoError(t, err)
+
+	submitted, err := contract.GetSubmittedAssignments(sim.Transaction(instructor), "bob", "cs101")
+	require.NoError(t, err)
+	require.Len(t, submitted, 1)
+	require.Equal(t, "our compiler", submitted[0].Work)
+	classes, err := contract.GetAllClasses(sim.Transaction(instructor), "carol")
+	require.NoError(t, err)
+	require.Equal(t, []string{"cs101"}, classes)
+
+	// The grade applies to everyone, with an adjustment for bob
+	_, err = contract.GradeAssignment(sim.Transaction(instructor), id, 80, "Works")
+	require.NoError(t, err)
+	previous, err := contract.AdjustMemberGrade(sim.Transaction(instructor), id, "bob", -10)
+	require.NoError(t, err)
+	require.Equal(t, 0, previous)
+	grades, err := contract.GetMemberGrades(sim.Transaction(instructor), id)
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.MemberGrade{
+		{Member: "alice", Grade: 80},
+		{Member: "bob", Grade: 70, Adjustment: -10},
+		{Member: "carol", Grade: 80},
+	}, grades)
+
+	// Membership changes are kept in the group's history, and the graded group can no longer change
+	history, err := contract.GetGroupHistory(sim.Transaction(instructor), "cs101", "team1")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, []string{"alice", "bob"}, history[0].Members)
+	require.Equal(t, "x509::CN=instructor", history[0].ModifiedBy)
+	require.Equal(t, []string{"alice", "bob", "carol"}, history[1].Members)
+	require.Equal(t, carol.ID, history[1].ModifiedBy)
+
+	graded := "the members of group team1 in class cs101 can not change once its assignment project is graded"
+	err = contract.LeaveGroup(sim.Transaction(instructor), "cs101", "team1", "carol")
+	requireContractError(t, err, chaincode.ErrConflict, graded)
+	dave, err = simulator.NewX509ClientIdentity("Org1MSP", "dave")
+	require.NoError(t, err)
+	err = contract.JoinGroup(sim.Transaction(dave), "cs101", "team1", "dave")
+	requireContractError(t, err, chaincode.ErrConflict, graded)
+	err = contract.SetGroupMembers(sim.Transaction(instructor), "cs101", "team1", []string{"alice", "bob", "carol", "dave"})
+	requireContractError(t, err, chaincode.ErrConflict, graded)
+	grades, err = contract.GetMemberGrades(sim.Transaction(instructor), id)
+	require.NoError(t, err)
+	require.Len(t, grades, 3)
+}
+
+func TestGroupMembershipRules(t *testing.T) {
+	sim := simulator.New("mychannel")
+	instructor := simulator.NewClientIdentity("Org1MSP", "x509::CN=instructor")
+	contract := chaincode.SmartContract{}
+
+	require.NoError(t, contract.SetGroupMembers(sim.Transaction(instructor), "cs101", "team1", []string{"alice"}))
+	err := contract.JoinGroup(sim.Transaction(instructor), "cs101", "team2", "alice")
+	requireContractError(t, err, chaincode.ErrConflict, "the student alice is already a member of group team1 in class cs101")
+	err = contract.SetGroupMembers(sim.Transaction(instructor), "cs101", "team2", []string{"bob", "bob"})
+	requireContractError(t, err, chaincode.ErrValidation, "the member bob is listed more than once")
+
+	// Joining again changes nothing, and other classes have groups of their own
+	require.NoError(t, contract.JoinGroup(sim.Transaction(instructor), "cs101", "team1", "alice"))
+	alice, err := simulator.NewX509ClientIdentity("Org1MSP", "alice")
+	require.NoError(t, err)
+	require.NoError(t, contract.JoinGroup(sim.Transaction(alice), "cs102", "team9", "alice"))
+	group, err := contract.ReadGroup(sim.Transaction(instructor), "cs101", "team1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"alice"}, group.Members)
+
+	err = contract.LeaveGroup(sim.Transaction(instructor), "cs101", "team1", "bob")
+	requireContractError(t, err, chaincode.ErrNotFound, "the student bob is not a member of group team1 in class cs101")
+
+	// Students may only sign themselves up or out, and only the instructor may define the groups
+	mallory, err := simulator.NewX509ClientIdentity("Org1MSP", "mallory")
+	require.NoError(t, err)
+	err = contract.JoinGroup(sim.Transaction(mallory), "cs101", "team2", "bob")
+	requireContractError(t, err, chaincode.ErrForbidden, "only the student bob or the instructor of class cs101 may change their group")
+	err = contract.LeaveGroup(sim.Transaction(mallory), "cs101", "team1", "alice")
+	requireContractError(t, err, chaincode.ErrForbidden, "only the student alice or the instructor of class cs101 may change their group")
+	err = contract.SetGroupMembers(sim.Transaction(mallory), "cs101", "team1", []string{"mallory"})
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 may add assignments or groups to it")
+	_, err = contract.CreateGroupAssignment(sim.Transaction(instructor), "cs101", "project", "instructor", "team3", "", "")
+	requireContractError(t, err, chaincode.ErrNotFound, "the group team3 does not exist in class cs101")
+
+	id, err := contract.CreateAssignment(sim.Transaction(instructor), "cs101", "hw1", "instructor", "alice", "", "")
+	require.NoError(t, err)
+	_, err = contract.AdjustMemberGrade(sim.Transaction(instructor), id, "alice", 5)
+	requireContractError(t, err, chaincode.ErrValidation, "the assignment "+id+" is not a group assignment")
+
+	groupID, err := contract.CreateGroupAssignment(sim.Transaction(instructor), "cs101", "project", "instructor", "team1", "", "")
+	require.NoError(t, err)
+	_, err = contract.AdjustMemberGrade(sim.Transaction(instructor), groupID, "bob", 5)
+	requireContractError(t, err, chaincode.ErrNotFound, "the student bob is not a member of group team1 in class cs101")
+}
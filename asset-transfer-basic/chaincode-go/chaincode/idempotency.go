@@ -0,0 +1,142 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// IdempotencyKeyField is the transient data field a client sets to make a write transaction idempotent. The
+// first transaction with a key records its result under the key; a later transaction from the same client
+// with the same key, function and arguments returns that result without running again, so a client can
+// safely retry a transaction whose outcome it never learned.
+const IdempotencyKeyField = "idempotencyKey"
+
+// requestObjectType is the composite key prefix under which request records are stored
+const requestObjectType = "Request"
+
+// idempotencyKeyPattern matches the keys clients may choose, such as UUIDs
+var idempotencyKeyPattern = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// Request records the result of a write transaction submitted with an idempotency key. Records are kept
+// under composite keys so they never appear in the asset range queries.
+type Request struct {
+	Key      string `json:"Key"`
+	Function string `json:"Function"`
+	// ArgsHash is the SHA-256 of the function name and arguments, so a key reused for another request is caught
+	ArgsHash string          `json:"ArgsHash"`
+	Result   json.RawMessage `json:"Result"`
+	TxID     string          `json:"TxID"`
+}
+
+// idempotent runs a write transaction. When the transaction carries an idempotency key, the result is
+// recorded under it, and a replay of the same request returns the recorded result instead of running again.
+// Failed transactions do not commit, so only successful results are ever recorded.
+func idempotent[T any](ctx contractapi.TransactionContextInterface, run func() (T, error)) (T, error) {
+	var result T
+
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return result, internalError("failed to read transient data", err)
+	}
+	key := string(transient[IdempotencyKeyField])
+	if key == "" {
+		return run()
+	}
+	if !idempotencyKeyPattern.MatchString(key) {
+		return result, validationError(IdempotencyKeyField, "the idempotency key must be 1 to 128 letters, digits, '.', '_', ':' or '-'")
+	}
+
+	recordKey, err := requestKey(ctx, key)
+	if err != nil {
+		return result, err
+	}
+	function, argsHash := requestDigest(ctx)
+
+	recordJSON, err := ctx.GetStub().GetState(recordKey)
+	if err != nil {
+		return result, internalError("failed to read from world state", err)
+	}
+	if recordJSON != nil {
+		var record Request
+		if err := json.Unmarshal(recordJSON, &record); err != nil {
+			return result, internalError("failed to parse request", err)
+		}
+		if record.Function != function || record.ArgsHash != argsHash {
+			return result, newContractError(ErrConflict, map[string]string{"idempotencyKey": key, "txId": record.TxID},
+				"the idempotency key %s was already used for a different request", key)
+		}
+		if err := json.Unmarshal(record.Result, &result); err != nil {
+			return result, internalError("failed to parse recorded result", err)
+		}
+		return result, nil
+	}
+
+	result, err = run()
+	if err != nil {
+		return result, err
+	}
+
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return result, internalError("failed to encode result", err)
+	}
+	record := Request{Key: key, Function: function, ArgsHash: argsHash, Result: resultJSON, TxID: ctx.GetStub().GetTxID()}
+	recordJSON, err = json.Marshal(record)
+	if err != nil {
+		return result, internalError("failed to encode request", err)
+	}
+	err = ctx.GetStub().PutState(recordKey, recordJSON)
+	if err != nil {
+		return result, internalError("failed to write to world state", err)
+	}
+
+	return result, nil
+}
+
+// idempotentError is idempotent for write transactions that return nothing but an error.
+func idempotentError(ctx contractapi.TransactionContextInterface, run func() error) error {
+	_, err := idempotent(ctx, func() (struct{}, error) {
+		return struct{}{}, run()
+	})
+	return err
+}
+
+// requestKey returns the world state key of the request record for an idempotency key. Keys are scoped to
+// the submitting client, so clients can not replay or block each other's requests.
+func requestKey(ctx contractapi.TransactionContextInterface, key string) (string, error) {
+	clientID, err := submittingClientID(ctx)
+	if err != nil {
+		return "", err
+	}
+	clientHash := sha256.Sum256([]byte(clientID))
+
+	recordKey, err := ctx.GetStub().CreateCompositeKey(requestObjectType, []string{hex.EncodeToString(clientHash[:]), key})
+	if err != nil {
+		return "", internalError("failed to create composite key", err)
+	}
+	return recordKey, nil
+}
+
+// requestDigest returns the function the transaction invokes and a hash of the function and its
+// arguments. Each argument is prefixed with its length, so the boundaries between arguments count.
+func requestDigest(ctx contractapi.TransactionContextInterface) (string, string) {
+	args := ctx.GetStub().GetArgs()
+	function := ""
+	if len(args) > 0 {
+		function = string(args[0])
+	}
+
+	digest := sha256.New()
+	length := make([]byte, 8)
+	for _, arg := range args {
+		binary.BigEndian.PutUint64(length, uint64(len(arg)))
+		digest.Write(length)
+		digest.Write(arg)
+	}
+	return function, hex.EncodeToString(digest.Sum(nil))
+}
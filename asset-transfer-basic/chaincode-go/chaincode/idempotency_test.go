@@ -0,0 +1,94 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/simulator"
+	"github.com/stretchr/testify/require"
+)
+
+// request starts a transaction invoking function with args, submitted by identity with an idempotency key
+// unless key is empty.
+func request(sim *simulator.Simulator, identity *simulator.ClientIdentity, key string, function string, args ...string) *contractapi.TransactionContext {
+	ctx := sim.Transaction(identity)
+	sim.Stub.SetArgs(function, args...)
+	if key != "" {
+		sim.Stub.SetTransient(map[string][]byte{chaincode.IdempotencyKeyField: []byte(key)})
+	}
+	return ctx
+}
+
+func TestIdempotentReplay(t *testing.T) {
+	sim := simulator.New("mychannel")
+	instructor := simulator.NewClientIdentity("Org1MSP", "x509::CN=instructor")
+	contract := chaincode.SmartContract{}
+
+	createArgs := []string{"cs101", "hw1", "instructor", "alice", "", "Essay"}
+	id, err := contract.CreateAssignment(request(sim, instructor, "create-1", "CreateAssignment", createArgs...), "cs101", "hw1", "instructor", "alice", "", "Essay")
+	require.NoError(t, err)
+
+	// Retrying the creation with the same key returns the ID instead of a conflict
+	replayed, err := contract.CreateAssignment(request(sim, instructor, "create-1", "CreateAssignment", createArgs...), "cs101", "hw1", "instructor", "alice", "", "Essay")
+	require.NoError(t, err)
+	require.Equal(t, id, replayed)
+
+	_, err = contract.CreateAssignment(request(sim, instructor, "", "CreateAssignment", createArgs...), "cs101", "hw1", "instructor", "alice", "", "Essay")
+	requireContractError(t, err, chaincode.ErrConflict, "the assignment hw1 already exists for student alice in class cs101")
+
+	// A replayed grade returns the grade it replaced the first time, and does not undo a later grade
+	previous, err := contract.GradeAssignment(request(sim, instructor, "grade-1", "GradeAssignment", id, "80", ""), id, 80, "")
+	require.NoError(t, err)
+	require.Equal(t, 0, previous)
+	previous, err = contract.GradeAssignment(request(sim, instructor, "grade-2", "GradeAssignment", id, "90", ""), id, 90, "")
+	require.NoError(t, err)
+	require.Equal(t, 80, previous)
+	previous, err = contract.GradeAssignment(request(sim, instructor, "grade-1", "GradeAssignment", id, "80", ""), id, 80, "")
+	require.NoError(t, err)
+	require.Equal(t, 0, previous)
+
+	asset, err := contract.ReadAsset(sim.Transaction(instructor), id)
+	require.NoError(t, err)
+	require.Equal(t, 90, asset.Grade)
+
+	history, err := sim.Stub.GetHistoryForKey(id)
+	require.NoError(t, err)
+	writes := 0
+	for history.HasNext() {
+		_, err := history.Next()
+		require.NoError(t, err)
+		writes++
+	}
+	require.Equal(t, 3, writes, "replays must not write the asset")
+
+	// Records are kept out of the asset range queries
+	assets, err := contract.GetAllAssets(sim.Transaction(instructor), "instructor", "cs101")
+	require.NoError(t, err)
+	require.Len(t, assets, 1)
+}
+
+func TestIdempotencyKeyReuse(t *testing.T) {
+	sim := simulator.New("mychannel")
+	instructor := simulator.NewClientIdentity("Org1MSP", "x509::CN=instructor")
+	other := simulator.NewClientIdentity("Org1MSP", "x509::CN=other")
+	contract := chaincode.SmartContract{}
+
+	id, err := contract.CreateAssignment(sim.Transaction(instructor), "cs101", "hw1", "instructor", "alice", "", "")
+	require.NoError(t, err)
+	require.NoError(t, contract.AddTA(sim.Transaction(instructor), "cs101", other.ID))
+	require.NoError(t, contract.AssignGrader(sim.Transaction(instructor), "cs101", other.ID, "hw1", nil))
+	_, err = contract.GradeAssignment(request(sim, instructor, "key-1", "GradeAssignment", id, "80", ""), id, 80, "")
+	require.NoError(t, err)
+
+	_, err = contract.GradeAssignment(request(sim, instructor, "key-1", "GradeAssignment", id, "70", ""), id, 70, "")
+	requireContractError(t, err, chaincode.ErrConflict, "the idempotency key key-1 was already used for a different request")
+
+	// Keys belong to the client that used them
+	previous, err := contract.GradeAssignment(request(sim, other, "key-1", "GradeAssignment", id, "70", ""), id, 70, "")
+	require.NoError(t, err)
+	require.Equal(t, 80, previous)
+
+	err = contract.ReleaseGrades(request(sim, instructor, "not a key", "ReleaseGrades", "cs101", "hw1"), "cs101", "hw1")
+	requireContractError(t, err, chaincode.ErrValidation, "the idempotency key must be 1 to 128 letters, digits, '.', '_', ':' or '-'")
+}
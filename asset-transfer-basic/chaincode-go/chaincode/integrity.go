@@ -0,0 +1,100 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// integrityReportObjectType is the composite key prefix under which integrity reports are stored
+const integrityReportObjectType = "IntegrityReport"
+
+// IntegrityReport anchors the hash of an off-chain report, such as a plagiarism check, on the ledger
+type IntegrityReport struct {
+	ClassID    string    `json:"ClassID"`
+	Title      string    `json:"Title"`
+	ReportHash string    `json:"ReportHash"`
+	RecordedBy string    `json:"RecordedBy"`
+	TxID       string    `json:"TxID"`
+	Timestamp  time.Time `json:"Timestamp"`
+}
+
+// RecordIntegrityReport stores the SHA-256 hash of an off-chain report about the titled assignment in a
+// class. Only the instructor of the class or a grade auditor may record reports. Reports are kept under
+// composite keys so they never appear in the asset range queries.
+func (s *SmartContract) RecordIntegrityReport(ctx contractapi.TransactionContextInterface, class string, title string, reportHash string) error {
+	return idempotentError(ctx, func() error {
+		if err := validateTitle(title); err != nil {
+			return err
+		}
+		if !sha256Pattern.MatchString(reportHash) {
+			return validationError("reportHash", "the report hash %s is not a hex SHA-256 digest", reportHash)
+		}
+		if err := s.authorizeAudit(ctx, class, "record integrity reports for it"); err != nil {
+			return err
+		}
+
+		recordedBy, err := submittingClientID(ctx)
+		if err != nil {
+			return err
+		}
+
+		txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+		if err != nil {
+			return internalError("failed to get transaction timestamp", err)
+		}
+
+		txID := ctx.GetStub().GetTxID()
+		key, err := ctx.GetStub().CreateCompositeKey(integrityReportObjectType, []string{class, title, txID})
+		if err != nil {
+			return internalError("failed to create composite key", err)
+		}
+
+		report := IntegrityReport{
+			ClassID:    class,
+			Title:      title,
+			ReportHash: reportHash,
+			RecordedBy: recordedBy,
+			TxID:       txID,
+			Timestamp:  txTimestamp.AsTime(),
+		}
+		reportJSON, err := json.Marshal(report)
+		if err != nil {
+			return internalError("failed to encode report", err)
+		}
+
+		err = ctx.GetStub().PutState(key, reportJSON)
+		if err != nil {
+			return internalError("failed to write to world state", err)
+		}
+
+		return nil
+	})
+}
+
+// GetIntegrityReports returns every integrity report recorded for the titled assignment in a class
+func (s *SmartContract) GetIntegrityReports(ctx contractapi.TransactionContextInterface, class string, title string) ([]*IntegrityReport, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(integrityReportObjectType, []string{class, title})
+	if err != nil {
+		return nil, internalError("failed to read from world state", err)
+	}
+	defer resultsIterator.Close()
+
+	reports := []*IntegrityReport{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, internalError("failed to read from world state", err)
+		}
+
+		var report IntegrityReport
+		err = json.Unmarshal(queryResponse.Value, &report)
+		if err != nil {
+			return nil, internalError("failed to parse report", err)
+		}
+		reports = append(reports, &report)
+	}
+
+	return reports, nil
+}
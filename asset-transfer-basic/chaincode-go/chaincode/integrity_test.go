@@ -0,0 +1,91 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestRecordIntegrityReport(t *testing.T) {
+	timestamp := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	chaincodeStub := &mocks.ChaincodeStub{}
+	chaincodeStub.GetTxIDReturns("tx1")
+	chaincodeStub.GetTxTimestampReturns(timestamppb.New(timestamp), nil)
+	chaincodeStub.CreateCompositeKeyReturns("\x00IntegrityReport\x00cs101\x00hw1\x00tx1\x00", nil)
+	clientIdentity := &mocks.ClientIdentity{}
+	clientIdentity.GetIDReturns("x509::CN=instructor", nil)
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	transactionContext.GetClientIdentityReturns(clientIdentity)
+
+	assetTransfer := chaincode.SmartContract{}
+	hash := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	err := assetTransfer.RecordIntegrityReport(transactionContext, "cs101", "hw1", hash)
+	require.NoError(t, err)
+
+	objectType, attributes := chaincodeStub.CreateCompositeKeyArgsForCall(0)
+	require.Equal(t, "IntegrityReport", objectType)
+	require.Equal(t, []string{"cs101", "hw1", "tx1"}, attributes)
+
+	key, bytes := chaincodeStub.PutStateArgsForCall(0)
+	require.Equal(t, "\x00IntegrityReport\x00cs101\x00hw1\x00tx1\x00", key)
+	var report chaincode.IntegrityReport
+	require.NoError(t, json.Unmarshal(bytes, &report))
+	require.Equal(t, chaincode.IntegrityReport{
+		ClassID:    "cs101",
+		Title:      "hw1",
+		ReportHash: hash,
+		RecordedBy: "x509::CN=instructor",
+		TxID:       "tx1",
+		Timestamp:  timestamp,
+	}, report)
+
+	err = assetTransfer.RecordIntegrityReport(transactionContext, "cs101", "hw1", "abc123")
+	requireContractError(t, err, chaincode.ErrValidation, "the report hash abc123 is not a hex SHA-256 digest")
+	err = assetTransfer.RecordIntegrityReport(transactionContext, "cs101", "", hash)
+	requireContractError(t, err, chaincode.ErrValidation, "the title must not be empty")
+	err = assetTransfer.RecordIntegrityReport(transactionContext, "", "hw1", hash)
+	requireContractError(t, err, chaincode.ErrValidation, "the class ID must not be empty")
+
+	chaincodeStub.GetTxTimestampReturns(nil, fmt.Errorf("no timestamp"))
+	err = assetTransfer.RecordIntegrityReport(transactionContext, "cs101", "hw1", hash)
+	requireContractError(t, err, chaincode.ErrInternal, "failed to get transaction timestamp: no timestamp")
+}
+
+func TestGetIntegrityReports(t *testing.T) {
+	report := &chaincode.IntegrityReport{ClassID: "cs101", Title: "hw1", ReportHash: "abc123", TxID: "tx1"}
+	bytes, err := json.Marshal(report)
+	require.NoError(t, err)
+
+	iterator := &mocks.StateQueryIterator{}
+	iterator.HasNextReturnsOnCall(0, true)
+	iterator.HasNextReturnsOnCall(1, false)
+	iterator.NextReturns(&queryresult.KV{Value: bytes}, nil)
+
+	chaincodeStub := &mocks.ChaincodeStub{}
+	chaincodeStub.GetStateByPartialCompositeKeyReturns(iterator, nil)
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+
+	assetTransfer := chaincode.SmartContract{}
+	reports, err := assetTransfer.GetIntegrityReports(transactionContext, "cs101", "hw1")
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.IntegrityReport{report}, reports)
+
+	objectType, attributes := chaincodeStub.GetStateByPartialCompositeKeyArgsForCall(0)
+	require.Equal(t, "IntegrityReport", objectType)
+	require.Equal(t, []string{"cs101", "hw1"}, attributes)
+
+	chaincodeStub.GetStateByPartialCompositeKeyReturns(nil, fmt.Errorf("failed retrieving reports"))
+	reports, err = assetTransfer.GetIntegrityReports(transactionContext, "cs101", "hw1")
+	requireContractError(t, err, chaincode.ErrInternal, "failed to read from world state: failed retrieving reports")
+	require.Nil(t, reports)
+}
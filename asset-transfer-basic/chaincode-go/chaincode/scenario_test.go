@@ -0,0 +1,191 @@
+package chaincode_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/simulator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGradingScenario(t *testing.T) {
+	sim := simulator.New("mychannel")
+	instructor := simulator.NewClientIdentity("Org1MSP", "x509::CN=instructor")
+	student, err := simulator.NewX509ClientIdentity("Org1MSP", "alice")
+	require.NoError(t, err)
+	contract := chaincode.SmartContract{}
+
+	// Create the class with its first assignment, publish its key and hand the assignment to the student
+	id, err := contract.CreateAssignment(sim.Transaction(instructor), "cs101", "hw1", "instructor", "alice", "4/24/2023", "Essay")
+	require.NoError(t, err)
+	version, err := contract.RotateClassKey(sim.Transaction(instructor), "cs101", base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	require.NoError(t, err)
+	require.Equal(t, 1, version)
+	_, err = contract.TransferAsset(sim.Transaction(instructor), id, "alice")
+	require.NoError(t, err)
+
+	assignments, err := contract.GetAllAssignments(sim.Transaction(student), "alice", "cs101")
+	require.NoError(t, err)
+	require.Len(t, assignments, 1)
+	require.Equal(t, id, assignments[0].ID)
+
+	// The student submits work and hands the assignment back
+	require.NoError(t, contract.SubmitAssignment(sim.Transaction(student), id, "my essay"))
+	_, err = contract.TransferAsset(sim.Transaction(student), id, "instructor")
+	require.NoError(t, err)
+
+	events := sim.Stub.Events()
+	require.Len(t, events, 1)
+	require.Equal(t, chaincode.WorkSubmittedEvent, events[0].Name)
+	var submitted chaincode.Asset
+	require.NoError(t, json.Unmarshal(events[0].Payload, &submitted))
+	require.Equal(t, "my essay", submitted.Work)
+
+	// The instructor grades and releases it
+	previous, err := contract.GradeAssignment(sim.Transaction(instructor), id, 90, "Good")
+	require.NoError(t, err)
+	require.Equal(t, 0, previous)
+	require.NoError(t, contract.ReleaseGrades(sim.Transaction(instructor), "cs101", "hw1"))
+
+	// Both tell clients what changed, without broadcasting the grade
+	events = sim.Stub.Events()
+	require.Len(t, events, 3)
+	require.Equal(t, chaincode.GradeChangedEvent, events[1].Name)
+	require.JSONEq(t, `{"ID":"`+id+`","ClassID":"cs101","Title":"hw1","StudentID":"alice"}`, string(events[1].Payload))
+	require.Equal(t, chaincode.GradesReleasedEvent, events[2].Name)
+	require.JSONEq(t, `{"ClassID":"cs101","Title":"hw1"}`, string(events[2].Payload))
+
+	asset, err := contract.ReadAsset(sim.Transaction(student), id)
+	require.NoError(t, err)
+	require.Equal(t, 90, asset.Grade)
+	require.True(t, asset.Released)
+	require.Equal(t, "instructor", asset.Owner)
+	err = contract.SubmitAssignment(sim.Transaction(student), id, "a better essay")
+	requireContractError(t, err, chaincode.ErrConflict, "the grade of the assignment "+id+" is released, so its work can not change")
+
+	classes, err := contract.GetAllClasses(sim.Transaction(student), "alice")
+	require.NoError(t, err)
+	require.Equal(t, []string{"cs101"}, classes, "the class record must not show up in range queries")
+
+	report, err := contract.GetGradeAnomalies(sim.Transaction(instructor), "cs101", 3)
+	require.NoError(t, err)
+	require.Equal(t, 1, report.AssetsScanned)
+	require.Empty(t, report.Anomalies)
+
+	// Only a teaching assistant the instructor delegated to may regrade, and their grades are not flagged
+	// as unauthorized, but a grade changed after release still is
+	ta := simulator.NewClientIdentity("Org1MSP", "x509::CN=ta")
+	_, err = contract.GradeAssignment(sim.Transaction(ta), id, 100, "Regraded")
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 or a teaching assistant assigned to it may grade the assignment "+id)
+	require.NoError(t, contract.AddTA(sim.Transaction(instructor), "cs101", "x509::CN=ta"))
+	require.NoError(t, contract.AssignGrader(sim.Transaction(instructor), "cs101", "x509::CN=ta", "hw1", nil))
+	_, err = contract.GradeAssignment(sim.Transaction(ta), id, 100, "Regraded")
+	require.NoError(t, err)
+
+	report, err = contract.GetGradeAnomalies(sim.Transaction(instructor), "cs101", 3)
+	require.NoError(t, err)
+	var kinds []string
+	for _, anomaly := range report.Anomalies {
+		kinds = append(kinds, anomaly.Kind)
+	}
+	require.Equal(t, []string{chaincode.AnomalyChangedAfterRelease}, kinds)
+
+	// Removing the teaching assistant does not turn their earlier grade into an unauthorized change, and
+	// only the instructor and grade auditors may scan the class
+	require.NoError(t, contract.RemoveTA(sim.Transaction(instructor), "cs101", "x509::CN=ta"))
+	_, err = contract.GetGradeAnomalies(sim.Transaction(student), "cs101", 3)
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 or a grade auditor may scan its grades")
+	auditor := simulator.NewClientIdentity("Org1MSP", "x509::CN=auditor")
+	auditor.Attributes["grade.auditor"] = "true"
+	report, err = contract.GetGradeAnomalies(sim.Transaction(auditor), "cs101", 3)
+	require.NoError(t, err)
+	require.Len(t, report.Anomalies, 1)
+	require.Equal(t, chaincode.AnomalyChangedAfterRelease, report.Anomalies[0].Kind)
+}
+
+func TestAssetChangesNeedInstructorScenario(t *testing.T) {
+	sim := simulator.New("mychannel")
+	instructor := simulator.NewClientIdentity("Org1MSP", "x509::CN=instructor")
+	student, err := simulator.NewX509ClientIdentity("Org1MSP", "alice")
+	require.NoError(t, err)
+	otherTA := simulator.NewClientIdentity("Org1MSP", "x509::CN=ta")
+	contract := chaincode.SmartContract{}
+
+	id, err := contract.CreateAssignment(sim.Transaction(instructor), "cs101", "hw1", "instructor", "alice", "", "Essay")
+	require.NoError(t, err)
+	_, err = contract.TransferAsset(sim.Transaction(instructor), id, "alice")
+	require.NoError(t, err)
+	require.NoError(t, contract.SubmitAssignment(sim.Transaction(student), id, "my essay"))
+
+	// A teaching assistant of another class is no teaching assistant of this one
+	_, err = contract.CreateAssignment(sim.Transaction(otherTA), "cs202", "hw1", "ta", "bob", "", "")
+	require.NoError(t, err)
+
+	// Neither the student nor the outsider may set a grade, move the submission or delete it
+	for _, caller := range []*simulator.ClientIdentity{student, otherTA} {
+		err = contract.UpdateAsset(sim.Transaction(caller), id, "", 100, "", "", "", "")
+		requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 may manage its grading")
+		err = contract.UpdateAsset(sim.Transaction(caller), id, "", -1, "", "", "", "cs202")
+		requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 may manage its grading")
+		err = contract.DeleteAsset(sim.Transaction(caller), id)
+		requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 may manage its grading")
+	}
+	_, err = contract.TransferAsset(sim.Transaction(otherTA), id, "ta")
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 may transfer the asset "+id+", or its holder return it")
+	_, err = contract.TransferAsset(sim.Transaction(student), id, "bob")
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 may transfer the asset "+id+", or its holder return it")
+
+	// The student may still hand the submission back, and after that may not take it again
+	_, err = contract.TransferAsset(sim.Transaction(student), id, "instructor")
+	require.NoError(t, err)
+	_, err = contract.TransferAsset(sim.Transaction(student), id, "alice")
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 may transfer the asset "+id+", or its holder return it")
+
+	asset, err := contract.ReadAsset(sim.Transaction(instructor), id)
+	require.NoError(t, err)
+	require.Equal(t, 0, asset.Grade)
+	require.Equal(t, "cs101", asset.ClassID)
+	require.Equal(t, "instructor", asset.Owner)
+
+	// The instructor keeps full control
+	require.NoError(t, contract.UpdateAsset(sim.Transaction(instructor), id, "", -1, "", "5/1/2023", "", ""))
+	require.NoError(t, contract.DeleteAsset(sim.Transaction(instructor), id))
+}
+
+func TestIntegrityReportScenario(t *testing.T) {
+	sim := simulator.New("mychannel")
+	instructor := simulator.NewClientIdentity("Org1MSP", "x509::CN=instructor")
+	student, err := simulator.NewX509ClientIdentity("Org1MSP", "alice")
+	require.NoError(t, err)
+	auditor := simulator.NewClientIdentity("Org1MSP", "x509::CN=auditor")
+	auditor.Attributes["grade.auditor"] = "true"
+	contract := chaincode.SmartContract{}
+	hashes := []string{
+		"2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		"486ea46224d1bb4fb680f34f7c9ad96a8f24ec88be73ea8e5a6c65260e9cb8a7",
+		"9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08",
+	}
+
+	_, err = contract.CreateAssignment(sim.Transaction(instructor), "cs101", "hw1", "instructor", "alice", "", "")
+	require.NoError(t, err)
+
+	// Only the instructor and grade auditors may anchor reports, which could clear or accuse a student
+	err = contract.RecordIntegrityReport(sim.Transaction(student), "cs101", "hw1", hashes[0])
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 or a grade auditor may record integrity reports for it")
+	require.NoError(t, contract.RecordIntegrityReport(sim.Transaction(instructor), "cs101", "hw1", hashes[0]))
+	require.NoError(t, contract.RecordIntegrityReport(sim.Transaction(auditor), "cs101", "hw1", hashes[1]))
+	require.NoError(t, contract.RecordIntegrityReport(sim.Transaction(instructor), "cs101", "hw2", hashes[2]))
+
+	reports, err := contract.GetIntegrityReports(sim.Transaction(instructor), "cs101", "hw1")
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	require.NotEqual(t, reports[0].TxID, reports[1].TxID)
+	require.True(t, reports[0].Timestamp.Before(reports[1].Timestamp))
+	require.Equal(t, auditor.ID, reports[1].RecordedBy)
+
+	assets, err := contract.GetAllAssets(sim.Transaction(instructor), "instructor", "cs101")
+	require.NoError(t, err)
+	require.Len(t, assets, 1)
+}
@@ -0,0 +1,175 @@
+// Package simulator runs contract functions against an in-memory ledger, so tests can exercise flows that
+// span several transactions, such as creating an assignment, submitting work, grading it and releasing the
+// grade, without scripting every stub call by hand.
+//
+//	sim := simulator.New("mychannel")
+//	instructor := simulator.NewClientIdentity("Org1MSP", "x509::CN=instructor")
+//	id, err := contract.CreateAssignment(sim.Transaction(instructor), "cs101", "hw1", "instructor", "alice", "", "")
+package simulator
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// Simulator is a ledger whose transactions are run one at a time, each with its own ID, timestamp and
+// submitting client.
+type Simulator struct {
+	Stub *Stub
+	// Clock is the timestamp of the next transaction; each transaction advances it by Tick
+	Clock time.Time
+	Tick  time.Duration
+}
+
+// New returns a simulator with an empty ledger for channelID. Transactions start at a fixed time one second
+// apart, so tests get the same timestamps on every run.
+func New(channelID string) *Simulator {
+	return &Simulator{
+		Stub:  NewStub(channelID),
+		Clock: time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC),
+		Tick:  time.Second,
+	}
+}
+
+// Transaction starts a new transaction submitted by identity, and returns the context to pass to a contract
+// function.
+func (sim *Simulator) Transaction(identity *ClientIdentity) *contractapi.TransactionContext {
+	sim.start(identity)
+
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(sim.Stub)
+	ctx.SetClientIdentity(identity)
+	return ctx
+}
+
+// Invoke runs function on chaincode as a new transaction submitted by identity, the way a peer would, and
+// rolls the transaction back if the chaincode rejects it. The contract API reads the submitting client from
+// the creator certificate, so identity must have a Certificate; see NewX509ClientIdentity.
+func (sim *Simulator) Invoke(chaincode shim.Chaincode, identity *ClientIdentity, function string, args ...string) peer.Response {
+	return sim.InvokeWithTransient(chaincode, identity, nil, function, args...)
+}
+
+// InvokeWithTransient runs function like Invoke, with transient as the transient data of the transaction.
+func (sim *Simulator) InvokeWithTransient(chaincode shim.Chaincode, identity *ClientIdentity, transient map[string][]byte, function string, args ...string) peer.Response {
+	sim.start(identity)
+	sim.Stub.SetTransient(transient)
+	sim.Stub.SetArgs(function, args...)
+
+	response := chaincode.Invoke(sim.Stub)
+	if response.Status >= shim.ERRORTHRESHOLD {
+		sim.Stub.Rollback()
+	}
+	return response
+}
+
+// start begins the next transaction and sets identity as its creator.
+func (sim *Simulator) start(identity *ClientIdentity) {
+	sim.Stub.StartTransaction(sim.Clock)
+	sim.Clock = sim.Clock.Add(sim.Tick)
+	sim.Stub.SetCreator(identity.serialize())
+}
+
+// ClientIdentity is a cid.ClientIdentity with a settable ID, MSP ID and attributes.
+type ClientIdentity struct {
+	ID          string
+	MSPID       string
+	Attributes  map[string]string
+	Certificate *x509.Certificate
+}
+
+// NewClientIdentity returns an identity from the given MSP with the given ID and no attributes.
+func NewClientIdentity(mspID string, id string) *ClientIdentity {
+	return &ClientIdentity{ID: id, MSPID: mspID, Attributes: map[string]string{}}
+}
+
+// NewX509ClientIdentity returns an identity from the given MSP with a new self-signed certificate for
+// commonName. Its ID is the one the contract API derives from the certificate.
+func NewX509ClientIdentity(mspID string, commonName string) (*ClientIdentity, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate: %w", err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+
+	identity := &ClientIdentity{MSPID: mspID, Attributes: map[string]string{}, Certificate: certificate}
+	stub := NewStub("")
+	stub.SetCreator(identity.serialize())
+	parsed, err := cid.New(stub)
+	if err != nil {
+		return nil, err
+	}
+	if identity.ID, err = parsed.GetID(); err != nil {
+		return nil, err
+	}
+	return identity, nil
+}
+
+// serialize returns the identity as the creator of a transaction. Identities with a certificate carry it as
+// PEM, as the peer's do; others carry their ID.
+func (identity *ClientIdentity) serialize() []byte {
+	idBytes := []byte(identity.ID)
+	if identity.Certificate != nil {
+		idBytes = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: identity.Certificate.Raw})
+	}
+	creator, err := proto.Marshal(&msp.SerializedIdentity{Mspid: identity.MSPID, IdBytes: idBytes})
+	if err != nil {
+		panic(fmt.Errorf("failed to serialize identity: %w", err))
+	}
+	return creator
+}
+
+func (identity *ClientIdentity) GetID() (string, error) {
+	return identity.ID, nil
+}
+
+func (identity *ClientIdentity) GetMSPID() (string, error) {
+	return identity.MSPID, nil
+}
+
+func (identity *ClientIdentity) GetAttributeValue(attrName string) (string, bool, error) {
+	value, found := identity.Attributes[attrName]
+	return value, found, nil
+}
+
+func (identity *ClientIdentity) AssertAttributeValue(attrName string, attrValue string) error {
+	value, found := identity.Attributes[attrName]
+	if !found {
+		return fmt.Errorf("attribute '%s' was not found", attrName)
+	}
+	if value != attrValue {
+		return fmt.Errorf("attribute '%s' equals '%s', not '%s'", attrName, value, attrValue)
+	}
+	return nil
+}
+
+func (identity *ClientIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return identity.Certificate, nil
+}
@@ -0,0 +1,512 @@
+package simulator
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// compositeKeyNamespace starts every composite key, which keeps them out of simple key range queries
+const compositeKeyNamespace = "\x00"
+
+// errRichQuery is returned by the CouchDB rich query functions, which need a state database to run against
+var errRichQuery = errors.New("rich queries are not supported by the simulator")
+
+// Event is a chaincode event set by a transaction.
+type Event struct {
+	TxID    string
+	Name    string
+	Payload []byte
+}
+
+// Stub is an in-memory shim.ChaincodeStubInterface. World state, private data, key history and events are
+// kept in maps; range queries return keys in order like the peer does. Each transaction started with
+// StartTransaction gets its own ID and timestamp. Like the peer, a transaction's writes go to a write set
+// that its own reads do not see; the write set is committed, and recorded for GetHistoryForKey, when the
+// next transaction starts or on Commit, and discarded by Rollback.
+type Stub struct {
+	channelID string
+
+	state       map[string][]byte
+	history     map[string][]*queryresult.KeyModification
+	private     map[string]map[string][]byte
+	endorsement map[string][]byte
+	events      []Event
+
+	txCount     int
+	txID        string
+	txTimestamp time.Time
+	creator     []byte
+	transient   map[string][]byte
+	args        [][]byte
+	// writes is the write set of the current transaction, in the order its keys were first written
+	writes []*write
+	// undo restores the endorsement policies and event the current transaction changed, newest change last
+	undo []func()
+}
+
+// write is the last value a transaction wrote to a key of the world state, or of a private data collection
+// if collection is set.
+type write struct {
+	collection string
+	key        string
+	value      []byte
+	isDelete   bool
+}
+
+// NewStub returns an empty ledger for channelID, with a first transaction started at the current time.
+func NewStub(channelID string) *Stub {
+	stub := &Stub{
+		channelID:   channelID,
+		state:       map[string][]byte{},
+		history:     map[string][]*queryresult.KeyModification{},
+		private:     map[string]map[string][]byte{},
+		endorsement: map[string][]byte{},
+	}
+	stub.StartTransaction(time.Now().UTC())
+	return stub
+}
+
+// StartTransaction commits the current transaction and begins a new one with a generated ID and the given
+// timestamp.
+func (s *Stub) StartTransaction(timestamp time.Time) {
+	s.Commit()
+	s.txCount++
+	s.txID = fmt.Sprintf("tx%d", s.txCount)
+	s.txTimestamp = timestamp
+	s.transient = nil
+	s.args = nil
+	s.undo = nil
+}
+
+// Commit applies the write set of the current transaction to the world state and private data, as the peer
+// does once the transaction is ordered and validated. Later writes of the transaction start a new write set.
+func (s *Stub) Commit() {
+	for _, w := range s.writes {
+		values := s.state
+		if w.collection != "" {
+			if s.private[w.collection] == nil && !w.isDelete {
+				s.private[w.collection] = map[string][]byte{}
+			}
+			values = s.private[w.collection]
+		}
+		if w.isDelete {
+			if _, ok := values[w.key]; !ok {
+				continue
+			}
+			delete(values, w.key)
+		} else {
+			values[w.key] = w.value
+		}
+		if w.collection == "" {
+			s.recordHistory(w.key, w.value, w.isDelete)
+		}
+	}
+	s.writes = nil
+	s.undo = nil
+}
+
+// Rollback discards the write set and undoes the event of the current transaction, as the peer discards
+// the results of a transaction that is only evaluated or that the chaincode rejects.
+func (s *Stub) Rollback() {
+	for i := len(s.undo) - 1; i >= 0; i-- {
+		s.undo[i]()
+	}
+	s.undo = nil
+	s.writes = nil
+}
+
+// buffer adds a write to the write set of the current transaction, replacing an earlier write to the key.
+func (s *Stub) buffer(w *write) {
+	for i, existing := range s.writes {
+		if existing.collection == w.collection && existing.key == w.key {
+			s.writes[i] = w
+			return
+		}
+	}
+	s.writes = append(s.writes, w)
+}
+
+// remember records how to restore key in values if the current transaction is rolled back.
+func (s *Stub) remember(values map[string][]byte, key string) {
+	previous, existed := values[key]
+	s.undo = append(s.undo, func() {
+		if existed {
+			values[key] = previous
+		} else {
+			delete(values, key)
+		}
+	})
+}
+
+// SetTxTimestamp changes the timestamp of the current transaction.
+func (s *Stub) SetTxTimestamp(timestamp time.Time) {
+	s.txTimestamp = timestamp
+}
+
+// SetCreator sets the serialized identity returned by GetCreator.
+func (s *Stub) SetCreator(creator []byte) {
+	s.creator = creator
+}
+
+// SetTransient sets the transient data of the current transaction.
+func (s *Stub) SetTransient(transient map[string][]byte) {
+	s.transient = transient
+}
+
+// SetArgs sets the function name and arguments of the current transaction.
+func (s *Stub) SetArgs(function string, args ...string) {
+	s.args = [][]byte{[]byte(function)}
+	for _, arg := range args {
+		s.args = append(s.args, []byte(arg))
+	}
+}
+
+// Events returns the events set by every transaction so far, oldest first.
+func (s *Stub) Events() []Event {
+	return append([]Event(nil), s.events...)
+}
+
+func (s *Stub) GetArgs() [][]byte {
+	return s.args
+}
+
+func (s *Stub) GetStringArgs() []string {
+	args := make([]string, 0, len(s.args))
+	for _, arg := range s.args {
+		args = append(args, string(arg))
+	}
+	return args
+}
+
+func (s *Stub) GetFunctionAndParameters() (string, []string) {
+	args := s.GetStringArgs()
+	if len(args) == 0 {
+		return "", []string{}
+	}
+	return args[0], args[1:]
+}
+
+func (s *Stub) GetArgsSlice() ([]byte, error) {
+	var slice []byte
+	for _, arg := range s.args {
+		slice = append(slice, arg...)
+	}
+	return slice, nil
+}
+
+func (s *Stub) GetTxID() string {
+	return s.txID
+}
+
+func (s *Stub) GetChannelID() string {
+	return s.channelID
+}
+
+func (s *Stub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) peer.Response {
+	return shim.Error("chaincode to chaincode calls are not supported by the simulator")
+}
+
+func (s *Stub) GetState(key string) ([]byte, error) {
+	return s.state[key], nil
+}
+
+func (s *Stub) PutState(key string, value []byte) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	if len(value) == 0 {
+		// The peer treats an empty value as a delete
+		return s.DelState(key)
+	}
+	s.buffer(&write{key: key, value: append([]byte(nil), value...)})
+	return nil
+}
+
+func (s *Stub) DelState(key string) error {
+	s.buffer(&write{key: key, isDelete: true})
+	return nil
+}
+
+// recordHistory adds a modification of key by the current transaction to its history.
+func (s *Stub) recordHistory(key string, value []byte, isDelete bool) {
+	s.history[key] = append(s.history[key], &queryresult.KeyModification{
+		TxId:      s.txID,
+		Value:     append([]byte(nil), value...),
+		Timestamp: timestamppb.New(s.txTimestamp),
+		IsDelete:  isDelete,
+	})
+}
+
+func (s *Stub) SetStateValidationParameter(key string, ep []byte) error {
+	s.remember(s.endorsement, key)
+	s.endorsement[key] = ep
+	return nil
+}
+
+func (s *Stub) GetStateValidationParameter(key string) ([]byte, error) {
+	return s.endorsement[key], nil
+}
+
+func (s *Stub) GetStateByRange(startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	return newRangeIterator(s.state, startKey, endKey), nil
+}
+
+func (s *Stub) GetStateByRangeWithPagination(startKey string, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	if bookmark != "" {
+		startKey = bookmark
+	}
+	iterator := newRangeIterator(s.state, startKey, endKey)
+	return iterator.page(pageSize)
+}
+
+func (s *Stub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	prefix, err := shim.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	return newPrefixIterator(s.state, prefix), nil
+}
+
+func (s *Stub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	prefix, err := shim.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	iterator := newPrefixIterator(s.state, prefix)
+	if bookmark != "" {
+		iterator.skipBefore(bookmark)
+	}
+	return iterator.page(pageSize)
+}
+
+func (s *Stub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return shim.CreateCompositeKey(objectType, attributes)
+}
+
+func (s *Stub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	if !strings.HasPrefix(compositeKey, compositeKeyNamespace) {
+		return "", nil, fmt.Errorf("%q is not a composite key", compositeKey)
+	}
+	parts := strings.Split(strings.TrimSuffix(compositeKey[len(compositeKeyNamespace):], "\x00"), "\x00")
+	return parts[0], parts[1:], nil
+}
+
+func (s *Stub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	return nil, errRichQuery
+}
+
+func (s *Stub) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	return nil, nil, errRichQuery
+}
+
+// GetHistoryForKey returns the modifications of key, newest first as the peer's history database does.
+func (s *Stub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	history := s.history[key]
+	modifications := make([]*queryresult.KeyModification, 0, len(history))
+	for i := len(history) - 1; i >= 0; i-- {
+		modifications = append(modifications, history[i])
+	}
+	return &historyIterator{modifications: modifications}, nil
+}
+
+func (s *Stub) GetPrivateData(collection string, key string) ([]byte, error) {
+	return s.private[collection][key], nil
+}
+
+func (s *Stub) GetPrivateDataHash(collection string, key string) ([]byte, error) {
+	value, ok := s.private[collection][key]
+	if !ok {
+		return nil, nil
+	}
+	hash := sha256.Sum256(value)
+	return hash[:], nil
+}
+
+func (s *Stub) PutPrivateData(collection string, key string, value []byte) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	s.buffer(&write{collection: collection, key: key, value: append([]byte(nil), value...)})
+	return nil
+}
+
+func (s *Stub) DelPrivateData(collection string, key string) error {
+	s.buffer(&write{collection: collection, key: key, isDelete: true})
+	return nil
+}
+
+func (s *Stub) PurgePrivateData(collection string, key string) error {
+	return s.DelPrivateData(collection, key)
+}
+
+func (s *Stub) SetPrivateDataValidationParameter(collection string, key string, ep []byte) error {
+	s.remember(s.endorsement, collection+"\x00"+key)
+	s.endorsement[collection+"\x00"+key] = ep
+	return nil
+}
+
+func (s *Stub) GetPrivateDataValidationParameter(collection string, key string) ([]byte, error) {
+	return s.endorsement[collection+"\x00"+key], nil
+}
+
+func (s *Stub) GetPrivateDataByRange(collection string, startKey string, endKey string) (shim.StateQueryIteratorInterface, error) {
+	return newRangeIterator(s.private[collection], startKey, endKey), nil
+}
+
+func (s *Stub) GetPrivateDataByPartialCompositeKey(collection string, objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	prefix, err := shim.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	return newPrefixIterator(s.private[collection], prefix), nil
+}
+
+func (s *Stub) GetPrivateDataQueryResult(collection string, query string) (shim.StateQueryIteratorInterface, error) {
+	return nil, errRichQuery
+}
+
+func (s *Stub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+func (s *Stub) GetTransient() (map[string][]byte, error) {
+	return s.transient, nil
+}
+
+func (s *Stub) GetBinding() ([]byte, error) {
+	return nil, nil
+}
+
+func (s *Stub) GetDecorations() map[string][]byte {
+	return nil
+}
+
+func (s *Stub) GetSignedProposal() (*peer.SignedProposal, error) {
+	return nil, nil
+}
+
+func (s *Stub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return timestamppb.New(s.txTimestamp), nil
+}
+
+// SetEvent sets the event of the current transaction. As on a peer, a transaction has at most one event, so
+// a later call replaces an earlier one.
+func (s *Stub) SetEvent(name string, payload []byte) error {
+	if name == "" {
+		return errors.New("event name can not be empty string")
+	}
+	event := Event{TxID: s.txID, Name: name, Payload: append([]byte(nil), payload...)}
+	previous := append([]Event(nil), s.events...)
+	s.undo = append(s.undo, func() {
+		s.events = previous
+	})
+	if last := len(s.events) - 1; last >= 0 && s.events[last].TxID == s.txID {
+		s.events[last] = event
+		return nil
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+// stateIterator iterates over a snapshot of key-value pairs in key order.
+type stateIterator struct {
+	results []*queryresult.KV
+	next    int
+}
+
+// newRangeIterator returns the keys of values in [startKey, endKey). An empty endKey has no upper bound.
+// Composite keys are left out, as the peer leaves them out of range queries.
+func newRangeIterator(values map[string][]byte, startKey string, endKey string) *stateIterator {
+	return newIterator(values, func(key string) bool {
+		if strings.HasPrefix(key, compositeKeyNamespace) {
+			return false
+		}
+		return key >= startKey && (endKey == "" || key < endKey)
+	})
+}
+
+// newPrefixIterator returns the keys of values starting with prefix.
+func newPrefixIterator(values map[string][]byte, prefix string) *stateIterator {
+	return newIterator(values, func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	})
+}
+
+func newIterator(values map[string][]byte, include func(key string) bool) *stateIterator {
+	iterator := &stateIterator{}
+	for key, value := range values {
+		if include(key) {
+			iterator.results = append(iterator.results, &queryresult.KV{Key: key, Value: append([]byte(nil), value...)})
+		}
+	}
+	sort.Slice(iterator.results, func(i, j int) bool {
+		return iterator.results[i].Key < iterator.results[j].Key
+	})
+	return iterator
+}
+
+// skipBefore drops the results with keys before bookmark.
+func (it *stateIterator) skipBefore(bookmark string) {
+	for len(it.results) > 0 && it.results[0].Key < bookmark {
+		it.results = it.results[1:]
+	}
+}
+
+// page limits the iterator to pageSize results and returns the bookmark of the next page.
+func (it *stateIterator) page(pageSize int32) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	metadata := &peer.QueryResponseMetadata{}
+	if pageSize > 0 && len(it.results) > int(pageSize) {
+		metadata.Bookmark = it.results[pageSize].Key
+		it.results = it.results[:pageSize]
+	}
+	metadata.FetchedRecordsCount = int32(len(it.results))
+	return it, metadata, nil
+}
+
+func (it *stateIterator) HasNext() bool {
+	return it.next < len(it.results)
+}
+
+func (it *stateIterator) Next() (*queryresult.KV, error) {
+	if !it.HasNext() {
+		return nil, errors.New("no more results")
+	}
+	it.next++
+	return it.results[it.next-1], nil
+}
+
+func (it *stateIterator) Close() error {
+	return nil
+}
+
+// historyIterator iterates over the modifications of a key.
+type historyIterator struct {
+	modifications []*queryresult.KeyModification
+	next          int
+}
+
+func (it *historyIterator) HasNext() bool {
+	return it.next < len(it.modifications)
+}
+
+func (it *historyIterator) Next() (*queryresult.KeyModification, error) {
+	if !it.HasNext() {
+		return nil, errors.New("no more results")
+	}
+	it.next++
+	return it.modifications[it.next-1], nil
+}
+
+func (it *historyIterator) Close() error {
+	return nil
+}
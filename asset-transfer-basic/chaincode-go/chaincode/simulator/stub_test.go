@@ -0,0 +1,255 @@
+package simulator_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/simulator"
+	"github.com/stretchr/testify/require"
+)
+
+func keys(t *testing.T, iterator shim.StateQueryIteratorInterface) []string {
+	t.Helper()
+	var result []string
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		require.NoError(t, err)
+		result = append(result, kv.Key)
+	}
+	require.NoError(t, iterator.Close())
+	return result
+}
+
+func TestStateAndRangeQueries(t *testing.T) {
+	stub := simulator.NewStub("mychannel")
+	for _, key := range []string{"c", "a", "b"} {
+		require.NoError(t, stub.PutState(key, []byte(key)))
+	}
+	compositeKey, err := stub.CreateCompositeKey("Class", []string{"cs101"})
+	require.NoError(t, err)
+	require.NoError(t, stub.PutState(compositeKey, []byte("class")))
+
+	value, err := stub.GetState("b")
+	require.NoError(t, err)
+	require.Nil(t, value, "a transaction must not read its own writes")
+	uncommitted, err := stub.GetStateByRange("", "")
+	require.NoError(t, err)
+	require.Empty(t, keys(t, uncommitted))
+	stub.Commit()
+
+	value, err = stub.GetState("b")
+	require.NoError(t, err)
+	require.Equal(t, []byte("b"), value)
+
+	all, err := stub.GetStateByRange("", "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b", "c"}, keys(t, all))
+
+	bounded, err := stub.GetStateByRange("b", "c")
+	require.NoError(t, err)
+	require.Equal(t, []string{"b"}, keys(t, bounded))
+
+	page, metadata, err := stub.GetStateByRangeWithPagination("", "", 2, "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "b"}, keys(t, page))
+	require.Equal(t, "c", metadata.Bookmark)
+	page, metadata, err = stub.GetStateByRangeWithPagination("", "", 2, metadata.Bookmark)
+	require.NoError(t, err)
+	require.Equal(t, []string{"c"}, keys(t, page))
+	require.Equal(t, "", metadata.Bookmark)
+
+	classes, err := stub.GetStateByPartialCompositeKey("Class", nil)
+	require.NoError(t, err)
+	require.Equal(t, []string{compositeKey}, keys(t, classes))
+	objectType, attributes, err := stub.SplitCompositeKey(compositeKey)
+	require.NoError(t, err)
+	require.Equal(t, "Class", objectType)
+	require.Equal(t, []string{"cs101"}, attributes)
+
+	require.NoError(t, stub.DelState("a"))
+	stub.Commit()
+	value, err = stub.GetState("a")
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	_, err = stub.GetQueryResult(`{"selector":{}}`)
+	require.Error(t, err)
+}
+
+func TestHistoryAndTimestamps(t *testing.T) {
+	stub := simulator.NewStub("mychannel")
+	first := time.Date(2023, time.April, 1, 9, 0, 0, 0, time.UTC)
+
+	stub.StartTransaction(first)
+	require.NoError(t, stub.PutState("hw1alice", []byte("v1")))
+	firstTxID := stub.GetTxID()
+	stub.StartTransaction(first.Add(time.Hour))
+	require.NoError(t, stub.PutState("hw1alice", []byte("v2")))
+	stub.StartTransaction(first.Add(2 * time.Hour))
+	require.NoError(t, stub.DelState("hw1alice"))
+	stub.Commit()
+
+	timestamp, err := stub.GetTxTimestamp()
+	require.NoError(t, err)
+	require.Equal(t, first.Add(2*time.Hour), timestamp.AsTime())
+
+	history, err := stub.GetHistoryForKey("hw1alice")
+	require.NoError(t, err)
+	var values []string
+	var deletes []bool
+	var lastTxID string
+	for history.HasNext() {
+		modification, err := history.Next()
+		require.NoError(t, err)
+		values = append(values, string(modification.Value))
+		deletes = append(deletes, modification.IsDelete)
+		lastTxID = modification.TxId
+	}
+	require.Equal(t, []string{"", "v2", "v1"}, values)
+	require.Equal(t, []bool{true, false, false}, deletes)
+	require.Equal(t, firstTxID, lastTxID)
+}
+
+func TestPrivateDataAndEvents(t *testing.T) {
+	stub := simulator.NewStub("mychannel")
+	require.NoError(t, stub.PutPrivateData("grades", "hw1alice", []byte("90")))
+	value, err := stub.GetPrivateData("grades", "hw1alice")
+	require.NoError(t, err)
+	require.Nil(t, value, "a transaction must not read its own writes")
+	stub.Commit()
+
+	value, err = stub.GetPrivateData("grades", "hw1alice")
+	require.NoError(t, err)
+	require.Equal(t, []byte("90"), value)
+	hash, err := stub.GetPrivateDataHash("grades", "hw1alice")
+	require.NoError(t, err)
+	require.Len(t, hash, 32)
+	public, err := stub.GetState("hw1alice")
+	require.NoError(t, err)
+	require.Nil(t, public, "private data must not leak into the world state")
+
+	inRange, err := stub.GetPrivateDataByRange("grades", "", "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"hw1alice"}, keys(t, inRange))
+
+	require.NoError(t, stub.PurgePrivateData("grades", "hw1alice"))
+	stub.Commit()
+	value, err = stub.GetPrivateData("grades", "hw1alice")
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	require.NoError(t, stub.SetEvent("First", []byte("1")))
+	require.NoError(t, stub.SetEvent("Second", []byte("2")))
+	stub.StartTransaction(time.Now())
+	require.NoError(t, stub.SetEvent("Third", []byte("3")))
+
+	events := stub.Events()
+	require.Len(t, events, 2)
+	require.Equal(t, "Second", events[0].Name)
+	require.Equal(t, "Third", events[1].Name)
+	require.NotEqual(t, events[0].TxID, events[1].TxID)
+}
+
+func TestRollback(t *testing.T) {
+	stub := simulator.NewStub("mychannel")
+	require.NoError(t, stub.PutState("hw1alice", []byte("v1")))
+	require.NoError(t, stub.SetEvent("Created", nil))
+
+	stub.StartTransaction(time.Now())
+	require.NoError(t, stub.PutState("hw1alice", []byte("v2")))
+	require.NoError(t, stub.PutState("hw1bob", []byte("v1")))
+	require.NoError(t, stub.PutPrivateData("grades", "hw1alice", []byte("90")))
+	require.NoError(t, stub.SetEvent("Updated", nil))
+	stub.Rollback()
+
+	value, err := stub.GetState("hw1alice")
+	require.NoError(t, err)
+	require.Equal(t, []byte("v1"), value)
+	value, err = stub.GetState("hw1bob")
+	require.NoError(t, err)
+	require.Nil(t, value)
+	value, err = stub.GetPrivateData("grades", "hw1alice")
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	history, err := stub.GetHistoryForKey("hw1alice")
+	require.NoError(t, err)
+	var values []string
+	for history.HasNext() {
+		modification, err := history.Next()
+		require.NoError(t, err)
+		values = append(values, string(modification.Value))
+	}
+	require.Equal(t, []string{"v1"}, values)
+
+	events := stub.Events()
+	require.Len(t, events, 1)
+	require.Equal(t, "Created", events[0].Name)
+}
+
+func TestClientIdentity(t *testing.T) {
+	identity := simulator.NewClientIdentity("Org1MSP", "x509::CN=instructor")
+	identity.Attributes["role"] = "instructor"
+
+	require.NoError(t, identity.AssertAttributeValue("role", "instructor"))
+	require.Error(t, identity.AssertAttributeValue("role", "student"))
+	require.Error(t, identity.AssertAttributeValue("department", "cs"))
+
+	sim := simulator.New("mychannel")
+	ctx := sim.Transaction(identity)
+	id, err := ctx.GetClientIdentity().GetID()
+	require.NoError(t, err)
+	require.Equal(t, "x509::CN=instructor", id)
+	creator, err := ctx.GetStub().GetCreator()
+	require.NoError(t, err)
+	require.NotEmpty(t, creator)
+
+	firstTxID := ctx.GetStub().GetTxID()
+	require.NotEqual(t, firstTxID, sim.Transaction(identity).GetStub().GetTxID())
+}
+
+func TestInvoke(t *testing.T) {
+	identity, err := simulator.NewX509ClientIdentity("Org1MSP", "alice")
+	require.NoError(t, err)
+	require.NotEmpty(t, identity.ID)
+
+	sim := simulator.New("mychannel")
+	chaincode := shim.Chaincode(invokeFunc(func(stub shim.ChaincodeStubInterface) peer.Response {
+		function, args := stub.GetFunctionAndParameters()
+		if err := stub.PutState(args[0], []byte(function)); err != nil {
+			return shim.Error(err.Error())
+		}
+		if function == "fail" {
+			return shim.Error("rejected")
+		}
+		return shim.Success([]byte(function))
+	}))
+
+	response := sim.Invoke(chaincode, identity, "put", "hw1alice")
+	require.Equal(t, int32(shim.OK), response.Status)
+	require.Equal(t, []byte("put"), response.Payload)
+
+	response = sim.Invoke(chaincode, identity, "fail", "hw1alice")
+	require.Equal(t, "rejected", response.Message)
+	value, err := sim.Stub.GetState("hw1alice")
+	require.NoError(t, err)
+	require.Equal(t, []byte("put"), value, "a rejected transaction must not change the world state")
+
+	ctx := sim.Transaction(identity)
+	id, err := ctx.GetClientIdentity().GetID()
+	require.NoError(t, err)
+	require.Equal(t, identity.ID, id)
+}
+
+// invokeFunc is a shim.Chaincode that handles every invocation with the function.
+type invokeFunc func(stub shim.ChaincodeStubInterface) peer.Response
+
+func (f invokeFunc) Init(stub shim.ChaincodeStubInterface) peer.Response {
+	return shim.Success(nil)
+}
+
+func (f invokeFunc) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
+	return f(stub)
+}
@@ -2,11 +2,27 @@ package chaincode
 
 import (
 	"encoding/json"
-	"fmt"
+	"regexp"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
+// WorkSubmittedEvent is the chaincode event emitted with the asset JSON whenever work is submitted
+const WorkSubmittedEvent = "WorkSubmitted"
+
+// Chaincode events emitted with a GradingEvent payload, so clients can reload what they show
+const (
+	// GradeChangedEvent is emitted when a grade or member adjustment is given, approved or rejected
+	GradeChangedEvent = "GradeChanged"
+	// GradesReleasedEvent is emitted when the grades of an assignment are released
+	GradesReleasedEvent = "GradesReleased"
+	// GroupChangedEvent is emitted when the members of a group change
+	GroupChangedEvent = "GroupChanged"
+)
+
+// sha256Pattern matches a lower-case hex SHA-256 digest
+var sha256Pattern = regexp.MustCompile("^[0-9a-f]{64}$")
+
 // SmartContract provides functions for managing an Asset
 type SmartContract struct {
 	contractapi.Contract
@@ -30,9 +46,34 @@ type Asset struct {
 	Grade        int    `json:"Grade"`
 	ID           string `json:"ID"`
 	InstructorID string `json:"InstructorID"`
-	Work         string `json:"Work"`
-	Owner        string `json:"Owner"`
-	ClassID      string `json:"ClassID"`
+	// StudentID is the student an assignment created with CreateAssignment was made for
+	StudentID string `json:"StudentID,omitempty" metadata:"StudentID,optional"`
+	// GroupID is the group a group assignment created with CreateGroupAssignment was made for
+	GroupID       string `json:"GroupID,omitempty" metadata:"GroupID,optional"`
+	Work          string `json:"Work"`
+	Owner         string `json:"Owner"`
+	ClassID       string `json:"ClassID"`
+	Feedback      string `json:"Feedback"`
+	Released      bool   `json:"Released"`
+	TestSuiteHash string `json:"TestSuiteHash"`
+	// MaxGrade is the grade awarded for full credit, set with SetMaxGrade; 0 means the default of 100
+	MaxGrade   int    `json:"MaxGrade,omitempty" metadata:"MaxGrade,optional"`
+	ModifiedBy string `json:"ModifiedBy"`
+	// GradedBy is the client that gave the current grade, the instructor or one of their teaching assistants
+	GradedBy string `json:"GradedBy,omitempty" metadata:"GradedBy,optional"`
+	// PendingGrade is a teaching assistant's grade waiting for the instructor's approval
+	PendingGrade *PendingGrade `json:"PendingGrade,omitempty" metadata:"PendingGrade,optional"`
+	// Attachments are stored off-chain; only their content hash and size are kept on the ledger
+	Attachments []Attachment `json:"Attachments,omitempty" metadata:"Attachments,optional"`
+	// Adjustments move individual members' grades on a group assignment away from the group's Grade
+	Adjustments []GradeAdjustment `json:"Adjustments,omitempty" metadata:"Adjustments,optional"`
+}
+
+// Attachment describes a file submitted with an assignment and kept in off-chain content-addressed storage
+type Attachment struct {
+	Name   string `json:"Name"`
+	SHA256 string `json:"SHA256"`
+	Size   int64  `json:"Size"`
 }
 
 // InitLedger adds a base set of assets to the ledger
@@ -70,190 +111,415 @@ func (s *SmartContract) InitLedger(ctx contractapi.TransactionContextInterface)
 	return nil
 }
 
-// CreateAsset issues a new asset to the world state with given details.
+// CreateAsset issues a new asset to the world state with given details. The ID, title, owner and class are
+// required, and the due date, if set, must be in one of the accepted layouts. Like the first assignment of a
+// class, the asset binds an unclaimed class to its creator, and only the class's instructor may add assets to
+// a claimed one. Assignments for students should be created with CreateAssignment, which generates their ID.
 func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface, id string, title string, grade int, owner string, date string, description string, class string) error {
-	exists, err := s.AssetExists(ctx, id)
-	if err != nil {
-		return err
-	}
-	if exists {
-		return fmt.Errorf("the asset %s already exists", id)
-	}
+	return idempotentError(ctx, func() error {
+		asset := Asset{
+			ID:           id,
+			Title:        title,
+			Date:         date,
+			Description:  description,
+			InstructorID: owner,
+			Grade:        grade,
+			Work:         "",
+			Owner:        owner,
+			ClassID:      class,
+		}
+		err := validateAsset(&asset)
+		if err != nil {
+			return err
+		}
 
-	// asset := Asset{
-	// 	ID:             id,
-	// 	Color:          color,
-	// 	Size:           size,
-	// 	Owner:          owner,
-	// 	AppraisedValue: appraisedValue,
-	// }
+		exists, err := s.AssetExists(ctx, id)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return assetAlreadyExists(id)
+		}
 
-	asset := Asset{
-		ID:           id,
-		Title:        title,
-		Date:         date,
-		Description:  description,
-		InstructorID: owner,
-		Grade:        grade,
-		Work:         "",
-		Owner:        owner,
-		ClassID:      class,
-	}
+		// asset := Asset{
+		// 	ID:             id,
+		// 	Color:          color,
+		// 	Size:           size,
+		// 	Owner:          owner,
+		// 	AppraisedValue: appraisedValue,
+		// }
 
-	assetJSON, err := json.Marshal(asset)
-	if err != nil {
-		return err
-	}
+		asset.ModifiedBy, err = submittingClientID(ctx)
+		if err != nil {
+			return err
+		}
+		err = s.claimClass(ctx, class, asset.ModifiedBy)
+		if err != nil {
+			return err
+		}
 
-	return ctx.GetStub().PutState(id, assetJSON)
+		return putAsset(ctx, &asset)
+	})
 }
 
 // ReadAsset returns the asset stored in the world state with given id.
 func (s *SmartContract) ReadAsset(ctx contractapi.TransactionContextInterface, id string) (*Asset, error) {
 	assetJSON, err := ctx.GetStub().GetState(id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
+		return nil, internalError("failed to read from world state", err)
 	}
 	if assetJSON == nil {
-		return nil, fmt.Errorf("the asset %s does not exist", id)
+		return nil, assetNotFound(id)
 	}
 
 	var asset Asset
 	err = json.Unmarshal(assetJSON, &asset)
 	if err != nil {
-		return nil, err
+		return nil, internalError("failed to parse asset", err)
 	}
 
 	return &asset, nil
 }
 
-// UpdateAsset updates an existing asset in the world state with provided parameters.
+// UpdateAsset updates the given fields of an existing asset in the world state. Empty strings and a
+// negative grade keep the current value, and the fields UpdateAsset has no parameter for, such as the
+// submitted work and the instructor, are left as they are. Only the instructor of the asset's class may
+// update it, and moving it to another class needs them to be that class's instructor too.
 func (s *SmartContract) UpdateAsset(ctx contractapi.TransactionContextInterface, id string, title string, grade int, owner string, date string, description string, class string) error {
-	exists, err := s.AssetExists(ctx, id)
-	if err != nil {
-		return err
-	}
-	if !exists {
-		return fmt.Errorf("the asset %s does not exist", id)
-	}
+	return idempotentError(ctx, func() error {
+		asset, err := s.ReadAsset(ctx, id)
+		if err != nil {
+			return err
+		}
+		if _, err := s.instructorClass(ctx, asset.ClassID); err != nil {
+			return err
+		}
 
-	// overwriting original asset with new asset
-	// asset := Asset{
-	// 	ID:             id,
-	// 	Color:          color,
-	// 	Size:           size,
-	// 	Owner:          owner,
-	// 	AppraisedValue: appraisedValue,
-	// }
-	asset := Asset{
-		ID:           id,
-		Title:        title,
-		Date:         date,
-		Description:  description,
-		Work:         "",
-		InstructorID: "",
-		Grade:        grade,
-		Owner:        owner,
-		ClassID:      class,
-	}
-	assetJSON, err := json.Marshal(asset)
-	if err != nil {
-		return err
-	}
+		if title != "" {
+			if err := validateTitle(title); err != nil {
+				return err
+			}
+			asset.Title = title
+		}
+		if grade >= 0 {
+			asset.Grade = grade
+		}
+		if owner != "" {
+			if err := validateName("owner", "owner", owner); err != nil {
+				return err
+			}
+			asset.Owner = owner
+		}
+		if date != "" {
+			if err := validateDate(date); err != nil {
+				return err
+			}
+			asset.Date = date
+		}
+		if description != "" {
+			if err := validateDescription(description); err != nil {
+				return err
+			}
+			asset.Description = description
+		}
+		asset.ModifiedBy, err = submittingClientID(ctx)
+		if err != nil {
+			return err
+		}
+		if class != "" && class != asset.ClassID {
+			if err := validateName("class", "class ID", class); err != nil {
+				return err
+			}
+			if err := s.claimClass(ctx, class, asset.ModifiedBy); err != nil {
+				return err
+			}
+			asset.ClassID = class
+		}
 
-	return ctx.GetStub().PutState(id, assetJSON)
+		return putAsset(ctx, asset)
+	})
 }
 
-// DeleteAsset deletes an given asset from the world state.
+// DeleteAsset deletes an given asset from the world state. Only the instructor of the asset's class may
+// delete it.
 func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface, id string) error {
-	exists, err := s.AssetExists(ctx, id)
-	if err != nil {
-		return err
-	}
-	if !exists {
-		return fmt.Errorf("the asset %s does not exist", id)
-	}
+	return idempotentError(ctx, func() error {
+		asset, err := s.ReadAsset(ctx, id)
+		if err != nil {
+			return err
+		}
+		if _, err := s.instructorClass(ctx, asset.ClassID); err != nil {
+			return err
+		}
+
+		err = ctx.GetStub().DelState(id)
+		if err != nil {
+			return internalError("failed to delete from world state", err)
+		}
 
-	return ctx.GetStub().DelState(id)
+		return nil
+	})
 }
 
 // AssetExists returns true when asset with given ID exists in world state
 func (s *SmartContract) AssetExists(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
 	assetJSON, err := ctx.GetStub().GetState(id)
 	if err != nil {
-		return false, fmt.Errorf("failed to read from world state: %v", err)
+		return false, internalError("failed to read from world state", err)
 	}
 
 	return assetJSON != nil, nil
 }
 
 // TransferAsset updates the owner field of asset with given id in world state, and returns the old owner.
+// The instructor of the asset's class may hand it to anyone; its holder may only hand it back to its
+// instructor.
 func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterface, id string, newOwner string) (string, error) {
-	asset, err := s.ReadAsset(ctx, id)
-	if err != nil {
-		return "", err
-	}
+	return idempotent(ctx, func() (string, error) {
+		asset, err := s.ReadAsset(ctx, id)
+		if err != nil {
+			return "", err
+		}
 
-	oldOwner := asset.Owner
-	asset.Owner = newOwner
+		allowed, err := s.isInstructor(ctx, asset.ClassID)
+		if err != nil {
+			return "", err
+		}
+		if !allowed && newOwner == asset.InstructorID {
+			allowed, err = s.actsAsHolder(ctx, asset)
+			if err != nil {
+				return "", err
+			}
+		}
+		if !allowed {
+			return "", newContractError(ErrForbidden, map[string]string{"class": asset.ClassID, "id": id},
+				"only the instructor of class %s may transfer the asset %s, or its holder return it", asset.ClassID, id)
+		}
 
-	assetJSON, err := json.Marshal(asset)
-	if err != nil {
-		return "", err
-	}
+		modifiedBy, err := submittingClientID(ctx)
+		if err != nil {
+			return "", err
+		}
 
-	err = ctx.GetStub().PutState(id, assetJSON)
-	if err != nil {
-		return "", err
-	}
+		oldOwner := asset.Owner
+		asset.Owner = newOwner
+		asset.ModifiedBy = modifiedBy
 
-	return oldOwner, nil
+		err = putAsset(ctx, asset)
+		if err != nil {
+			return "", err
+		}
+
+		return oldOwner, nil
+	})
 }
 
-// TransferAsset updates the owner field of asset with given id in world state, and returns the old owner.
-func (s *SmartContract) GradeAssignment(ctx contractapi.TransactionContextInterface, id string, grade int) (int, error) {
-	asset, err := s.ReadAsset(ctx, id)
-	if err != nil {
-		return -1, err
-	}
+// GradeAssignment records the grade and feedback for the submission with given id, and returns the old grade.
+// Only the instructor of the class and the teaching assistants assigned to the submission may grade it, so a
+// class that predates class records must be claimed with ClaimClass first. A teaching assistant's grade is
+// kept as pending when the class requires approval.
+func (s *SmartContract) GradeAssignment(ctx contractapi.TransactionContextInterface, id string, grade int, feedback string) (int, error) {
+	return idempotent(ctx, func() (int, error) {
+		asset, err := s.ReadAsset(ctx, id)
+		if err != nil {
+			return -1, err
+		}
 
-	oldGrade := asset.Grade
-	asset.Grade = grade
+		modifiedBy, pending, err := s.authorizeGrading(ctx, asset)
+		if err != nil {
+			return -1, err
+		}
 
-	assetJSON, err := json.Marshal(asset)
-	if err != nil {
-		return -1, err
-	}
+		oldGrade := asset.Grade
+		asset.ModifiedBy = modifiedBy
+		if pending {
+			// Member adjustments already waiting for approval stay with the new grade
+			var adjustments []GradeAdjustment
+			if asset.PendingGrade != nil {
+				adjustments = asset.PendingGrade.Adjustments
+			}
+			asset.PendingGrade = &PendingGrade{Grade: grade, Feedback: feedback, GradedBy: modifiedBy, TxID: ctx.GetStub().GetTxID(), Adjustments: adjustments}
+		} else {
+			asset.Grade = grade
+			asset.Feedback = feedback
+			asset.GradedBy = modifiedBy
+			asset.PendingGrade = nil
+		}
 
-	err = ctx.GetStub().PutState(id, assetJSON)
-	if err != nil {
-		return -1, err
-	}
+		err = putAsset(ctx, asset)
+		if err != nil {
+			return -1, err
+		}
+		err = assetGradeChanged(ctx, asset)
+		if err != nil {
+			return -1, err
+		}
 
-	return oldGrade, nil
+		return oldGrade, nil
+	})
 }
 
-// TransferAsset updates the owner field of asset with given id in world state, and returns the old owner.
+// SubmitAssignment records the work for the assignment with given id and emits a WorkSubmitted event. Like
+// AttachFile, only the instructor of the class, the holder of the assignment and the student or group
+// members it was made for may submit. Work can not change once its grade is released.
 func (s *SmartContract) SubmitAssignment(ctx contractapi.TransactionContextInterface, id string, work string) error {
-	asset, err := s.ReadAsset(ctx, id)
-	if err != nil {
-		return err
-	}
+	return idempotentError(ctx, func() error {
+		asset, err := s.ReadAsset(ctx, id)
+		if err != nil {
+			return err
+		}
 
-	// oldGrade := asset.Grade
-	asset.Work = work
+		allowed, err := s.worksOn(ctx, asset)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return newContractError(ErrForbidden, map[string]string{"id": id},
+				"only the holder or student of the assignment %s may submit work for it", id)
+		}
+		if asset.Released {
+			return newContractError(ErrConflict, map[string]string{"id": id},
+				"the grade of the assignment %s is released, so its work can not change", id)
+		}
 
-	assetJSON, err := json.Marshal(asset)
-	if err != nil {
-		return err
-	}
+		modifiedBy, err := submittingClientID(ctx)
+		if err != nil {
+			return err
+		}
 
-	err = ctx.GetStub().PutState(id, assetJSON)
-	if err != nil {
-		return err
-	}
+		// oldGrade := asset.Grade
+		asset.Work = work
+		asset.ModifiedBy = modifiedBy
 
-	return nil
+		assetJSON, err := json.Marshal(asset)
+		if err != nil {
+			return internalError("failed to encode asset", err)
+		}
+
+		err = ctx.GetStub().PutState(id, assetJSON)
+		if err != nil {
+			return internalError("failed to write to world state", err)
+		}
+
+		err = ctx.GetStub().SetEvent(WorkSubmittedEvent, assetJSON)
+		if err != nil {
+			return internalError("failed to set event", err)
+		}
+
+		return nil
+	})
+}
+
+// ReleaseGrades marks every submission of the titled assignment in a class as released to students. Only
+// the instructor of the class may release its grades.
+func (s *SmartContract) ReleaseGrades(ctx contractapi.TransactionContextInterface, class string, title string) error {
+	return idempotentError(ctx, func() error {
+		if _, err := s.instructorClass(ctx, class); err != nil {
+			return err
+		}
+		err := updateAssignment(ctx, class, title, func(asset *Asset) bool {
+			if asset.Released {
+				return false
+			}
+			asset.Released = true
+			return true
+		})
+		if err != nil {
+			return err
+		}
+
+		return setGradingEvent(ctx, GradesReleasedEvent, &GradingEvent{ClassID: class, Title: title})
+	})
+}
+
+// SetTestSuiteHash records the content hash of the autograder test suite on every copy of the titled
+// assignment in a class, so students can verify which tests graded them. Only the instructor of the class
+// may publish its test suites.
+func (s *SmartContract) SetTestSuiteHash(ctx contractapi.TransactionContextInterface, class string, title string, hash string) error {
+	return idempotentError(ctx, func() error {
+		if _, err := s.instructorClass(ctx, class); err != nil {
+			return err
+		}
+		return updateAssignment(ctx, class, title, func(asset *Asset) bool {
+			if asset.TestSuiteHash == hash {
+				return false
+			}
+			asset.TestSuiteHash = hash
+			return true
+		})
+	})
+}
+
+// SetMaxGrade sets the grade awarded for full credit on every copy of an assignment in a class. Only the
+// instructor of the class may set it.
+func (s *SmartContract) SetMaxGrade(ctx contractapi.TransactionContextInterface, class string, title string, maxGrade int) error {
+	return idempotentError(ctx, func() error {
+		if maxGrade <= 0 {
+			return validationError("maxGrade", "the maximum grade must be positive")
+		}
+		if _, err := s.instructorClass(ctx, class); err != nil {
+			return err
+		}
+		return updateAssignment(ctx, class, title, func(asset *Asset) bool {
+			if asset.MaxGrade == maxGrade {
+				return false
+			}
+			asset.MaxGrade = maxGrade
+			return true
+		})
+	})
+}
+
+// AttachFile records the hash and size of a file submitted with the assignment with given id, replacing
+// any earlier attachment with the same name. Only the instructor of the class, the holder of the assignment
+// and the student or group members it was made for may attach files to it. Students are recognized by the
+// common name of their certificate or by a username the instructor registered with RegisterStudent.
+func (s *SmartContract) AttachFile(ctx contractapi.TransactionContextInterface, id string, name string, hash string, size int64) error {
+	return idempotentError(ctx, func() error {
+		if name == "" {
+			return validationError("name", "the attachment name must not be empty")
+		}
+		if !sha256Pattern.MatchString(hash) {
+			return validationError("hash", "the attachment hash %s is not a hex SHA-256 digest", hash)
+		}
+		if size < 0 {
+			return validationError("size", "the attachment size must not be negative")
+		}
+
+		asset, err := s.ReadAsset(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		allowed, err := s.worksOn(ctx, asset)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return newContractError(ErrForbidden, map[string]string{"id": id},
+				"only the holder or student of the assignment %s may attach files to it", id)
+		}
+
+		modifiedBy, err := submittingClientID(ctx)
+		if err != nil {
+			return err
+		}
+
+		attachment := Attachment{Name: name, SHA256: hash, Size: size}
+		replaced := false
+		for i := range asset.Attachments {
+			if asset.Attachments[i].Name == name {
+				asset.Attachments[i] = attachment
+				replaced = true
+			}
+		}
+		if !replaced {
+			asset.Attachments = append(asset.Attachments, attachment)
+		}
+		asset.ModifiedBy = modifiedBy
+
+		return putAsset(ctx, asset)
+	})
 }
 
 // GetAllAssets returns all assets found in world state
@@ -262,7 +528,7 @@ func (s *SmartContract) GetAllAssets(ctx contractapi.TransactionContextInterface
 	// open-ended query of all assets in the chaincode namespace.
 	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
 	if err != nil {
-		return nil, err
+		return nil, internalError("failed to read from world state", err)
 	}
 	defer resultsIterator.Close()
 
@@ -270,13 +536,13 @@ func (s *SmartContract) GetAllAssets(ctx contractapi.TransactionContextInterface
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
 		if err != nil {
-			return nil, err
+			return nil, internalError("failed to read from world state", err)
 		}
 
 		var asset Asset
 		err = json.Unmarshal(queryResponse.Value, &asset)
 		if err != nil {
-			return nil, err
+			return nil, internalError("failed to parse asset", err)
 		}
 		if asset.InstructorID == username && asset.Owner == username && asset.ClassID == class {
 			assets = append(assets, &asset)
@@ -293,23 +559,28 @@ func (s *SmartContract) GetAllClasses(ctx contractapi.TransactionContextInterfac
 	// open-ended query of all assets in the chaincode namespace.
 	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
 	if err != nil {
-		return nil, err
+		return nil, internalError("failed to read from world state", err)
 	}
 	defer resultsIterator.Close()
 
+	groups := newGroupMembers(ctx)
 	classes := map[string]int{}
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
 		if err != nil {
-			return nil, err
+			return nil, internalError("failed to read from world state", err)
 		}
 
 		var asset Asset
 		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return nil, internalError("failed to parse asset", err)
+		}
+		assignee, err := groups.isAssignee(&asset, username)
 		if err != nil {
 			return nil, err
 		}
-		if asset.InstructorID == username || asset.ID[len(asset.ID)-len(username):] == username {
+		if asset.InstructorID == username || assignee {
 			classes[asset.ClassID] = 1
 		}
 	}
@@ -327,23 +598,31 @@ func (s *SmartContract) GetAllAssignments(ctx contractapi.TransactionContextInte
 	// open-ended query of all assets in the chaincode namespace.
 	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
 	if err != nil {
-		return nil, err
+		return nil, internalError("failed to read from world state", err)
 	}
 	defer resultsIterator.Close()
 
+	groups := newGroupMembers(ctx)
 	var assets []*Asset
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
 		if err != nil {
-			return nil, err
+			return nil, internalError("failed to read from world state", err)
 		}
 
 		var asset Asset
 		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return nil, internalError("failed to parse asset", err)
+		}
+		if asset.ClassID != class {
+			continue
+		}
+		holds, err := groups.holds(&asset, username)
 		if err != nil {
 			return nil, err
 		}
-		if asset.Owner == username && asset.ClassID == class {
+		if holds {
 			assets = append(assets, &asset)
 		}
 	}
@@ -356,26 +635,178 @@ func (s *SmartContract) GetSubmittedAssignments(ctx contractapi.TransactionConte
 	// open-ended query of all assets in the chaincode namespace.
 	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
 	if err != nil {
-		return nil, err
+		return nil, internalError("failed to read from world state", err)
 	}
 	defer resultsIterator.Close()
 
+	groups := newGroupMembers(ctx)
 	var assets []*Asset
 	for resultsIterator.HasNext() {
 		queryResponse, err := resultsIterator.Next()
 		if err != nil {
-			return nil, err
+			return nil, internalError("failed to read from world state", err)
 		}
 
 		var asset Asset
 		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return nil, internalError("failed to parse asset", err)
+		}
+		if asset.ClassID != class {
+			continue
+		}
+		assignee, err := groups.isAssignee(&asset, username)
+		if err != nil {
+			return nil, err
+		}
+		holds, err := groups.holds(&asset, username)
 		if err != nil {
 			return nil, err
 		}
-		if asset.ID[len(asset.ID)-len(username):] == username && asset.Owner != username && asset.ClassID == class {
+		if assignee && !holds {
+			assets = append(assets, &asset)
+		}
+	}
+
+	return assets, nil
+}
+
+// GetAssignmentSubmissions returns every submitted piece of work for the titled assignment in a class
+func (s *SmartContract) GetAssignmentSubmissions(ctx contractapi.TransactionContextInterface, class string, title string) ([]*Asset, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return nil, internalError("failed to read from world state", err)
+	}
+	defer resultsIterator.Close()
+
+	assets := []*Asset{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, internalError("failed to read from world state", err)
+		}
+
+		var asset Asset
+		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return nil, internalError("failed to parse asset", err)
+		}
+		if asset.ClassID == class && asset.Title == title && asset.Work != "" {
 			assets = append(assets, &asset)
 		}
 	}
 
 	return assets, nil
 }
+
+// submittingClientID returns the identity of the client that submitted the current transaction.
+func submittingClientID(ctx contractapi.TransactionContextInterface) (string, error) {
+	id, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", internalError("failed to get client identity", err)
+	}
+
+	return id, nil
+}
+
+// submittingUsername returns the username of the submitting client, the common name of its certificate, or
+// "" if it has none.
+func submittingUsername(ctx contractapi.TransactionContextInterface) (string, error) {
+	certificate, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return "", internalError("failed to get client certificate", err)
+	}
+	if certificate == nil {
+		return "", nil
+	}
+
+	return certificate.Subject.CommonName, nil
+}
+
+// GradingEvent is the payload of the events that tell clients what changed. Every client of the channel
+// receives it, so it names the assignment or group but never carries a grade.
+type GradingEvent struct {
+	ID        string `json:"ID,omitempty"`
+	ClassID   string `json:"ClassID"`
+	Title     string `json:"Title,omitempty"`
+	StudentID string `json:"StudentID,omitempty"`
+	GroupID   string `json:"GroupID,omitempty"`
+}
+
+// setGradingEvent sets the chaincode event of the transaction.
+func setGradingEvent(ctx contractapi.TransactionContextInterface, name string, event *GradingEvent) error {
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return internalError("failed to encode event", err)
+	}
+	err = ctx.GetStub().SetEvent(name, eventJSON)
+	if err != nil {
+		return internalError("failed to set event", err)
+	}
+
+	return nil
+}
+
+// assetGradeChanged sets the GradeChanged event for an assignment.
+func assetGradeChanged(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	return setGradingEvent(ctx, GradeChangedEvent, &GradingEvent{
+		ID:        asset.ID,
+		ClassID:   asset.ClassID,
+		Title:     asset.Title,
+		StudentID: asset.StudentID,
+		GroupID:   asset.GroupID,
+	})
+}
+
+// putAsset writes an asset to the world state under its ID.
+func putAsset(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return internalError("failed to encode asset", err)
+	}
+
+	err = ctx.GetStub().PutState(asset.ID, assetJSON)
+	if err != nil {
+		return internalError("failed to write to world state", err)
+	}
+
+	return nil
+}
+
+// updateAssignment applies update to every asset of the titled assignment in a class, writing back those it changes.
+func updateAssignment(ctx contractapi.TransactionContextInterface, class string, title string, update func(asset *Asset) bool) error {
+	modifiedBy, err := submittingClientID(ctx)
+	if err != nil {
+		return err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return internalError("failed to read from world state", err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return internalError("failed to read from world state", err)
+		}
+
+		var asset Asset
+		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return internalError("failed to parse asset", err)
+		}
+		if asset.ClassID != class || asset.Title != title || !update(&asset) {
+			continue
+		}
+
+		asset.ModifiedBy = modifiedBy
+		err = putAsset(ctx, &asset)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
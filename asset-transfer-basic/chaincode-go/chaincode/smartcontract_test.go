@@ -1,10 +1,14 @@
 package chaincode_test
 
 import (
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"testing"
 
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
@@ -28,6 +32,16 @@ type stateQueryIterator interface {
 	shim.StateQueryIteratorInterface
 }
 
+//go:generate counterfeiter -o mocks/historyqueryiterator.go -fake-name HistoryQueryIterator . historyQueryIterator
+type historyQueryIterator interface {
+	shim.HistoryQueryIteratorInterface
+}
+
+//go:generate counterfeiter -o mocks/clientidentity.go -fake-name ClientIdentity . clientIdentity
+type clientIdentity interface {
+	cid.ClientIdentity
+}
+
 func TestInitLedger(t *testing.T) {
 	chaincodeStub := &mocks.ChaincodeStub{}
 	transactionContext := &mocks.TransactionContext{}
@@ -36,28 +50,63 @@ func TestInitLedger(t *testing.T) {
 	assetTransfer := chaincode.SmartContract{}
 	err := assetTransfer.InitLedger(transactionContext)
 	require.NoError(t, err)
-
-	chaincodeStub.PutStateReturns(fmt.Errorf("failed inserting key"))
-	err = assetTransfer.InitLedger(transactionContext)
-	require.EqualError(t, err, "failed to put to world state. failed inserting key")
+	require.Equal(t, 0, chaincodeStub.PutStateCallCount())
 }
 
 func TestCreateAsset(t *testing.T) {
 	chaincodeStub := &mocks.ChaincodeStub{}
+	chaincodeStub.GetStateByRangeReturns(&mocks.StateQueryIterator{}, nil)
 	transactionContext := &mocks.TransactionContext{}
 	transactionContext.GetStubReturns(chaincodeStub)
+	clientIdentity := &mocks.ClientIdentity{}
+	clientIdentity.GetIDReturns("x509::CN=instructor", nil)
+	transactionContext.GetClientIdentityReturns(clientIdentity)
 
 	assetTransfer := chaincode.SmartContract{}
-	err := assetTransfer.CreateAsset(transactionContext, "", "", 0, "", 0)
+	err := assetTransfer.CreateAsset(transactionContext, "hw1alice", "hw1", 0, "instructor", "4/24/2023", "Essay", "cs101")
 	require.NoError(t, err)
+	_, assetJSON := chaincodeStub.PutStateArgsForCall(1)
+	var created chaincode.Asset
+	require.NoError(t, json.Unmarshal(assetJSON, &created))
+	require.Equal(t, "instructor", created.InstructorID)
+	require.Equal(t, "x509::CN=instructor", created.ModifiedBy)
 
 	chaincodeStub.GetStateReturns([]byte{}, nil)
-	err = assetTransfer.CreateAsset(transactionContext, "asset1", "", 0, "", 0)
-	require.EqualError(t, err, "the asset asset1 already exists")
+	err = assetTransfer.CreateAsset(transactionContext, "asset1", "hw1", 0, "instructor", "", "", "cs101")
+	requireContractError(t, err, chaincode.ErrConflict, "the asset asset1 already exists")
 
 	chaincodeStub.GetStateReturns(nil, fmt.Errorf("unable to retrieve asset"))
-	err = assetTransfer.CreateAsset(transactionContext, "asset1", "", 0, "", 0)
-	require.EqualError(t, err, "failed to read from world state: unable to retrieve asset")
+	err = assetTransfer.CreateAsset(transactionContext, "asset1", "hw1", 0, "instructor", "", "", "cs101")
+	requireContractError(t, err, chaincode.ErrInternal, "failed to read from world state: unable to retrieve asset")
+}
+
+func TestCreateAssetValidation(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	assetTransfer := chaincode.SmartContract{}
+
+	tests := []struct {
+		id, title, owner, date, class string
+		grade                         int
+		message                       string
+	}{
+		{"", "hw1", "instructor", "", "cs101", 0, "the asset ID must not be empty"},
+		{"hw1\x00alice", "hw1", "instructor", "", "cs101", 0, "the asset ID must not contain control characters"},
+		{"hw1alice", "", "instructor", "", "cs101", 0, "the title must not be empty"},
+		{"hw1alice", strings.Repeat("t", 129), "instructor", "", "cs101", 0, "the title must not be longer than 128 characters"},
+		{"hw1alice", "hw1", "", "", "cs101", 0, "the owner must not be empty"},
+		{"hw1alice", "hw1", "in structor", "", "cs101", 0, `the owner "in structor" may only contain letters, digits and the characters @ . _ -`},
+		{"hw1alice", "hw1", "instructor", "", "", 0, "the class ID must not be empty"},
+		{"hw1alice", "hw1", "instructor", "next week", "cs101", 0, `the due date "next week" must be formatted as M/D/YYYY, YYYY-MM-DD or RFC 3339`},
+		{"hw1alice", "hw1", "instructor", "", "cs101", -1, "the grade must not be negative"},
+	}
+	for _, test := range tests {
+		err := assetTransfer.CreateAsset(transactionContext, test.id, test.title, test.grade, test.owner, test.date, "", test.class)
+		requireContractError(t, err, chaincode.ErrValidation, test.message)
+	}
+	require.Equal(t, 0, chaincodeStub.GetStateCallCount())
+	require.Equal(t, 0, chaincodeStub.PutStateCallCount())
 }
 
 func TestReadAsset(t *testing.T) {
@@ -77,11 +126,11 @@ func TestReadAsset(t *testing.T) {
 
 	chaincodeStub.GetStateReturns(nil, fmt.Errorf("unable to retrieve asset"))
 	_, err = assetTransfer.ReadAsset(transactionContext, "")
-	require.EqualError(t, err, "failed to read from world state: unable to retrieve asset")
+	requireContractError(t, err, chaincode.ErrInternal, "failed to read from world state: unable to retrieve asset")
 
 	chaincodeStub.GetStateReturns(nil, nil)
 	asset, err = assetTransfer.ReadAsset(transactionContext, "asset1")
-	require.EqualError(t, err, "the asset asset1 does not exist")
+	requireContractError(t, err, chaincode.ErrNotFound, "the asset asset1 does not exist")
 	require.Nil(t, asset)
 }
 
@@ -89,66 +138,143 @@ func TestUpdateAsset(t *testing.T) {
 	chaincodeStub := &mocks.ChaincodeStub{}
 	transactionContext := &mocks.TransactionContext{}
 	transactionContext.GetStubReturns(chaincodeStub)
+	clientIdentity := &mocks.ClientIdentity{}
+	clientIdentity.GetIDReturns("x509::CN=instructor", nil)
+	transactionContext.GetClientIdentityReturns(clientIdentity)
 
-	expectedAsset := &chaincode.Asset{ID: "asset1"}
+	expectedAsset := &chaincode.Asset{ID: "asset1", Title: "hw1", Grade: 80, Owner: "instructor", InstructorID: "instructor", Work: "essay", ClassID: "cs101"}
 	bytes, err := json.Marshal(expectedAsset)
 	require.NoError(t, err)
+	classJSON, err := json.Marshal(&chaincode.Class{ClassID: "cs101", InstructorClient: "x509::CN=instructor"})
+	require.NoError(t, err)
 
-	chaincodeStub.GetStateReturns(bytes, nil)
+	chaincodeStub.CreateCompositeKeyReturns("\x00Class\x00cs101\x00", nil)
+	chaincodeStub.GetStateCalls(func(key string) ([]byte, error) {
+		if key == "\x00Class\x00cs101\x00" {
+			return classJSON, nil
+		}
+		return bytes, nil
+	})
 	assetTransfer := chaincode.SmartContract{}
-	err = assetTransfer.UpdateAsset(transactionContext, "", "", 0, "", 0)
+	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "", -1, "alice", "2023-05-01", "", "")
 	require.NoError(t, err)
+	_, assetJSON := chaincodeStub.PutStateArgsForCall(0)
+	var updated chaincode.Asset
+	require.NoError(t, json.Unmarshal(assetJSON, &updated))
+	require.Equal(t, chaincode.Asset{
+		ID:           "asset1",
+		Title:        "hw1",
+		Date:         "2023-05-01",
+		Grade:        80,
+		Owner:        "alice",
+		InstructorID: "instructor",
+		Work:         "essay",
+		ClassID:      "cs101",
+		ModifiedBy:   "x509::CN=instructor",
+	}, updated)
+
+	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "", -1, "", "someday", "", "")
+	requireContractError(t, err, chaincode.ErrValidation, `the due date "someday" must be formatted as M/D/YYYY, YYYY-MM-DD or RFC 3339`)
+
+	clientIdentity.GetIDReturns("x509::CN=alice", nil)
+	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "", 100, "", "", "", "")
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 may manage its grading")
 
 	chaincodeStub.GetStateReturns(nil, nil)
-	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "", 0, "", 0)
-	require.EqualError(t, err, "the asset asset1 does not exist")
+	chaincodeStub.GetStateCalls(nil)
+	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "", 0, "", "", "", "")
+	requireContractError(t, err, chaincode.ErrNotFound, "the asset asset1 does not exist")
 
 	chaincodeStub.GetStateReturns(nil, fmt.Errorf("unable to retrieve asset"))
-	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "", 0, "", 0)
-	require.EqualError(t, err, "failed to read from world state: unable to retrieve asset")
+	err = assetTransfer.UpdateAsset(transactionContext, "asset1", "", 0, "", "", "", "")
+	requireContractError(t, err, chaincode.ErrInternal, "failed to read from world state: unable to retrieve asset")
 }
 
 func TestDeleteAsset(t *testing.T) {
 	chaincodeStub := &mocks.ChaincodeStub{}
 	transactionContext := &mocks.TransactionContext{}
 	transactionContext.GetStubReturns(chaincodeStub)
+	clientIdentity := &mocks.ClientIdentity{}
+	clientIdentity.GetIDReturns("x509::CN=instructor", nil)
+	transactionContext.GetClientIdentityReturns(clientIdentity)
 
-	asset := &chaincode.Asset{ID: "asset1"}
+	asset := &chaincode.Asset{ID: "asset1", ClassID: "cs101"}
 	bytes, err := json.Marshal(asset)
 	require.NoError(t, err)
+	classJSON, err := json.Marshal(&chaincode.Class{ClassID: "cs101", InstructorClient: "x509::CN=instructor"})
+	require.NoError(t, err)
 
-	chaincodeStub.GetStateReturns(bytes, nil)
+	chaincodeStub.CreateCompositeKeyReturns("\x00Class\x00cs101\x00", nil)
+	chaincodeStub.GetStateCalls(func(key string) ([]byte, error) {
+		if key == "\x00Class\x00cs101\x00" {
+			return classJSON, nil
+		}
+		return bytes, nil
+	})
 	chaincodeStub.DelStateReturns(nil)
 	assetTransfer := chaincode.SmartContract{}
-	err = assetTransfer.DeleteAsset(transactionContext, "")
+	err = assetTransfer.DeleteAsset(transactionContext, "asset1")
 	require.NoError(t, err)
+	require.Equal(t, "asset1", chaincodeStub.DelStateArgsForCall(0))
 
+	clientIdentity.GetIDReturns("x509::CN=alice", nil)
+	err = assetTransfer.DeleteAsset(transactionContext, "asset1")
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 may manage its grading")
+	require.Equal(t, 1, chaincodeStub.DelStateCallCount())
+
+	chaincodeStub.GetStateCalls(nil)
 	chaincodeStub.GetStateReturns(nil, nil)
 	err = assetTransfer.DeleteAsset(transactionContext, "asset1")
-	require.EqualError(t, err, "the asset asset1 does not exist")
+	requireContractError(t, err, chaincode.ErrNotFound, "the asset asset1 does not exist")
 
 	chaincodeStub.GetStateReturns(nil, fmt.Errorf("unable to retrieve asset"))
 	err = assetTransfer.DeleteAsset(transactionContext, "")
-	require.EqualError(t, err, "failed to read from world state: unable to retrieve asset")
+	requireContractError(t, err, chaincode.ErrInternal, "failed to read from world state: unable to retrieve asset")
 }
 
 func TestTransferAsset(t *testing.T) {
 	chaincodeStub := &mocks.ChaincodeStub{}
 	transactionContext := &mocks.TransactionContext{}
 	transactionContext.GetStubReturns(chaincodeStub)
+	clientIdentity := &mocks.ClientIdentity{}
+	clientIdentity.GetIDReturns("x509::CN=instructor", nil)
+	transactionContext.GetClientIdentityReturns(clientIdentity)
 
-	asset := &chaincode.Asset{ID: "asset1"}
+	asset := &chaincode.Asset{ID: "asset1", ClassID: "cs101", InstructorID: "instructor", Owner: "alice"}
 	bytes, err := json.Marshal(asset)
 	require.NoError(t, err)
+	classJSON, err := json.Marshal(&chaincode.Class{ClassID: "cs101", InstructorClient: "x509::CN=instructor"})
+	require.NoError(t, err)
 
-	chaincodeStub.GetStateReturns(bytes, nil)
+	chaincodeStub.CreateCompositeKeyReturns("\x00Class\x00cs101\x00", nil)
+	chaincodeStub.GetStateCalls(func(key string) ([]byte, error) {
+		if key == "\x00Class\x00cs101\x00" {
+			return classJSON, nil
+		}
+		return bytes, nil
+	})
 	assetTransfer := chaincode.SmartContract{}
-	_, err = assetTransfer.TransferAsset(transactionContext, "", "")
+	oldOwner, err := assetTransfer.TransferAsset(transactionContext, "asset1", "bob")
 	require.NoError(t, err)
+	require.Equal(t, "alice", oldOwner)
+
+	// The holder may only hand the asset back to its instructor
+	clientIdentity.GetIDReturns("x509::CN=alice", nil)
+	clientIdentity.GetX509CertificateReturns(&x509.Certificate{Subject: pkix.Name{CommonName: "alice"}}, nil)
+	_, err = assetTransfer.TransferAsset(transactionContext, "asset1", "bob")
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 may transfer the asset asset1, or its holder return it")
+	oldOwner, err = assetTransfer.TransferAsset(transactionContext, "asset1", "instructor")
+	require.NoError(t, err)
+	require.Equal(t, "alice", oldOwner)
+
+	clientIdentity.GetX509CertificateReturns(&x509.Certificate{Subject: pkix.Name{CommonName: "bob"}}, nil)
+	_, err = assetTransfer.TransferAsset(transactionContext, "asset1", "instructor")
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 may transfer the asset asset1, or its holder return it")
 
+	chaincodeStub.GetStateCalls(nil)
 	chaincodeStub.GetStateReturns(nil, fmt.Errorf("unable to retrieve asset"))
 	_, err = assetTransfer.TransferAsset(transactionContext, "", "")
-	require.EqualError(t, err, "failed to read from world state: unable to retrieve asset")
+	requireContractError(t, err, chaincode.ErrInternal, "failed to read from world state: unable to retrieve asset")
 }
 
 func TestGetAllAssets(t *testing.T) {
@@ -167,18 +293,294 @@ func TestGetAllAssets(t *testing.T) {
 
 	chaincodeStub.GetStateByRangeReturns(iterator, nil)
 	assetTransfer := &chaincode.SmartContract{}
-	assets, err := assetTransfer.GetAllAssets(transactionContext)
+	assets, err := assetTransfer.GetAllAssets(transactionContext, "", "")
 	require.NoError(t, err)
 	require.Equal(t, []*chaincode.Asset{asset}, assets)
 
 	iterator.HasNextReturns(true)
 	iterator.NextReturns(nil, fmt.Errorf("failed retrieving next item"))
-	assets, err = assetTransfer.GetAllAssets(transactionContext)
-	require.EqualError(t, err, "failed retrieving next item")
+	assets, err = assetTransfer.GetAllAssets(transactionContext, "", "")
+	requireContractError(t, err, chaincode.ErrInternal, "failed to read from world state: failed retrieving next item")
 	require.Nil(t, assets)
 
 	chaincodeStub.GetStateByRangeReturns(nil, fmt.Errorf("failed retrieving all assets"))
-	assets, err = assetTransfer.GetAllAssets(transactionContext)
-	require.EqualError(t, err, "failed retrieving all assets")
+	assets, err = assetTransfer.GetAllAssets(transactionContext, "", "")
+	requireContractError(t, err, chaincode.ErrInternal, "failed to read from world state: failed retrieving all assets")
 	require.Nil(t, assets)
 }
+
+func TestReleaseGrades(t *testing.T) {
+	submission := &chaincode.Asset{ID: "hw1alice", Title: "hw1", ClassID: "cs101"}
+	submissionBytes, err := json.Marshal(submission)
+	require.NoError(t, err)
+	other := &chaincode.Asset{ID: "hw2alice", Title: "hw2", ClassID: "cs101"}
+	otherBytes, err := json.Marshal(other)
+	require.NoError(t, err)
+
+	iterator := &mocks.StateQueryIterator{}
+	iterator.HasNextReturnsOnCall(0, true)
+	iterator.HasNextReturnsOnCall(1, true)
+	iterator.HasNextReturnsOnCall(2, false)
+	iterator.NextReturnsOnCall(0, &queryresult.KV{Value: submissionBytes}, nil)
+	iterator.NextReturnsOnCall(1, &queryresult.KV{Value: otherBytes}, nil)
+
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	clientIdentity := &mocks.ClientIdentity{}
+	clientIdentity.GetIDReturns("x509::CN=instructor", nil)
+	transactionContext.GetClientIdentityReturns(clientIdentity)
+
+	classJSON, err := json.Marshal(&chaincode.Class{ClassID: "cs101", InstructorClient: "x509::CN=instructor"})
+	require.NoError(t, err)
+	chaincodeStub.GetStateReturns(classJSON, nil)
+	chaincodeStub.GetStateByRangeReturns(iterator, nil)
+	assetTransfer := chaincode.SmartContract{}
+	err = assetTransfer.ReleaseGrades(transactionContext, "cs101", "hw1")
+	require.NoError(t, err)
+	require.Equal(t, 1, chaincodeStub.PutStateCallCount())
+
+	id, bytes := chaincodeStub.PutStateArgsForCall(0)
+	require.Equal(t, "hw1alice", id)
+	var released chaincode.Asset
+	require.NoError(t, json.Unmarshal(bytes, &released))
+	require.True(t, released.Released)
+	require.Equal(t, "x509::CN=instructor", released.ModifiedBy)
+
+	clientIdentity.GetIDReturns("x509::CN=alice", nil)
+	err = assetTransfer.ReleaseGrades(transactionContext, "cs101", "hw1")
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 may manage its grading")
+
+	clientIdentity.GetIDReturns("", fmt.Errorf("no identity"))
+	err = assetTransfer.ReleaseGrades(transactionContext, "cs101", "hw1")
+	requireContractError(t, err, chaincode.ErrInternal, "failed to get client identity: no identity")
+}
+
+func TestGradeAssignment(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	clientIdentity := &mocks.ClientIdentity{}
+	clientIdentity.GetIDReturns("x509::CN=instructor", nil)
+	transactionContext.GetClientIdentityReturns(clientIdentity)
+
+	asset := &chaincode.Asset{ID: "hw1alice", ClassID: "cs101", Grade: 40}
+	bytes, err := json.Marshal(asset)
+	require.NoError(t, err)
+	classJSON, err := json.Marshal(&chaincode.Class{ClassID: "cs101", InstructorClient: "x509::CN=instructor"})
+	require.NoError(t, err)
+
+	chaincodeStub.CreateCompositeKeyReturns("\x00Class\x00cs101\x00", nil)
+	chaincodeStub.GetStateCalls(func(key string) ([]byte, error) {
+		if key == "\x00Class\x00cs101\x00" {
+			return classJSON, nil
+		}
+		return bytes, nil
+	})
+	assetTransfer := chaincode.SmartContract{}
+	oldGrade, err := assetTransfer.GradeAssignment(transactionContext, "hw1alice", 90, "well done")
+	require.NoError(t, err)
+	require.Equal(t, 40, oldGrade)
+
+	_, bytes = chaincodeStub.PutStateArgsForCall(0)
+	var graded chaincode.Asset
+	require.NoError(t, json.Unmarshal(bytes, &graded))
+	require.Equal(t, 90, graded.Grade)
+	require.Equal(t, "well done", graded.Feedback)
+
+	clientIdentity.GetIDReturns("x509::CN=alice", nil)
+	_, err = assetTransfer.GradeAssignment(transactionContext, "hw1alice", 100, "")
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 or a teaching assistant assigned to it may grade the assignment hw1alice")
+
+	chaincodeStub.GetStateReturns(nil, nil)
+	_, err = assetTransfer.GradeAssignment(transactionContext, "hw1alice", 90, "")
+	requireContractError(t, err, chaincode.ErrNotFound, "the asset hw1alice does not exist")
+}
+
+func TestSubmitAssignment(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	clientIdentity := &mocks.ClientIdentity{}
+	clientIdentity.GetIDReturns("x509::CN=alice", nil)
+	clientIdentity.GetX509CertificateReturns(&x509.Certificate{Subject: pkix.Name{CommonName: "alice"}}, nil)
+	transactionContext.GetClientIdentityReturns(clientIdentity)
+
+	asset := &chaincode.Asset{ID: "hw1alice", ClassID: "cs101", StudentID: "alice", Owner: "alice"}
+	bytes, err := json.Marshal(asset)
+	require.NoError(t, err)
+	classJSON, err := json.Marshal(&chaincode.Class{ClassID: "cs101", InstructorClient: "x509::CN=instructor"})
+	require.NoError(t, err)
+
+	chaincodeStub.CreateCompositeKeyReturns("\x00Class\x00cs101\x00", nil)
+	chaincodeStub.GetStateCalls(func(key string) ([]byte, error) {
+		if key == "\x00Class\x00cs101\x00" {
+			return classJSON, nil
+		}
+		return bytes, nil
+	})
+	assetTransfer := chaincode.SmartContract{}
+	err = assetTransfer.SubmitAssignment(transactionContext, "hw1alice", "42")
+	require.NoError(t, err)
+
+	_, stored := chaincodeStub.PutStateArgsForCall(0)
+	name, payload := chaincodeStub.SetEventArgsForCall(0)
+	require.Equal(t, chaincode.WorkSubmittedEvent, name)
+	require.Equal(t, stored, payload)
+
+	var submitted chaincode.Asset
+	require.NoError(t, json.Unmarshal(payload, &submitted))
+	require.Equal(t, "42", submitted.Work)
+
+	chaincodeStub.SetEventReturns(fmt.Errorf("event rejected"))
+	err = assetTransfer.SubmitAssignment(transactionContext, "hw1alice", "42")
+	requireContractError(t, err, chaincode.ErrInternal, "failed to set event: event rejected")
+
+	clientIdentity.GetIDReturns("x509::CN=mallory", nil)
+	clientIdentity.GetX509CertificateReturns(&x509.Certificate{Subject: pkix.Name{CommonName: "mallory"}}, nil)
+	err = assetTransfer.SubmitAssignment(transactionContext, "hw1alice", "43")
+	requireContractError(t, err, chaincode.ErrForbidden, "only the holder or student of the assignment hw1alice may submit work for it")
+}
+
+func TestSetTestSuiteHash(t *testing.T) {
+	current := &chaincode.Asset{ID: "hw1alice", Title: "hw1", ClassID: "cs101", TestSuiteHash: "abc"}
+	currentBytes, err := json.Marshal(current)
+	require.NoError(t, err)
+	stale := &chaincode.Asset{ID: "hw1bob", Title: "hw1", ClassID: "cs101", TestSuiteHash: "old"}
+	staleBytes, err := json.Marshal(stale)
+	require.NoError(t, err)
+
+	iterator := &mocks.StateQueryIterator{}
+	iterator.HasNextReturnsOnCall(0, true)
+	iterator.HasNextReturnsOnCall(1, true)
+	iterator.HasNextReturnsOnCall(2, false)
+	iterator.NextReturnsOnCall(0, &queryresult.KV{Value: currentBytes}, nil)
+	iterator.NextReturnsOnCall(1, &queryresult.KV{Value: staleBytes}, nil)
+
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	clientIdentity := &mocks.ClientIdentity{}
+	clientIdentity.GetIDReturns("x509::CN=instructor", nil)
+	transactionContext.GetClientIdentityReturns(clientIdentity)
+
+	classJSON, err := json.Marshal(&chaincode.Class{ClassID: "cs101", InstructorClient: "x509::CN=instructor"})
+	require.NoError(t, err)
+	chaincodeStub.GetStateReturns(classJSON, nil)
+	chaincodeStub.GetStateByRangeReturns(iterator, nil)
+	assetTransfer := chaincode.SmartContract{}
+	err = assetTransfer.SetTestSuiteHash(transactionContext, "cs101", "hw1", "abc")
+	require.NoError(t, err)
+	require.Equal(t, 1, chaincodeStub.PutStateCallCount())
+
+	id, bytes := chaincodeStub.PutStateArgsForCall(0)
+	require.Equal(t, "hw1bob", id)
+	var updated chaincode.Asset
+	require.NoError(t, json.Unmarshal(bytes, &updated))
+	require.Equal(t, "abc", updated.TestSuiteHash)
+
+	clientIdentity.GetIDReturns("x509::CN=autograder", nil)
+	err = assetTransfer.SetTestSuiteHash(transactionContext, "cs101", "hw1", "def")
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 may manage its grading")
+}
+
+func TestSetMaxGrade(t *testing.T) {
+	current := &chaincode.Asset{ID: "hw1alice", Title: "hw1", ClassID: "cs101", MaxGrade: 50}
+	currentBytes, err := json.Marshal(current)
+	require.NoError(t, err)
+	unset := &chaincode.Asset{ID: "hw1bob", Title: "hw1", ClassID: "cs101"}
+	unsetBytes, err := json.Marshal(unset)
+	require.NoError(t, err)
+
+	iterator := &mocks.StateQueryIterator{}
+	iterator.HasNextReturnsOnCall(0, true)
+	iterator.HasNextReturnsOnCall(1, true)
+	iterator.HasNextReturnsOnCall(2, false)
+	iterator.NextReturnsOnCall(0, &queryresult.KV{Value: currentBytes}, nil)
+	iterator.NextReturnsOnCall(1, &queryresult.KV{Value: unsetBytes}, nil)
+
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	clientIdentity := &mocks.ClientIdentity{}
+	clientIdentity.GetIDReturns("x509::CN=instructor", nil)
+	transactionContext.GetClientIdentityReturns(clientIdentity)
+
+	classJSON, err := json.Marshal(&chaincode.Class{ClassID: "cs101", InstructorClient: "x509::CN=instructor"})
+	require.NoError(t, err)
+	chaincodeStub.GetStateReturns(classJSON, nil)
+	chaincodeStub.GetStateByRangeReturns(iterator, nil)
+	assetTransfer := chaincode.SmartContract{}
+	err = assetTransfer.SetMaxGrade(transactionContext, "cs101", "hw1", 50)
+	require.NoError(t, err)
+	require.Equal(t, 1, chaincodeStub.PutStateCallCount())
+
+	id, bytes := chaincodeStub.PutStateArgsForCall(0)
+	require.Equal(t, "hw1bob", id)
+	var updated chaincode.Asset
+	require.NoError(t, json.Unmarshal(bytes, &updated))
+	require.Equal(t, 50, updated.MaxGrade)
+
+	err = assetTransfer.SetMaxGrade(transactionContext, "cs101", "hw1", 0)
+	requireContractError(t, err, chaincode.ErrValidation, "the maximum grade must be positive")
+
+	clientIdentity.GetIDReturns("x509::CN=autograder", nil)
+	err = assetTransfer.SetMaxGrade(transactionContext, "cs101", "hw1", 40)
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 may manage its grading")
+}
+
+func TestAttachFile(t *testing.T) {
+	chaincodeStub := &mocks.ChaincodeStub{}
+	transactionContext := &mocks.TransactionContext{}
+	transactionContext.GetStubReturns(chaincodeStub)
+	clientIdentity := &mocks.ClientIdentity{}
+	clientIdentity.GetIDReturns("x509::CN=alice", nil)
+	clientIdentity.GetX509CertificateReturns(&x509.Certificate{Subject: pkix.Name{CommonName: "alice"}}, nil)
+	transactionContext.GetClientIdentityReturns(clientIdentity)
+
+	hash := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	asset := &chaincode.Asset{ID: "hw1alice", ClassID: "cs101", Attachments: []chaincode.Attachment{{Name: "report.pdf", SHA256: hash, Size: 1}}}
+	bytes, err := json.Marshal(asset)
+	require.NoError(t, err)
+	classJSON, err := json.Marshal(&chaincode.Class{ClassID: "cs101", InstructorClient: "x509::CN=instructor"})
+	require.NoError(t, err)
+
+	chaincodeStub.CreateCompositeKeyReturns("\x00Class\x00cs101\x00", nil)
+	chaincodeStub.GetStateCalls(func(key string) ([]byte, error) {
+		if key == "\x00Class\x00cs101\x00" {
+			return classJSON, nil
+		}
+		return bytes, nil
+	})
+	assetTransfer := chaincode.SmartContract{}
+	err = assetTransfer.AttachFile(transactionContext, "hw1alice", "report.pdf", hash, 4)
+	require.NoError(t, err)
+	err = assetTransfer.AttachFile(transactionContext, "hw1alice", "code.zip", hash, 2048)
+	require.NoError(t, err)
+
+	var updated chaincode.Asset
+	_, stored := chaincodeStub.PutStateArgsForCall(0)
+	require.NoError(t, json.Unmarshal(stored, &updated))
+	require.Equal(t, []chaincode.Attachment{{Name: "report.pdf", SHA256: hash, Size: 4}}, updated.Attachments)
+
+	_, stored = chaincodeStub.PutStateArgsForCall(1)
+	require.NoError(t, json.Unmarshal(stored, &updated))
+	require.Len(t, updated.Attachments, 2)
+	require.Equal(t, chaincode.Attachment{Name: "code.zip", SHA256: hash, Size: 2048}, updated.Attachments[1])
+
+	err = assetTransfer.AttachFile(transactionContext, "hw1alice", "report.pdf", "not-a-hash", 4)
+	requireContractError(t, err, chaincode.ErrValidation, "the attachment hash not-a-hash is not a hex SHA-256 digest")
+	err = assetTransfer.AttachFile(transactionContext, "hw1alice", "", hash, 4)
+	requireContractError(t, err, chaincode.ErrValidation, "the attachment name must not be empty")
+	err = assetTransfer.AttachFile(transactionContext, "hw1alice", "report.pdf", hash, -1)
+	requireContractError(t, err, chaincode.ErrValidation, "the attachment size must not be negative")
+
+	clientIdentity.GetX509CertificateReturns(&x509.Certificate{Subject: pkix.Name{CommonName: "bob"}}, nil)
+	err = assetTransfer.AttachFile(transactionContext, "hw1alice", "report.pdf", hash, 4)
+	requireContractError(t, err, chaincode.ErrForbidden, "only the holder or student of the assignment hw1alice may attach files to it")
+
+	clientIdentity.GetIDReturns("x509::CN=instructor", nil)
+	clientIdentity.GetX509CertificateReturns(&x509.Certificate{Subject: pkix.Name{CommonName: "User1@org1.example.com"}}, nil)
+	err = assetTransfer.AttachFile(transactionContext, "hw1alice", "report.pdf", hash, 4)
+	require.NoError(t, err)
+}
@@ -0,0 +1,180 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// histogramBinWidth is the width in points of each bin of an assignment's grade histogram, which covers
+// 0 to the assignment's maximum grade
+const histogramBinWidth = 10
+
+// minStatsGroupSize is the fewest graded submissions whose grade statistics GetAssignmentStats shows to
+// anyone but the class's staff, since the mean or range of a few grades gives away single students' grades
+const minStatsGroupSize = 5
+
+// statsPercentiles are the percentiles reported by GetAssignmentStats
+var statsPercentiles = []int{10, 25, 75, 90}
+
+// AssignmentStats summarizes the grades of the submissions of an assignment. It holds aggregates only,
+// never a single student's grade.
+type AssignmentStats struct {
+	ClassID        string          `json:"ClassID"`
+	Title          string          `json:"Title"`
+	Assigned       int             `json:"Assigned"`
+	Count          int             `json:"Count"`
+	Ungraded       int             `json:"Ungraded"`
+	SubmissionRate float64         `json:"SubmissionRate"`
+	Mean           float64         `json:"Mean"`
+	Median         float64         `json:"Median"`
+	StdDev         float64         `json:"StdDev"`
+	Min            int             `json:"Min"`
+	Max            int             `json:"Max"`
+	Percentiles    []*Percentile   `json:"Percentiles"`
+	Histogram      []*HistogramBin `json:"Histogram"`
+	Suppressed     bool            `json:"Suppressed,omitempty" metadata:"Suppressed,optional"`
+}
+
+// Percentile is the grade below which the given percentage of submissions fall
+type Percentile struct {
+	Percentile int     `json:"Percentile"`
+	Grade      float64 `json:"Grade"`
+}
+
+// HistogramBin counts the submissions graded from Low up to, but not including, High. The last bin also
+// holds grades of High and above, and the first grades below Low.
+type HistogramBin struct {
+	Low   int `json:"Low"`
+	High  int `json:"High"`
+	Count int `json:"Count"`
+}
+
+// GetAssignmentStats returns the grade statistics of the submissions of the titled assignment in a class.
+// Count is the number of graded submissions, which alone the statistics cover, and Ungraded the number
+// of submissions not yet graded or whose grade awaits the instructor's approval. Grades written with
+// UpdateAsset record no grader, so a submission counts as graded if it has a grader or a grade other than
+// 0; a grade of 0 written that way counts as ungraded. SubmissionRate is the share of every copy handed out
+// that has work submitted, graded or not. The instructor and teaching assistants of the class may read the
+// statistics at any time; anyone else only once the grades are released, and with every figure derived
+// from the grades left out and Suppressed set while fewer than minStatsGroupSize submissions are graded.
+func (s *SmartContract) GetAssignmentStats(ctx contractapi.TransactionContextInterface, class string, title string) (*AssignmentStats, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return nil, internalError("failed to read from world state", err)
+	}
+	defer resultsIterator.Close()
+
+	stats := &AssignmentStats{ClassID: class, Title: title, Percentiles: []*Percentile{}, Histogram: []*HistogramBin{}}
+	released := true
+	top := 0
+	var grades []int
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, internalError("failed to read from world state", err)
+		}
+
+		var asset Asset
+		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return nil, internalError("failed to parse asset", err)
+		}
+		if asset.ClassID != class || asset.Title != title {
+			continue
+		}
+
+		stats.Assigned++
+		released = released && asset.Released
+		if limit := maxGrade(&asset); limit > top {
+			top = limit
+		}
+		switch {
+		case asset.Work == "":
+		case (asset.GradedBy != "" || asset.Grade != 0) && asset.PendingGrade == nil:
+			grades = append(grades, asset.Grade)
+		default:
+			stats.Ungraded++
+		}
+	}
+	if stats.Assigned == 0 {
+		return nil, newContractError(ErrNotFound, map[string]string{"class": class, "title": title}, "the assignment %s does not exist in class %s", title, class)
+	}
+
+	stats.Count = len(grades)
+	stats.SubmissionRate = float64(stats.Count+stats.Ungraded) / float64(stats.Assigned)
+	if !released || stats.Count < minStatsGroupSize {
+		staff, err := s.isClassStaff(ctx, class)
+		if err != nil {
+			return nil, err
+		}
+		if !staff && !released {
+			return nil, newContractError(ErrForbidden, map[string]string{"class": class, "title": title},
+				"the statistics of %s are available once its grades are released", title)
+		}
+		if !staff {
+			stats.Suppressed = true
+			return stats, nil
+		}
+	}
+
+	for low := 0; low < top; low += histogramBinWidth {
+		stats.Histogram = append(stats.Histogram, &HistogramBin{Low: low, High: low + histogramBinWidth})
+	}
+	if stats.Count == 0 {
+		return stats, nil
+	}
+
+	sort.Ints(grades)
+	stats.Min = grades[0]
+	stats.Max = grades[len(grades)-1]
+	sum := 0
+	for _, grade := range grades {
+		sum += grade
+		bin := grade / histogramBinWidth
+		if bin < 0 {
+			bin = 0
+		} else if bin >= len(stats.Histogram) {
+			bin = len(stats.Histogram) - 1
+		}
+		stats.Histogram[bin].Count++
+	}
+	stats.Mean = float64(sum) / float64(stats.Count)
+	variance := 0.0
+	for _, grade := range grades {
+		variance += (float64(grade) - stats.Mean) * (float64(grade) - stats.Mean)
+	}
+	stats.StdDev = math.Sqrt(variance / float64(stats.Count))
+	stats.Median = percentile(grades, 50)
+	for _, p := range statsPercentiles {
+		stats.Percentiles = append(stats.Percentiles, &Percentile{Percentile: p, Grade: percentile(grades, p)})
+	}
+
+	return stats, nil
+}
+
+// isClassStaff reports whether the submitting client is the instructor or a teaching assistant of a
+// class.
+func (s *SmartContract) isClassStaff(ctx contractapi.TransactionContextInterface, class string) (bool, error) {
+	clientID, err := submittingClientID(ctx)
+	if err != nil {
+		return false, err
+	}
+	record, err := s.boundClass(ctx, class)
+	if err != nil {
+		return false, err
+	}
+
+	return record != nil && (record.InstructorClient == clientID || findGrader(record, clientID) != nil), nil
+}
+
+// percentile returns the p-th percentile of sorted grades, interpolating linearly between neighbouring
+// grades.
+func percentile(grades []int, p int) float64 {
+	rank := float64(p) / 100 * float64(len(grades)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return float64(grades[lower]) + (rank-float64(lower))*float64(grades[upper]-grades[lower])
+}
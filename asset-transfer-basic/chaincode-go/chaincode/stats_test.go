@@ -0,0 +1,109 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/simulator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssignmentStats(t *testing.T) {
+	sim := simulator.New("mychannel")
+	instructor := simulator.NewClientIdentity("Org1MSP", "x509::CN=instructor")
+	student := simulator.NewClientIdentity("Org1MSP", "x509::CN=alice")
+	ta := simulator.NewClientIdentity("Org1MSP", "x509::CN=ta")
+	contract := chaincode.SmartContract{}
+
+	// Four students' work is graded, frank's is not, gina's grade awaits approval and erin hands in nothing
+	grades := map[string]int{"alice": 70, "bob": 80, "carol": 95, "dave": 100}
+	ids := map[string]string{}
+	for _, name := range []string{"alice", "bob", "carol", "dave", "erin", "frank", "gina"} {
+		id, err := contract.CreateAssignment(sim.Transaction(instructor), "cs101", "hw1", "instructor", name, "", "Essay")
+		require.NoError(t, err)
+		ids[name] = id
+		if name != "erin" {
+			require.NoError(t, contract.SubmitAssignment(sim.Transaction(instructor), id, "work of "+name))
+		}
+		if grade, ok := grades[name]; ok {
+			_, err = contract.GradeAssignment(sim.Transaction(instructor), id, grade, "")
+			require.NoError(t, err)
+		}
+	}
+	require.NoError(t, contract.AddTA(sim.Transaction(instructor), "cs101", "x509::CN=ta"))
+	require.NoError(t, contract.AssignGrader(sim.Transaction(instructor), "cs101", "x509::CN=ta", "hw1", []string{"gina"}))
+	require.NoError(t, contract.SetGradeApproval(sim.Transaction(instructor), "cs101", true))
+	_, err := contract.GradeAssignment(sim.Transaction(ta), ids["gina"], 0, "")
+	require.NoError(t, err)
+
+	_, err = contract.GetAssignmentStats(sim.Transaction(student), "cs101", "hw1")
+	requireContractError(t, err, chaincode.ErrForbidden, "the statistics of hw1 are available once its grades are released")
+	_, err = contract.GetAssignmentStats(sim.Transaction(instructor), "cs101", "hw2")
+	requireContractError(t, err, chaincode.ErrNotFound, "the assignment hw2 does not exist in class cs101")
+
+	// Four grades are too few to show students anything derived from them, but the staff see everything
+	require.NoError(t, contract.ReleaseGrades(sim.Transaction(instructor), "cs101", "hw1"))
+	stats, err := contract.GetAssignmentStats(sim.Transaction(student), "cs101", "hw1")
+	require.NoError(t, err)
+	require.True(t, stats.Suppressed)
+	require.Equal(t, 4, stats.Count)
+	require.Equal(t, 2, stats.Ungraded)
+	require.Zero(t, stats.Mean)
+	require.Zero(t, stats.Max)
+	require.Empty(t, stats.Percentiles)
+	require.Empty(t, stats.Histogram)
+
+	stats, err = contract.GetAssignmentStats(sim.Transaction(instructor), "cs101", "hw1")
+	require.NoError(t, err)
+	require.False(t, stats.Suppressed)
+	require.Equal(t, 7, stats.Assigned)
+	require.Equal(t, 4, stats.Count, "only approved grades count")
+	require.Equal(t, 2, stats.Ungraded)
+	require.InDelta(t, 6.0/7, stats.SubmissionRate, 1e-9)
+	require.InDelta(t, 86.25, stats.Mean, 1e-9)
+	require.InDelta(t, 87.5, stats.Median, 1e-9)
+	require.InDelta(t, 11.924, stats.StdDev, 1e-3)
+	require.Equal(t, 70, stats.Min)
+	require.Equal(t, 100, stats.Max)
+	require.Equal(t, []*chaincode.Percentile{
+		{Percentile: 10, Grade: 73},
+		{Percentile: 25, Grade: 77.5},
+		{Percentile: 75, Grade: 96.25},
+		{Percentile: 90, Grade: 98.5},
+	}, stats.Percentiles)
+
+	require.Len(t, stats.Histogram, 10)
+	counts := []int{}
+	for _, bin := range stats.Histogram {
+		counts = append(counts, bin.Count)
+	}
+	require.Equal(t, []int{0, 0, 0, 0, 0, 0, 0, 1, 1, 2}, counts, "a full-credit grade belongs in the last bin")
+
+	// A grade written with UpdateAsset has no grader but still counts, and makes the group large enough
+	require.NoError(t, contract.UpdateAsset(sim.Transaction(instructor), ids["frank"], "", 90, "", "", "", ""))
+	stats, err = contract.GetAssignmentStats(sim.Transaction(student), "cs101", "hw1")
+	require.NoError(t, err)
+	require.False(t, stats.Suppressed)
+	require.Equal(t, 5, stats.Count)
+	require.Equal(t, 1, stats.Ungraded)
+	require.InDelta(t, 87, stats.Mean, 1e-9)
+	require.Len(t, stats.Histogram, 10)
+}
+
+func TestAssignmentStatsMaxGrade(t *testing.T) {
+	sim := simulator.New("mychannel")
+	instructor := simulator.NewClientIdentity("Org1MSP", "x509::CN=instructor")
+	contract := chaincode.SmartContract{}
+
+	id, err := contract.CreateAssignment(sim.Transaction(instructor), "cs101", "quiz1", "instructor", "alice", "", "Quiz")
+	require.NoError(t, err)
+	require.NoError(t, contract.SetMaxGrade(sim.Transaction(instructor), "cs101", "quiz1", 25))
+	require.NoError(t, contract.SubmitAssignment(sim.Transaction(instructor), id, "answers"))
+	_, err = contract.GradeAssignment(sim.Transaction(instructor), id, 25, "")
+	require.NoError(t, err)
+
+	stats, err := contract.GetAssignmentStats(sim.Transaction(instructor), "cs101", "quiz1")
+	require.NoError(t, err)
+	require.Len(t, stats.Histogram, 3, "the histogram covers the assignment's maximum grade")
+	require.Equal(t, 1, stats.Histogram[2].Count)
+}
@@ -0,0 +1,158 @@
+package chaincode
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// studentObjectType is the composite key prefix under which student registrations are stored
+const studentObjectType = "Student"
+
+// Student binds a username to the client ID that acts for it in a class. Clients whose certificate is not
+// issued to the username, like the shared identity the student program signs with on the test network, act
+// for the usernames the instructor registers to them; several usernames may share a client.
+type Student struct {
+	ClassID  string `json:"ClassID"`
+	Username string `json:"Username"`
+	ClientID string `json:"ClientID"`
+}
+
+// RegisterStudent binds a username to a client ID in a class, so the client may act as that student in it.
+// Only the instructor of the class may register students, since a client can not prove on its own which
+// student it signs for; students show the instructor their client ID, as returned by GetClientID.
+// Registering a username again binds it to the new client.
+func (s *SmartContract) RegisterStudent(ctx contractapi.TransactionContextInterface, class string, username string, clientID string) error {
+	return idempotentError(ctx, func() error {
+		if err := validateName("username", "username", username); err != nil {
+			return err
+		}
+		if clientID == "" {
+			return validationError("clientID", "the client ID must not be empty")
+		}
+		if _, err := s.instructorClass(ctx, class); err != nil {
+			return err
+		}
+
+		return putStudent(ctx, &Student{ClassID: class, Username: username, ClientID: clientID})
+	})
+}
+
+// ReadStudent returns the registration of a username in a class, or nil if it is not registered.
+func (s *SmartContract) ReadStudent(ctx contractapi.TransactionContextInterface, class string, username string) (*Student, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(studentObjectType, []string{class, username})
+	if err != nil {
+		return nil, internalError("failed to create composite key", err)
+	}
+
+	recordJSON, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, internalError("failed to read from world state", err)
+	}
+	if recordJSON == nil {
+		return nil, nil
+	}
+
+	var record Student
+	err = json.Unmarshal(recordJSON, &record)
+	if err != nil {
+		return nil, internalError("failed to parse student", err)
+	}
+
+	return &record, nil
+}
+
+// putStudent writes a student registration.
+func putStudent(ctx contractapi.TransactionContextInterface, record *Student) error {
+	key, err := ctx.GetStub().CreateCompositeKey(studentObjectType, []string{record.ClassID, record.Username})
+	if err != nil {
+		return internalError("failed to create composite key", err)
+	}
+	recordJSON, err := json.Marshal(record)
+	if err != nil {
+		return internalError("failed to encode student", err)
+	}
+	err = ctx.GetStub().PutState(key, recordJSON)
+	if err != nil {
+		return internalError("failed to write to world state", err)
+	}
+
+	return nil
+}
+
+// actsAs reports whether the submitting client may act as username in a class: its certificate is issued
+// to the username, or the instructor registered the username to it.
+func (s *SmartContract) actsAs(ctx contractapi.TransactionContextInterface, class string, username string) (bool, error) {
+	if username == "" {
+		return false, nil
+	}
+	commonName, err := submittingUsername(ctx)
+	if err != nil {
+		return false, err
+	}
+	if commonName == username {
+		return true, nil
+	}
+
+	record, err := s.ReadStudent(ctx, class, username)
+	if err != nil || record == nil {
+		return false, err
+	}
+	clientID, err := submittingClientID(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return record.ClientID == clientID, nil
+}
+
+// actsAsAssignee reports whether the submitting client may act as the student an asset was created for or,
+// for a group assignment, as a current member of its group. Assets created before StudentID was recorded
+// are matched against the common name of the client's certificate only.
+func (s *SmartContract) actsAsAssignee(ctx contractapi.TransactionContextInterface, asset *Asset) (bool, error) {
+	if asset.GroupID == "" {
+		if asset.StudentID != "" {
+			return s.actsAs(ctx, asset.ClassID, asset.StudentID)
+		}
+		username, err := submittingUsername(ctx)
+		if err != nil {
+			return false, err
+		}
+		return username != "" && isStudentOf(asset, username), nil
+	}
+
+	record, err := readGroup(ctx, asset.ClassID, asset.GroupID)
+	if err != nil || record == nil {
+		return false, err
+	}
+	for _, member := range record.Members {
+		acts, err := s.actsAs(ctx, asset.ClassID, member)
+		if err != nil || acts {
+			return acts, err
+		}
+	}
+	return false, nil
+}
+
+// actsAsHolder reports whether the submitting client may act as the holder of an asset: its owner or, for
+// a group assignment handed to the group, a member of the group.
+func (s *SmartContract) actsAsHolder(ctx contractapi.TransactionContextInterface, asset *Asset) (bool, error) {
+	if asset.GroupID != "" && asset.Owner == asset.GroupID {
+		return s.actsAsAssignee(ctx, asset)
+	}
+	return s.actsAs(ctx, asset.ClassID, asset.Owner)
+}
+
+// worksOn reports whether the submitting client may submit work or attach files for an asset: the
+// instructor of its class, its holder, or the student or a member of the group it was made for.
+func (s *SmartContract) worksOn(ctx contractapi.TransactionContextInterface, asset *Asset) (bool, error) {
+	allowed, err := s.isInstructor(ctx, asset.ClassID)
+	if err != nil || allowed {
+		return allowed, err
+	}
+	allowed, err = s.actsAsHolder(ctx, asset)
+	if err != nil || allowed {
+		return allowed, err
+	}
+	return s.actsAsAssignee(ctx, asset)
+}
@@ -0,0 +1,56 @@
+package chaincode_test
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/simulator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterStudent(t *testing.T) {
+	sim := simulator.New("mychannel")
+	instructor := simulator.NewClientIdentity("Org1MSP", "x509::CN=instructor")
+	shared, err := simulator.NewX509ClientIdentity("Org2MSP", "User1@org2.example.com")
+	require.NoError(t, err)
+	other, err := simulator.NewX509ClientIdentity("Org2MSP", "User2@org2.example.com")
+	require.NoError(t, err)
+	contract := chaincode.SmartContract{}
+	hash := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+	id, err := contract.CreateAssignment(sim.Transaction(instructor), "cs101", "hw1", "instructor", "alice", "", "")
+	require.NoError(t, err)
+	_, err = contract.TransferAsset(sim.Transaction(instructor), id, "alice")
+	require.NoError(t, err)
+
+	// The shared identity's certificate does not name alice, so it may act for her only once registered
+	err = contract.AttachFile(sim.Transaction(shared), id, "report.pdf", hash, 4)
+	requireContractError(t, err, chaincode.ErrForbidden, "only the holder or student of the assignment "+id+" may attach files to it")
+	require.NoError(t, contract.RegisterStudent(sim.Transaction(instructor), "cs101", "alice", shared.ID))
+	require.NoError(t, contract.RegisterStudent(sim.Transaction(instructor), "cs101", "bob", shared.ID))
+	require.NoError(t, contract.AttachFile(sim.Transaction(shared), id, "report.pdf", hash, 4))
+
+	record, err := contract.ReadStudent(sim.Transaction(instructor), "cs101", "alice")
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.Student{ClassID: "cs101", Username: "alice", ClientID: shared.ID}, record)
+	record, err = contract.ReadStudent(sim.Transaction(instructor), "cs102", "alice")
+	require.NoError(t, err)
+	require.Nil(t, record)
+
+	// A classmate can not register alice's name to their own client, nor act for her
+	err = contract.RegisterStudent(sim.Transaction(other), "cs101", "alice", other.ID)
+	requireContractError(t, err, chaincode.ErrForbidden, "only the instructor of class cs101 may manage its grading")
+	err = contract.AttachFile(sim.Transaction(other), id, "report.pdf", hash, 4)
+	requireContractError(t, err, chaincode.ErrForbidden, "only the holder or student of the assignment "+id+" may attach files to it")
+
+	// The instructor may move a username to another client
+	require.NoError(t, contract.RegisterStudent(sim.Transaction(instructor), "cs101", "alice", other.ID))
+	require.NoError(t, contract.AttachFile(sim.Transaction(other), id, "report.pdf", hash, 4))
+	err = contract.AttachFile(sim.Transaction(shared), id, "report.pdf", hash, 4)
+	requireContractError(t, err, chaincode.ErrForbidden, "only the holder or student of the assignment "+id+" may attach files to it")
+
+	err = contract.RegisterStudent(sim.Transaction(instructor), "cs101", "", shared.ID)
+	requireContractError(t, err, chaincode.ErrValidation, "the username must not be empty")
+	err = contract.RegisterStudent(sim.Transaction(instructor), "cs101", "carol", "")
+	requireContractError(t, err, chaincode.ErrValidation, "the client ID must not be empty")
+}
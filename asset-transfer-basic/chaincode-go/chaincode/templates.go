@@ -0,0 +1,165 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// maxTermOffsetDays bounds how far CreateClassFromTemplate may move due dates, about ten years either way
+const maxTermOffsetDays = 3660
+
+// ClassTemplate holds the definitions of every assignment in a class, without any student's work or grade,
+// so the class can be run again in a later term.
+type ClassTemplate struct {
+	ClassID     string                `json:"ClassID"`
+	Assignments []*AssignmentTemplate `json:"Assignments"`
+}
+
+// AssignmentTemplate is the definition of an assignment shared by every student's copy of it. The
+// autograder test suite hash and maximum grade are kept, so a cloned assignment is graded the same way.
+type AssignmentTemplate struct {
+	Title         string `json:"Title"`
+	Date          string `json:"Date"`
+	Description   string `json:"Description"`
+	TestSuiteHash string `json:"TestSuiteHash"`
+	MaxGrade      int    `json:"MaxGrade,omitempty" metadata:"MaxGrade,optional"`
+}
+
+// ExportClassTemplate returns the template of a class, with one definition per assignment title taken from
+// the first copy of it on the ledger. Assignments are sorted by title.
+func (s *SmartContract) ExportClassTemplate(ctx contractapi.TransactionContextInterface, class string) (*ClassTemplate, error) {
+	if err := validateName("class", "class ID", class); err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
+	if err != nil {
+		return nil, internalError("failed to read from world state", err)
+	}
+	defer resultsIterator.Close()
+
+	assignments := map[string]*AssignmentTemplate{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, internalError("failed to read from world state", err)
+		}
+
+		var asset Asset
+		err = json.Unmarshal(queryResponse.Value, &asset)
+		if err != nil {
+			return nil, internalError("failed to parse asset", err)
+		}
+		if asset.ClassID != class || assignments[asset.Title] != nil {
+			continue
+		}
+		assignments[asset.Title] = &AssignmentTemplate{
+			Title:         asset.Title,
+			Date:          asset.Date,
+			Description:   asset.Description,
+			TestSuiteHash: asset.TestSuiteHash,
+			MaxGrade:      asset.MaxGrade,
+		}
+	}
+	if len(assignments) == 0 {
+		return nil, newContractError(ErrNotFound, map[string]string{"class": class}, "the class %s has no assignments", class)
+	}
+
+	template := &ClassTemplate{ClassID: class}
+	for _, assignment := range assignments {
+		template.Assignments = append(template.Assignments, assignment)
+	}
+	sort.Slice(template.Assignments, func(i, j int) bool {
+		return template.Assignments[i].Title < template.Assignments[j].Title
+	})
+
+	return template, nil
+}
+
+// CreateClassFromTemplate creates a copy of every assignment of a template, as exported by
+// ExportClassTemplate, for each student of a new class. Due dates move by offsetDays. Like CreateAssignment,
+// the copies are held by the instructor until they are transferred to the students. It returns the IDs of
+// the copies, student by student in the order of the template's assignments.
+func (s *SmartContract) CreateClassFromTemplate(ctx contractapi.TransactionContextInterface, class string, templateJSON string, instructor string, students []string, offsetDays int) ([]string, error) {
+	return idempotent(ctx, func() ([]string, error) {
+		var template ClassTemplate
+		if err := json.Unmarshal([]byte(templateJSON), &template); err != nil {
+			return nil, validationError("template", "the template must be a class template in JSON: %s", err)
+		}
+		if len(template.Assignments) == 0 {
+			return nil, validationError("template", "the template must contain at least one assignment")
+		}
+		if len(students) == 0 {
+			return nil, validationError("students", "the class must have at least one student")
+		}
+		if offsetDays < -maxTermOffsetDays || offsetDays > maxTermOffsetDays {
+			return nil, validationError("offsetDays", "the term offset must be between %d and %d days", -maxTermOffsetDays, maxTermOffsetDays)
+		}
+
+		titles := map[string]bool{}
+		dates := make([]string, len(template.Assignments))
+		for i, assignment := range template.Assignments {
+			if assignment == nil {
+				return nil, validationError("template", "the template must not contain empty assignments")
+			}
+			if titles[assignment.Title] {
+				return nil, validationError("template", "the template contains the assignment %s more than once", assignment.Title)
+			}
+			titles[assignment.Title] = true
+			if assignment.MaxGrade < 0 {
+				return nil, validationError("template", "the maximum grade of the assignment %s must not be negative", assignment.Title)
+			}
+
+			date, err := shiftDueDate(assignment.Date, offsetDays)
+			if err != nil {
+				return nil, err
+			}
+			dates[i] = date
+		}
+
+		var ids []string
+		enrolled := map[string]bool{}
+		for _, student := range students {
+			if enrolled[student] {
+				return nil, validationError("students", "the student %s is listed more than once", student)
+			}
+			enrolled[student] = true
+
+			for i, assignment := range template.Assignments {
+				asset, err := s.newAssignment(ctx, class, assignment.Title, instructor, student, dates[i], assignment.Description)
+				if err != nil {
+					return nil, err
+				}
+				asset.TestSuiteHash = assignment.TestSuiteHash
+				asset.MaxGrade = assignment.MaxGrade
+
+				err = putAsset(ctx, asset)
+				if err != nil {
+					return nil, err
+				}
+				ids = append(ids, asset.ID)
+			}
+		}
+
+		return ids, nil
+	})
+}
+
+// shiftDueDate moves a due date by a number of days, keeping the layout it was written in. An empty due date
+// stays empty.
+func shiftDueDate(date string, days int) (string, error) {
+	if date == "" {
+		return "", nil
+	}
+	for _, layout := range dueDateLayouts {
+		dueDate, err := time.Parse(layout, date)
+		if err != nil {
+			continue
+		}
+		return dueDate.AddDate(0, 0, days).Format(layout), nil
+	}
+	return "", validationError("date", "the due date %q must be formatted as M/D/YYYY, YYYY-MM-DD or RFC 3339", date)
+}
@@ -0,0 +1,97 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/simulator"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloneClassFromTemplate(t *testing.T) {
+	sim := simulator.New("mychannel")
+	instructor := simulator.NewClientIdentity("Org1MSP", "x509::CN=instructor")
+	contract := chaincode.SmartContract{}
+
+	// Last term's class, with work and grades that must not be copied
+	for _, student := range []string{"alice", "bob"} {
+		_, err := contract.CreateAssignment(sim.Transaction(instructor), "cs101-f23", "hw1", "instructor", student, "9/15/2023", "Essay")
+		require.NoError(t, err)
+		_, err = contract.CreateAssignment(sim.Transaction(instructor), "cs101-f23", "exam", "instructor", student, "2023-12-14T09:00:00Z", "Final exam")
+		require.NoError(t, err)
+	}
+	require.NoError(t, contract.SetTestSuiteHash(sim.Transaction(instructor), "cs101-f23", "hw1", "abc"))
+	graded, err := contract.AssignmentID(sim.Transaction(instructor), "cs101-f23", "hw1", "alice")
+	require.NoError(t, err)
+	require.NoError(t, contract.SubmitAssignment(sim.Transaction(instructor), graded, "my essay"))
+	_, err = contract.GradeAssignment(sim.Transaction(instructor), graded, 90, "Good")
+	require.NoError(t, err)
+
+	template, err := contract.ExportClassTemplate(sim.Transaction(instructor), "cs101-f23")
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.ClassTemplate{
+		ClassID: "cs101-f23",
+		Assignments: []*chaincode.AssignmentTemplate{
+			{Title: "exam", Date: "2023-12-14T09:00:00Z", Description: "Final exam"},
+			{Title: "hw1", Date: "9/15/2023", Description: "Essay", TestSuiteHash: "abc"},
+		},
+	}, template)
+
+	templateJSON, err := json.Marshal(template)
+	require.NoError(t, err)
+	ids, err := contract.CreateClassFromTemplate(sim.Transaction(instructor), "cs101-f24", string(templateJSON), "instructor", []string{"carol"}, 366)
+	require.NoError(t, err)
+	require.Len(t, ids, 2)
+
+	exam, err := contract.ReadAsset(sim.Transaction(instructor), ids[0])
+	require.NoError(t, err)
+	require.Equal(t, "2024-12-14T09:00:00Z", exam.Date)
+	homework, err := contract.ReadAsset(sim.Transaction(instructor), ids[1])
+	require.NoError(t, err)
+	require.Equal(t, chaincode.Asset{
+		ID:            ids[1],
+		Title:         "hw1",
+		Date:          "9/15/2024",
+		Description:   "Essay",
+		InstructorID:  "instructor",
+		StudentID:     "carol",
+		Owner:         "instructor",
+		ClassID:       "cs101-f24",
+		TestSuiteHash: "abc",
+		ModifiedBy:    "x509::CN=instructor",
+	}, *homework)
+
+	// Cloning again would duplicate the assignments
+	_, err = contract.CreateClassFromTemplate(sim.Transaction(instructor), "cs101-f24", string(templateJSON), "instructor", []string{"dave", "carol"}, 366)
+	requireContractError(t, err, chaincode.ErrConflict, "the assignment exam already exists for student carol in class cs101-f24")
+}
+
+func TestCreateClassFromTemplateValidation(t *testing.T) {
+	sim := simulator.New("mychannel")
+	instructor := simulator.NewClientIdentity("Org1MSP", "x509::CN=instructor")
+	contract := chaincode.SmartContract{}
+
+	_, err := contract.ExportClassTemplate(sim.Transaction(instructor), "cs101")
+	requireContractError(t, err, chaincode.ErrNotFound, "the class cs101 has no assignments")
+
+	tests := []struct {
+		template string
+		students []string
+		offset   int
+		message  string
+	}{
+		{`not json`, []string{"alice"}, 0, "the template must be a class template in JSON: invalid character 'o' in literal null (expecting 'u')"},
+		{`{"Assignments":[]}`, []string{"alice"}, 0, "the template must contain at least one assignment"},
+		{`{"Assignments":[{"Title":"hw1"}]}`, nil, 0, "the class must have at least one student"},
+		{`{"Assignments":[{"Title":"hw1"}]}`, []string{"alice"}, 4000, "the term offset must be between -3660 and 3660 days"},
+		{`{"Assignments":[{"Title":"hw1"},{"Title":"hw1"}]}`, []string{"alice"}, 0, "the template contains the assignment hw1 more than once"},
+		{`{"Assignments":[{"Title":"hw1","Date":"next week"}]}`, []string{"alice"}, 0, `the due date "next week" must be formatted as M/D/YYYY, YYYY-MM-DD or RFC 3339`},
+		{`{"Assignments":[{"Title":"hw1"}]}`, []string{"alice", "alice"}, 0, "the student alice is listed more than once"},
+		{`{"Assignments":[{"Title":"hw1"}]}`, []string{"alice smith"}, 0, `the student "alice smith" may only contain letters, digits and the characters @ . _ -`},
+	}
+	for _, test := range tests {
+		_, err := contract.CreateClassFromTemplate(sim.Transaction(instructor), "cs101", test.template, "instructor", test.students, test.offset)
+		requireContractError(t, err, chaincode.ErrValidation, test.message)
+	}
+}
@@ -0,0 +1,120 @@
+package chaincode
+
+import (
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Length limits for the text fields of an asset
+const (
+	maxIDLength          = 192
+	maxTitleLength       = 128
+	maxNameLength        = 64
+	maxDescriptionLength = 4096
+)
+
+// namePattern matches the usernames and class IDs accepted by the contract
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9@_-][A-Za-z0-9@._-]*$`)
+
+// validateID checks an asset ID. IDs are built from an assignment title and a username, so spaces are
+// allowed, but control characters, which also mark composite keys, are not.
+func validateID(id string) error {
+	if id == "" {
+		return validationError("id", "the asset ID must not be empty")
+	}
+	return validateText("id", "asset ID", id, maxIDLength)
+}
+
+// validateName checks a username or class ID.
+func validateName(argument string, description string, name string) error {
+	if name == "" {
+		return validationError(argument, "the %s must not be empty", description)
+	}
+	if len(name) > maxNameLength {
+		return validationError(argument, "the %s must not be longer than %d characters", description, maxNameLength)
+	}
+	if !namePattern.MatchString(name) {
+		return validationError(argument, "the %s %q may only contain letters, digits and the characters @ . _ -", description, name)
+	}
+	return nil
+}
+
+// validateTitle checks an assignment title.
+func validateTitle(title string) error {
+	if title == "" {
+		return validationError("title", "the title must not be empty")
+	}
+	return validateText("title", "title", title, maxTitleLength)
+}
+
+// validateDate checks that a due date, if set, is in one of the accepted layouts.
+func validateDate(date string) error {
+	if date == "" {
+		return nil
+	}
+	if _, ok := parseDueDate(date); !ok {
+		return validationError("date", "the due date %q must be formatted as M/D/YYYY, YYYY-MM-DD or RFC 3339", date)
+	}
+	return nil
+}
+
+// validateDescription checks that a description is valid UTF-8 and not too long. Unlike the other text
+// fields it may span several lines.
+func validateDescription(description string) error {
+	if len(description) > maxDescriptionLength {
+		return validationError("description", "the description must not be longer than %d characters", maxDescriptionLength)
+	}
+	if !utf8.ValidString(description) {
+		return validationError("description", "the description must be valid UTF-8")
+	}
+	return nil
+}
+
+// validateGrade checks that a grade is not negative.
+func validateGrade(grade int) error {
+	if grade < 0 {
+		return validationError("grade", "the grade must not be negative")
+	}
+	return nil
+}
+
+// validateText checks that a free text field is valid UTF-8, free of control characters and no longer than
+// maxLength bytes.
+func validateText(argument string, description string, text string, maxLength int) error {
+	if len(text) > maxLength {
+		return validationError(argument, "the %s must not be longer than %d characters", description, maxLength)
+	}
+	if !utf8.ValidString(text) {
+		return validationError(argument, "the %s must be valid UTF-8", description)
+	}
+	for _, r := range text {
+		if unicode.IsControl(r) {
+			return validationError(argument, "the %s must not contain control characters", description)
+		}
+	}
+	return nil
+}
+
+// validateAsset checks the fields of an asset before it is written by CreateAsset or UpdateAsset.
+func validateAsset(asset *Asset) error {
+	if err := validateID(asset.ID); err != nil {
+		return err
+	}
+	if err := validateTitle(asset.Title); err != nil {
+		return err
+	}
+	if err := validateGrade(asset.Grade); err != nil {
+		return err
+	}
+	if err := validateName("owner", "owner", asset.Owner); err != nil {
+		return err
+	}
+	if err := validateName("class", "class ID", asset.ClassID); err != nil {
+		return err
+	}
+	if err := validateDate(asset.Date); err != nil {
+		return err
+	}
+	return validateDescription(asset.Description)
+}
@@ -0,0 +1,169 @@
+package chaincode_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode/mocks"
+	"github.com/stretchr/testify/require"
+)
+
+// FuzzCreateAsset checks that CreateAsset either rejects its arguments as invalid without touching the
+// world state, or stores exactly the asset it was given.
+func FuzzCreateAsset(f *testing.F) {
+	f.Add("hw1alice", "hw1", 0, "instructor", "4/24/2023", "Essay", "cs101")
+	f.Add("", "", -1, "", "", "", "")
+	f.Add("hw 1 alice", "hw 1", 100, "bob@example.com", "2023-04-24T10:00:00Z", "Line one\nline two", "cs-101")
+	f.Add("hw1\x00alice", "hw1\xff", 5, "in structor", "tomorrow", "\xff", "cs/101")
+
+	f.Fuzz(func(t *testing.T, id string, title string, grade int, owner string, date string, description string, class string) {
+		chaincodeStub := &mocks.ChaincodeStub{}
+		chaincodeStub.GetStateByRangeReturns(&mocks.StateQueryIterator{}, nil)
+		transactionContext := &mocks.TransactionContext{}
+		transactionContext.GetStubReturns(chaincodeStub)
+		clientIdentity := &mocks.ClientIdentity{}
+		clientIdentity.GetIDReturns("x509::CN=instructor", nil)
+		transactionContext.GetClientIdentityReturns(clientIdentity)
+
+		assetTransfer := chaincode.SmartContract{}
+		err := assetTransfer.CreateAsset(transactionContext, id, title, grade, owner, date, description, class)
+		if err != nil {
+			requireValidationError(t, err)
+			require.Equal(t, 0, chaincodeStub.PutStateCallCount())
+			return
+		}
+
+		// The class record of the new class is written first
+		require.Equal(t, 2, chaincodeStub.PutStateCallCount())
+		key, assetJSON := chaincodeStub.PutStateArgsForCall(1)
+		require.Equal(t, id, key)
+		var stored chaincode.Asset
+		require.NoError(t, json.Unmarshal(assetJSON, &stored))
+		require.Equal(t, chaincode.Asset{
+			ID:           id,
+			Title:        title,
+			Date:         date,
+			Description:  description,
+			Grade:        grade,
+			InstructorID: owner,
+			Owner:        owner,
+			ClassID:      class,
+			ModifiedBy:   "x509::CN=instructor",
+		}, stored)
+	})
+}
+
+// FuzzUpdateAsset checks that UpdateAsset never changes the fields it has no parameter for, and only changes
+// the others when given a value for them.
+func FuzzUpdateAsset(f *testing.F) {
+	f.Add("hw2", 90, "alice", "5/1/2023", "Revised", "cs102")
+	f.Add("", -1, "", "", "", "")
+	f.Add("\x7f", 0, "a b", "2023-13-45", "\xff", "cs 101")
+
+	f.Fuzz(func(t *testing.T, title string, grade int, owner string, date string, description string, class string) {
+		existing := chaincode.Asset{
+			ID:            "hw1alice",
+			Title:         "hw1",
+			Date:          "4/24/2023",
+			Description:   "Essay",
+			Grade:         70,
+			InstructorID:  "instructor",
+			Work:          "my essay",
+			Owner:         "instructor",
+			ClassID:       "cs101",
+			Feedback:      "Good",
+			Released:      true,
+			TestSuiteHash: "abc",
+			Attachments:   []chaincode.Attachment{{Name: "essay.pdf", SHA256: "def", Size: 10}},
+		}
+		existingJSON, err := json.Marshal(existing)
+		require.NoError(t, err)
+		classJSON, err := json.Marshal(&chaincode.Class{ClassID: "cs101", InstructorClient: "x509::CN=instructor"})
+		require.NoError(t, err)
+
+		chaincodeStub := &mocks.ChaincodeStub{}
+		chaincodeStub.CreateCompositeKeyReturns("\x00Class\x00cs101\x00", nil)
+		chaincodeStub.GetStateCalls(func(key string) ([]byte, error) {
+			if key == "\x00Class\x00cs101\x00" {
+				return classJSON, nil
+			}
+			return existingJSON, nil
+		})
+		transactionContext := &mocks.TransactionContext{}
+		transactionContext.GetStubReturns(chaincodeStub)
+		clientIdentity := &mocks.ClientIdentity{}
+		clientIdentity.GetIDReturns("x509::CN=instructor", nil)
+		transactionContext.GetClientIdentityReturns(clientIdentity)
+
+		assetTransfer := chaincode.SmartContract{}
+		err = assetTransfer.UpdateAsset(transactionContext, existing.ID, title, grade, owner, date, description, class)
+		if err != nil {
+			requireValidationError(t, err)
+			require.Equal(t, 0, chaincodeStub.PutStateCallCount())
+			return
+		}
+
+		_, assetJSON := chaincodeStub.PutStateArgsForCall(0)
+		var updated chaincode.Asset
+		require.NoError(t, json.Unmarshal(assetJSON, &updated))
+
+		expected := existing
+		expected.ModifiedBy = "x509::CN=instructor"
+		if title != "" {
+			expected.Title = title
+		}
+		if grade >= 0 {
+			expected.Grade = grade
+		}
+		if owner != "" {
+			expected.Owner = owner
+		}
+		if date != "" {
+			expected.Date = date
+		}
+		if description != "" {
+			expected.Description = description
+		}
+		if class != "" {
+			expected.ClassID = class
+		}
+		require.Equal(t, expected, updated)
+	})
+}
+
+// FuzzGetAllClasses checks that matching usernames against asset IDs of any length cannot panic.
+func FuzzGetAllClasses(f *testing.F) {
+	f.Add("hw1alice", "alice", "instructor")
+	f.Add("a", "alice", "instructor")
+	f.Add("", "", "")
+
+	f.Fuzz(func(t *testing.T, id string, username string, instructor string) {
+		assetJSON, err := json.Marshal(chaincode.Asset{ID: id, InstructorID: instructor, ClassID: "cs101"})
+		require.NoError(t, err)
+
+		iterator := &mocks.StateQueryIterator{}
+		iterator.HasNextReturnsOnCall(0, true)
+		iterator.HasNextReturnsOnCall(1, false)
+		iterator.NextReturns(&queryresult.KV{Key: id, Value: assetJSON}, nil)
+		chaincodeStub := &mocks.ChaincodeStub{}
+		chaincodeStub.GetStateByRangeReturns(iterator, nil)
+		transactionContext := &mocks.TransactionContext{}
+		transactionContext.GetStubReturns(chaincodeStub)
+
+		assetTransfer := chaincode.SmartContract{}
+		classes, err := assetTransfer.GetAllClasses(transactionContext, username)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(classes), 1)
+	})
+}
+
+// requireValidationError checks that err reports invalid arguments.
+func requireValidationError(t *testing.T, err error) {
+	t.Helper()
+	var contractErr *chaincode.ContractError
+	require.True(t, errors.As(err, &contractErr), "expected a ContractError, got %v", err)
+	require.Equal(t, chaincode.ErrValidation, contractErr.Code, contractErr.Message)
+}
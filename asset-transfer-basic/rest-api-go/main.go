@@ -2,25 +2,54 @@ package main
 
 import (
 	"fmt"
+	"os"
 	"rest-api-go/web"
 )
 
 func main() {
-	//Initialize setup for Org1
-	cryptoPath := "../../test-network/organizations/peerOrganizations/org1.example.com"
-	orgConfig := web.OrgSetup{
-		OrgName:      "Org1",
-		MSPID:        "Org1MSP",
-		CertPath:     cryptoPath + "/users/User1@org1.example.com/msp/signcerts/cert.pem",
-		KeyPath:      cryptoPath + "/users/User1@org1.example.com/msp/keystore/",
-		TLSCertPath:  cryptoPath + "/peers/peer0.org1.example.com/tls/ca.crt",
-		PeerEndpoint: "localhost:7051",
-		GatewayPeer:  "peer0.org1.example.com",
+	orgConfigs := []web.OrgSetup{defaultOrgSetup()}
+	if configPath := os.Getenv("ORGS_CONFIG"); configPath != "" {
+		configs, err := web.LoadOrgSetups(configPath)
+		if err != nil {
+			fmt.Println("Error loading organizations: ", err)
+			os.Exit(1)
+		}
+		orgConfigs = configs
+	}
+
+	var orgSetups []*web.OrgSetup
+	for _, orgConfig := range orgConfigs {
+		orgSetup, err := web.Initialize(orgConfig)
+		if err != nil {
+			fmt.Printf("Error initializing setup for %s: %s\n", orgConfig.OrgName, err)
+			os.Exit(1)
+		}
+		orgSetups = append(orgSetups, orgSetup)
 	}
 
-	orgSetup, err := web.Initialize(orgConfig)
-	if err != nil {
-		fmt.Println("Error initializing setup for Org1: ", err)
+	// HTTPS is optional, see README.md
+	web.Serve(web.ServerConfig{
+		Address:      ":3000",
+		CertPath:     os.Getenv("SERVER_CERT"),
+		KeyPath:      os.Getenv("SERVER_KEY"),
+		ClientCAPath: os.Getenv("CLIENT_CA"),
+	}, orgSetups...)
+}
+
+// defaultOrgSetup is the test network's Org1, served when no ORGS_CONFIG file is given.
+func defaultOrgSetup() web.OrgSetup {
+	cryptoPath := "../../test-network/organizations/peerOrganizations/org1.example.com"
+	return web.OrgSetup{
+		OrgName:       "Org1",
+		MSPID:         "Org1MSP",
+		CertPath:      cryptoPath + "/users/User1@org1.example.com/msp/signcerts/cert.pem",
+		KeyPath:       cryptoPath + "/users/User1@org1.example.com/msp/keystore/",
+		TLSCertPath:   cryptoPath + "/peers/peer0.org1.example.com/tls/ca.crt",
+		PeerEndpoint:  "localhost:7051",
+		GatewayPeer:   "peer0.org1.example.com",
+		ChannelName:   "mychannel",
+		ChaincodeName: "basic",
+		// Per-user identities are optional, see README.md
+		WalletPath: os.Getenv("WALLET_PATH"),
 	}
-	web.Serve(web.OrgSetup(*orgSetup))
 }
@@ -1,10 +1,14 @@
 package web
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 
 	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"google.golang.org/grpc"
 )
 
 // OrgSetup contains organization's config to interact with the network.
@@ -17,15 +21,74 @@ type OrgSetup struct {
 	TLSCertPath  string
 	PeerEndpoint string
 	GatewayPeer  string
-	Gateway      client.Gateway
+	Gateway      client.Gateway `json:"-"`
+	// ChannelName and ChaincodeName locate the grading chaincode served by the typed REST resources
+	ChannelName   string
+	ChaincodeName string
+	// WalletPath is the directory of per-user identities. When set, every request must be authenticated and
+	// runs as the caller's own identity instead of the default one above.
+	WalletPath string
+
+	connection   *grpc.ClientConn
+	gateways     *gatewayCache
+	transactions *transactionStore
+}
+
+// ServerConfig contains the settings of the HTTP server shared by every organization.
+type ServerConfig struct {
+	Address string
+	// CertPath and KeyPath enable HTTPS. ClientCAPath additionally lets callers authenticate with client
+	// certificates issued by the CAs in that PEM file.
+	CertPath     string
+	KeyPath      string
+	ClientCAPath string
 }
 
-// Serve starts http web server.
-func Serve(setups OrgSetup) {
-	http.HandleFunc("/query", setups.Query)
-	http.HandleFunc("/invoke", setups.Invoke)
-	fmt.Println("Listening (http://localhost:3000/)...")
-	if err := http.ListenAndServe(":3000", nil); err != nil {
+// Handler returns the routes served for the organization. Everything except the health check runs as the
+// authenticated caller.
+func (setup *OrgSetup) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/query", setup.Query)
+	mux.HandleFunc("/invoke", setup.Invoke)
+	mux.HandleFunc("/transactions/", setup.Transactions)
+	mux.HandleFunc("/events/chaincode", setup.ChaincodeEvents)
+	mux.HandleFunc("/events/blocks", setup.BlockEvents)
+	NewGradingAPI(func(r *http.Request) Contract {
+		return GatewayContract{setup.requestGateway(r).GetNetwork(setup.ChannelName).GetContract(setup.ChaincodeName)}
+	}).Register(mux)
+
+	handler := http.NewServeMux()
+	handler.HandleFunc("/health", setup.Health)
+	handler.Handle("/", setup.Authenticate(mux))
+	return handler
+}
+
+// Serve starts http web server, routing each request to one of the organizations.
+func Serve(config ServerConfig, setups ...*OrgSetup) {
+	server := &http.Server{Addr: config.Address, Handler: NewOrgRouter(setups...)}
+	if config.CertPath == "" {
+		fmt.Printf("Listening (http://localhost%s/)...\n", config.Address)
+		if err := server.ListenAndServe(); err != nil {
+			fmt.Println(err)
+		}
+		return
+	}
+
+	if config.ClientCAPath != "" {
+		caPEM, err := ioutil.ReadFile(config.ClientCAPath)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		clientCAs := x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(caPEM) {
+			fmt.Printf("no certificates found in %s\n", config.ClientCAPath)
+			return
+		}
+		server.TLSConfig = &tls.Config{ClientCAs: clientCAs, ClientAuth: tls.VerifyClientCertIfGiven}
+	}
+	fmt.Printf("Listening (https://localhost%s/)...\n", config.Address)
+	if err := server.ListenAndServeTLS(config.CertPath, config.KeyPath); err != nil {
 		fmt.Println(err)
 	}
 }
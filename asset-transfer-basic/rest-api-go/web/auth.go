@@ -0,0 +1,123 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+type contextKey int
+
+const (
+	callerKey contextKey = iota
+	gatewayKey
+)
+
+// gatewayCache holds one Gateway per authenticated user, all sharing the organization's gRPC connection.
+type gatewayCache struct {
+	mu       sync.Mutex
+	gateways map[string]*client.Gateway
+}
+
+// Authenticate identifies the caller of every request and runs the request as that caller's own Fabric
+// identity from the wallet. Callers present either a bearer token, or a client certificate whose common
+// name is their wallet user name when the server uses mutual TLS. Without a wallet every request runs as the
+// organization's default identity.
+func (setup *OrgSetup) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if setup.WalletPath == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		username, err := setup.caller(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="cryptograder"`)
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+
+		gateway, err := setup.gatewayFor(username)
+		if errors.Is(err, errUnknownUser) {
+			writeError(w, http.StatusForbidden, fmt.Sprintf("no identity for %s in the wallet", username))
+			return
+		}
+		if err != nil {
+			fmt.Printf("failed to connect as %s: %s\n", username, err)
+			writeError(w, http.StatusInternalServerError, "failed to connect to the gateway")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), callerKey, username)
+		ctx = context.WithValue(ctx, gatewayKey, gateway)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// caller returns the wallet user name of the client that sent a request.
+func (setup *OrgSetup) caller(r *http.Request) (string, error) {
+	authorization := r.Header.Get("Authorization")
+	if authorization == "" && r.URL.Query().Has("access_token") {
+		// Browsers cannot set headers on EventSource streams, so the token may come in the query instead
+		authorization = "Bearer " + r.URL.Query().Get("access_token")
+	}
+	if authorization != "" {
+		token := strings.TrimPrefix(authorization, "Bearer ")
+		if token == authorization || token == "" {
+			return "", errors.New("unsupported authorization scheme")
+		}
+		username, err := NewWallet(setup.WalletPath).UserForToken(token)
+		if errors.Is(err, errUnknownUser) {
+			return "", errors.New("invalid bearer token")
+		}
+		return username, err
+	}
+
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return r.TLS.VerifiedChains[0][0].Subject.CommonName, nil
+	}
+
+	return "", errors.New("authentication required")
+}
+
+// gatewayFor returns the Gateway of a wallet user, connecting it on first use.
+func (setup *OrgSetup) gatewayFor(username string) (*client.Gateway, error) {
+	setup.gateways.mu.Lock()
+	defer setup.gateways.mu.Unlock()
+
+	if gateway, ok := setup.gateways.gateways[username]; ok {
+		return gateway, nil
+	}
+
+	id, sign, err := NewWallet(setup.WalletPath).Identity(username, setup.MSPID)
+	if err != nil {
+		return nil, err
+	}
+	gateway, err := connect(setup.connection, id, sign)
+	if err != nil {
+		return nil, err
+	}
+	setup.gateways.gateways[username] = gateway
+	return gateway, nil
+}
+
+// requestGateway returns the Gateway of the caller of an authenticated request, or the organization's
+// default Gateway if the server runs without a wallet.
+func (setup *OrgSetup) requestGateway(r *http.Request) *client.Gateway {
+	if gateway, ok := r.Context().Value(gatewayKey).(*client.Gateway); ok {
+		return gateway
+	}
+	return &setup.Gateway
+}
+
+// Caller returns the wallet user name of the authenticated caller of a request, or "" if the server runs
+// without a wallet.
+func Caller(r *http.Request) string {
+	username, _ := r.Context().Value(callerKey).(string)
+	return username
+}
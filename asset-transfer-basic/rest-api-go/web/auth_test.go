@@ -0,0 +1,174 @@
+package web
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// addWalletUser writes a self-signed identity and bearer token for username into the wallet directory.
+func addWalletUser(t *testing.T, wallet string, username string, token string) *x509.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: username},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Join(wallet, username)
+	for _, sub := range []string{"signcerts", "keystore"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, filepath.Join(dir, "signcerts", "cert.pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certDER}))
+	writeFile(t, filepath.Join(dir, "keystore", "priv_sk"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}))
+	if token != "" {
+		digest := sha256.Sum256([]byte(token))
+		writeFile(t, filepath.Join(dir, "token.sha256"), []byte(hex.EncodeToString(digest[:])+"\n"))
+	}
+
+	certificate, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificate
+}
+
+func writeFile(t *testing.T, name string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(name, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func newTestSetup(t *testing.T, wallet string) *OrgSetup {
+	t.Helper()
+	// Dialing is lazy, so gateways can be opened without a peer listening
+	connection, err := grpc.Dial("localhost:7051", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { connection.Close() })
+	return &OrgSetup{
+		MSPID:      "Org1MSP",
+		WalletPath: wallet,
+		connection: connection,
+		gateways:   &gatewayCache{gateways: map[string]*client.Gateway{}},
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	wallet := t.TempDir()
+	aliceCert := addWalletUser(t, wallet, "alice", "alice-token")
+	bobCert := addWalletUser(t, wallet, "bob", "")
+	setup := newTestSetup(t, wallet)
+
+	var caller string
+	var gateway *client.Gateway
+	handler := setup.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		caller = Caller(r)
+		gateway = setup.requestGateway(r)
+	}))
+
+	request := func(authorization string, peer *x509.Certificate) int {
+		caller, gateway = "", nil
+		r := httptest.NewRequest(http.MethodGet, "/classes", nil)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		if peer != nil {
+			r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{peer}}}
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, r)
+		return recorder.Code
+	}
+
+	if status := request("", nil); status != http.StatusUnauthorized {
+		t.Errorf("expected an anonymous request to be rejected, got %d", status)
+	}
+	if status := request("Bearer wrong-token", nil); status != http.StatusUnauthorized {
+		t.Errorf("expected an unknown token to be rejected, got %d", status)
+	}
+	if status := request("Basic YWxpY2U6cGFzcw==", nil); status != http.StatusUnauthorized {
+		t.Errorf("expected basic authentication to be rejected, got %d", status)
+	}
+
+	if status := request("Bearer alice-token", nil); status != http.StatusOK || caller != "alice" {
+		t.Fatalf("expected alice to be authenticated by token, got %d as %q", status, caller)
+	}
+	x509Identity := gateway.Identity()
+	if x509Identity.MspID() != "Org1MSP" || string(x509Identity.Credentials()) != string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: aliceCert.Raw})) {
+		t.Errorf("expected the request to run as alice's wallet identity")
+	}
+	aliceGateway := gateway
+	request("Bearer alice-token", nil)
+	if gateway != aliceGateway {
+		t.Error("expected alice's gateway to be reused")
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/events/chaincode?access_token=alice-token", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	if caller != "alice" || gateway != aliceGateway {
+		t.Errorf("expected alice to be authenticated by the access_token parameter, got %q", caller)
+	}
+
+	if status := request("", bobCert); status != http.StatusOK || caller != "bob" {
+		t.Fatalf("expected bob to be authenticated by client certificate, got %d as %q", status, caller)
+	}
+	if gateway == aliceGateway {
+		t.Error("expected bob to have a separate gateway")
+	}
+
+	mallory := &x509.Certificate{Subject: pkix.Name{CommonName: "mallory"}}
+	if status := request("", mallory); status != http.StatusForbidden {
+		t.Errorf("expected a caller without a wallet identity to be forbidden, got %d", status)
+	}
+	escape := &x509.Certificate{Subject: pkix.Name{CommonName: "../alice"}}
+	if status := request("", escape); status != http.StatusForbidden {
+		t.Errorf("expected a user name outside the wallet to be forbidden, got %d", status)
+	}
+}
+
+func TestAuthenticateWithoutWallet(t *testing.T) {
+	setup := &OrgSetup{}
+	var gateway *client.Gateway
+	handler := setup.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gateway = setup.requestGateway(r)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/classes", nil))
+	if gateway != &setup.Gateway {
+		t.Error("expected requests to use the default gateway when no wallet is configured")
+	}
+}
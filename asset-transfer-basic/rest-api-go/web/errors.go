@@ -0,0 +1,35 @@
+package web
+
+import (
+	"net/http"
+
+	"assetTransfer/graderr"
+)
+
+// ErrorResponse is the body of every failed grading API request.
+type ErrorResponse struct {
+	Error string `json:"error"`
+	// Code is the kind of failure for failed transactions, such as NotFound or Conflict
+	Code          graderr.Code `json:"code,omitempty"`
+	TransactionID string       `json:"transactionId,omitempty"`
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, ErrorResponse{Error: message})
+}
+
+// writeGatewayError reports a failed transaction with the HTTP status that best describes the failure stage
+// and cause.
+func writeGatewayError(w http.ResponseWriter, err error) {
+	httpStatus, response := gatewayErrorResponse(err)
+	writeJSON(w, httpStatus, response)
+}
+
+func gatewayErrorResponse(err error) (int, ErrorResponse) {
+	classified := graderr.Classify(err)
+	response := ErrorResponse{Error: classified.Message, TransactionID: classified.TransactionID}
+	if classified.Code != graderr.Unknown {
+		response.Code = classified.Code
+	}
+	return classified.HTTPStatus(), response
+}
@@ -0,0 +1,238 @@
+package web
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// keepAliveInterval is how often an idle event stream sends a comment so proxies do not close it.
+var keepAliveInterval = 15 * time.Second
+
+// ChaincodeEventMessage is the data of one chaincode event in an event stream.
+type ChaincodeEventMessage struct {
+	BlockNumber   uint64 `json:"blockNumber"`
+	TransactionID string `json:"transactionId"`
+	ChaincodeName string `json:"chaincodeName"`
+	EventName     string `json:"eventName"`
+	Payload       string `json:"payload"`
+}
+
+// BlockMessage is the data of one block in an event stream.
+type BlockMessage struct {
+	BlockNumber  uint64             `json:"blockNumber"`
+	DataHash     string             `json:"dataHash"`
+	PreviousHash string             `json:"previousHash"`
+	Transactions []BlockTransaction `json:"transactions"`
+}
+
+// BlockTransaction describes one transaction in a block and whether it was valid.
+type BlockTransaction struct {
+	TransactionID string `json:"transactionId"`
+	Code          string `json:"code"`
+}
+
+// ChaincodeEvents handles GET /events/chaincode?channel=&chaincode=&startBlock=, streaming chaincode events
+// as Server-Sent Events. Each event's ID is its checkpoint, "<block>:<transaction ID>". A reconnecting
+// browser sends the last one back in the Last-Event-ID header and the stream resumes after it; clients can
+// also pass a checkpoint explicitly with the checkpoint parameter. Without either, the stream starts at
+// startBlock, or at the next block to be committed.
+func (setup *OrgSetup) ChaincodeEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	channel := queryOr(r, "channel", setup.ChannelName)
+	chaincode := queryOr(r, "chaincode", setup.ChaincodeName)
+
+	var options []client.ChaincodeEventsOption
+	checkpoint := r.Header.Get("Last-Event-ID")
+	if checkpoint == "" {
+		checkpoint = r.URL.Query().Get("checkpoint")
+	}
+	if checkpoint != "" {
+		checkpointer, err := parseChaincodeCheckpoint(checkpoint)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		options = append(options, client.WithCheckpoint(checkpointer))
+	} else if startBlock := r.URL.Query().Get("startBlock"); startBlock != "" {
+		blockNumber, err := strconv.ParseUint(startBlock, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid startBlock %q", startBlock))
+			return
+		}
+		options = append(options, client.WithStartBlock(blockNumber))
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	events, err := setup.requestGateway(r).GetNetwork(channel).ChaincodeEvents(ctx, chaincode, options...)
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+
+	streamEvents(w, r, events, func(event *client.ChaincodeEvent) (string, interface{}) {
+		return fmt.Sprintf("%d:%s", event.BlockNumber, event.TransactionID), ChaincodeEventMessage{
+			BlockNumber:   event.BlockNumber,
+			TransactionID: event.TransactionID,
+			ChaincodeName: event.ChaincodeName,
+			EventName:     event.EventName,
+			Payload:       string(event.Payload),
+		}
+	})
+}
+
+// BlockEvents handles GET /events/blocks?channel=&startBlock=, streaming committed blocks as Server-Sent
+// Events. Each event's ID is the block number, and a reconnecting client resumes from the block after the
+// one in its Last-Event-ID header.
+func (setup *OrgSetup) BlockEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	channel := queryOr(r, "channel", setup.ChannelName)
+
+	var options []client.BlockEventsOption
+	if lastEventID := r.Header.Get("Last-Event-ID"); lastEventID != "" {
+		blockNumber, err := strconv.ParseUint(lastEventID, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid Last-Event-ID %q", lastEventID))
+			return
+		}
+		options = append(options, client.WithStartBlock(blockNumber+1))
+	} else if startBlock := r.URL.Query().Get("startBlock"); startBlock != "" {
+		blockNumber, err := strconv.ParseUint(startBlock, 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid startBlock %q", startBlock))
+			return
+		}
+		options = append(options, client.WithStartBlock(blockNumber))
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	blocks, err := setup.requestGateway(r).GetNetwork(channel).BlockEvents(ctx, options...)
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+
+	streamEvents(w, r, blocks, func(block *common.Block) (string, interface{}) {
+		message := blockMessage(block)
+		return strconv.FormatUint(message.BlockNumber, 10), message
+	})
+}
+
+// parseChaincodeCheckpoint reads a "<block>:<transaction ID>" chaincode event checkpoint.
+func parseChaincodeCheckpoint(checkpoint string) (*client.InMemoryCheckpointer, error) {
+	block, transactionID, _ := strings.Cut(checkpoint, ":")
+	blockNumber, err := strconv.ParseUint(block, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %q", checkpoint)
+	}
+	checkpointer := &client.InMemoryCheckpointer{}
+	checkpointer.CheckpointTransaction(blockNumber, transactionID)
+	return checkpointer, nil
+}
+
+// blockMessage summarizes a block with the ID and validation code of each of its transactions.
+func blockMessage(block *common.Block) BlockMessage {
+	message := BlockMessage{
+		BlockNumber:  block.GetHeader().GetNumber(),
+		DataHash:     hex.EncodeToString(block.GetHeader().GetDataHash()),
+		PreviousHash: hex.EncodeToString(block.GetHeader().GetPreviousHash()),
+		Transactions: []BlockTransaction{},
+	}
+
+	var validationCodes []byte
+	if metadata := block.GetMetadata().GetMetadata(); len(metadata) > int(common.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		validationCodes = metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER]
+	}
+	for i, data := range block.GetData().GetData() {
+		transaction := BlockTransaction{TransactionID: transactionID(data)}
+		if i < len(validationCodes) {
+			transaction.Code = peer.TxValidationCode(validationCodes[i]).String()
+		}
+		message.Transactions = append(message.Transactions, transaction)
+	}
+	return message
+}
+
+// transactionID reads the transaction ID from the channel header of a block's envelope, or "" if the
+// envelope cannot be parsed.
+func transactionID(envelopeBytes []byte) string {
+	envelope := &common.Envelope{}
+	if err := proto.Unmarshal(envelopeBytes, envelope); err != nil {
+		return ""
+	}
+	payload := &common.Payload{}
+	if err := proto.Unmarshal(envelope.GetPayload(), payload); err != nil {
+		return ""
+	}
+	channelHeader := &common.ChannelHeader{}
+	if err := proto.Unmarshal(payload.GetHeader().GetChannelHeader(), channelHeader); err != nil {
+		return ""
+	}
+	return channelHeader.GetTxId()
+}
+
+// streamEvents writes each event received until the client disconnects or the channel closes as a
+// Server-Sent Event, with the ID and data returned by message.
+func streamEvents[T any](w http.ResponseWriter, r *http.Request, events <-chan T, message func(T) (string, interface{})) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			id, data := message(event)
+			dataJSON, err := json.Marshal(data)
+			if err != nil {
+				fmt.Printf("failed to encode event: %s\n", err)
+				continue
+			}
+			fmt.Fprintf(w, "id: %s\ndata: %s\n\n", id, dataJSON)
+			flusher.Flush()
+		}
+	}
+}
+
+// queryOr returns a query parameter, or fallback if it is not set.
+func queryOr(r *http.Request, name string, fallback string) string {
+	if value := r.URL.Query().Get(name); value != "" {
+		return value
+	}
+	return fallback
+}
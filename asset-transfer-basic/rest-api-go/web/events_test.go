@@ -0,0 +1,78 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestStreamEvents(t *testing.T) {
+	events := make(chan *client.ChaincodeEvent, 2)
+	events <- &client.ChaincodeEvent{BlockNumber: 5, TransactionID: "tx1", ChaincodeName: "basic", EventName: "WorkSubmitted", Payload: []byte(`{"ID":"hw1alice"}`)}
+	events <- &client.ChaincodeEvent{BlockNumber: 6, TransactionID: "tx2", ChaincodeName: "basic", EventName: "WorkSubmitted", Payload: []byte("plain")}
+	close(events)
+
+	recorder := httptest.NewRecorder()
+	streamEvents(recorder, httptest.NewRequest(http.MethodGet, "/events/chaincode", nil), events, func(event *client.ChaincodeEvent) (string, interface{}) {
+		return event.TransactionID, ChaincodeEventMessage{BlockNumber: event.BlockNumber, EventName: event.EventName, Payload: string(event.Payload)}
+	})
+
+	if contentType := recorder.Header().Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("expected an event stream, got %q", contentType)
+	}
+	expected := "id: tx1\ndata: {\"blockNumber\":5,\"transactionId\":\"\",\"chaincodeName\":\"\",\"eventName\":\"WorkSubmitted\",\"payload\":\"{\\\"ID\\\":\\\"hw1alice\\\"}\"}\n\n" +
+		"id: tx2\ndata: {\"blockNumber\":6,\"transactionId\":\"\",\"chaincodeName\":\"\",\"eventName\":\"WorkSubmitted\",\"payload\":\"plain\"}\n\n"
+	if body := recorder.Body.String(); body != expected {
+		t.Errorf("unexpected stream\n%s\nexpected\n%s", body, expected)
+	}
+}
+
+func TestParseChaincodeCheckpoint(t *testing.T) {
+	checkpointer, err := parseChaincodeCheckpoint("12:abc123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkpointer.BlockNumber() != 12 || checkpointer.TransactionID() != "abc123" {
+		t.Errorf("unexpected checkpoint %d %q", checkpointer.BlockNumber(), checkpointer.TransactionID())
+	}
+
+	checkpointer, err = parseChaincodeCheckpoint("12")
+	if err != nil || checkpointer.BlockNumber() != 12 || checkpointer.TransactionID() != "" {
+		t.Errorf("expected a block-only checkpoint, got %v %v", checkpointer, err)
+	}
+
+	if _, err := parseChaincodeCheckpoint("latest:abc"); err == nil {
+		t.Error("expected a non-numeric block to be rejected")
+	}
+}
+
+func TestBlockMessage(t *testing.T) {
+	envelope := func(transactionID string) []byte {
+		channelHeader, _ := proto.Marshal(&common.ChannelHeader{TxId: transactionID})
+		payload, _ := proto.Marshal(&common.Payload{Header: &common.Header{ChannelHeader: channelHeader}})
+		envelopeBytes, _ := proto.Marshal(&common.Envelope{Payload: payload})
+		return envelopeBytes
+	}
+
+	metadata := make([][]byte, common.BlockMetadataIndex_TRANSACTIONS_FILTER+1)
+	metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER] = []byte{byte(peer.TxValidationCode_VALID), byte(peer.TxValidationCode_MVCC_READ_CONFLICT)}
+	block := &common.Block{
+		Header:   &common.BlockHeader{Number: 9, DataHash: []byte{0xab}, PreviousHash: []byte{0xcd}},
+		Data:     &common.BlockData{Data: [][]byte{envelope("tx1"), envelope("tx2")}},
+		Metadata: &common.BlockMetadata{Metadata: metadata},
+	}
+
+	message := blockMessage(block)
+	if message.BlockNumber != 9 || message.DataHash != "ab" || message.PreviousHash != "cd" {
+		t.Errorf("unexpected block header %+v", message)
+	}
+	expected := []BlockTransaction{{TransactionID: "tx1", Code: "VALID"}, {TransactionID: "tx2", Code: "MVCC_READ_CONFLICT"}}
+	if len(message.Transactions) != 2 || message.Transactions[0] != expected[0] || message.Transactions[1] != expected[1] {
+		t.Errorf("unexpected transactions %+v", message.Transactions)
+	}
+}
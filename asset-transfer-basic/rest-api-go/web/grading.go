@@ -0,0 +1,452 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Contract is the part of a gateway contract the grading API uses, satisfied by GatewayContract.
+type Contract interface {
+	EvaluateTransaction(name string, args ...string) ([]byte, error)
+	// SubmitWithKey submits a transaction with an idempotency key, so a retry returns the recorded result.
+	SubmitWithKey(idempotencyKey string, name string, args ...string) ([]byte, error)
+}
+
+// Submission is one student's copy of an assignment, as returned by the grading API.
+type Submission struct {
+	ID          string       `json:"id"`
+	Title       string       `json:"title"`
+	ClassID     string       `json:"classId"`
+	DueDate     string       `json:"dueDate"`
+	Description string       `json:"description"`
+	Instructor  string       `json:"instructor"`
+	Student     string       `json:"student,omitempty"`
+	Group       string       `json:"group,omitempty"`
+	Owner       string       `json:"owner"`
+	Work        string       `json:"work"`
+	Grade       int          `json:"grade"`
+	Feedback    string       `json:"feedback"`
+	Released    bool         `json:"released"`
+	Attachments []Attachment `json:"attachments"`
+}
+
+// Attachment describes a file stored off-chain for a submission.
+type Attachment struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// NewAssignment is the request body for creating an assignment for a student.
+type NewAssignment struct {
+	Title       string `json:"title"`
+	DueDate     string `json:"dueDate"`
+	Description string `json:"description"`
+	Instructor  string `json:"instructor"`
+	Student     string `json:"student"`
+}
+
+// WorkRequest is the request body for submitting work.
+type WorkRequest struct {
+	Work string `json:"work"`
+}
+
+// GradeRequest is the request body for grading a submission.
+type GradeRequest struct {
+	Grade    int    `json:"grade"`
+	Feedback string `json:"feedback"`
+}
+
+// GradeResponse reports the grade recorded for a submission and the grade it replaced.
+type GradeResponse struct {
+	ID            string `json:"id"`
+	Grade         int    `json:"grade"`
+	PreviousGrade int    `json:"previousGrade"`
+}
+
+// GradebookEntry is one row of a class gradebook.
+type GradebookEntry struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Student  string `json:"student"`
+	Group    string `json:"group,omitempty"`
+	Grade    int    `json:"grade"`
+	Released bool   `json:"released"`
+}
+
+// asset mirrors the chaincode's JSON form of an assignment.
+type asset struct {
+	ID           string
+	Title        string
+	Date         string
+	Description  string
+	InstructorID string
+	StudentID    string
+	GroupID      string
+	Owner        string
+	ClassID      string
+	Work         string
+	Grade        int
+	Feedback     string
+	Released     bool
+	Attachments  []struct {
+		Name   string
+		SHA256 string
+		Size   int64
+	}
+}
+
+func (a asset) submission() Submission {
+	attachments := make([]Attachment, 0, len(a.Attachments))
+	for _, attachment := range a.Attachments {
+		attachments = append(attachments, Attachment{Name: attachment.Name, SHA256: attachment.SHA256, Size: attachment.Size})
+	}
+	return Submission{
+		ID:          a.ID,
+		Title:       a.Title,
+		ClassID:     a.ClassID,
+		DueDate:     a.Date,
+		Description: a.Description,
+		Instructor:  a.InstructorID,
+		Student:     a.student(),
+		Group:       a.GroupID,
+		Owner:       a.Owner,
+		Work:        a.Work,
+		Grade:       a.Grade,
+		Feedback:    a.Feedback,
+		Released:    a.Released,
+		Attachments: attachments,
+	}
+}
+
+// student returns the student the asset was created for. Assets created before the chaincode recorded the
+// student have IDs made of the title followed by the student, and group assignments have no student.
+func (a asset) student() string {
+	if a.StudentID != "" || a.GroupID != "" {
+		return a.StudentID
+	}
+	return strings.TrimPrefix(a.ID, a.Title)
+}
+
+// ContractResolver returns the contract a request's transactions run against, connected as the caller.
+type ContractResolver func(r *http.Request) Contract
+
+// GradingAPI serves typed REST resources for classes, assignments, submissions and gradebooks on top of the
+// grading chaincode.
+type GradingAPI struct {
+	contracts ContractResolver
+}
+
+// NewGradingAPI returns a grading API that runs each request's transactions against the contract contracts
+// resolves for it.
+func NewGradingAPI(contracts ContractResolver) *GradingAPI {
+	return &GradingAPI{contracts: contracts}
+}
+
+// Register adds the grading API routes to mux.
+func (api *GradingAPI) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/classes", api.Classes)
+	mux.HandleFunc("/classes/", api.Assignments)
+	mux.HandleFunc("/submissions/", api.Submissions)
+	mux.HandleFunc("/gradebook", api.Gradebook)
+	mux.HandleFunc("/openapi.yaml", OpenAPISpec)
+}
+
+// Classes handles GET /classes?user=<username>, listing the classes a user teaches or is enrolled in. The
+// user defaults to the authenticated caller.
+func (api *GradingAPI) Classes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	user, ok := queryUser(w, r, "user")
+	if !ok {
+		return
+	}
+	if user == "" {
+		writeError(w, http.StatusBadRequest, "the user query parameter is required")
+		return
+	}
+
+	result, err := api.contracts(r).EvaluateTransaction("GetAllClasses", user)
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	classes := []string{}
+	if err := unmarshalResult(result, &classes); err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, classes)
+}
+
+// Assignments handles /classes/{id}/assignments. GET lists the assignments a user currently holds in the
+// class; POST creates an assignment for a student and hands it to them.
+func (api *GradingAPI) Assignments(w http.ResponseWriter, r *http.Request) {
+	class, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/classes/"), "/")
+	if class == "" || rest != "assignments" {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		user, ok := queryUser(w, r, "user")
+		if !ok {
+			return
+		}
+		if user == "" {
+			writeError(w, http.StatusBadRequest, "the user query parameter is required")
+			return
+		}
+		assets, err := api.queryAssets(r, "GetAllAssignments", user, class)
+		if err != nil {
+			writeGatewayError(w, err)
+			return
+		}
+		submissions := make([]Submission, 0, len(assets))
+		for _, asset := range assets {
+			submissions = append(submissions, asset.submission())
+		}
+		writeJSON(w, http.StatusOK, submissions)
+
+	case http.MethodPost:
+		var request NewAssignment
+		if !readJSON(w, r, &request) {
+			return
+		}
+		if request.Title == "" || request.Instructor == "" || request.Student == "" {
+			writeError(w, http.StatusBadRequest, "title, instructor and student are required")
+			return
+		}
+		key, ok := idempotencyKey(w, r)
+		if !ok {
+			return
+		}
+
+		result, err := api.contracts(r).SubmitWithKey(transactionKey(key, "CreateAssignment"), "CreateAssignment", class, request.Title, request.Instructor, request.Student, request.DueDate, request.Description)
+		if err != nil {
+			writeGatewayError(w, err)
+			return
+		}
+		id := string(result)
+		if _, err := api.contracts(r).SubmitWithKey(transactionKey(key, "TransferAsset"), "TransferAsset", id, request.Student); err != nil {
+			writeGatewayError(w, err)
+			return
+		}
+
+		created, err := api.readAsset(r, id)
+		if err != nil {
+			writeGatewayError(w, err)
+			return
+		}
+		w.Header().Set("Location", "/submissions/"+id)
+		writeJSON(w, http.StatusCreated, created.submission())
+
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost)
+	}
+}
+
+// Submissions handles /submissions/{id} and /submissions/{id}/grade. GET returns a submission, PUT records
+// the student's work and returns the submission to the instructor, and POST to the grade resource records a
+// grade and feedback.
+func (api *GradingAPI) Submissions(w http.ResponseWriter, r *http.Request) {
+	id, rest, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/submissions/"), "/")
+	if id == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case rest == "" && r.Method == http.MethodGet:
+		submission, err := api.readAsset(r, id)
+		if err != nil {
+			writeGatewayError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, submission.submission())
+
+	case rest == "" && r.Method == http.MethodPut:
+		var request WorkRequest
+		if !readJSON(w, r, &request) {
+			return
+		}
+		if request.Work == "" {
+			writeError(w, http.StatusBadRequest, "work is required")
+			return
+		}
+		key, ok := idempotencyKey(w, r)
+		if !ok {
+			return
+		}
+
+		current, err := api.readAsset(r, id)
+		if err != nil {
+			writeGatewayError(w, err)
+			return
+		}
+		if _, err := api.contracts(r).SubmitWithKey(transactionKey(key, "SubmitAssignment"), "SubmitAssignment", id, request.Work); err != nil {
+			writeGatewayError(w, err)
+			return
+		}
+		if _, err := api.contracts(r).SubmitWithKey(transactionKey(key, "TransferAsset"), "TransferAsset", id, current.InstructorID); err != nil {
+			writeGatewayError(w, err)
+			return
+		}
+
+		submitted, err := api.readAsset(r, id)
+		if err != nil {
+			writeGatewayError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, submitted.submission())
+
+	case rest == "":
+		methodNotAllowed(w, http.MethodGet, http.MethodPut)
+
+	case rest == "grade" && r.Method == http.MethodPost:
+		var request GradeRequest
+		if !readJSON(w, r, &request) {
+			return
+		}
+		key, ok := idempotencyKey(w, r)
+		if !ok {
+			return
+		}
+
+		result, err := api.contracts(r).SubmitWithKey(transactionKey(key, "GradeAssignment"), "GradeAssignment", id, strconv.Itoa(request.Grade), request.Feedback)
+		if err != nil {
+			writeGatewayError(w, err)
+			return
+		}
+		previous, err := strconv.Atoi(string(result))
+		if err != nil {
+			writeError(w, http.StatusBadGateway, fmt.Sprintf("unexpected previous grade %q", result))
+			return
+		}
+		writeJSON(w, http.StatusOK, GradeResponse{ID: id, Grade: request.Grade, PreviousGrade: previous})
+
+	case rest == "grade":
+		methodNotAllowed(w, http.MethodPost)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Gradebook handles GET /gradebook?instructor=<username>&class=<id>, listing the grades of every submission
+// handed in to the instructor for the class.
+func (api *GradingAPI) Gradebook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	instructor, ok := queryUser(w, r, "instructor")
+	if !ok {
+		return
+	}
+	class := r.URL.Query().Get("class")
+	if instructor == "" || class == "" {
+		writeError(w, http.StatusBadRequest, "the instructor and class query parameters are required")
+		return
+	}
+
+	assets, err := api.queryAssets(r, "GetAllAssets", instructor, class)
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	entries := make([]GradebookEntry, 0, len(assets))
+	for _, asset := range assets {
+		entries = append(entries, GradebookEntry{
+			ID:       asset.ID,
+			Title:    asset.Title,
+			Student:  asset.student(),
+			Group:    asset.GroupID,
+			Grade:    asset.Grade,
+			Released: asset.Released,
+		})
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func (api *GradingAPI) readAsset(r *http.Request, id string) (*asset, error) {
+	result, err := api.contracts(r).EvaluateTransaction("ReadAsset", id)
+	if err != nil {
+		return nil, err
+	}
+	var a asset
+	if err := json.Unmarshal(result, &a); err != nil {
+		return nil, fmt.Errorf("failed to parse asset %s: %w", id, err)
+	}
+	return &a, nil
+}
+
+func (api *GradingAPI) queryAssets(r *http.Request, function string, args ...string) ([]asset, error) {
+	result, err := api.contracts(r).EvaluateTransaction(function, args...)
+	if err != nil {
+		return nil, err
+	}
+	var assets []asset
+	if err := unmarshalResult(result, &assets); err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+// unmarshalResult parses a transaction result, treating an empty result as a nil slice the way the
+// contract API serializes one.
+func unmarshalResult(result []byte, v interface{}) error {
+	if len(result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(result, v); err != nil {
+		return fmt.Errorf("failed to parse transaction result: %w", err)
+	}
+	return nil
+}
+
+// queryUser returns the user a request asks about. Authenticated callers may only ask about themselves, so
+// the query parameter may be left out and is refused with 403 Forbidden if it names anyone else. Without a
+// wallet every request runs as the same identity, and the query parameter picks the user. It returns false
+// once it has written an error response.
+func queryUser(w http.ResponseWriter, r *http.Request, name string) (string, bool) {
+	user := r.URL.Query().Get(name)
+	caller := Caller(r)
+	if caller == "" {
+		return user, true
+	}
+	if user != "" && user != caller {
+		writeError(w, http.StatusForbidden, fmt.Sprintf("the %s query parameter must be the authenticated caller %s", name, caller))
+		return "", false
+	}
+	return caller, true
+}
+
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %s", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Printf("failed to write response: %s\n", err)
+	}
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
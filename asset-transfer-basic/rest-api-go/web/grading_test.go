@@ -0,0 +1,278 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fakeContract records the result of each keyed transaction like the chaincode does, and replays it when
+// the key is used again.
+type fakeContract struct {
+	assets    map[string]asset
+	submitted int
+	recorded  map[string][]byte
+	keys      []string
+}
+
+func newFakeContract() *fakeContract {
+	return &fakeContract{assets: map[string]asset{}, recorded: map[string][]byte{}}
+}
+
+func (f *fakeContract) EvaluateTransaction(name string, args ...string) ([]byte, error) {
+	switch name {
+	case "ReadAsset":
+		a, ok := f.assets[args[0]]
+		if !ok {
+			return nil, chaincodeError("the asset " + args[0] + " does not exist")
+		}
+		return json.Marshal(a)
+	case "GetAllClasses":
+		return nil, nil
+	case "GetAllAssets":
+		var assets []asset
+		for _, a := range f.assets {
+			if a.InstructorID == args[0] && a.Owner == args[0] && a.ClassID == args[1] {
+				assets = append(assets, a)
+			}
+		}
+		return json.Marshal(assets)
+	}
+	return nil, errors.New("unexpected evaluate " + name)
+}
+
+func (f *fakeContract) SubmitWithKey(idempotencyKey string, name string, args ...string) ([]byte, error) {
+	f.keys = append(f.keys, idempotencyKey)
+	request := idempotencyKey + " " + name + " " + strings.Join(args, " ")
+	if result, ok := f.recorded[request]; ok {
+		return result, nil
+	}
+	result, err := f.submit(name, args...)
+	if err == nil {
+		f.recorded[request] = result
+	}
+	return result, err
+}
+
+func (f *fakeContract) submit(name string, args ...string) ([]byte, error) {
+	f.submitted++
+	switch name {
+	case "CreateAssignment":
+		id := args[0] + "-" + args[1] + "-" + args[3]
+		f.assets[id] = asset{ID: id, Title: args[1], InstructorID: args[2], StudentID: args[3], Owner: args[2], Date: args[4], Description: args[5], ClassID: args[0]}
+		return []byte(id), nil
+	case "TransferAsset":
+		a := f.assets[args[0]]
+		a.Owner = args[1]
+		f.assets[args[0]] = a
+		return nil, nil
+	case "SubmitAssignment":
+		a := f.assets[args[0]]
+		a.Work = args[1]
+		f.assets[args[0]] = a
+		return nil, nil
+	case "GradeAssignment":
+		a := f.assets[args[0]]
+		previous := a.Grade
+		a.Grade, _ = strconv.Atoi(args[1])
+		f.assets[args[0]] = a
+		return []byte(strconv.Itoa(previous)), nil
+	}
+	return nil, errors.New("unexpected submit " + name)
+}
+
+func chaincodeError(message string) error {
+	grpcStatus, _ := status.New(codes.Unknown, "evaluate call to endorser returned error: "+message).
+		WithDetails(&gateway.ErrorDetail{Address: "peer0.org1.example.com:7051", MspId: "Org1MSP", Message: message})
+	return grpcStatus.Err()
+}
+
+func serve(api *GradingAPI, method string, target string, body string) *httptest.ResponseRecorder {
+	return serveWithKey(api, method, target, body, "")
+}
+
+func serveWithKey(api *GradingAPI, method string, target string, body string, idempotencyKey string) *httptest.ResponseRecorder {
+	mux := http.NewServeMux()
+	api.Register(mux)
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	if idempotencyKey != "" {
+		request.Header.Set(IdempotencyKeyHeader, idempotencyKey)
+	}
+	mux.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestAssignmentLifecycle(t *testing.T) {
+	contract := newFakeContract()
+	api := NewGradingAPI(func(*http.Request) Contract { return contract })
+
+	response := serve(api, http.MethodPost, "/classes/cs101/assignments",
+		`{"title":"hw1","dueDate":"2022-01-02","description":"first","instructor":"prof","student":"alice"}`)
+	if response.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", response.Code, response.Body)
+	}
+	if location := response.Header().Get("Location"); location != "/submissions/cs101-hw1-alice" {
+		t.Errorf("unexpected Location %q", location)
+	}
+	var created Submission
+	if err := json.Unmarshal(response.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if created.Owner != "alice" || created.Student != "alice" || created.ClassID != "cs101" || created.DueDate != "2022-01-02" {
+		t.Errorf("unexpected submission %+v", created)
+	}
+
+	response = serve(api, http.MethodPut, "/submissions/cs101-hw1-alice", `{"work":"my answer"}`)
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", response.Code, response.Body)
+	}
+	if contract.assets["cs101-hw1-alice"].Owner != "prof" || contract.assets["cs101-hw1-alice"].Work != "my answer" {
+		t.Errorf("expected the work to be handed back to the instructor, got %+v", contract.assets["cs101-hw1-alice"])
+	}
+
+	response = serve(api, http.MethodPost, "/submissions/cs101-hw1-alice/grade", `{"grade":90,"feedback":"good"}`)
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", response.Code, response.Body)
+	}
+	if body := strings.TrimSpace(response.Body.String()); body != `{"id":"cs101-hw1-alice","grade":90,"previousGrade":0}` {
+		t.Errorf("unexpected grade response %s", body)
+	}
+
+	response = serve(api, http.MethodGet, "/gradebook?instructor=prof&class=cs101", "")
+	if body := strings.TrimSpace(response.Body.String()); body != `[{"id":"cs101-hw1-alice","title":"hw1","student":"alice","grade":90,"released":false}]` {
+		t.Errorf("unexpected gradebook %s", body)
+	}
+}
+
+func TestRequestValidation(t *testing.T) {
+	contract := newFakeContract()
+	api := NewGradingAPI(func(*http.Request) Contract { return contract })
+
+	tests := []struct {
+		method string
+		target string
+		body   string
+		status int
+	}{
+		{http.MethodGet, "/classes", "", http.StatusBadRequest},
+		{http.MethodDelete, "/classes", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/classes/cs101/assignments", `{"title":"hw1"}`, http.StatusBadRequest},
+		{http.MethodPost, "/classes/cs101/assignments", `{"colour":"blue"}`, http.StatusBadRequest},
+		{http.MethodGet, "/classes/cs101/students", "", http.StatusNotFound},
+		{http.MethodPut, "/submissions/hw1alice", `{"work":""}`, http.StatusBadRequest},
+		{http.MethodGet, "/submissions/hw1alice/grade", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/submissions/missing", "", http.StatusNotFound},
+		{http.MethodGet, "/gradebook?class=cs101", "", http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		response := serve(api, test.method, test.target, test.body)
+		if response.Code != test.status {
+			t.Errorf("%s %s: expected %d, got %d: %s", test.method, test.target, test.status, response.Code, response.Body)
+		}
+	}
+
+	response := serve(api, http.MethodGet, "/classes?user=alice", "")
+	if body := strings.TrimSpace(response.Body.String()); response.Code != http.StatusOK || body != "[]" {
+		t.Errorf("expected an empty class list, got %d %s", response.Code, body)
+	}
+}
+
+func TestQueryUserAuthenticated(t *testing.T) {
+	contract := newFakeContract()
+	contract.assets["cs101-hw1-alice"] = asset{ID: "cs101-hw1-alice", Title: "hw1", InstructorID: "prof", StudentID: "alice", Owner: "prof", ClassID: "cs101"}
+	api := NewGradingAPI(func(*http.Request) Contract { return contract })
+	mux := http.NewServeMux()
+	api.Register(mux)
+
+	// An authenticated caller always asks about themselves, whatever the query names
+	request := func(caller string, target string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r = r.WithContext(context.WithValue(r.Context(), callerKey, caller))
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, r)
+		return recorder
+	}
+
+	for _, target := range []string{"/gradebook?class=cs101", "/gradebook?instructor=prof&class=cs101"} {
+		response := request("prof", target)
+		if body := strings.TrimSpace(response.Body.String()); response.Code != http.StatusOK || !strings.Contains(body, "cs101-hw1-alice") {
+			t.Errorf("%s: expected prof's gradebook, got %d %s", target, response.Code, body)
+		}
+	}
+	for _, target := range []string{"/gradebook?instructor=prof&class=cs101", "/classes?user=alice", "/classes/cs101/assignments?user=alice"} {
+		if response := request("mallory", target); response.Code != http.StatusForbidden {
+			t.Errorf("%s: expected another caller to be forbidden, got %d %s", target, response.Code, response.Body)
+		}
+	}
+	if response := request("alice", "/classes"); response.Code != http.StatusOK {
+		t.Errorf("expected the user to default to the caller, got %d %s", response.Code, response.Body)
+	}
+}
+
+func TestIdempotentRetry(t *testing.T) {
+	contract := newFakeContract()
+	api := NewGradingAPI(func(*http.Request) Contract { return contract })
+	contract.assets["hw1alice"] = asset{ID: "hw1alice", Title: "hw1", InstructorID: "prof", Owner: "prof", Grade: 70}
+
+	// The first response is lost, and the client retries after another grade was recorded
+	first := serveWithKey(api, http.MethodPost, "/submissions/hw1alice/grade", `{"grade":80}`, "grade-1")
+	serveWithKey(api, http.MethodPost, "/submissions/hw1alice/grade", `{"grade":90}`, "grade-2")
+	retry := serveWithKey(api, http.MethodPost, "/submissions/hw1alice/grade", `{"grade":80}`, "grade-1")
+	if retry.Code != http.StatusOK || retry.Body.String() != first.Body.String() {
+		t.Errorf("expected the retry to return %s, got %d %s", first.Body, retry.Code, retry.Body)
+	}
+	if key := retry.Header().Get(IdempotencyKeyHeader); key != "grade-1" {
+		t.Errorf("expected the key to be echoed, got %q", key)
+	}
+	if contract.keys[0] != "grade-1.GradeAssignment" {
+		t.Errorf("expected the transaction to be keyed by request and name, got %q", contract.keys[0])
+	}
+	if contract.submitted != 2 || contract.assets["hw1alice"].Grade != 90 {
+		t.Errorf("expected the retry not to be applied, got %d transactions and grade %d", contract.submitted, contract.assets["hw1alice"].Grade)
+	}
+
+	// Without a header, each request gets a new key the client can retry with
+	generated := serve(api, http.MethodPost, "/submissions/hw1alice/grade", `{"grade":95}`).Header().Get(IdempotencyKeyHeader)
+	if !idempotencyKeyPattern.MatchString(generated) {
+		t.Fatalf("expected a generated key, got %q", generated)
+	}
+	if again := serve(api, http.MethodPost, "/submissions/hw1alice/grade", `{"grade":95}`).Header().Get(IdempotencyKeyHeader); again == generated {
+		t.Errorf("expected a new key for each request, got %q twice", again)
+	}
+
+	response := serveWithKey(api, http.MethodPost, "/submissions/hw1alice/grade", `{"grade":95}`, "not a key")
+	if response.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an invalid key, got %d: %s", response.Code, response.Body)
+	}
+}
+
+func TestGatewayErrorStatus(t *testing.T) {
+	tests := []struct {
+		err     error
+		status  int
+		message string
+	}{
+		{chaincodeError("the asset hw1alice does not exist"), http.StatusNotFound, "the asset hw1alice does not exist"},
+		{chaincodeError("the asset hw1alice already exists"), http.StatusConflict, "the asset hw1alice already exists"},
+		{chaincodeError("the attachment name must not be empty"), http.StatusBadRequest, "the attachment name must not be empty"},
+		{status.Error(codes.Unavailable, "no peers available"), http.StatusServiceUnavailable, "no peers available"},
+		{status.Error(codes.DeadlineExceeded, "timed out"), http.StatusGatewayTimeout, "timed out"},
+		{errors.New("failed to parse asset"), http.StatusInternalServerError, "failed to parse asset"},
+	}
+	for _, test := range tests {
+		httpStatus, response := gatewayErrorResponse(test.err)
+		if httpStatus != test.status || response.Error != test.message {
+			t.Errorf("%v: expected %d %q, got %d %q", test.err, test.status, test.message, httpStatus, response.Error)
+		}
+	}
+}
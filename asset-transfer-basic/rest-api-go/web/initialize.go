@@ -21,7 +21,22 @@ func Initialize(setup OrgSetup) (*OrgSetup, error) {
 	id := setup.newIdentity()
 	sign := setup.newSign()
 
-	gateway, err := client.Connect(
+	gateway, err := connect(clientConnection, id, sign)
+	if err != nil {
+		panic(err)
+	}
+	setup.Gateway = *gateway
+	setup.connection = clientConnection
+	setup.gateways = &gatewayCache{gateways: map[string]*client.Gateway{}}
+	setup.transactions = newTransactionStore()
+	log.Println("Initialization complete")
+	return &setup, nil
+}
+
+// connect opens a Gateway for an identity over an existing gRPC connection, so that any number of
+// identities can share one connection to the peer.
+func connect(clientConnection *grpc.ClientConn, id identity.Identity, sign identity.Sign) (*client.Gateway, error) {
+	return client.Connect(
 		id,
 		client.WithSign(sign),
 		client.WithClientConnection(clientConnection),
@@ -30,12 +45,6 @@ func Initialize(setup OrgSetup) (*OrgSetup, error) {
 		client.WithSubmitTimeout(5*time.Second),
 		client.WithCommitStatusTimeout(1*time.Minute),
 	)
-	if err != nil {
-		panic(err)
-	}
-	setup.Gateway = *gateway
-	log.Println("Initialization complete")
-	return &setup, nil
 }
 
 // newGrpcConnection creates a gRPC connection to the Gateway server.
@@ -59,42 +68,50 @@ func (setup OrgSetup) newGrpcConnection() *grpc.ClientConn {
 
 // newIdentity creates a client identity for this Gateway connection using an X.509 certificate.
 func (setup OrgSetup) newIdentity() *identity.X509Identity {
-	certificate, err := loadCertificate(setup.CertPath)
-	if err != nil {
-		panic(err)
-	}
-
-	id, err := identity.NewX509Identity(setup.MSPID, certificate)
+	id, err := loadIdentity(setup.MSPID, setup.CertPath)
 	if err != nil {
 		panic(err)
 	}
-
 	return id
 }
 
 // newSign creates a function that generates a digital signature from a message digest using a private key.
 func (setup OrgSetup) newSign() identity.Sign {
-	files, err := ioutil.ReadDir(setup.KeyPath)
+	sign, err := loadSign(setup.KeyPath)
 	if err != nil {
-		panic(fmt.Errorf("failed to read private key directory: %w", err))
+		panic(err)
 	}
-	privateKeyPEM, err := ioutil.ReadFile(path.Join(setup.KeyPath, files[0].Name()))
+	return sign
+}
 
+// loadIdentity reads the X.509 identity of an MSP member from its certificate file.
+func loadIdentity(mspID string, certPath string) (*identity.X509Identity, error) {
+	certificate, err := loadCertificate(certPath)
 	if err != nil {
-		panic(fmt.Errorf("failed to read private key file: %w", err))
+		return nil, err
 	}
+	return identity.NewX509Identity(mspID, certificate)
+}
 
-	privateKey, err := identity.PrivateKeyFromPEM(privateKeyPEM)
+// loadSign creates a signing function from the first private key in a keystore directory.
+func loadSign(keyPath string) (identity.Sign, error) {
+	files, err := ioutil.ReadDir(keyPath)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to read private key directory: %w", err)
 	}
-
-	sign, err := identity.NewPrivateKeySign(privateKey)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no private key in %s", keyPath)
+	}
+	privateKeyPEM, err := ioutil.ReadFile(path.Join(keyPath, files[0].Name()))
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to read private key file: %w", err)
 	}
 
-	return sign
+	privateKey, err := identity.PrivateKeyFromPEM(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	return identity.NewPrivateKeySign(privateKey)
 }
 
 func loadCertificate(filename string) (*x509.Certificate, error) {
@@ -1,40 +1,76 @@
 package web
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"strconv"
 
+	"assetTransfer/graderr"
 	"github.com/hyperledger/fabric-gateway/pkg/client"
 )
 
-// Invoke handles chaincode invoke requests.
+// Invoke handles chaincode invoke requests. By default it waits for the transaction to commit; with
+// async=true it responds 202 as soon as the transaction is sent to the orderer, and the outcome can be
+// polled at the returned /transactions/{txid} location. The transaction carries the request's
+// Idempotency-Key, so retrying a request with the key the client chose does not apply it twice.
 func (setup *OrgSetup) Invoke(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("Received Invoke request")
 	if err := r.ParseForm(); err != nil {
-		fmt.Fprintf(w, "ParseForm() err: %s", err)
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("ParseForm() err: %s", err))
 		return
 	}
 	chainCodeName := r.FormValue("chaincodeid")
 	channelID := r.FormValue("channelid")
 	function := r.FormValue("function")
 	args := r.Form["args"]
-	fmt.Printf("channel: %s, chaincode: %s, function: %s, args: %s\n", channelID, chainCodeName, function, args)
-	network := setup.Gateway.GetNetwork(channelID)
+	async, _ := strconv.ParseBool(r.FormValue("async"))
+	key, ok := idempotencyKey(w, r)
+	if !ok {
+		return
+	}
+	fmt.Printf("channel: %s, chaincode: %s, function: %s, args: %s, async: %t\n", channelID, chainCodeName, function, args, async)
+	network := setup.requestGateway(r).GetNetwork(channelID)
 	contract := network.GetContract(chainCodeName)
-	txn_proposal, err := contract.NewProposal(function, client.WithArguments(args...))
+	txn_proposal, err := contract.NewProposal(function, client.WithArguments(args...), withIdempotencyKey(key))
 	if err != nil {
-		fmt.Fprintf(w, "Error creating txn proposal: %s", err)
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Error creating txn proposal: %s", err))
 		return
 	}
 	txn_endorsed, err := txn_proposal.Endorse()
 	if err != nil {
-		fmt.Fprintf(w, "Error endorsing txn: %s", err)
+		writeGatewayError(w, err)
 		return
 	}
 	txn_committed, err := txn_endorsed.Submit()
 	if err != nil {
-		fmt.Fprintf(w, "Error submitting transaction: %s", err)
+		writeGatewayError(w, err)
+		return
+	}
+
+	response := TransactionResponse{
+		TransactionID: txn_committed.TransactionID(),
+		Status:        TransactionPending,
+		Result:        string(txn_endorsed.Result()),
+	}
+	if async {
+		setup.transactions.add(Caller(r), response, func(ctx context.Context) (*client.Status, error) {
+			return txn_committed.StatusWithContext(ctx)
+		})
+		w.Header().Set("Location", "/transactions/"+response.TransactionID)
+		writeJSON(w, http.StatusAccepted, response)
 		return
 	}
-	fmt.Fprintf(w, "Transaction ID : %s Response: %s", txn_committed.TransactionID(), txn_endorsed.Result())
+
+	status, err := txn_committed.StatusWithContext(r.Context())
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	response = committedResponse(response, status)
+	if status.Successful {
+		writeJSON(w, http.StatusOK, response)
+	} else {
+		writeJSON(w, graderr.ForValidationCode(status.Code).HTTPStatus(), response)
+	}
 }
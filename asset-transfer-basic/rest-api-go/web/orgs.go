@@ -0,0 +1,144 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+// OrgHeader names the organization a request is for when it does not use an /orgs/{name} path prefix.
+const OrgHeader = "X-Fabric-Org"
+
+// healthTimeout bounds how long a health check waits for an idle connection to the peer to come up.
+var healthTimeout = 3 * time.Second
+
+// LoadOrgSetups reads the organizations to serve from a JSON file holding an array of OrgSetup objects.
+func LoadOrgSetups(path string) ([]OrgSetup, error) {
+	configJSON, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read organization config: %w", err)
+	}
+	var setups []OrgSetup
+	if err := json.Unmarshal(configJSON, &setups); err != nil {
+		return nil, fmt.Errorf("failed to parse organization config %s: %w", path, err)
+	}
+	if len(setups) == 0 {
+		return nil, fmt.Errorf("no organizations in %s", path)
+	}
+
+	names := map[string]bool{}
+	for _, setup := range setups {
+		name := strings.ToLower(setup.OrgName)
+		if name == "" {
+			return nil, fmt.Errorf("organization with MSP ID %q in %s has no name", setup.MSPID, path)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("organization %s appears more than once in %s", setup.OrgName, path)
+		}
+		names[name] = true
+	}
+	return setups, nil
+}
+
+// OrgRouter sends each request to the handler of the organization it names, either with an /orgs/{name}
+// path prefix or the X-Fabric-Org header. Requests naming no organization go to the first one.
+type OrgRouter struct {
+	orgs       map[string]http.Handler
+	defaultOrg http.Handler
+}
+
+// NewOrgRouter returns a router over the handlers of setups.
+func NewOrgRouter(setups ...*OrgSetup) *OrgRouter {
+	router := &OrgRouter{orgs: map[string]http.Handler{}}
+	for _, setup := range setups {
+		handler := setup.Handler()
+		router.orgs[strings.ToLower(setup.OrgName)] = handler
+		if router.defaultOrg == nil {
+			router.defaultOrg = handler
+		}
+	}
+	return router
+}
+
+func (router *OrgRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if rest, ok := cutPathPrefix(r.URL.Path, "/orgs/"); ok {
+		name, path, _ := strings.Cut(rest, "/")
+		handler, found := router.orgs[strings.ToLower(name)]
+		if !found {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("unknown organization %s", name))
+			return
+		}
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = "/" + path
+		r2.URL.RawPath = ""
+		handler.ServeHTTP(w, r2)
+		return
+	}
+
+	if name := r.Header.Get(OrgHeader); name != "" {
+		handler, found := router.orgs[strings.ToLower(name)]
+		if !found {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("unknown organization %s", name))
+			return
+		}
+		handler.ServeHTTP(w, r)
+		return
+	}
+
+	if router.defaultOrg == nil {
+		http.NotFound(w, r)
+		return
+	}
+	router.defaultOrg.ServeHTTP(w, r)
+}
+
+func cutPathPrefix(path string, prefix string) (string, bool) {
+	if !strings.HasPrefix(path, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(path, prefix), true
+}
+
+// HealthResponse reports the state of an organization's connection to its gateway peer.
+type HealthResponse struct {
+	Org          string `json:"org"`
+	PeerEndpoint string `json:"peerEndpoint"`
+	State        string `json:"state"`
+	Healthy      bool   `json:"healthy"`
+}
+
+// Health handles GET /health, reporting 200 if the organization's gRPC connection to its peer is ready and
+// 503 otherwise. An idle connection is woken up and given a few seconds to connect.
+func (setup *OrgSetup) Health(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	response := HealthResponse{Org: setup.OrgName, PeerEndpoint: setup.PeerEndpoint, State: "NOT_CONNECTED"}
+	if setup.connection != nil {
+		state := setup.connection.GetState()
+		if state != connectivity.Ready {
+			setup.connection.Connect()
+			ctx, cancel := context.WithTimeout(r.Context(), healthTimeout)
+			for state != connectivity.Ready && setup.connection.WaitForStateChange(ctx, state) {
+				state = setup.connection.GetState()
+			}
+			cancel()
+		}
+		response.State = state.String()
+		response.Healthy = state == connectivity.Ready
+	}
+
+	if response.Healthy {
+		writeJSON(w, http.StatusOK, response)
+	} else {
+		writeJSON(w, http.StatusServiceUnavailable, response)
+	}
+}
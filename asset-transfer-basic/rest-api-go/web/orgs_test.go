@@ -0,0 +1,104 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func newUnreachableOrg(t *testing.T, name string) *OrgSetup {
+	t.Helper()
+	// Nothing listens on port 1, so the connection can never become ready
+	connection, err := grpc.Dial("localhost:1", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { connection.Close() })
+	return &OrgSetup{
+		OrgName:      name,
+		PeerEndpoint: "localhost:1",
+		WalletPath:   t.TempDir(),
+		connection:   connection,
+		gateways:     &gatewayCache{gateways: map[string]*client.Gateway{}},
+	}
+}
+
+func TestOrgRouter(t *testing.T) {
+	previousTimeout := healthTimeout
+	healthTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { healthTimeout = previousTimeout })
+
+	router := NewOrgRouter(newUnreachableOrg(t, "Org1"), newUnreachableOrg(t, "Org2"))
+
+	request := func(target string, org string) (int, HealthResponse) {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		if org != "" {
+			r.Header.Set(OrgHeader, org)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, r)
+		var health HealthResponse
+		json.Unmarshal(recorder.Body.Bytes(), &health)
+		return recorder.Code, health
+	}
+
+	tests := []struct {
+		target string
+		header string
+		org    string
+	}{
+		{"/health", "", "Org1"},
+		{"/orgs/org2/health", "", "Org2"},
+		{"/orgs/Org1/health", "Org2", "Org1"},
+		{"/health", "ORG2", "Org2"},
+	}
+	for _, test := range tests {
+		status, health := request(test.target, test.header)
+		if status != http.StatusServiceUnavailable || health.Org != test.org || health.Healthy {
+			t.Errorf("%s (%s): expected unhealthy %s, got %d %+v", test.target, test.header, test.org, status, health)
+		}
+	}
+
+	if status, _ := request("/orgs/org3/health", ""); status != http.StatusNotFound {
+		t.Errorf("expected an unknown organization in the path to be rejected, got %d", status)
+	}
+	if status, _ := request("/health", "Org3"); status != http.StatusNotFound {
+		t.Errorf("expected an unknown organization in the header to be rejected, got %d", status)
+	}
+	if status, _ := request("/orgs/org2/classes", ""); status != http.StatusUnauthorized {
+		t.Errorf("expected organization routes other than health to require authentication, got %d", status)
+	}
+}
+
+func TestLoadOrgSetups(t *testing.T) {
+	dir := t.TempDir()
+	write := func(config string) string {
+		path := filepath.Join(dir, "orgs.json")
+		if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	setups, err := LoadOrgSetups(write(`[{"OrgName":"Org1","MSPID":"Org1MSP","PeerEndpoint":"localhost:7051"},{"OrgName":"Org2","MSPID":"Org2MSP"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(setups) != 2 || setups[0].MSPID != "Org1MSP" || setups[0].PeerEndpoint != "localhost:7051" || setups[1].OrgName != "Org2" {
+		t.Errorf("unexpected setups %+v", setups)
+	}
+
+	for _, config := range []string{`[]`, `[{"MSPID":"Org1MSP"}]`, `[{"OrgName":"Org1"},{"OrgName":"org1"}]`, `{`} {
+		if _, err := LoadOrgSetups(write(config)); err == nil {
+			t.Errorf("expected %s to be rejected", config)
+		}
+	}
+}
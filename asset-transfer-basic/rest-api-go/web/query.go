@@ -14,7 +14,7 @@ func (setup OrgSetup) Query(w http.ResponseWriter, r *http.Request) {
 	function := queryParams.Get("function")
 	args := r.URL.Query()["args"]
 	fmt.Printf("channel: %s, chaincode: %s, function: %s, args: %s\n", channelID, chainCodeName, function, args)
-	network := setup.Gateway.GetNetwork(channelID)
+	network := setup.requestGateway(r).GetNetwork(channelID)
 	contract := network.GetContract(chainCodeName)
 	evaluateResponse, err := contract.EvaluateTransaction(function, args...)
 	if err != nil {
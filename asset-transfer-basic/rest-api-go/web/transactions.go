@@ -0,0 +1,198 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"github.com/hyperledger/fabric-protos-go-apiv2/msp"
+	"google.golang.org/protobuf/proto"
+)
+
+// Transaction states reported by the transactions endpoint.
+const (
+	TransactionPending = "pending"
+	TransactionValid   = "valid"
+	TransactionInvalid = "invalid"
+)
+
+// statusPollTimeout bounds how long a status request waits for a pending transaction to commit.
+var statusPollTimeout = 1 * time.Second
+
+// transactionRetention is how long a transaction submitted through the server stays tracked. Older
+// transactions are looked up on the gateway like any other.
+const transactionRetention = time.Hour
+
+// transactionIDPattern matches a transaction ID as generated by the gateway client, a hex SHA-256 digest.
+var transactionIDPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+// TransactionResponse reports a submitted transaction and, once known, its validation outcome.
+type TransactionResponse struct {
+	TransactionID string `json:"transactionId"`
+	Status        string `json:"status"`
+	// Code is the peer validation code, such as VALID or MVCC_READ_CONFLICT, once the transaction commits
+	Code        string `json:"code,omitempty"`
+	BlockNumber uint64 `json:"blockNumber,omitempty"`
+	Result      string `json:"result,omitempty"`
+}
+
+// commitStatusFunc waits for the commit status of a transaction until ctx is done.
+type commitStatusFunc func(ctx context.Context) (*client.Status, error)
+
+type trackedTransaction struct {
+	caller    string
+	status    commitStatusFunc
+	response  TransactionResponse
+	added     time.Time
+	committed time.Time
+}
+
+// transactionStore tracks the transactions submitted asynchronously through this server, so callers can poll
+// for their outcome along with the result returned at submission. Other transactions, such as those
+// submitted through another instance or before a restart, are looked up on the gateway.
+type transactionStore struct {
+	mu           sync.Mutex
+	transactions map[string]*trackedTransaction
+}
+
+func newTransactionStore() *transactionStore {
+	return &transactionStore{transactions: map[string]*trackedTransaction{}}
+}
+
+func (store *transactionStore) add(caller string, response TransactionResponse, status commitStatusFunc) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.evict()
+	store.transactions[response.TransactionID] = &trackedTransaction{caller: caller, status: status, response: response, added: time.Now()}
+}
+
+// evict forgets the transactions tracked for longer than transactionRetention, pending or not. It must be
+// called with mu held.
+func (store *transactionStore) evict() {
+	for id, transaction := range store.transactions {
+		if time.Since(transaction.added) > transactionRetention {
+			delete(store.transactions, id)
+		}
+	}
+}
+
+// lookup returns the current state of a transaction. A transaction caller submitted through this server
+// is checked with the peer until it is seen to commit; any other is checked with untracked, which reports
+// its commit status but not its result. It returns false if the transaction is neither tracked for caller
+// nor a well-formed transaction ID.
+func (store *transactionStore) lookup(ctx context.Context, caller string, transactionID string, untracked commitStatusFunc) (TransactionResponse, bool, error) {
+	store.mu.Lock()
+	store.evict()
+	transaction, ok := store.transactions[transactionID]
+	if !ok || transaction.caller != caller {
+		store.mu.Unlock()
+		if !transactionIDPattern.MatchString(transactionID) {
+			return TransactionResponse{}, false, nil
+		}
+		transaction = &trackedTransaction{status: untracked, response: TransactionResponse{TransactionID: transactionID, Status: TransactionPending}}
+		response, err := pollCommitStatus(ctx, transaction)
+		return response, true, err
+	}
+	response, committed := transaction.response, !transaction.committed.IsZero()
+	store.mu.Unlock()
+	if committed {
+		return response, true, nil
+	}
+
+	response, err := pollCommitStatus(ctx, transaction)
+	if err != nil || response.Status == TransactionPending {
+		return response, true, err
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	transaction.response = response
+	transaction.committed = time.Now()
+	return response, true, nil
+}
+
+// pollCommitStatus waits up to statusPollTimeout for a transaction to commit, returning it still pending if
+// it does not.
+func pollCommitStatus(ctx context.Context, transaction *trackedTransaction) (TransactionResponse, error) {
+	response := transaction.response
+
+	pollCtx, cancel := context.WithTimeout(ctx, statusPollTimeout)
+	defer cancel()
+	status, err := transaction.status(pollCtx)
+	if err != nil {
+		if pollCtx.Err() != nil {
+			return response, nil
+		}
+		return response, err
+	}
+
+	return committedResponse(response, status), nil
+}
+
+// gatewayCommitStatus returns the commit status of any transaction on a channel, as the gateway reports it
+// to the identity gw connects as.
+func gatewayCommitStatus(gw *client.Gateway, channel string, transactionID string) commitStatusFunc {
+	return func(ctx context.Context) (*client.Status, error) {
+		id := gw.Identity()
+		creator, err := proto.Marshal(&msp.SerializedIdentity{Mspid: id.MspID(), IdBytes: id.Credentials()})
+		if err != nil {
+			return nil, err
+		}
+		request, err := proto.Marshal(&gateway.CommitStatusRequest{ChannelId: channel, TransactionId: transactionID, Identity: creator})
+		if err != nil {
+			return nil, err
+		}
+		signedRequest, err := proto.Marshal(&gateway.SignedCommitStatusRequest{Request: request})
+		if err != nil {
+			return nil, err
+		}
+		commit, err := gw.NewCommit(signedRequest)
+		if err != nil {
+			return nil, err
+		}
+		return commit.StatusWithContext(ctx)
+	}
+}
+
+func committedResponse(response TransactionResponse, status *client.Status) TransactionResponse {
+	response.Status = TransactionInvalid
+	if status.Successful {
+		response.Status = TransactionValid
+	}
+	response.Code = status.Code.String()
+	response.BlockNumber = status.BlockNumber
+	return response
+}
+
+// Transactions handles GET /transactions/{txid}, reporting whether a transaction is still pending or committed
+// as valid or invalid. The result is included for transactions the caller submitted asynchronously through
+// this server within transactionRetention. A transaction the peer has not seen is reported pending.
+func (setup *OrgSetup) Transactions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	transactionID := strings.TrimPrefix(r.URL.Path, "/transactions/")
+	if transactionID == "" || strings.Contains(transactionID, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	untracked := gatewayCommitStatus(setup.requestGateway(r), setup.ChannelName, transactionID)
+	response, found, err := setup.transactions.lookup(r.Context(), Caller(r), transactionID, untracked)
+	if !found {
+		writeError(w, http.StatusNotFound, "unknown transaction "+transactionID)
+		return
+	}
+	if err != nil {
+		writeGatewayError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,104 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+)
+
+// fakeCommit reports its status once committed is closed, and blocks until then.
+type fakeCommit struct {
+	committed chan struct{}
+	status    *client.Status
+}
+
+func (commit *fakeCommit) wait(ctx context.Context) (*client.Status, error) {
+	select {
+	case <-commit.committed:
+		return commit.status, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestTransactionStatus(t *testing.T) {
+	previousTimeout := statusPollTimeout
+	statusPollTimeout = 10 * time.Millisecond
+	t.Cleanup(func() { statusPollTimeout = previousTimeout })
+
+	setup := &OrgSetup{transactions: newTransactionStore()}
+	valid := &fakeCommit{committed: make(chan struct{}), status: &client.Status{Code: peer.TxValidationCode_VALID, Successful: true, BlockNumber: 7}}
+	conflict := &fakeCommit{committed: make(chan struct{}), status: &client.Status{Code: peer.TxValidationCode_MVCC_READ_CONFLICT}}
+	setup.transactions.add("", TransactionResponse{TransactionID: "tx1", Status: TransactionPending, Result: "90"}, valid.wait)
+	setup.transactions.add("", TransactionResponse{TransactionID: "tx2", Status: TransactionPending}, conflict.wait)
+	setup.transactions.add("alice", TransactionResponse{TransactionID: "tx3", Status: TransactionPending}, valid.wait)
+
+	get := func(transactionID string) (int, TransactionResponse) {
+		recorder := httptest.NewRecorder()
+		setup.Transactions(recorder, httptest.NewRequest(http.MethodGet, "/transactions/"+transactionID, nil))
+		var response TransactionResponse
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		return recorder.Code, response
+	}
+
+	if status, response := get("tx1"); status != http.StatusOK || response != (TransactionResponse{TransactionID: "tx1", Status: TransactionPending, Result: "90"}) {
+		t.Errorf("expected tx1 to be pending, got %d %+v", status, response)
+	}
+
+	close(valid.committed)
+	close(conflict.committed)
+	if status, response := get("tx1"); status != http.StatusOK || response != (TransactionResponse{TransactionID: "tx1", Status: TransactionValid, Code: "VALID", BlockNumber: 7, Result: "90"}) {
+		t.Errorf("expected tx1 to be valid, got %d %+v", status, response)
+	}
+	if status, response := get("tx2"); status != http.StatusOK || response.Status != TransactionInvalid || response.Code != "MVCC_READ_CONFLICT" {
+		t.Errorf("expected tx2 to be invalid, got %d %+v", status, response)
+	}
+
+	if status, _ := get("tx4"); status != http.StatusNotFound {
+		t.Errorf("expected a malformed transaction ID to be reported missing, got %d", status)
+	}
+}
+
+func TestUntrackedTransactionStatus(t *testing.T) {
+	previousTimeout := statusPollTimeout
+	statusPollTimeout = 10 * time.Millisecond
+	t.Cleanup(func() { statusPollTimeout = previousTimeout })
+
+	store := newTransactionStore()
+	tracked := &fakeCommit{committed: make(chan struct{})}
+	aliceTransactionID := strings.Repeat("cd", 32)
+	store.add("alice", TransactionResponse{TransactionID: aliceTransactionID, Status: TransactionPending, Result: "90"}, tracked.wait)
+	gateway := &fakeCommit{committed: make(chan struct{}), status: &client.Status{Code: peer.TxValidationCode_VALID, Successful: true, BlockNumber: 9}}
+	transactionID := strings.Repeat("ab", 32)
+
+	// A transaction submitted elsewhere is read from the gateway, without its result
+	response, found, err := store.lookup(context.Background(), "", transactionID, gateway.wait)
+	if !found || err != nil || response != (TransactionResponse{TransactionID: transactionID, Status: TransactionPending}) {
+		t.Errorf("expected the untracked transaction to be pending, got %t %v %+v", found, err, response)
+	}
+	close(gateway.committed)
+	response, found, err = store.lookup(context.Background(), "", transactionID, gateway.wait)
+	if !found || err != nil || response != (TransactionResponse{TransactionID: transactionID, Status: TransactionValid, Code: "VALID", BlockNumber: 9}) {
+		t.Errorf("expected the untracked transaction to be valid, got %t %v %+v", found, err, response)
+	}
+
+	// Another caller's transaction does not reveal its result
+	response, _, _ = store.lookup(context.Background(), "", aliceTransactionID, gateway.wait)
+	if response.Status != TransactionValid || response.Result != "" {
+		t.Errorf("expected another caller's transaction to be read from the gateway, got %+v", response)
+	}
+
+	// Transactions are forgotten once older than the retention, even if still pending
+	store.transactions[aliceTransactionID].added = time.Now().Add(-2 * transactionRetention)
+	store.add("alice", TransactionResponse{TransactionID: "tx5", Status: TransactionPending}, tracked.wait)
+	if _, ok := store.transactions[aliceTransactionID]; ok || len(store.transactions) != 1 {
+		t.Errorf("expected the stale pending transaction to be evicted, got %d tracked", len(store.transactions))
+	}
+}
@@ -0,0 +1,94 @@
+package web
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+)
+
+// usernamePattern limits wallet user names to characters that cannot escape the wallet directory.
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9@_-][A-Za-z0-9@._-]*$`)
+
+// errUnknownUser is returned for callers with no identity in the wallet.
+var errUnknownUser = errors.New("unknown user")
+
+// Wallet is a directory holding the Fabric identity of every user allowed to call the REST server. Each user
+// has a folder laid out like the msp folder produced by the Fabric CA client:
+//
+//	<wallet>/<username>/signcerts/cert.pem
+//	<wallet>/<username>/keystore/<private key>
+//	<wallet>/<username>/token.sha256    hex SHA-256 of the user's bearer token, if they use one
+type Wallet struct {
+	dir string
+}
+
+// NewWallet returns the wallet stored in dir.
+func NewWallet(dir string) *Wallet {
+	return &Wallet{dir: dir}
+}
+
+func (wallet *Wallet) userDir(username string) (string, error) {
+	if !usernamePattern.MatchString(username) {
+		return "", errUnknownUser
+	}
+	dir := filepath.Join(wallet.dir, username)
+	if _, err := os.Stat(dir); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", errUnknownUser
+		}
+		return "", err
+	}
+	return dir, nil
+}
+
+// Identity returns the X.509 identity and signer of a user.
+func (wallet *Wallet) Identity(username string, mspID string) (*identity.X509Identity, identity.Sign, error) {
+	dir, err := wallet.userDir(username)
+	if err != nil {
+		return nil, nil, err
+	}
+	id, err := loadIdentity(mspID, filepath.Join(dir, "signcerts", "cert.pem"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load identity of %s: %w", username, err)
+	}
+	sign, err := loadSign(filepath.Join(dir, "keystore"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load private key of %s: %w", username, err)
+	}
+	return id, sign, nil
+}
+
+// UserForToken returns the user whose bearer token hash matches token.
+func (wallet *Wallet) UserForToken(token string) (string, error) {
+	digest := sha256.Sum256([]byte(token))
+	entries, err := os.ReadDir(wallet.dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read wallet: %w", err)
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		stored, err := os.ReadFile(filepath.Join(wallet.dir, entry.Name(), "token.sha256"))
+		if err != nil {
+			continue
+		}
+		expected, err := hex.DecodeString(strings.TrimSpace(string(stored)))
+		if err != nil {
+			continue
+		}
+		if subtle.ConstantTimeCompare(expected, digest[:]) == 1 {
+			return entry.Name(), nil
+		}
+	}
+	return "", errUnknownUser
+}